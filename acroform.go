@@ -0,0 +1,675 @@
+package gofpdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field flags as defined in the pdf specification (section 12.7.3.1 and
+// following of ISO 32000-1).
+const (
+	formFlagReadOnly    = 1 << 0
+	formFlagRequired    = 1 << 1
+	formFlagMultiline   = 1 << 12
+	formFlagPassword    = 1 << 13
+	formFlagNoToggleOff = 1 << 14
+	formFlagRadio       = 1 << 15
+	formFlagPushbutton  = 1 << 16
+	formFlagCombo       = 1 << 17
+	formFlagEdit        = 1 << 18
+)
+
+// FormFieldOptions specifies the properties of an interactive form field
+// added with AddTextField, AddCheckBox, AddRadioGroup, AddChoiceField or
+// AddPushButton. The font, text color, draw color, fill color and line width
+// in effect when the field is added are used for its appearance.
+type FormFieldOptions struct {
+	// Name is the name of the field. It must be unique within the document
+	// and may not contain a period. If empty, a name is generated.
+	Name string
+	// Value is the current value of the field. For radio groups it is the
+	// value of the selected button.
+	Value string
+	// Default is the value the field takes when the form is reset.
+	Default string
+	// Tooltip is the text that viewers display when hovering over the field.
+	Tooltip string
+	// ReadOnly prevents the user from changing the value of the field.
+	ReadOnly bool
+	// Required indicates that the field must have a value when the form is
+	// submitted.
+	Required bool
+	// MaxLen is the maximum number of characters of a text field. Zero
+	// means no limit.
+	MaxLen int
+	// Multiline allows a text field to contain several lines of text.
+	Multiline bool
+	// Password causes a text field to hide the characters that are typed.
+	Password bool
+	// Editable allows a combo box to accept values that are not listed in
+	// its options.
+	Editable bool
+	// Align is the horizontal alignment of text: "L" (default), "C" or "R".
+	Align string
+	// Border draws a border around the field with the current draw color
+	// and line width.
+	Border bool
+	// Fill paints the background of the field with the current fill color.
+	Fill bool
+	// TabOrder sets the position of the field in the tab order of its page.
+	// Fields with the same value keep the order in which they were added.
+	TabOrder int
+	// JavaScript is run when a push button is activated.
+	JavaScript string
+}
+
+// RadioButton specifies the position and value of one button of a radio
+// group. See AddRadioGroup.
+type RadioButton struct {
+	X, Y  float64 // upper left corner of the button
+	Size  float64 // width and height of the button
+	Value string  // value of the group when this button is selected
+}
+
+type formField struct {
+	ft       string // field type: Tx, Btn or Ch
+	flags    int
+	name     string
+	value    string
+	defValue string
+	tooltip  string
+	maxLen   int
+	align    int      // quadding: 0 left, 1 centered, 2 right
+	da       string   // default appearance, empty for fields without text
+	opts     []string // choice field options
+	js       string   // push button action
+	utf8     bool     // strings are UTF-8 encoded
+	ref      int      // object number of the value of a signature field
+	kids     []*formWidget
+	objNum   int
+}
+
+type formAppearance struct {
+	state   string // appearance state; empty for fields with a single appearance
+	content []byte
+	objNum  int
+}
+
+type formWidget struct {
+	field    *formField
+	kid      bool // widget is a kid of field rather than merged with it
+	x, y     float64
+	w, h     float64 // rectangle in points, lower left origin
+	state    string  // current appearance state
+	mk       string  // border and background colors
+	caption  string  // normal caption
+	ap       []formAppearance
+	tabOrder int
+	objNum   int
+}
+
+type formRecType struct {
+	fields []*formField
+	names  map[string]bool
+}
+
+// formStyle holds the font and colors in effect when a field is added
+type formStyle struct {
+	fontPt float64
+	text   string // text color operator
+	stroke string // text color as stroking operator
+	border string // border color operator, empty for no border
+	fill   string // background color operator, empty for no background
+	lineWd float64
+}
+
+// pdfName escapes s for use as a pdf name object, without the leading slash
+func pdfName(s string) string {
+	var b strings.Builder
+	for j := 0; j < len(s); j++ {
+		c := s[j]
+		if c < 0x21 || c > 0x7e || strings.IndexByte("()<>[]{}/%#", c) >= 0 {
+			fmt.Fprintf(&b, "#%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// colorArray returns the components of clr as a pdf array
+func colorArray(clr colorType) string {
+	return "[" + clr.components() + "]"
+}
+
+// formAddField validates and registers a new field. It returns false if the
+// field cannot be added.
+func (f *Fpdf) formAddField(fld *formField, opt FormFieldOptions, needFont bool) bool {
+	if f.err != nil {
+		return false
+	}
+	if f.page < 1 {
+		f.err = fmt.Errorf("form field must be added to a page")
+		return false
+	}
+	if needFont && f.currentFont.Name == "" {
+		f.err = fmt.Errorf("font must be set before adding form field")
+		return false
+	}
+	if f.form.names == nil {
+		f.form.names = make(map[string]bool)
+	}
+	fld.name = opt.Name
+	if fld.name == "" {
+		fld.name = sprintf("field%d", len(f.form.fields)+1)
+	}
+	if strings.Contains(fld.name, ".") {
+		f.err = fmt.Errorf("form field name %s may not contain a period", fld.name)
+		return false
+	}
+	if f.form.names[fld.name] {
+		f.err = fmt.Errorf("form field %s is already defined", fld.name)
+		return false
+	}
+	f.form.names[fld.name] = true
+	fld.tooltip = opt.Tooltip
+	fld.utf8 = f.isCurrentUTF8
+	if opt.ReadOnly {
+		fld.flags |= formFlagReadOnly
+	}
+	if opt.Required {
+		fld.flags |= formFlagRequired
+	}
+	switch strings.ToUpper(opt.Align) {
+	case "C":
+		fld.align = 1
+	case "R":
+		fld.align = 2
+	}
+	if needFont {
+		fld.da = sprintf("/F%s %.2f Tf %s", f.currentFont.i, f.fontSizePt, f.color.text.str)
+	}
+	f.form.fields = append(f.form.fields, fld)
+	return true
+}
+
+// formAddWidget places a widget of fld on the current page
+func (f *Fpdf) formAddWidget(fld *formField, x, y, w, h float64, opt FormFieldOptions) (wd *formWidget) {
+	wd = &formWidget{
+		field:    fld,
+		x:        x * f.k,
+		y:        (f.h - y - h) * f.k,
+		w:        w * f.k,
+		h:        h * f.k,
+		tabOrder: opt.TabOrder,
+	}
+	var mk []string
+	if opt.Border {
+		mk = append(mk, "/BC "+colorArray(f.color.draw))
+	}
+	if opt.Fill {
+		mk = append(mk, "/BG "+colorArray(f.color.fill))
+	}
+	wd.mk = strings.Join(mk, " ")
+	f.pageWidgets[f.page] = append(f.pageWidgets[f.page], wd)
+	return
+}
+
+func (f *Fpdf) formCurrentStyle(opt FormFieldOptions) (st formStyle) {
+	st.fontPt = f.fontSizePt
+	st.text = f.color.text.str
+	st.stroke = f.color.text.strokeStr()
+	st.lineWd = f.lineWidth * f.k
+	if opt.Border {
+		st.border = f.color.draw.str
+	}
+	if opt.Fill {
+		st.fill = f.color.fill.str
+	}
+	return
+}
+
+// formEncode returns s encoded for the current font and escaped for use in a
+// content stream
+func (f *Fpdf) formEncode(s string) string {
+	if f.isCurrentUTF8 {
+		for _, uni := range s {
+			f.currentFont.usedRunes[int(uni)] = int(uni)
+		}
+		return f.escape(utf8toutf16(s, false))
+	}
+	return f.escape(s)
+}
+
+// formTextString returns s as a pdf text string
+func (f *Fpdf) formTextString(s string, utf8 bool) string {
+	if utf8 {
+		return f.textstring(utf8toutf16(s))
+	}
+	return f.textstring(s)
+}
+
+// formFrame writes the background and border of a rectangular widget
+func formFrame(b *fmtBuffer, w, h float64, st formStyle) {
+	if st.fill != "" {
+		b.printf("%s 0 0 %.2f %.2f re f\n", st.fill, w, h)
+	}
+	if st.border != "" {
+		b.printf("%s %.2f w %.2f %.2f %.2f %.2f re S\n", st.border, st.lineWd,
+			st.lineWd/2, st.lineWd/2, w-st.lineWd, h-st.lineWd)
+	}
+}
+
+// formCircle returns the path of a circle centered in a square widget of the
+// specified size
+func formCircle(size, r float64) string {
+	const k = 0.5523
+	c := size / 2
+	return sprintf("%.2f %.2f m %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c",
+		c+r, c,
+		c+r, c+r*k, c+r*k, c+r, c, c+r,
+		c-r*k, c+r, c-r, c+r*k, c-r, c,
+		c-r, c-r*k, c-r*k, c-r, c, c-r,
+		c+r*k, c-r, c+r, c-r*k, c+r, c)
+}
+
+// formTextAppearance returns the appearance stream of a field displaying
+// lines of text. The line with index sel, if any, is highlighted.
+func (f *Fpdf) formTextAppearance(w, h float64, lines []string, multi bool, sel int, align int, st formStyle) []byte {
+	var b fmtBuffer
+	formFrame(&b, w, h, st)
+	b.printf("/Tx BMC\nq\n2 2 %.2f %.2f re W n\n", w-4, h-4)
+	lead := st.fontPt * 1.15
+	y := h/2 - 0.3*st.fontPt
+	if multi {
+		y = h - 2 - 0.9*st.fontPt
+	}
+	for j, str := range lines {
+		if j == sel {
+			b.printf("0.600 0.757 0.855 rg 2 %.2f %.2f %.2f re f\n", y-0.25*lead, w-4, lead)
+		}
+		x := 2.0
+		if align > 0 {
+			tw := float64(f.GetStringSymbolWidth(str)) * st.fontPt / 1000
+			if align == 1 {
+				x = (w - tw) / 2
+			} else {
+				x = w - 2 - tw
+			}
+		}
+		b.printf("BT %s /F%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+			st.text, f.currentFont.i, st.fontPt, x, y, f.formEncode(str))
+		y -= lead
+	}
+	b.printf("Q\nEMC")
+	return b.Bytes()
+}
+
+// AddTextField adds an interactive text field to the current page. The upper
+// left corner of the field is at (x, y) and its size is w by h. The value
+// is displayed with the current font and text color. Properties of the field
+// are specified with opt; the fields Multiline, Password and MaxLen apply
+// only to text fields.
+//
+// Fields that use a UTF-8 font only embed the glyphs of the initial value,
+// so text typed by the user may not display correctly if other characters
+// are used.
+func (f *Fpdf) AddTextField(x, y, w, h float64, opt FormFieldOptions) {
+	fld := &formField{ft: "Tx", value: opt.Value, defValue: opt.Default, maxLen: opt.MaxLen}
+	if opt.Multiline {
+		fld.flags |= formFlagMultiline
+	}
+	if opt.Password {
+		fld.flags |= formFlagPassword
+	}
+	if !f.formAddField(fld, opt, true) {
+		return
+	}
+	wd := f.formAddWidget(fld, x, y, w, h, opt)
+	lines := []string{opt.Value}
+	if opt.Password {
+		lines[0] = strings.Repeat("*", len([]rune(opt.Value)))
+	} else if opt.Multiline {
+		lines = strings.Split(opt.Value, "\n")
+	}
+	wd.ap = []formAppearance{{content: f.formTextAppearance(wd.w, wd.h, lines,
+		opt.Multiline, -1, fld.align, f.formCurrentStyle(opt))}}
+}
+
+// AddCheckBox adds an interactive check box to the current page. The upper
+// left corner of the box is at (x, y) and its width and height are both
+// size. checked specifies the initial state of the box, which is also the
+// state it returns to when the form is reset. The check mark is drawn with
+// the current text color. The fields Value and Default of opt are ignored.
+func (f *Fpdf) AddCheckBox(x, y, size float64, checked bool, opt FormFieldOptions) {
+	fld := &formField{ft: "Btn", value: "Off"}
+	if checked {
+		fld.value = "Yes"
+	}
+	fld.defValue = fld.value
+	if !f.formAddField(fld, opt, false) {
+		return
+	}
+	wd := f.formAddWidget(fld, x, y, size, size, opt)
+	wd.state = fld.value
+	wd.caption = "4" // ZapfDingbats check mark
+	st := f.formCurrentStyle(opt)
+	s := wd.w
+	var on, off fmtBuffer
+	formFrame(&off, s, s, st)
+	formFrame(&on, s, s, st)
+	on.printf("q %s %.2f w 1 J 1 j %.2f %.2f m %.2f %.2f l %.2f %.2f l S Q",
+		st.stroke, s*0.1, s*0.2, s*0.5, s*0.42, s*0.25, s*0.8, s*0.78)
+	wd.ap = []formAppearance{
+		{state: "Yes", content: on.Bytes()},
+		{state: "Off", content: off.Bytes()},
+	}
+}
+
+// AddRadioGroup adds a group of interactive radio buttons to the current
+// page. At most one button of the group can be selected at a time. Each
+// button is drawn as a circle of the specified size, with the current draw
+// and fill colors if opt.Border and opt.Fill are set, and a dot in the
+// current text color when selected. opt.Value and opt.Default hold the value
+// of the initially selected button and the button selected when the form is
+// reset. buttons must hold at least one button.
+func (f *Fpdf) AddRadioGroup(buttons []RadioButton, opt FormFieldOptions) {
+	if len(buttons) == 0 {
+		f.SetErrorf("radio group must have at least one button")
+		return
+	}
+	fld := &formField{ft: "Btn", flags: formFlagRadio | formFlagNoToggleOff,
+		value: opt.Value, defValue: opt.Default}
+	if fld.value == "" {
+		fld.value = "Off"
+	}
+	if fld.defValue == "" {
+		fld.defValue = "Off"
+	}
+	if !f.formAddField(fld, opt, false) {
+		return
+	}
+	st := f.formCurrentStyle(opt)
+	for _, btn := range buttons {
+		if btn.Value == "" || btn.Value == "Off" {
+			f.err = fmt.Errorf("radio button of group %s has invalid value %q", fld.name, btn.Value)
+			return
+		}
+		wd := f.formAddWidget(fld, btn.X, btn.Y, btn.Size, btn.Size, opt)
+		wd.kid = true
+		wd.state = "Off"
+		if btn.Value == fld.value {
+			wd.state = btn.Value
+		}
+		wd.caption = "l" // ZapfDingbats bullet
+		s := wd.w
+		var frame fmtBuffer
+		if st.fill != "" {
+			frame.printf("%s %s f\n", st.fill, formCircle(s, s/2))
+		}
+		if st.border != "" {
+			frame.printf("%s %.2f w %s S\n", st.border, st.lineWd, formCircle(s, (s-st.lineWd)/2))
+		}
+		on := frame.String() + sprintf("%s %s f", st.text, formCircle(s, s/4))
+		wd.ap = []formAppearance{
+			{state: btn.Value, content: []byte(on)},
+			{state: "Off", content: frame.Bytes()},
+		}
+		fld.kids = append(fld.kids, wd)
+	}
+}
+
+// AddChoiceField adds an interactive choice field to the current page. The
+// upper left corner of the field is at (x, y) and its size is w by h. If
+// combo is true, the field is a drop-down combo box, otherwise it is a
+// scrollable list box. options lists the items the user can choose from and
+// opt.Value, if not empty, holds the selected item. Set opt.Editable to let
+// the user type a value in a combo box that is not in the list.
+func (f *Fpdf) AddChoiceField(x, y, w, h float64, options []string, combo bool, opt FormFieldOptions) {
+	fld := &formField{ft: "Ch", value: opt.Value, defValue: opt.Default, opts: options}
+	if combo {
+		fld.flags |= formFlagCombo
+		if opt.Editable {
+			fld.flags |= formFlagEdit
+		}
+	}
+	if !f.formAddField(fld, opt, true) {
+		return
+	}
+	wd := f.formAddWidget(fld, x, y, w, h, opt)
+	st := f.formCurrentStyle(opt)
+	var content []byte
+	if combo {
+		content = f.formTextAppearance(wd.w, wd.h, []string{opt.Value}, false, -1, fld.align, st)
+	} else {
+		sel := -1
+		for j, o := range options {
+			if o == opt.Value {
+				sel = j
+			}
+		}
+		content = f.formTextAppearance(wd.w, wd.h, options, true, sel, fld.align, st)
+	}
+	wd.ap = []formAppearance{{content: content}}
+}
+
+// AddPushButton adds an interactive push button to the current page. The
+// upper left corner of the button is at (x, y) and its size is w by h.
+// caption is centered on the button with the current font and text color.
+// The action of the button is specified with opt.JavaScript.
+func (f *Fpdf) AddPushButton(x, y, w, h float64, caption string, opt FormFieldOptions) {
+	fld := &formField{ft: "Btn", flags: formFlagPushbutton, js: opt.JavaScript, align: 1}
+	if !f.formAddField(fld, opt, true) {
+		return
+	}
+	wd := f.formAddWidget(fld, x, y, w, h, opt)
+	wd.caption = caption
+	wd.ap = []formAppearance{{content: f.formTextAppearance(wd.w, wd.h, []string{caption},
+		false, -1, 1, f.formCurrentStyle(opt))}}
+}
+
+// formValue returns v formatted as the value of fld
+func (f *Fpdf) formValue(fld *formField, v string) string {
+	if fld.ft == "Btn" {
+		return "/" + pdfName(v)
+	}
+	return f.formTextString(v, fld.utf8)
+}
+
+// formDropDeletedWidgets removes from the fields the widgets that are no
+// longer on a page, after pages are deleted. Fields left without widgets are
+// removed from the form, and their names can be used again.
+func (f *Fpdf) formDropDeletedWidgets() {
+	placed := make(map[*formWidget]bool)
+	for _, list := range f.pageWidgets {
+		for _, wd := range list {
+			placed[wd] = true
+		}
+	}
+	used := make(map[*formField]bool)
+	for wd := range placed {
+		used[wd.field] = true
+	}
+	fields := f.form.fields[:0]
+	for _, fld := range f.form.fields {
+		if !used[fld] {
+			delete(f.form.names, fld.name)
+			continue
+		}
+		kids := fld.kids[:0]
+		for _, wd := range fld.kids {
+			if placed[wd] {
+				kids = append(kids, wd)
+			}
+		}
+		fld.kids = kids
+		fields = append(fields, fld)
+	}
+	f.form.fields = fields
+}
+
+// formNumberObjects assigns object numbers to the widgets, their appearance
+// streams and the radio groups. These objects are written by
+// formPutObjects, in the same order, starting with object number n. It
+// returns the next object number.
+func (f *Fpdf) formNumberObjects(n int) int {
+	for _, list := range f.pageWidgets {
+		for _, wd := range list {
+			wd.objNum = n
+			n++
+			if !wd.kid {
+				wd.field.objNum = wd.objNum
+			}
+			for j := range wd.ap {
+				wd.ap[j].objNum = n
+				n++
+			}
+		}
+	}
+	for _, fld := range f.form.fields {
+		if len(fld.kids) > 0 {
+			fld.objNum = n
+			n++
+		}
+	}
+	return n
+}
+
+// formPutAnnots appends the references to the widgets of the specified page
+// to an /Annots array, in tab order
+func (f *Fpdf) formPutAnnots(out *fmtBuffer, page int) {
+	list := make([]*formWidget, len(f.pageWidgets[page]))
+	copy(list, f.pageWidgets[page])
+	sort.SliceStable(list, func(i, j int) bool { return list[i].tabOrder < list[j].tabOrder })
+	for _, wd := range list {
+		out.printf("%d 0 R ", wd.objNum)
+	}
+}
+
+func (f *Fpdf) formPutField(fld *formField) {
+	f.outf("/FT /%s /T %s", fld.ft, f.formTextString(fld.name, fld.utf8))
+	if fld.tooltip != "" {
+		f.outf("/TU %s", f.formTextString(fld.tooltip, fld.utf8))
+	}
+	if fld.flags != 0 {
+		f.outf("/Ff %d", fld.flags)
+	}
+	if fld.ref > 0 {
+		f.outf("/V %d 0 R", fld.ref)
+	} else if fld.value != "" {
+		f.outf("/V %s", f.formValue(fld, fld.value))
+	}
+	if fld.defValue != "" {
+		f.outf("/DV %s", f.formValue(fld, fld.defValue))
+	}
+	if fld.maxLen > 0 {
+		f.outf("/MaxLen %d", fld.maxLen)
+	}
+	if fld.align > 0 {
+		f.outf("/Q %d", fld.align)
+	}
+	if fld.da != "" {
+		f.outf("/DA %s", f.textstring(fld.da))
+	}
+	if len(fld.opts) > 0 {
+		var opts fmtBuffer
+		opts.printf("/Opt [")
+		for _, o := range fld.opts {
+			opts.printf("%s ", f.formTextString(o, fld.utf8))
+		}
+		opts.printf("]")
+		f.out(opts.String())
+	}
+	if fld.js != "" {
+		f.outf("/A <</S /JavaScript /JS %s>>", f.textstring(fld.js))
+	}
+}
+
+// formPutObjects writes the widgets, their appearance streams and the radio
+// groups
+func (f *Fpdf) formPutObjects() {
+	for page, list := range f.pageWidgets {
+		for _, wd := range list {
+			f.newobj()
+			f.outf("<</Type /Annot /Subtype /Widget /Rect [%.2f %.2f %.2f %.2f] /F 4 /P %d 0 R",
+				wd.x, wd.y, wd.x+wd.w, wd.y+wd.h, f.pageObj(page))
+			if wd.kid {
+				f.outf("/Parent %d 0 R", wd.field.objNum)
+			} else {
+				f.formPutField(wd.field)
+			}
+			if wd.caption != "" {
+				f.outf("/MK <<%s /CA %s>>", wd.mk, f.formTextString(wd.caption, wd.field.utf8))
+			} else if wd.mk != "" {
+				f.outf("/MK <<%s>>", wd.mk)
+			}
+			if wd.state != "" {
+				f.outf("/AS /%s", pdfName(wd.state))
+			}
+			if len(wd.ap) == 1 && wd.ap[0].state == "" {
+				f.outf("/AP <</N %d 0 R>>>>", wd.ap[0].objNum)
+			} else {
+				var ap fmtBuffer
+				ap.printf("/AP <</N <<")
+				for _, a := range wd.ap {
+					ap.printf("/%s %d 0 R ", pdfName(a.state), a.objNum)
+				}
+				ap.printf(">>>>>>")
+				f.out(ap.String())
+			}
+			f.out("endobj")
+			for _, a := range wd.ap {
+				f.newobj()
+				f.outf("<</Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f] /Resources %d 0 R", wd.w, wd.h, f.resourcesObj())
+				if f.compress {
+					data := sliceCompress(a.content)
+					f.outf("/Filter /FlateDecode /Length %d>>", f.protect.streamLen(len(data)))
+					f.putstream(data)
+				} else {
+					f.outf("/Length %d>>", f.protect.streamLen(len(a.content)))
+					f.putstream(a.content)
+				}
+				f.out("endobj")
+			}
+		}
+	}
+	for _, fld := range f.form.fields {
+		if len(fld.kids) > 0 {
+			f.newobj()
+			f.out("<<")
+			f.formPutField(fld)
+			var kids fmtBuffer
+			kids.printf("/Kids [")
+			for _, wd := range fld.kids {
+				kids.printf("%d 0 R ", wd.objNum)
+			}
+			kids.printf("]")
+			f.out(kids.String())
+			f.out(">>")
+			f.out("endobj")
+		}
+	}
+}
+
+func (f *Fpdf) formPutCatalog() {
+	if len(f.form.fields) == 0 {
+		return
+	}
+	var fields fmtBuffer
+	fields.printf("/AcroForm <</Fields [")
+	sigFlags := false
+	for _, fld := range f.form.fields {
+		fields.printf("%d 0 R ", fld.objNum)
+		sigFlags = sigFlags || fld.ft == "Sig"
+	}
+	fields.printf("] /DR %d 0 R", f.resourcesObj())
+	if sigFlags {
+		// SignaturesExist and AppendOnly
+		fields.printf(" /SigFlags 3")
+	}
+	fields.printf(">>")
+	f.out(fields.String())
+}
@@ -0,0 +1,510 @@
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Annotation flags, see AnnotationOptions
+const (
+	AnnotationFlagInvisible      = 1 << 0
+	AnnotationFlagHidden         = 1 << 1
+	AnnotationFlagPrint          = 1 << 2
+	AnnotationFlagNoZoom         = 1 << 3
+	AnnotationFlagNoRotate       = 1 << 4
+	AnnotationFlagNoView         = 1 << 5
+	AnnotationFlagReadOnly       = 1 << 6
+	AnnotationFlagLocked         = 1 << 7
+	AnnotationFlagToggleNoView   = 1 << 8
+	AnnotationFlagLockedContents = 1 << 9
+)
+
+// AnnotationOptions specifies the properties shared by markup annotations.
+// Annotations are drawn with the current draw color and line width.
+type AnnotationOptions struct {
+	// Author is the name of the person who made the annotation.
+	Author string
+	// Contents is the text of the note, displayed in a popup window.
+	Contents string
+	// Subject is a short description of the annotation.
+	Subject string
+	// Transparency is the transparency of the annotation, from 0 (opaque)
+	// to 1 (invisible), so that the zero value is opaque.
+	Transparency float64
+	// CreationDate is the date of the annotation. If zero, the creation
+	// date of the document is used.
+	CreationDate time.Time
+	// Flags is a combination of the AnnotationFlag constants. If zero, the
+	// annotation is printed (AnnotationFlagPrint).
+	Flags int
+	// Open displays the popup window of the note when the document is
+	// opened.
+	Open bool
+	// Fill paints the interior of square, circle and polygon annotations
+	// and the background of free text annotations with the current fill
+	// color.
+	Fill bool
+}
+
+// annotType holds a markup annotation of a page
+type annotType struct {
+	subtype    string
+	x, y, w, h float64 // rectangle in points, from the lower left corner of the page
+	opt        AnnotationOptions
+	color      string // /C array, empty for none
+	extra      string // entries specific to the subtype
+	lineWd     float64
+	ap         []byte // appearance stream, drawn in the rectangle
+	multiply   bool   // appearance is blended with the page with the Multiply blend mode
+	popup      bool
+	objNum     int
+	popupNum   int
+	apNum      int
+}
+
+// annotTextIcons lists the icons of sticky notes
+var annotTextIcons = map[string]bool{
+	"Comment": true, "Key": true, "Note": true, "Help": true,
+	"NewParagraph": true, "Paragraph": true, "Insert": true,
+}
+
+// annotAdd validates opt and registers an annotation on the current page.
+// The rectangle is given in user units with (x, y) at its upper left
+// corner.
+func (f *Fpdf) annotAdd(subtype string, x, y, w, h float64, opt AnnotationOptions) (an *annotType) {
+	if f.err != nil {
+		return nil
+	}
+	if f.page < 1 {
+		f.err = fmt.Errorf("annotation must be added to a page")
+		return nil
+	}
+	if opt.Transparency < 0 || opt.Transparency > 1 {
+		f.err = fmt.Errorf("annotation transparency %.2f is out of range", opt.Transparency)
+		return nil
+	}
+	an = &annotType{
+		subtype: subtype,
+		x:       x * f.k,
+		y:       (f.h - y - h) * f.k,
+		w:       w * f.k,
+		h:       h * f.k,
+		opt:     opt,
+		color:   colorArray(f.color.draw),
+		lineWd:  f.lineWidth * f.k,
+		popup:   opt.Contents != "",
+	}
+	f.pageAnnots[f.page] = append(f.pageAnnots[f.page], an)
+	return
+}
+
+// annotBounds returns the rectangle enclosing points, in user units,
+// enlarged by margin on each side
+func annotBounds(points []PointType, margin float64) (x, y, w, h float64) {
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, pt := range points {
+		x0, y0 = math.Min(x0, pt.X), math.Min(y0, pt.Y)
+		x1, y1 = math.Max(x1, pt.X), math.Max(y1, pt.Y)
+	}
+	return x0 - margin, y0 - margin, x1 - x0 + 2*margin, y1 - y0 + 2*margin
+}
+
+// annotPoints returns points as pdf coordinates in points, formatted for an
+// annotation dictionary, and the path through them relative to the lower
+// left corner of the annotation
+func (f *Fpdf) annotPoints(an *annotType, points []PointType) (coords, path string) {
+	var c, p fmtBuffer
+	for j, pt := range points {
+		px, py := pt.X*f.k, (f.h-pt.Y)*f.k
+		c.printf("%.2f %.2f ", px, py)
+		op := "l"
+		if j == 0 {
+			op = "m"
+		}
+		p.printf("%.2f %.2f %s ", px-an.x, py-an.y, op)
+	}
+	return strings.TrimSpace(c.String()), p.String()
+}
+
+// AddTextAnnotation adds a sticky note to the current page, with the upper
+// left corner of its icon at (x, y). icon is "Comment", "Key", "Note",
+// "Help", "NewParagraph", "Paragraph" or "Insert"; it is "Note" if empty.
+// Viewers that display the appearance of the note rather than their own
+// icons show it as a note in the current draw color. The text of the note,
+// opt.Contents, is displayed in a popup window.
+func (f *Fpdf) AddTextAnnotation(x, y float64, icon string, opt AnnotationOptions) {
+	if icon == "" {
+		icon = "Note"
+	}
+	if !annotTextIcons[icon] {
+		f.SetErrorf("invalid text annotation icon %s", icon)
+		return
+	}
+	if opt.Flags == 0 {
+		opt.Flags = AnnotationFlagPrint | AnnotationFlagNoZoom | AnnotationFlagNoRotate
+	}
+	size := 20 / f.k
+	an := f.annotAdd("Text", x, y, size, size, opt)
+	if an == nil {
+		return
+	}
+	an.popup = true
+	an.extra = "/Name /" + icon
+	if opt.Open {
+		an.extra += " /Open true"
+	}
+	an.ap = []byte(sprintf("%s 0 G 0.5 w 0.5 0.5 19 19 re B\n"+
+		"1 w 4 15 m 16 15 l 4 11 m 16 11 l 4 7 m 12 7 l S",
+		f.color.draw.fillStr()))
+}
+
+// AddFreeTextAnnotation adds an annotation to the current page that
+// displays text directly on the page, in a box whose upper left corner is
+// at (x, y) and whose size is w by h. The text is wrapped to the width of
+// the box and displayed with the current font, font size and text color.
+// The box has a border in the current draw color and line width, and is
+// filled with the current fill color if opt.Fill is true.
+func (f *Fpdf) AddFreeTextAnnotation(x, y, w, h float64, text string, opt AnnotationOptions) {
+	if f.err == nil && f.currentFont.Name == "" {
+		f.err = fmt.Errorf("font must be set before adding free text annotation")
+		return
+	}
+	opt.Contents = text
+	an := f.annotAdd("FreeText", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	an.popup = false
+	an.extra = sprintf("/DA %s /BS <</W %.2f>>",
+		f.textstring(sprintf("/F%s %.2f Tf %s", f.currentFont.i, f.fontSizePt, f.color.text.str)), an.lineWd)
+	// The color of a free text annotation is its background color
+	st := formStyle{border: f.color.draw.str, lineWd: an.lineWd}
+	an.color = ""
+	if opt.Fill {
+		an.color = colorArray(f.color.fill)
+		st.fill = f.color.fill.str
+	}
+	var b fmtBuffer
+	formFrame(&b, an.w, an.h, st)
+	pad := an.lineWd + 2
+	lead := f.fontSizePt * 1.15
+	b.printf("q %.2f %.2f %.2f %.2f re W n BT %s /F%s %.2f Tf\n",
+		pad, pad, an.w-2*pad, an.h-2*pad, f.color.text.str, f.currentFont.i, f.fontSizePt)
+	ty := an.h - pad - 0.9*f.fontSizePt
+	for _, line := range f.SplitText(text, (an.w-2*pad)/f.k+2*f.cMargin) {
+		b.printf("1 0 0 1 %.2f %.2f Tm (%s) Tj\n", pad, ty, f.formEncode(line))
+		ty -= lead
+	}
+	b.printf("ET Q")
+	an.ap = b.Bytes()
+}
+
+// AddTextMarkupAnnotation adds an annotation to the current page that
+// marks up the text of the cell whose upper left corner is at (x, y) and
+// whose size is w by h, using the same values as the call to Cell or
+// CellFormat that wrote the text. subtype is "Highlight", "Underline",
+// "StrikeOut" or "Squiggly". The markup is drawn in the current draw color.
+// The appearance of a highlight is multiplied with the page so that the text
+// remains visible, except in PDF/A-1 documents, which do not allow blend
+// modes.
+func (f *Fpdf) AddTextMarkupAnnotation(subtype string, x, y, w, h float64, opt AnnotationOptions) {
+	switch subtype {
+	case "Highlight", "Underline", "StrikeOut", "Squiggly":
+	default:
+		f.SetErrorf("invalid text markup annotation %s", subtype)
+		return
+	}
+	an := f.annotAdd(subtype, x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	// Corners in the order upper left, upper right, lower left, lower right
+	an.extra = sprintf("/QuadPoints [%.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f]",
+		an.x, an.y+an.h, an.x+an.w, an.y+an.h, an.x, an.y, an.x+an.w, an.y)
+	clr := f.color.draw
+	fill := clr.fillStr()
+	lw := math.Max(an.h/14, 0.5)
+	var b fmtBuffer
+	switch subtype {
+	case "Highlight":
+		// The text shows through the highlight
+		an.multiply = true
+		b.printf("/GS1 gs %s 0 0 %.2f %.2f re f", fill, an.w, an.h)
+	case "Underline":
+		b.printf("%s %.2f w 0 %.2f m %.2f %.2f l S", clr.str, lw, an.h*0.15, an.w, an.h*0.15)
+	case "StrikeOut":
+		b.printf("%s %.2f w 0 %.2f m %.2f %.2f l S", clr.str, lw, an.h/2, an.w, an.h/2)
+	case "Squiggly":
+		step := an.h / 8
+		b.printf("%s %.2f w 0 %.2f m", clr.str, lw, lw)
+		for j, px := 1, step; px <= an.w; j, px = j+1, px+step {
+			b.printf(" %.2f %.2f l", px, lw+float64(j%2)*step)
+		}
+		b.printf(" S")
+	}
+	an.ap = b.Bytes()
+}
+
+// AddSquareAnnotation adds an annotation to the current page that draws a
+// rectangle whose upper left corner is at (x, y) and whose size is w by h.
+// The rectangle is filled with the current fill color if opt.Fill is true.
+func (f *Fpdf) AddSquareAnnotation(x, y, w, h float64, opt AnnotationOptions) {
+	an := f.annotAdd("Square", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	op := f.annotShapeStyle(an)
+	lw := an.lineWd
+	an.ap = []byte(sprintf("%.2f %.2f %.2f %.2f re %s", lw/2, lw/2, an.w-lw, an.h-lw, op))
+}
+
+// AddCircleAnnotation adds an annotation to the current page that draws an
+// ellipse inscribed in the rectangle whose upper left corner is at (x, y)
+// and whose size is w by h. The ellipse is filled with the current fill
+// color if opt.Fill is true.
+func (f *Fpdf) AddCircleAnnotation(x, y, w, h float64, opt AnnotationOptions) {
+	an := f.annotAdd("Circle", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	op := f.annotShapeStyle(an)
+	const k = 0.5523
+	cx, cy := an.w/2, an.h/2
+	rx, ry := cx-an.lineWd/2, cy-an.lineWd/2
+	an.ap = []byte(sprintf("%.2f %.2f m %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c %s",
+		cx+rx, cy,
+		cx+rx, cy+ry*k, cx+rx*k, cy+ry, cx, cy+ry,
+		cx-rx*k, cy+ry, cx-rx, cy+ry*k, cx-rx, cy,
+		cx-rx, cy-ry*k, cx-rx*k, cy-ry, cx, cy-ry,
+		cx+rx*k, cy-ry, cx+rx, cy-ry*k, cx+rx, cy, op))
+}
+
+// AddLineAnnotation adds an annotation to the current page that draws a
+// line from (x1, y1) to (x2, y2).
+func (f *Fpdf) AddLineAnnotation(x1, y1, x2, y2 float64, opt AnnotationOptions) {
+	points := []PointType{{x1, y1}, {x2, y2}}
+	x, y, w, h := annotBounds(points, f.lineWidth)
+	an := f.annotAdd("Line", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	coords, path := f.annotPoints(an, points)
+	an.extra = sprintf("/L [%s] /BS <</W %.2f>>", coords, an.lineWd)
+	an.ap = []byte(sprintf("%s %.2f w %sS", f.color.draw.str, an.lineWd, path))
+}
+
+// AddPolygonAnnotation adds an annotation to the current page that draws
+// the closed polygon through points. The polygon is filled with the
+// current fill color if opt.Fill is true.
+func (f *Fpdf) AddPolygonAnnotation(points []PointType, opt AnnotationOptions) {
+	if f.err == nil && len(points) < 2 {
+		f.err = fmt.Errorf("polygon annotation requires at least two points")
+		return
+	}
+	x, y, w, h := annotBounds(points, f.lineWidth)
+	an := f.annotAdd("Polygon", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	coords, path := f.annotPoints(an, points)
+	op := f.annotShapeStyle(an)
+	an.extra += sprintf(" /Vertices [%s]", coords)
+	an.ap = []byte(sprintf("%sh %s", path, op))
+}
+
+// AddInkAnnotation adds a freehand annotation to the current page made of
+// one or more strokes, each of which is a path through the specified
+// points.
+func (f *Fpdf) AddInkAnnotation(strokes [][]PointType, opt AnnotationOptions) {
+	var all []PointType
+	for _, stroke := range strokes {
+		all = append(all, stroke...)
+	}
+	if f.err == nil && len(all) == 0 {
+		f.err = fmt.Errorf("ink annotation requires at least one point")
+		return
+	}
+	x, y, w, h := annotBounds(all, f.lineWidth)
+	an := f.annotAdd("Ink", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	var list, paths fmtBuffer
+	for _, stroke := range strokes {
+		coords, path := f.annotPoints(an, stroke)
+		list.printf("[%s]", coords)
+		paths.printf("%s", path)
+	}
+	an.extra = sprintf("/InkList [%s] /BS <</W %.2f>>", list.String(), an.lineWd)
+	an.ap = []byte(sprintf("%s %.2f w 1 J 1 j %sS", f.color.draw.str, an.lineWd, paths.String()))
+}
+
+// AddStampAnnotation adds a rubber stamp annotation to the current page, in
+// the rectangle whose upper left corner is at (x, y) and whose size is w
+// by h. name is the name of the stamp, such as "Approved", "Draft",
+// "Confidential" or "ForComment"; its words are displayed in capitals with
+// the current font, framed in the current draw color.
+func (f *Fpdf) AddStampAnnotation(x, y, w, h float64, name string, opt AnnotationOptions) {
+	if f.err == nil && f.currentFont.Name == "" {
+		f.err = fmt.Errorf("font must be set before adding stamp annotation")
+		return
+	}
+	if f.err == nil && name == "" {
+		f.err = fmt.Errorf("stamp annotation requires a name")
+		return
+	}
+	an := f.annotAdd("Stamp", x, y, w, h, opt)
+	if an == nil {
+		return
+	}
+	an.extra = "/Name /" + pdfName(name)
+	var label strings.Builder
+	for j, r := range name {
+		if j > 0 && unicode.IsUpper(r) {
+			label.WriteByte(' ')
+		}
+		label.WriteRune(unicode.ToUpper(r))
+	}
+	text := label.String()
+	lw := math.Max(an.h/20, 1)
+	fontPt := an.h * 0.5
+	if tw := float64(f.GetStringSymbolWidth(text)) * fontPt / 1000; tw > an.w-4*lw {
+		fontPt *= (an.w - 4*lw) / tw
+	}
+	tw := float64(f.GetStringSymbolWidth(text)) * fontPt / 1000
+	clr := f.color.draw
+	an.ap = []byte(sprintf("%s %.2f w %.2f %.2f %.2f %.2f re S\n"+
+		"BT %s /F%s %.2f Tf %.2f %.2f Td (%s) Tj ET",
+		clr.str, lw, lw/2, lw/2, an.w-lw, an.h-lw,
+		clr.fillStr(), f.currentFont.i, fontPt,
+		(an.w-tw)/2, an.h/2-0.35*fontPt, f.formEncode(text)))
+}
+
+// annotShapeStyle sets the border and interior color entries of a shape
+// annotation and returns the painting operator of its appearance
+func (f *Fpdf) annotShapeStyle(an *annotType) string {
+	an.extra = sprintf("/BS <</W %.2f>>", an.lineWd)
+	op := sprintf("%s %.2f w ", f.color.draw.str, an.lineWd)
+	if an.opt.Fill {
+		an.extra += " /IC " + colorArray(f.color.fill)
+		return op + f.color.fill.str + " B"
+	}
+	return op + "S"
+}
+
+// annotNumberObjects assigns object numbers to the annotations, their
+// popups and their appearance streams. These objects are written by
+// annotPutObjects, in the same order, starting with object number n.
+func (f *Fpdf) annotNumberObjects(n int) int {
+	for _, list := range f.pageAnnots {
+		for _, an := range list {
+			an.objNum = n
+			n++
+			an.apNum = n
+			n++
+			if an.popup {
+				an.popupNum = n
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// annotPutAnnots appends the references to the annotations of the
+// specified page and their popups to an /Annots array
+func (f *Fpdf) annotPutAnnots(out *fmtBuffer, page int) {
+	for _, an := range f.pageAnnots[page] {
+		out.printf("%d 0 R ", an.objNum)
+		if an.popup {
+			out.printf("%d 0 R ", an.popupNum)
+		}
+	}
+}
+
+// annotPutObjects writes the annotations, their appearance streams and
+// their popups
+func (f *Fpdf) annotPutObjects() {
+	for page, list := range f.pageAnnots {
+		pageWd, pageHt, _ := f.PageSize(page)
+		for _, an := range list {
+			f.newobj()
+			f.outf("<</Type /Annot /Subtype /%s /Rect [%.2f %.2f %.2f %.2f] /P %d 0 R",
+				an.subtype, an.x, an.y, an.x+an.w, an.y+an.h, f.pageObj(page))
+			flags := an.opt.Flags
+			if flags == 0 {
+				flags = AnnotationFlagPrint
+			}
+			f.outf("/F %d", flags)
+			if an.color != "" {
+				f.outf("/C %s", an.color)
+			}
+			if an.opt.Author != "" {
+				f.outf("/T %s", f.textstring(utf8toutf16(an.opt.Author)))
+			}
+			if an.opt.Contents != "" {
+				f.outf("/Contents %s", f.textstring(utf8toutf16(an.opt.Contents)))
+			}
+			if an.opt.Subject != "" {
+				f.outf("/Subj %s", f.textstring(utf8toutf16(an.opt.Subject)))
+			}
+			date := an.opt.CreationDate
+			if date.IsZero() {
+				date = timeOrNow(f.creationDate)
+			}
+			f.outf("/CreationDate %s /M %s", f.textstring(f.pdfDate(date)), f.textstring(f.pdfDate(date)))
+			if an.opt.Transparency > 0 {
+				f.outf("/CA %.3f", 1-an.opt.Transparency)
+			}
+			if an.extra != "" {
+				f.out(an.extra)
+			}
+			if an.popup {
+				f.outf("/Popup %d 0 R", an.popupNum)
+			}
+			f.outf("/AP <</N %d 0 R>>>>", an.apNum)
+			f.out("endobj")
+			f.newobj()
+			if an.multiply {
+				// Blend modes other than Normal are not allowed in PDF/A-1
+				mode := "Multiply"
+				if f.pdfaPart() == 1 {
+					mode = "Normal"
+				}
+				f.outf("<</Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f]", an.w, an.h)
+				f.outf("/Resources <</ExtGState <</GS1 <</Type /ExtGState /BM /%s>>>>>>", mode)
+			} else {
+				f.outf("<</Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f] /Resources %d 0 R", an.w, an.h, f.resourcesObj())
+			}
+			if f.compress {
+				data := sliceCompress(an.ap)
+				f.outf("/Filter /FlateDecode /Length %d>>", f.protect.streamLen(len(data)))
+				f.putstream(data)
+			} else {
+				f.outf("/Length %d>>", f.protect.streamLen(len(an.ap)))
+				f.putstream(an.ap)
+			}
+			f.out("endobj")
+			if an.popup {
+				// The popup window is placed beside the annotation, on the
+				// side where it fits in the page
+				const popupWd, popupHt = 180.0, 100.0
+				px := an.x + an.w
+				if px+popupWd > pageWd*f.k {
+					px = math.Max(an.x-popupWd, 0)
+				}
+				py := math.Max(math.Min(an.y+an.h, pageHt*f.k)-popupHt, 0)
+				f.newobj()
+				f.outf("<</Type /Annot /Subtype /Popup /Rect [%.2f %.2f %.2f %.2f] /P %d 0 R /Parent %d 0 R /Open %t>>",
+					px, py, px+popupWd, py+popupHt, f.pageObj(page), an.objNum, an.opt.Open)
+				f.out("endobj")
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+func TestMarkupAnnotations(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetDrawColor(255, 0, 0)
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pdf.AddTextAnnotation(100, 100, "Comment", gofpdf.AnnotationOptions{
+		Author: "QA", Contents: "Check this", CreationDate: date, Open: true,
+	})
+	pdf.SetXY(100, 200)
+	pdf.Cell(120, 20, "Reviewed text")
+	pdf.AddTextMarkupAnnotation("Highlight", 100, 200, 120, 20, gofpdf.AnnotationOptions{Transparency: 0.5})
+	pdf.AddSquareAnnotation(50, 300, 100, 50, gofpdf.AnnotationOptions{Fill: true})
+	pdf.AddLineAnnotation(50, 400, 250, 450, gofpdf.AnnotationOptions{})
+	pdf.AddInkAnnotation([][]gofpdf.PointType{{{X: 10, Y: 10}, {X: 20, Y: 30}}}, gofpdf.AnnotationOptions{})
+	pdf.AddStampAnnotation(300, 500, 150, 50, "NotApproved", gofpdf.AnnotationOptions{})
+	s := string(formOutput(t, pdf))
+	for _, want := range []string{
+		"/Annots [5 0 R 7 0 R 8 0 R 10 0 R 12 0 R 14 0 R 16 0 R ]",
+		"<</Type /Annot /Subtype /Text /Rect [100.00 672.00 120.00 692.00] /P 3 0 R",
+		"/F 28",
+		"/T (\xfe\xff\x00Q\x00A)",
+		"/CreationDate (D:20240102030405) /M (D:20240102030405)",
+		"/Name /Comment /Open true",
+		"/Popup 7 0 R",
+		"/Subtype /Popup /Rect [120.00 592.00 300.00 692.00] /P 3 0 R /Parent 5 0 R /Open true>>",
+		"/Subtype /Highlight /Rect [100.00 572.00 220.00 592.00]",
+		"/CA 0.500",
+		"/Resources <</ExtGState <</GS1 <</Type /ExtGState /BM /Multiply>>>>>>",
+		"/GS1 gs 1.000 0.000 0.000 rg 0 0 120.00 20.00 re f",
+		"/QuadPoints [100.00 592.00 220.00 592.00 100.00 572.00 220.00 572.00]",
+		"/Subtype /Square",
+		"/C [1.000 0.000 0.000]",
+		"/IC [0.000]",
+		"/L [50.00 392.00 250.00 342.00]",
+		"/InkList [[10.00 782.00 20.00 762.00]]",
+		"/Name /NotApproved",
+		"(NOT APPROVED) Tj",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestAnnotationColorSpaces(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetDrawColorCMYK(0, 100, 100, 0)
+	pdf.AddTextAnnotation(100, 100, "Note", gofpdf.AnnotationOptions{})
+	pdf.AddTextMarkupAnnotation("Highlight", 100, 200, 120, 20, gofpdf.AnnotationOptions{})
+	pdf.SetDrawColorGray(128)
+	pdf.AddStampAnnotation(300, 500, 150, 50, "Draft", gofpdf.AnnotationOptions{})
+	s := string(formOutput(t, pdf))
+	for _, want := range []string{
+		"/C [0.000 1.000 1.000 0.000]",
+		"0.000 1.000 1.000 0.000 k 0 G 0.5 w",
+		"/GS1 gs 0.000 1.000 1.000 0.000 k 0 0 120.00 20.00 re f",
+		"/C [0.502]",
+		"0.502 G 2.50 w",
+		"BT 0.502 g /F",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestFreeTextAnnotation(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddFreeTextAnnotation(20, 20, 40, 20, "A comment written on the page", gofpdf.AnnotationOptions{})
+	s := string(formOutput(t, pdf))
+	if !strings.Contains(s, "/Subtype /FreeText") || !strings.Contains(s, " 10.00 Tf 0.000 g) /BS <</W 0.57>>") {
+		t.Errorf("free text annotation missing")
+	}
+	if strings.Contains(s, "/Subtype /Popup") {
+		t.Errorf("free text annotation should not have a popup")
+	}
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	for _, fn := range []func(pdf *gofpdf.Fpdf){
+		func(pdf *gofpdf.Fpdf) { pdf.AddTextAnnotation(0, 0, "Star", gofpdf.AnnotationOptions{}) },
+		func(pdf *gofpdf.Fpdf) { pdf.AddTextMarkupAnnotation("Bold", 0, 0, 10, 10, gofpdf.AnnotationOptions{}) },
+		func(pdf *gofpdf.Fpdf) {
+			pdf.AddSquareAnnotation(0, 0, 10, 10, gofpdf.AnnotationOptions{Transparency: 2})
+		},
+		func(pdf *gofpdf.Fpdf) { pdf.AddFreeTextAnnotation(0, 0, 10, 10, "x", gofpdf.AnnotationOptions{}) },
+		func(pdf *gofpdf.Fpdf) { pdf.AddPolygonAnnotation(nil, gofpdf.AnnotationOptions{}) },
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		fn(pdf)
+		if !pdf.Err() {
+			t.Errorf("expected error")
+		}
+	}
+}
@@ -21,6 +21,16 @@ type Attachment struct {
 	// and might be modified by the pdf reader.
 	Description string
 
+	// MimeType is the media type of the content, such as "text/xml". It is
+	// only written in PDF/A-3 documents; "application/octet-stream" is used
+	// if it is empty.
+	MimeType string
+
+	// Relationship is the relationship of the attachment to the document
+	// in PDF/A-3 documents: "Source", "Data", "Alternative", "Supplement" or
+	// "Unspecified" (the default if it is empty).
+	Relationship string
+
 	objectNumber int // filled when content is included
 }
 
@@ -36,14 +46,24 @@ func checksum(data []byte) string {
 
 // Writes a compressed file like object as ``/EmbeddedFile``. Compressing is
 // done with deflate. Includes length, compressed length and MD5 checksum.
-func (f *Fpdf) writeCompressedFileObject(content []byte) {
+func (f *Fpdf) writeCompressedFileObject(content []byte, mimeType string) {
 	lenUncompressed := len(content)
 	sum := checksum(content)
 	compressed := sliceCompress(content)
 	lenCompressed := len(compressed)
 	f.newobj()
-	f.outf("<< /Type /EmbeddedFile /Length %d /Filter /FlateDecode /Params << /CheckSum <%s> /Size %d >> >>\n",
-		lenCompressed, sum, lenUncompressed)
+	if f.pdfaPart() == 3 {
+		// PDF/A-3 requires the media type and modification date
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		f.outf("<< /Type /EmbeddedFile /Subtype /%s /Length %d /Filter /FlateDecode /Params << /CheckSum <%s> /Size %d /ModDate %s >> >>\n",
+			pdfName(mimeType), f.protect.streamLen(lenCompressed), sum, lenUncompressed,
+			f.textstring(f.pdfDate(timeOrNow(f.modDate))))
+	} else {
+		f.outf("<< /Type /EmbeddedFile /Length %d /Filter /FlateDecode /Params << /CheckSum <%s> /Size %d >> >>\n",
+			f.protect.streamLen(lenCompressed), sum, lenUncompressed)
+	}
 	f.putstream(compressed)
 	f.out("endobj")
 }
@@ -55,13 +75,21 @@ func (f *Fpdf) embed(a *Attachment) {
 	}
 	oldState := f.state
 	f.state = 1 // we write file content in the main buffer
-	f.writeCompressedFileObject(a.Content)
+	f.writeCompressedFileObject(a.Content, a.MimeType)
 	streamID := f.n
 	f.newobj()
-	f.outf("<< /Type /Filespec /F () /UF %s /EF << /F %d 0 R >> /Desc %s\n>>",
+	var rel string
+	if f.pdfaPart() == 3 {
+		rel = a.Relationship
+		if rel == "" {
+			rel = "Unspecified"
+		}
+		rel = " /AFRelationship /" + pdfName(rel)
+	}
+	f.outf("<< /Type /Filespec /F () /UF %s /EF << /F %d 0 R >> /Desc %s%s\n>>",
 		f.textstring(utf8toutf16(a.Filename)),
 		streamID,
-		f.textstring(utf8toutf16(a.Description)))
+		f.textstring(utf8toutf16(a.Description)), rel)
 	f.out("endobj")
 	a.objectNumber = f.n
 	f.state = oldState
@@ -0,0 +1,696 @@
+package gofpdf
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// Base directions of paragraphs of text
+const (
+	bidiLTR  = 0  // left to right
+	bidiRTL  = 1  // right to left
+	bidiAuto = -1 // direction of the first strong character, left to right if none
+)
+
+// bidiMaxDepth is the maximum explicit embedding level
+const bidiMaxDepth = 125
+
+// bidiBrackets maps the opening paired brackets to their closing brackets
+var bidiBrackets = map[rune]rune{
+	'(': ')', '[': ']', '{': '}', 0x2045: 0x2046, 0x207D: 0x207E, 0x208D: 0x208E,
+	0x2308: 0x2309, 0x230A: 0x230B, 0x2329: 0x232A, 0x2768: 0x2769, 0x27E8: 0x27E9,
+	0x3008: 0x3009, 0x300A: 0x300B, 0x300C: 0x300D, 0x300E: 0x300F, 0x3010: 0x3011,
+	0xFF08: 0xFF09, 0xFF3B: 0xFF3D, 0xFF5B: 0xFF5D,
+}
+
+// bidiMirrors maps the characters with the Bidi_Mirrored property to their
+// mirrored glyphs
+var bidiMirrors = func() map[rune]rune {
+	m := map[rune]rune{
+		'<': '>', 0xAB: 0xBB, 0x2039: 0x203A, 0x2264: 0x2265, 0x226A: 0x226B,
+		0x2282: 0x2283, 0x2286: 0x2287, 0xFF1C: 0xFF1E,
+	}
+	for open, close := range bidiBrackets {
+		m[open] = close
+	}
+	for r, mirror := range m {
+		m[mirror] = r
+	}
+	return m
+}()
+
+// bidiStatusType is an entry of the directional status stack of rules X1-X8
+type bidiStatusType struct {
+	level    uint8
+	override bidi.Class // L, R, or ON if there is no override
+	isolate  bool
+}
+
+// bidiParagraph holds the embedding levels of text resolved with the Unicode
+// bidirectional algorithm (UAX #9). The text may consist of several
+// paragraphs separated by paragraph separators such as '\n'.
+type bidiParagraph struct {
+	runes      []rune
+	classes    []bidi.Class // original bidi class of each rune
+	levels     []uint8      // resolved embedding level of each rune
+	paraLevels []uint8      // embedding level of the paragraph of each rune
+}
+
+// bidiClass returns the bidi class of the rune r
+func bidiClass(r rune) bidi.Class {
+	p, _ := bidi.LookupRune(r)
+	return p.Class()
+}
+
+// bidiNeeded returns true if the runes, with the base direction dir, are not
+// displayed in logical order
+func bidiNeeded(runes []rune, dir int) bool {
+	if dir != bidiLTR {
+		return true
+	}
+	for _, r := range runes {
+		if r >= 0x0590 {
+			switch bidiClass(r) {
+			case bidi.R, bidi.AL, bidi.AN, bidi.RLE, bidi.RLO, bidi.RLI, bidi.FSI:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newBidiParagraph resolves the embedding levels of the runes with the base
+// direction dir
+func newBidiParagraph(runes []rune, dir int) *bidiParagraph {
+	n := len(runes)
+	p := &bidiParagraph{
+		runes:      runes,
+		classes:    make([]bidi.Class, n),
+		levels:     make([]uint8, n),
+		paraLevels: make([]uint8, n),
+	}
+	for j, r := range runes {
+		p.classes[j] = bidiClass(r)
+	}
+	start := 0
+	for j := range runes {
+		if p.classes[j] == bidi.B {
+			p.resolve(start, j+1, dir)
+			start = j + 1
+		}
+	}
+	if start < n {
+		p.resolve(start, n, dir)
+	}
+	return p
+}
+
+// bidiIsolate returns true if the class c is that of an isolate initiator
+func bidiIsolate(c bidi.Class) bool {
+	return c == bidi.LRI || c == bidi.RLI || c == bidi.FSI
+}
+
+// bidiRemoved returns true if the class c is that of a character removed by
+// rule X9
+func bidiRemoved(c bidi.Class) bool {
+	switch c {
+	case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.PDF, bidi.BN:
+		return true
+	}
+	return false
+}
+
+// bidiStrong returns the strong direction of the class c for rules N0-N2:
+// L, R (for R, AL, EN and AN) or ON
+func bidiStrong(c bidi.Class) bidi.Class {
+	switch c {
+	case bidi.L:
+		return bidi.L
+	case bidi.R, bidi.AL, bidi.EN, bidi.AN:
+		return bidi.R
+	}
+	return bidi.ON
+}
+
+// bidiNextLevel returns the least odd (rtl) or even level greater than level
+func bidiNextLevel(level uint8, rtl bool) uint8 {
+	if rtl {
+		return (level + 1) | 1
+	}
+	return (level + 2) &^ 1
+}
+
+// matchingPDI returns the position of the PDI that matches the isolate
+// initiator at pos in classes, or len(classes) if there is none
+func matchingPDI(classes []bidi.Class, pos int) int {
+	depth := 1
+	for j := pos + 1; j < len(classes); j++ {
+		switch {
+		case bidiIsolate(classes[j]):
+			depth++
+		case classes[j] == bidi.PDI:
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(classes)
+}
+
+// firstStrong returns the direction, L or R, of the first strong character
+// of classes that is not part of an isolate, or ON if there is none (rules
+// P2 and P3)
+func firstStrong(classes []bidi.Class) bidi.Class {
+	depth := 0
+	for _, c := range classes {
+		switch {
+		case bidiIsolate(c):
+			depth++
+		case c == bidi.PDI:
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && c == bidi.L:
+			return bidi.L
+		case depth == 0 && (c == bidi.R || c == bidi.AL):
+			return bidi.R
+		}
+	}
+	return bidi.ON
+}
+
+// resolve resolves the embedding levels of the paragraph made of the runes
+// from start to end
+func (p *bidiParagraph) resolve(start, end, dir int) {
+	orig := p.classes[start:end]
+	levels := p.levels[start:end]
+	n := len(orig)
+	paraLevel := uint8(bidiLTR)
+	if dir == bidiRTL || (dir == bidiAuto && firstStrong(orig) == bidi.R) {
+		paraLevel = bidiRTL
+	}
+	for j := start; j < end; j++ {
+		p.paraLevels[j] = paraLevel
+	}
+	types := make([]bidi.Class, n)
+	copy(types, orig)
+
+	// Explicit levels and directions (X1-X8)
+	stack := []bidiStatusType{{level: paraLevel, override: bidi.ON}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for j, c := range orig {
+		top := stack[len(stack)-1]
+		levels[j] = top.level
+		switch c {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO:
+			level := bidiNextLevel(top.level, c == bidi.RLE || c == bidi.RLO)
+			if level <= bidiMaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				status := bidiStatusType{level: level, override: bidi.ON}
+				if c == bidi.RLO {
+					status.override = bidi.R
+				} else if c == bidi.LRO {
+					status.override = bidi.L
+				}
+				stack = append(stack, status)
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidi.RLI, bidi.LRI, bidi.FSI:
+			if top.override != bidi.ON {
+				types[j] = top.override
+			}
+			rtl := c == bidi.RLI
+			if c == bidi.FSI {
+				rtl = firstStrong(orig[j+1:matchingPDI(orig, j)]) == bidi.R
+			}
+			level := bidiNextLevel(top.level, rtl)
+			if level <= bidiMaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				validIsolates++
+				stack = append(stack, bidiStatusType{level: level, override: bidi.ON, isolate: true})
+			} else {
+				overflowIsolates++
+			}
+		case bidi.PDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[j] = top.level
+			if top.override != bidi.ON {
+				types[j] = top.override
+			}
+		case bidi.PDF:
+			switch {
+			case overflowIsolates > 0:
+			case overflowEmbeddings > 0:
+				overflowEmbeddings--
+			case !top.isolate && len(stack) >= 2:
+				stack = stack[:len(stack)-1]
+			}
+		case bidi.B:
+			levels[j] = paraLevel
+		case bidi.BN:
+			// Removed by X9
+		default:
+			if top.override != bidi.ON {
+				types[j] = top.override
+			}
+		}
+	}
+
+	// Level runs (X9, X10), as lists of the positions of their characters
+	var runs [][]int
+	runAt := make(map[int]int)
+	prev := -1
+	for j := 0; j < n; j++ {
+		if bidiRemoved(orig[j]) {
+			continue
+		}
+		if prev < 0 || levels[j] != levels[prev] {
+			runAt[j] = len(runs)
+			runs = append(runs, nil)
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], j)
+		prev = j
+	}
+
+	// Isolating run sequences, which chain the level runs of isolates
+	chained := make([]bool, len(runs))
+	for k, run := range runs {
+		if chained[k] {
+			continue
+		}
+		seq := append([]int(nil), run...)
+		for {
+			last := seq[len(seq)-1]
+			if !bidiIsolate(orig[last]) {
+				break
+			}
+			next, ok := runAt[matchingPDI(orig, last)]
+			if !ok {
+				break
+			}
+			chained[next] = true
+			seq = append(seq, runs[next]...)
+		}
+		p.resolveSequence(start, seq, types, paraLevel)
+	}
+
+	// Removed characters take the level of the preceding character
+	for j := 0; j < n; j++ {
+		if bidiRemoved(orig[j]) {
+			if j == 0 {
+				levels[j] = paraLevel
+			} else {
+				levels[j] = levels[j-1]
+			}
+		}
+	}
+}
+
+// resolveSequence resolves the weak and neutral types (W1-W7, N0-N2) and the
+// implicit levels (I1, I2) of the isolating run sequence seq, made of
+// positions relative to start
+func (p *bidiParagraph) resolveSequence(start int, seq []int, types []bidi.Class, paraLevel uint8) {
+	orig := p.classes[start:]
+	levels := p.levels[start:]
+	first, last := seq[0], seq[len(seq)-1]
+	level := levels[first]
+
+	// Start and end of sequence types
+	prevLevel, nextLevel := paraLevel, paraLevel
+	for j := first - 1; j >= 0; j-- {
+		if !bidiRemoved(orig[j]) {
+			prevLevel = levels[j]
+			break
+		}
+	}
+	if !bidiIsolate(orig[last]) {
+		for j := last + 1; j < len(types); j++ {
+			if !bidiRemoved(orig[j]) {
+				nextLevel = levels[j]
+				break
+			}
+		}
+	}
+	levelType := func(l uint8) bidi.Class {
+		if l&1 != 0 {
+			return bidi.R
+		}
+		return bidi.L
+	}
+	sos, eos := levelType(max8(prevLevel, level)), levelType(max8(nextLevel, level))
+	embedding := levelType(level)
+
+	t := make([]bidi.Class, len(seq))
+	for k, j := range seq {
+		t[k] = types[j]
+	}
+	n := len(t)
+
+	// W1: non-spacing marks take the type of the preceding character
+	for k := range t {
+		if t[k] == bidi.NSM {
+			switch {
+			case k == 0:
+				t[k] = sos
+			case bidiIsolate(orig[seq[k-1]]) || orig[seq[k-1]] == bidi.PDI:
+				t[k] = bidi.ON
+			default:
+				t[k] = t[k-1]
+			}
+		}
+	}
+	// W2, W3: European numbers after Arabic letters are Arabic numbers
+	strong := sos
+	for k, c := range t {
+		switch c {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.AL {
+				t[k] = bidi.AN
+			}
+		}
+	}
+	for k, c := range t {
+		if c == bidi.AL {
+			t[k] = bidi.R
+		}
+	}
+	// W4: single separators between numbers
+	for k := 1; k < n-1; k++ {
+		switch {
+		case t[k] == bidi.ES && t[k-1] == bidi.EN && t[k+1] == bidi.EN:
+			t[k] = bidi.EN
+		case t[k] == bidi.CS && t[k-1] == t[k+1] && (t[k-1] == bidi.EN || t[k-1] == bidi.AN):
+			t[k] = t[k-1]
+		}
+	}
+	// W5: terminators adjacent to European numbers
+	for k := 0; k < n; k++ {
+		if t[k] != bidi.ET {
+			continue
+		}
+		end := k
+		for end < n && t[end] == bidi.ET {
+			end++
+		}
+		if (k > 0 && t[k-1] == bidi.EN) || (end < n && t[end] == bidi.EN) {
+			for ; k < end; k++ {
+				t[k] = bidi.EN
+			}
+		}
+		k = end
+	}
+	// W6: remaining separators and terminators are neutral
+	for k, c := range t {
+		if c == bidi.ES || c == bidi.ET || c == bidi.CS {
+			t[k] = bidi.ON
+		}
+	}
+	// W7: European numbers after left-to-right text
+	strong = sos
+	for k, c := range t {
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				t[k] = bidi.L
+			}
+		}
+	}
+
+	// N0: paired brackets
+	type bracketPair struct{ open, close int }
+	var pairs []bracketPair
+	type opener struct {
+		close rune
+		pos   int
+	}
+	var openers []opener
+brackets:
+	for k, c := range t {
+		if c != bidi.ON {
+			continue
+		}
+		r := p.runes[start+seq[k]]
+		if close, ok := bidiBrackets[r]; ok {
+			if len(openers) == 63 {
+				break brackets
+			}
+			openers = append(openers, opener{close, k})
+			continue
+		}
+		for o := len(openers) - 1; o >= 0; o-- {
+			if openers[o].close == r {
+				pairs = append(pairs, bracketPair{openers[o].pos, k})
+				openers = openers[:o]
+				break
+			}
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a].open < pairs[b].open })
+	setBracket := func(k int, c bidi.Class) {
+		t[k] = c
+		for k++; k < n && orig[seq[k]] == bidi.NSM; k++ {
+			t[k] = c
+		}
+	}
+	for _, pair := range pairs {
+		var found bidi.Class = bidi.ON
+		for k := pair.open + 1; k < pair.close; k++ {
+			if s := bidiStrong(t[k]); s == embedding {
+				found = embedding
+				break
+			} else if s != bidi.ON {
+				found = s
+			}
+		}
+		if found != bidi.ON && found != embedding {
+			// Only the opposite direction inside: use it if the context
+			// before the brackets has it too
+			context := sos
+			for k := pair.open - 1; k >= 0; k-- {
+				if s := bidiStrong(t[k]); s != bidi.ON {
+					context = s
+					break
+				}
+			}
+			if context != found {
+				found = embedding
+			}
+		}
+		if found != bidi.ON {
+			setBracket(pair.open, found)
+			setBracket(pair.close, found)
+		}
+	}
+
+	// N1, N2: neutrals between characters of the same direction take it,
+	// others take the embedding direction
+	neutral := func(c bidi.Class) bool {
+		switch c {
+		case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+			return true
+		}
+		return false
+	}
+	for k := 0; k < n; {
+		if !neutral(t[k]) {
+			k++
+			continue
+		}
+		end := k
+		for end < n && neutral(t[end]) {
+			end++
+		}
+		before, after := sos, eos
+		if k > 0 {
+			before = bidiStrong(t[k-1])
+		}
+		if end < n {
+			after = bidiStrong(t[end])
+		}
+		c := embedding
+		if before == after {
+			c = before
+		}
+		for ; k < end; k++ {
+			t[k] = c
+		}
+	}
+
+	// I1, I2: implicit levels
+	for k, j := range seq {
+		switch {
+		case level&1 == 0 && t[k] == bidi.R:
+			levels[j] = level + 1
+		case level&1 == 0 && (t[k] == bidi.AN || t[k] == bidi.EN):
+			levels[j] = level + 2
+		case level&1 != 0 && (t[k] == bidi.L || t[k] == bidi.AN || t[k] == bidi.EN):
+			levels[j] = level + 1
+		}
+	}
+}
+
+// max8 returns the larger of a and b
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// visual returns the runes from start to end, a line of a paragraph, in
+// visual order, with the characters of right-to-left runs mirrored (rules
+// L1, L2 and L4)
+func (p *bidiParagraph) visual(start, end int) []rune {
+	runes := append([]rune(nil), p.runes[start:end]...)
+	levels := append([]uint8(nil), p.levels[start:end]...)
+	if len(runes) == 0 {
+		return runes
+	}
+	// L1: separators and trailing whitespace take the paragraph level
+	paraLevel := p.paraLevels[start]
+	trailing := true
+	for k := len(runes) - 1; k >= 0; k-- {
+		switch c := p.classes[start+k]; {
+		case c == bidi.B || c == bidi.S:
+			levels[k] = paraLevel
+			trailing = true
+		case trailing && (c == bidi.WS || c == bidi.PDI || bidiIsolate(c) || bidiRemoved(c)):
+			levels[k] = paraLevel
+		default:
+			trailing = false
+		}
+	}
+	// L2: reverse the runs at each level, from the highest to the lowest
+	// odd level
+	highest, lowest := levels[0], levels[0]
+	for _, l := range levels {
+		highest = max8(highest, l)
+		if l < lowest {
+			lowest = l
+		}
+	}
+	for l := highest; l >= lowest|1; l-- {
+		for k := 0; k < len(runes); {
+			if levels[k] < l {
+				k++
+				continue
+			}
+			end := k
+			for end < len(runes) && levels[end] >= l {
+				end++
+			}
+			for a, b := k, end-1; a < b; a, b = a+1, b-1 {
+				runes[a], runes[b] = runes[b], runes[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			k = end
+		}
+	}
+	// L4: mirrored characters
+	for k, r := range runes {
+		if levels[k]&1 != 0 {
+			if mirror, ok := bidiMirrors[r]; ok {
+				runes[k] = mirror
+			}
+		}
+	}
+	return runes
+}
+
+// SetTextDirection sets the base direction of the paragraphs of text that is
+// output with a UTF-8 font. dirStr is "ltr" for left to right, the default,
+// "rtl" for right to left or "auto" for the direction of the first strong
+// character of each paragraph, left to right if there is none. RTL() and
+// LTR() are equivalent to "rtl" and "ltr".
+//
+// Text is displayed according to the Unicode bidirectional algorithm (UAX
+// #9): runs of right-to-left characters, such as Hebrew and Arabic, are
+// reversed and their brackets mirrored, while numbers and left-to-right
+// characters keep their order. Text that is wrapped by MultiCell() or Write()
+// is reordered line by line after the embedding levels of its paragraphs have
+// been resolved.
+func (f *Fpdf) SetTextDirection(dirStr string) {
+	switch strings.ToLower(dirStr) {
+	case "ltr":
+		f.isRTL, f.isAutoDir = false, false
+	case "rtl":
+		f.isRTL, f.isAutoDir = true, false
+	case "auto":
+		f.isRTL, f.isAutoDir = false, true
+	default:
+		f.SetErrorf("unrecognized text direction: %s", dirStr)
+	}
+}
+
+// bidiDir returns the base direction of paragraphs of text
+func (f *Fpdf) bidiDir() int {
+	switch {
+	case f.isAutoDir:
+		return bidiAuto
+	case f.isRTL:
+		return bidiRTL
+	}
+	return bidiLTR
+}
+
+// bidiParagraph returns the embedding levels of the UTF-8 text runes with
+// the current base direction, or nil if the text is displayed in logical
+// order
+func (f *Fpdf) bidiParagraph(runes []rune) *bidiParagraph {
+	if !f.isCurrentUTF8 || !bidiNeeded(runes, f.bidiDir()) {
+		return nil
+	}
+	return newBidiParagraph(runes, f.bidiDir())
+}
+
+// visualText returns the UTF-8 string txtStr, a single line of text, in
+// visual order
+func (f *Fpdf) visualText(txtStr string) string {
+	runes := []rune(txtStr)
+	if p := f.bidiParagraph(runes); p != nil {
+		return string(p.visual(0, len(runes)))
+	}
+	return txtStr
+}
+
+// isRTLParagraph returns true if the paragraph of the UTF-8 text runes that
+// contains the rune at pos is right to left
+func (f *Fpdf) isRTLParagraph(p *bidiParagraph, pos int) bool {
+	if p == nil || pos >= len(p.paraLevels) {
+		return f.isRTL
+	}
+	return p.paraLevels[pos] == bidiRTL
+}
+
+// bidiLine sets the visual order of the runes from start to end of the
+// paragraph p for the next call of CellFormat(), which outputs them as a line
+func (f *Fpdf) bidiLine(p *bidiParagraph, start, end int) {
+	if p != nil {
+		f.visualLine = string(p.visual(start, end))
+		f.hasVisualLine = true
+	}
+}
+
+// isBidiRTL returns true if the rune r is a strong right-to-left character
+func isBidiRTL(r rune) bool {
+	if r < 0x0590 {
+		return false
+	}
+	c := bidiClass(r)
+	return c == bidi.R || c == bidi.AL
+}
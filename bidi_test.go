@@ -0,0 +1,126 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// bidiLines returns the text of each line of the first page of the document
+// that is shown with a Td operator, as the concatenation of its UTF-16 string
+// operands
+func bidiLines(t *testing.T, pdf *gofpdf.Fpdf) []string {
+	t.Helper()
+	return bidiReaderLines(t, pagesRead(t, pdf))
+}
+
+// bidiReaderLines is like bidiLines for the document read by r
+func bidiReaderLines(t *testing.T, r *pdfreader.Reader) (lines []string) {
+	t.Helper()
+	for _, line := range strings.Split(updateContent(t, r, 1), "\n") {
+		if !strings.Contains(line, " Td ") {
+			continue
+		}
+		var units []uint16
+		var str []byte
+		depth := 0
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case depth == 0:
+				if c == '(' {
+					depth++
+				}
+				continue
+			case c == '\\':
+				j++
+				c = line[j]
+				if c == 'r' {
+					c = '\r'
+				}
+			case c == ')':
+				depth--
+				if depth == 0 {
+					for k := 0; k+1 < len(str); k += 2 {
+						units = append(units, uint16(str[k])<<8|uint16(str[k+1]))
+					}
+					str = str[:0]
+					continue
+				}
+			}
+			str = append(str, c)
+		}
+		lines = append(lines, string(utf16.Decode(units)))
+	}
+	return
+}
+
+// bidiPdf returns a document with a current UTF-8 font that has Hebrew glyphs
+func bidiPdf() *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.AddUTF8Font("dejavu", "", "DejaVuSansCondensed.ttf")
+	pdf.SetFont("dejavu", "", 12)
+	pdf.AddPage()
+	return pdf
+}
+
+func TestBidiCell(t *testing.T) {
+	for _, test := range []struct {
+		dir, txt, visual string
+	}{
+		{"ltr", "abc 123", "abc 123"},
+		{"ltr", "שלום abc", "םולש abc"},
+		{"ltr", "abc שלום 12", "abc 12 םולש"},
+		{"rtl", "שלום abc 123!", "!abc 123 םולש"},
+		{"rtl", "שלום 123!", "!123 םולש"},
+		{"rtl", "(שלום) [x]", "[x] (םולש)"},
+		{"rtl", "a(b)c", "a(b)c"},
+		{"rtl", "שלום 1.5% (abc)", "(abc) 1.5% םולש"},
+		{"auto", "שלום abc", "abc םולש"},
+		{"auto", "123 abc שלום", "123 abc םולש"},
+		{"auto", "123 שלום abc", "abc םולש 123"},
+		{"ltr", "abc ⁧שלום abc⁩ def", "abc ⁧abc םולש⁩ def"},
+	} {
+		pdf := bidiPdf()
+		pdf.SetTextDirection(test.dir)
+		pdf.CellFormat(0, 10, test.txt, "", 1, "", false, 0, "")
+		if err := pdf.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if lines := bidiLines(t, pdf); len(lines) != 1 || lines[0] != test.visual {
+			t.Errorf("%s %q: displayed as %q, expected %q", test.dir, test.txt, lines, test.visual)
+		}
+	}
+	pdf := bidiPdf()
+	pdf.SetTextDirection("up")
+	if err := pdf.Error(); err == nil || err.Error() != "unrecognized text direction: up" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestBidiWrap(t *testing.T) {
+	// Each line is reordered after the paragraph is wrapped
+	pdf := bidiPdf()
+	pdf.RTL()
+	txt := "אבג abc דהו\nשלום 12"
+	w := pdf.GetStringWidth("אבג abc") + 2*pdf.GetCellMargin() + 1
+	pdf.MultiCell(w, 10, txt, "", "R", false)
+	pdf.Text(20, 100, "דהו (12)")
+	pdf.LTR()
+	pdf.SetXY(20, 120)
+	pdf.Write(10, "abc דהו")
+	if err := pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"abc גבא", "והד", "12 םולש", "(12) והד", "abc והד"}
+	if lines := bidiLines(t, pdf); strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("displayed as %q, expected %q", lines, want)
+	}
+	lines := pdf.SplitText(txt, w)
+	if len(lines) != 3 || lines[0] != "אבג abc" {
+		t.Errorf("SplitText returned %q", lines)
+	}
+}
@@ -0,0 +1,190 @@
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// BookmarkOptions specifies the appearance and the destination of a
+// bookmark added with BookmarkOptions() or InsertBookmark().
+type BookmarkOptions struct {
+	Color  []int   // RGB color of the title {R, G, B}; nil for the viewer default
+	Bold   bool    // display the title in bold
+	Italic bool    // display the title in italics
+	Open   bool    // show the children of the bookmark when the document is opened
+	Page   int     // 1-based page of the destination; 0 for the current page
+	Y      float64 // vertical position of the destination on its page
+	// Fit is the way the destination is displayed: "XYZ" (or "") keeps the
+	// current magnification, "FitH" and "FitBH" fit the width of the page
+	// or of its contents, with Y at the top of the window, and "Fit" and
+	// "FitB" fit the page or its contents in the window.
+	Fit string
+	// Dest is the name of a destination defined with AddNamedDest. If not
+	// empty, Page, Y and Fit are ignored.
+	Dest string
+}
+
+// newOutline returns a validated bookmark for the specified options
+func (f *Fpdf) newOutline(txtStr string, level int, opt BookmarkOptions) (o outlineType, ok bool) {
+	if f.err != nil {
+		return
+	}
+	if level < 0 {
+		f.err = fmt.Errorf("incorrect bookmark level: %d", level)
+		return
+	}
+	switch opt.Fit {
+	case "", "XYZ", "Fit", "FitH", "FitB", "FitBH":
+	default:
+		f.err = fmt.Errorf("incorrect bookmark destination type: %s", opt.Fit)
+		return
+	}
+	if opt.Color != nil && len(opt.Color) != 3 {
+		f.err = fmt.Errorf("bookmark color must have 3 components")
+		return
+	}
+	page := opt.Page
+	if page == 0 {
+		page = f.PageNo()
+	}
+	if f.isCurrentUTF8 {
+		txtStr = utf8toutf16(txtStr)
+	}
+	o = outlineType{text: txtStr, level: level, y: opt.Y, p: page, prev: -1, last: -1, next: -1, first: -1,
+		id: len(f.outlines), fitStr: opt.Fit, dest: opt.Dest, open: opt.Open}
+	if opt.Color != nil {
+		o.color = sprintf("[%.3f %.3f %.3f]",
+			float64(opt.Color[0])/255, float64(opt.Color[1])/255, float64(opt.Color[2])/255)
+	}
+	if opt.Italic {
+		o.style |= 1
+	}
+	if opt.Bold {
+		o.style |= 2
+	}
+	return o, true
+}
+
+// BookmarkOptions sets a bookmark that will be displayed in a sidebar
+// outline, as Bookmark() does, with the appearance and destination
+// specified by opt. txtStr is the title of the bookmark and level its level
+// in the outline; 0 is the top level. The returned identifier refers to the
+// bookmark in InsertBookmark() and MoveBookmark().
+func (f *Fpdf) BookmarkOptions(txtStr string, level int, opt BookmarkOptions) (id int) {
+	o, ok := f.newOutline(txtStr, level, opt)
+	if !ok {
+		return -1
+	}
+	f.outlines = append(f.outlines, o)
+	return o.id
+}
+
+// InsertBookmark inserts a bookmark in the outline before the bookmark
+// identified by beforeID, which is returned by BookmarkOptions() or
+// InsertBookmark(), or is the 0-based creation order of a bookmark added
+// with Bookmark(). This makes it possible to add bookmarks for earlier pages,
+// such as a table of contents that is generated last, in their logical
+// position. The returned identifier refers to the new bookmark.
+func (f *Fpdf) InsertBookmark(beforeID int, txtStr string, level int, opt BookmarkOptions) (id int) {
+	pos := f.outlineIndex(beforeID)
+	if pos < 0 {
+		return -1
+	}
+	o, ok := f.newOutline(txtStr, level, opt)
+	if !ok {
+		return -1
+	}
+	f.outlines = append(f.outlines, outlineType{})
+	copy(f.outlines[pos+1:], f.outlines[pos:])
+	f.outlines[pos] = o
+	return o.id
+}
+
+// MoveBookmark moves the bookmark identified by id, together with its
+// descendants, before the bookmark identified by beforeID, or to the end of
+// the outline if beforeID is -1. The bookmark takes the specified level and
+// the levels of its descendants are shifted accordingly.
+func (f *Fpdf) MoveBookmark(id, beforeID, level int) {
+	from := f.outlineIndex(id)
+	if from < 0 || (beforeID != -1 && f.outlineIndex(beforeID) < 0) {
+		return
+	}
+	if level < 0 {
+		f.err = fmt.Errorf("incorrect bookmark level: %d", level)
+		return
+	}
+	end := from + 1
+	for end < len(f.outlines) && f.outlines[end].level > f.outlines[from].level {
+		end++
+	}
+	subtree := make([]outlineType, end-from)
+	copy(subtree, f.outlines[from:end])
+	shift := level - subtree[0].level
+	for j := range subtree {
+		subtree[j].level += shift
+	}
+	rest := append(f.outlines[:from:from], f.outlines[end:]...)
+	pos := len(rest)
+	if beforeID != -1 {
+		pos = -1
+		for j, o := range rest {
+			if o.id == beforeID {
+				pos = j
+			}
+		}
+		if pos < 0 {
+			f.err = fmt.Errorf("bookmark %d cannot be moved before itself or its descendants", id)
+			return
+		}
+	}
+	outlines := make([]outlineType, 0, len(f.outlines))
+	outlines = append(outlines, rest[:pos]...)
+	outlines = append(outlines, subtree...)
+	f.outlines = append(outlines, rest[pos:]...)
+}
+
+// outlineIndex returns the position in the outline of the bookmark
+// identified by id, or -1 after setting an error if there is none
+func (f *Fpdf) outlineIndex(id int) int {
+	if f.err != nil {
+		return -1
+	}
+	for j, o := range f.outlines {
+		if o.id == id {
+			return j
+		}
+	}
+	f.err = fmt.Errorf("bookmark %d does not exist", id)
+	return -1
+}
+
+// outlineEndDoc checks that the levels of the outline form a tree and that
+// bookmarks refer to existing pages. Bookmarks set before the first page
+// refer to the first page.
+func (f *Fpdf) outlineEndDoc() {
+	level := -1
+	for j, o := range f.outlines {
+		if o.dest == "" && o.p == 0 {
+			o.p = 1
+			f.outlines[j].p = 1
+		}
+		if o.level > level+1 {
+			f.SetErrorf("bookmark level %d follows level %d", o.level, level)
+		}
+		if o.dest == "" && (o.p < 1 || o.p > f.page) {
+			f.SetErrorf("page %d of bookmark does not exist", o.p)
+		}
+		level = o.level
+	}
+}
+
+// outlineDest returns the destination of a bookmark
+func (f *Fpdf) outlineDest(o outlineType) string {
+	switch {
+	case o.dest != "":
+		return f.textstring(o.dest)
+	case o.fitStr != "":
+		return f.destArray(o.p, o.fitStr, fitParams(o.fitStr, o.y))
+	}
+	_, h, _ := f.PageSize(o.p)
+	return sprintf("[%d 0 R /XYZ 0 %.2f null]", f.pageObj(o.p), (h-o.y)*f.k)
+}
@@ -0,0 +1,98 @@
+package gofpdf_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+// bookmarkTitles returns the titles of the bookmarks in the order of their
+// objects
+func bookmarkTitles(s string) []string {
+	var titles []string
+	for _, m := range regexp.MustCompile(`/Title \((.*?)\)`).FindAllStringSubmatch(s, -1) {
+		titles = append(titles, m[1])
+	}
+	return titles
+}
+
+func TestBookmarkOptions(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.AddPage()
+	part := pdf.BookmarkOptions("Part 1", 0, gofpdf.BookmarkOptions{
+		Color: []int{255, 0, 0}, Bold: true, Italic: true, Open: true, Page: 1, Y: 100,
+	})
+	pdf.BookmarkOptions("Chapter 1", 1, gofpdf.BookmarkOptions{Page: 1, Fit: "Fit"})
+	pdf.BookmarkOptions("Chapter 2", 1, gofpdf.BookmarkOptions{Fit: "FitH", Y: 50})
+	pdf.Bookmark("Part 2", 0, 0)
+	pdf.Bookmark("Chapter 3", 1, 0)
+	pdf.InsertBookmark(part, "Cover", 0, gofpdf.BookmarkOptions{Page: 1})
+	s := string(formOutput(t, pdf))
+	if got := strings.Join(bookmarkTitles(s), ","); got != "Cover,Part 1,Chapter 1,Chapter 2,Part 2,Chapter 3" {
+		t.Errorf("unexpected bookmark order %s", got)
+	}
+	for _, want := range []string{
+		"/Dest [3 0 R /XYZ 0 692.00 null]\n/C [1.000 0.000 0.000]\n/F 3\n/Count 2>>",
+		"/Dest [3 0 R /Fit]\n/Count 0>>",
+		"/Dest [5 0 R /FitH 742.00]\n/Count 0>>",
+		"/Title (Part 2)\n/Parent 13 0 R\n/Prev 8 0 R\n/First 12 0 R\n/Last 12 0 R\n" +
+			"/Dest [5 0 R /XYZ 0 792.00 null]\n/Count -1>>",
+		"<</Type /Outlines /First 7 0 R\n/Last 11 0 R /Count 5>>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestBookmarkBeforeFirstPage(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.Bookmark("Title", 0, 0)
+	pdf.AddPage()
+	pdf.AddPage()
+	pdf.Bookmark("Body", 0, 0)
+	s := string(formOutput(t, pdf))
+	if !strings.Contains(s, "/Next 8 0 R\n/Dest [3 0 R /XYZ 0 792.00 null]") {
+		t.Errorf("bookmark set before the first page does not refer to it")
+	}
+}
+
+func TestMoveBookmark(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	a := pdf.BookmarkOptions("A", 0, gofpdf.BookmarkOptions{})
+	pdf.BookmarkOptions("A1", 1, gofpdf.BookmarkOptions{})
+	b := pdf.BookmarkOptions("B", 0, gofpdf.BookmarkOptions{})
+	c := pdf.BookmarkOptions("C", 0, gofpdf.BookmarkOptions{})
+	pdf.MoveBookmark(a, -1, 0)
+	pdf.MoveBookmark(c, a, 1)
+	pdf.MoveBookmark(a, b, 0)
+	s := string(formOutput(t, pdf))
+	if got := strings.Join(bookmarkTitles(s), ","); got != "A,A1,B,C" {
+		t.Errorf("unexpected bookmark order %s", got)
+	}
+	if !strings.Contains(s, "/Title (B)\n/Parent 9 0 R\n/Prev 5 0 R\n/First 8 0 R\n/Last 8 0 R") {
+		t.Errorf("moved subtree not attached to its new parent")
+	}
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	a = pdf.BookmarkOptions("A", 0, gofpdf.BookmarkOptions{})
+	a1 := pdf.BookmarkOptions("A1", 1, gofpdf.BookmarkOptions{})
+	pdf.MoveBookmark(a, a1, 0)
+	if !pdf.Err() {
+		t.Errorf("expected error moving a bookmark into its subtree")
+	}
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.Bookmark("Deep", 2, 0)
+	pdf.Close()
+	if !pdf.Err() {
+		t.Errorf("expected error for bookmark level without parent")
+	}
+}
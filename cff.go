@@ -0,0 +1,476 @@
+package gofpdf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// cffDictEntry is an operator of a CFF DICT with its operands, which are kept
+// in their original encoding
+type cffDictEntry struct {
+	op       int      // operator, 1200 + n for the two-byte operator 12 n
+	operands [][]byte // encoded operands
+}
+
+// cffPrivateType holds a Private DICT and its local subroutines
+type cffPrivateType struct {
+	dict  []cffDictEntry
+	subrs []byte // local Subrs INDEX, empty if there is none
+}
+
+// cffFontType holds the parts of the CFF table of an OpenType font that are
+// needed to subset it. A name-keyed font is held like a CID-keyed font with a
+// single font DICT.
+type cffFontType struct {
+	name        []byte           // font name
+	top         []cffDictEntry   // Top DICT
+	strings     [][]byte         // String INDEX
+	gsubrs      []byte           // Global Subr INDEX
+	charStrings [][]byte         // charstring of each glyph
+	fdSelect    []int            // font DICT of each glyph
+	fonts       [][]cffDictEntry // font DICTs, without their Private operator
+	privates    []cffPrivateType // Private DICT of each font DICT
+}
+
+// CFF DICT operators
+const (
+	cffCharset     = 15
+	cffEncoding    = 16
+	cffCharStrings = 17
+	cffPrivate     = 18
+	cffSubrs       = 19
+	cffCharType    = 1206
+	cffROS         = 1230
+	cffCIDCount    = 1234
+	cffFDArray     = 1236
+	cffFDSelect    = 1237
+)
+
+// cffReadIndex reads the INDEX at pos in data and returns its items and the
+// position that follows it
+func cffReadIndex(data []byte, pos int) (items [][]byte, end int, err error) {
+	if pos < 0 || pos+2 > len(data) {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX offset %d", pos)
+	}
+	count := int(data[pos])<<8 | int(data[pos+1])
+	if count == 0 {
+		return nil, pos + 2, nil
+	}
+	if pos+3 > len(data) {
+		return nil, 0, fmt.Errorf("truncated CFF INDEX")
+	}
+	offSize := int(data[pos+2])
+	start := pos + 3 + (count+1)*offSize - 1
+	if offSize < 1 || offSize > 4 || start >= len(data) {
+		return nil, 0, fmt.Errorf("truncated CFF INDEX")
+	}
+	offset := func(j int) int {
+		v := 0
+		for _, b := range data[pos+3+j*offSize : pos+3+(j+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return start + v
+	}
+	prev := offset(0)
+	for j := 1; j <= count; j++ {
+		next := offset(j)
+		if next < prev || next > len(data) {
+			return nil, 0, fmt.Errorf("invalid CFF INDEX offsets")
+		}
+		items = append(items, data[prev:next])
+		prev = next
+	}
+	return items, prev, nil
+}
+
+// cffWriteIndex returns the INDEX of items
+func cffWriteIndex(items [][]byte) []byte {
+	if len(items) == 0 {
+		return []byte{0, 0}
+	}
+	size := 1
+	for _, item := range items {
+		size += len(item)
+	}
+	offSize := 1
+	for size >= 1<<(8*uint(offSize)) {
+		offSize++
+	}
+	buf := []byte{byte(len(items) >> 8), byte(len(items)), byte(offSize)}
+	putOffset := func(v int) {
+		for j := offSize - 1; j >= 0; j-- {
+			buf = append(buf, byte(v>>(8*uint(j))))
+		}
+	}
+	offset := 1
+	putOffset(offset)
+	for _, item := range items {
+		offset += len(item)
+		putOffset(offset)
+	}
+	for _, item := range items {
+		buf = append(buf, item...)
+	}
+	return buf
+}
+
+// cffReadDict reads the operators and operands of a DICT
+func cffReadDict(data []byte) (dict []cffDictEntry, err error) {
+	var operands [][]byte
+	for pos := 0; pos < len(data); {
+		b0 := data[pos]
+		size := 1
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			if b0 == 12 {
+				if pos+1 >= len(data) {
+					return nil, fmt.Errorf("truncated CFF DICT")
+				}
+				op = 1200 + int(data[pos+1])
+				size = 2
+			}
+			dict = append(dict, cffDictEntry{op: op, operands: operands})
+			operands = nil
+			pos += size
+			continue
+		case b0 == 28:
+			size = 3
+		case b0 == 29:
+			size = 5
+		case b0 == 30:
+			// Real number, whose nibbles end with 0xf
+			for size = 1; pos+size < len(data) && data[pos+size]&0xf != 0xf &&
+				data[pos+size]>>4 != 0xf; size++ {
+			}
+			size++
+		case b0 >= 32 && b0 <= 246:
+		case b0 >= 247 && b0 <= 254:
+			size = 2
+		default:
+			return nil, fmt.Errorf("invalid CFF DICT operand %d", b0)
+		}
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("truncated CFF DICT")
+		}
+		operands = append(operands, data[pos:pos+size])
+		pos += size
+	}
+	return
+}
+
+// cffWriteDict returns the encoding of dict
+func cffWriteDict(dict []cffDictEntry) []byte {
+	var buf []byte
+	for _, e := range dict {
+		for _, operand := range e.operands {
+			buf = append(buf, operand...)
+		}
+		if e.op >= 1200 {
+			buf = append(buf, 12, byte(e.op-1200))
+		} else {
+			buf = append(buf, byte(e.op))
+		}
+	}
+	return buf
+}
+
+// cffDictInts returns the integer operands of the operator op of dict, or
+// nil if dict does not have the operator
+func cffDictInts(dict []cffDictEntry, op int) (values []int) {
+	for _, e := range dict {
+		if e.op == op {
+			values = make([]int, 0, len(e.operands))
+			for _, b := range e.operands {
+				var v int
+				switch b0 := int(b[0]); {
+				case b0 == 28:
+					v = int(int16(uint16(b[1])<<8 | uint16(b[2])))
+				case b0 == 29:
+					v = int(int32(uint32(b[1])<<24 | uint32(b[2])<<16 | uint32(b[3])<<8 | uint32(b[4])))
+				case b0 >= 32 && b0 <= 246:
+					v = b0 - 139
+				case b0 >= 247 && b0 <= 250:
+					v = (b0-247)*256 + int(b[1]) + 108
+				case b0 >= 251 && b0 <= 254:
+					v = -(b0-251)*256 - int(b[1]) - 108
+				}
+				values = append(values, v)
+			}
+			return
+		}
+	}
+	return nil
+}
+
+// cffInt encodes v as a five-byte DICT operand, so that the size of a DICT
+// does not depend on the offsets it holds
+func cffInt(v int) []byte {
+	return []byte{29, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// cffReadPrivate reads the Private DICT of size bytes at offset in data, and
+// its local subroutines
+func cffReadPrivate(data []byte, size, offset int) (private cffPrivateType, err error) {
+	if size < 0 || offset < 0 || offset+size > len(data) {
+		return private, fmt.Errorf("invalid CFF Private DICT offset %d", offset)
+	}
+	if private.dict, err = cffReadDict(data[offset : offset+size]); err != nil {
+		return
+	}
+	if subrs := cffDictInts(private.dict, cffSubrs); len(subrs) == 1 {
+		var end int
+		if _, end, err = cffReadIndex(data, offset+subrs[0]); err == nil {
+			private.subrs = data[offset+subrs[0] : end]
+		}
+	}
+	return
+}
+
+// parseCFF parses the CFF table of an OpenType font
+func parseCFF(data []byte) (cff *cffFontType, err error) {
+	if len(data) < 4 || data[0] != 1 {
+		return nil, fmt.Errorf("missing or unsupported CFF table")
+	}
+	cff = new(cffFontType)
+	var names, tops, items [][]byte
+	var pos, end int
+	if names, pos, err = cffReadIndex(data, int(data[2])); err != nil {
+		return
+	}
+	if tops, pos, err = cffReadIndex(data, pos); err != nil {
+		return
+	}
+	if len(names) != 1 || len(tops) != 1 {
+		return nil, fmt.Errorf("CFF table must hold exactly one font")
+	}
+	cff.name = names[0]
+	if cff.strings, pos, err = cffReadIndex(data, pos); err != nil {
+		return
+	}
+	if _, end, err = cffReadIndex(data, pos); err != nil {
+		return
+	}
+	cff.gsubrs = data[pos:end]
+	if cff.top, err = cffReadDict(tops[0]); err != nil {
+		return
+	}
+	if tp := cffDictInts(cff.top, cffCharType); tp != nil && (len(tp) != 1 || tp[0] != 2) {
+		return nil, fmt.Errorf("unsupported CFF charstring type")
+	}
+	charStrings := cffDictInts(cff.top, cffCharStrings)
+	if len(charStrings) != 1 {
+		return nil, fmt.Errorf("CFF font has no charstrings")
+	}
+	if cff.charStrings, _, err = cffReadIndex(data, charStrings[0]); err != nil {
+		return
+	}
+	count := len(cff.charStrings)
+	cff.fdSelect = make([]int, count)
+	if cffDictInts(cff.top, cffROS) == nil {
+		// Name-keyed font
+		p := cffDictInts(cff.top, cffPrivate)
+		if len(p) != 2 {
+			return nil, fmt.Errorf("CFF font has no Private DICT")
+		}
+		var private cffPrivateType
+		if private, err = cffReadPrivate(data, p[0], p[1]); err != nil {
+			return
+		}
+		cff.fonts = [][]cffDictEntry{nil}
+		cff.privates = []cffPrivateType{private}
+		return
+	}
+	// CID-keyed font
+	fdArray, fdSelect := cffDictInts(cff.top, cffFDArray), cffDictInts(cff.top, cffFDSelect)
+	if len(fdArray) != 1 || len(fdSelect) != 1 {
+		return nil, fmt.Errorf("CID-keyed CFF font has no FDArray or FDSelect")
+	}
+	if items, _, err = cffReadIndex(data, fdArray[0]); err != nil {
+		return
+	}
+	for _, item := range items {
+		var dict, font []cffDictEntry
+		if dict, err = cffReadDict(item); err != nil {
+			return
+		}
+		for _, e := range dict {
+			if e.op != cffPrivate {
+				font = append(font, e)
+			}
+		}
+		var private cffPrivateType
+		if p := cffDictInts(dict, cffPrivate); len(p) == 2 {
+			if private, err = cffReadPrivate(data, p[0], p[1]); err != nil {
+				return
+			}
+		}
+		cff.fonts = append(cff.fonts, font)
+		cff.privates = append(cff.privates, private)
+	}
+	if err = cff.readFDSelect(data, fdSelect[0]); err != nil {
+		return
+	}
+	return
+}
+
+// readFDSelect reads the FDSelect of a CID-keyed font at pos in data
+func (cff *cffFontType) readFDSelect(data []byte, pos int) error {
+	count := len(cff.charStrings)
+	invalid := fmt.Errorf("invalid CFF FDSelect")
+	if pos < 0 || pos >= len(data) {
+		return invalid
+	}
+	switch data[pos] {
+	case 0:
+		if pos+1+count > len(data) {
+			return invalid
+		}
+		for g := 0; g < count; g++ {
+			cff.fdSelect[g] = int(data[pos+1+g])
+		}
+	case 3:
+		n := kernUint16(data, pos+1)
+		if pos+5+n*3 > len(data) {
+			return invalid
+		}
+		for j := 0; j < n; j++ {
+			rec := pos + 3 + j*3
+			first, next, fd := kernUint16(data, rec), kernUint16(data, rec+3), int(data[rec+2])
+			for g := first; g < next && g < count; g++ {
+				cff.fdSelect[g] = fd
+			}
+		}
+	default:
+		return invalid
+	}
+	for _, fd := range cff.fdSelect {
+		if fd >= len(cff.fonts) {
+			return invalid
+		}
+	}
+	return nil
+}
+
+// subset returns a CID-keyed CFF font made of the glyphs of the font listed
+// in glyphs, whose first element is 0 for .notdef, with the corresponding
+// CIDs in cids. Subroutines are kept as they are, so the charstrings are
+// copied unchanged; only the font DICTs that the glyphs use are kept.
+func (cff *cffFontType) subset(glyphs, cids []int) []byte {
+	strs := append(append([][]byte{}, cff.strings...), []byte("Adobe"), []byte("Identity"))
+	sid := 391 + len(cff.strings) // the first 391 SIDs are standard strings
+	// Font DICTs used by the glyphs
+	fdMap := make(map[int]int)
+	var fds []int
+	fdSelect := []byte{0}
+	charset := []byte{0}
+	var charStrings [][]byte
+	maxCID := 0
+	for j, g := range glyphs {
+		fd := cff.fdSelect[g]
+		n, ok := fdMap[fd]
+		if !ok {
+			n = len(fds)
+			fdMap[fd] = n
+			fds = append(fds, fd)
+		}
+		fdSelect = append(fdSelect, byte(n))
+		charStrings = append(charStrings, cff.charStrings[g])
+		if j > 0 {
+			charset = append(charset, byte(cids[j]>>8), byte(cids[j]))
+		}
+		maxCID = max(maxCID, cids[j])
+	}
+	// The Top DICT begins with ROS, and ends with the offsets of the other
+	// structures, which follow it in this order
+	top := []cffDictEntry{{op: cffROS, operands: [][]byte{cffInt(sid), cffInt(sid + 1), cffInt(0)}}}
+	for _, e := range cff.top {
+		switch e.op {
+		case cffROS, cffCIDCount, cffCharset, cffEncoding, cffCharStrings, cffPrivate, cffFDArray, cffFDSelect:
+		default:
+			top = append(top, e)
+		}
+	}
+	top = append(top, cffDictEntry{op: cffCIDCount, operands: [][]byte{cffInt(maxCID + 1)}})
+	n := len(top)
+	for _, op := range []int{cffCharset, cffFDSelect, cffCharStrings, cffFDArray} {
+		top = append(top, cffDictEntry{op: op, operands: [][]byte{cffInt(0)}})
+	}
+	nameIndex := cffWriteIndex([][]byte{cff.name})
+	stringIndex := cffWriteIndex(strs)
+	charStringIndex := cffWriteIndex(charStrings)
+	pos := 4 + len(nameIndex) + len(cffWriteIndex([][]byte{cffWriteDict(top)})) + len(stringIndex) + len(cff.gsubrs)
+	for j, size := range []int{len(charset), len(fdSelect), len(charStringIndex)} {
+		top[n+j].operands[0] = cffInt(pos)
+		pos += size
+	}
+	top[n+3].operands[0] = cffInt(pos)
+	// Font DICTs and their Private DICTs, whose local subroutines follow them
+	fonts := make([][]byte, len(fds))
+	privates := make([][]byte, len(fds))
+	for j, fd := range fds {
+		private := cff.privates[fd]
+		var dict []cffDictEntry
+		for _, e := range private.dict {
+			if e.op != cffSubrs {
+				dict = append(dict, e)
+			}
+		}
+		if len(private.subrs) > 0 {
+			dict = append(dict, cffDictEntry{op: cffSubrs, operands: [][]byte{cffInt(0)}})
+			dict[len(dict)-1].operands[0] = cffInt(len(cffWriteDict(dict)))
+		}
+		privates[j] = cffWriteDict(dict)
+		font := append(append([]cffDictEntry{}, cff.fonts[fd]...),
+			cffDictEntry{op: cffPrivate, operands: [][]byte{cffInt(len(privates[j])), cffInt(0)}})
+		fonts[j] = cffWriteDict(font)
+		privates[j] = append(privates[j], private.subrs...)
+	}
+	pos += len(cffWriteIndex(fonts))
+	for j := range fonts {
+		// The offset of the Private DICT is the last operand of the font DICT
+		copy(fonts[j][len(fonts[j])-6:], cffInt(pos))
+		pos += len(privates[j])
+	}
+	buf := []byte{1, 0, 4, 4}
+	buf = append(buf, nameIndex...)
+	buf = append(buf, cffWriteIndex([][]byte{cffWriteDict(top)})...)
+	buf = append(buf, stringIndex...)
+	buf = append(buf, cff.gsubrs...)
+	buf = append(buf, charset...)
+	buf = append(buf, fdSelect...)
+	buf = append(buf, charStringIndex...)
+	buf = append(buf, cffWriteIndex(fonts)...)
+	for _, private := range privates {
+		buf = append(buf, private...)
+	}
+	return buf
+}
+
+// generateCutCFF returns the CFF font program of the subset of the font
+// made of the glyphs of usedRunes. The CIDs of the glyphs are their runes, as
+// in the codes of the text shown with the Identity-H encoding.
+func (utf *utf8FontFile) generateCutCFF(usedRunes map[int]int) []byte {
+	type glyphRune struct{ g, r int }
+	var list []glyphRune
+	utf.LastRune = 0
+	for _, r := range usedRunes {
+		utf.LastRune = max(utf.LastRune, r)
+		if g, ok := utf.charSymbolDictionary[r]; ok && r > 0 && g > 0 && g < len(utf.cffFont.charStrings) {
+			list = append(list, glyphRune{g, r})
+		}
+	}
+	// The glyphs keep their original order
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].g != list[j].g {
+			return list[i].g < list[j].g
+		}
+		return list[i].r < list[j].r
+	})
+	glyphs, cids := []int{0}, []int{0}
+	for _, gr := range list {
+		glyphs = append(glyphs, gr.g)
+		cids = append(cids, gr.r)
+	}
+	return utf.cffFont.subset(glyphs, cids)
+}
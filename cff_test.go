@@ -0,0 +1,197 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// CFFTest.otf is the test font of golang.org/x/image/font/sfnt, with
+// PostScript outlines for the runes '0', '1', 'Q' and U+4E2D
+
+// cffEmbedded returns the first descendant font of the first page of the
+// document that has an embedded CFF font program, and that program
+func cffEmbedded(t *testing.T, pdf *gofpdf.Fpdf) (pdfreader.Dict, *pdfreader.Stream) {
+	t.Helper()
+	r := pagesRead(t, pdf)
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	fonts, _ := r.ResolveDict(res["Font"])
+	for _, ref := range fonts {
+		fd, _ := r.ResolveDict(ref)
+		descendants, _ := r.ResolveArray(fd["DescendantFonts"])
+		if len(descendants) != 1 {
+			continue
+		}
+		cid, _ := r.ResolveDict(descendants[0])
+		desc, _ := r.ResolveDict(cid["FontDescriptor"])
+		obj, _ := r.Resolve(desc["FontFile3"])
+		if stm, ok := obj.(*pdfreader.Stream); ok {
+			return cid, stm
+		}
+	}
+	t.Fatalf("no embedded CFF font found")
+	return nil, nil
+}
+
+// otfWithCFF returns the OpenType font otf with its CFF table replaced by
+// cff, which has numGlyphs glyphs
+func otfWithCFF(otf, cff []byte, numGlyphs int) []byte {
+	buf := append([]byte{}, otf...)
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	n := int(binary.BigEndian.Uint16(otf[4:]))
+	for j := 0; j < n; j++ {
+		rec := buf[12+j*16:]
+		switch string(rec[:4]) {
+		case "CFF ":
+			binary.BigEndian.PutUint32(rec[8:], uint32(len(buf)))
+			binary.BigEndian.PutUint32(rec[12:], uint32(len(cff)))
+		case "maxp":
+			binary.BigEndian.PutUint16(buf[binary.BigEndian.Uint32(rec[8:])+4:], uint16(numGlyphs))
+		case "hhea":
+			// A single horizontal metric, which the others repeat
+			binary.BigEndian.PutUint16(buf[binary.BigEndian.Uint32(rec[8:])+34:], 1)
+		case "hmtx":
+			binary.BigEndian.PutUint32(rec[12:], uint32(4+2*(numGlyphs-1)))
+		}
+	}
+	return append(buf, cff...)
+}
+
+// cffCompareGlyphs checks that the glyphs of runes in the font sub are the
+// same as those in the font orig
+func cffCompareGlyphs(t *testing.T, orig, sub []byte, runes string) {
+	t.Helper()
+	fo, err := sfnt.Parse(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The glyphs of the subset keep their original order
+	var b sfnt.Buffer
+	var glyphs []int
+	for _, r := range runes {
+		g, _ := fo.GlyphIndex(&b, r)
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+	fs, err := sfnt.Parse(otfWithCFF(orig, sub, len(glyphs)+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, g := range glyphs {
+		want, err := fo.LoadGlyph(&b, sfnt.GlyphIndex(g), fixed.I(1000), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append([]sfnt.Segment{}, want...)
+		got, err := fs.LoadGlyph(&b, sfnt.GlyphIndex(j+1), fixed.I(1000), nil)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("glyph %d: got %v, expected %v (%v)", g, got, want, err)
+		}
+	}
+}
+
+func TestCFFFont(t *testing.T) {
+	otf, err := ioutil.ReadFile("font/CFFTest.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("cff", "", "font/CFFTest.otf")
+	pdf.SetFont("cff", "", 20)
+	pdf.AddPage()
+	pdf.Text(10, 20, "0Q中")
+	if w := pdf.GetStringWidth("01Q"); w != 2000*20/1000/pdf.GetConversionRatio() {
+		t.Errorf("unexpected width %.3f", w)
+	}
+	cid, stm := cffEmbedded(t, pdf)
+	if cid["Subtype"] != pdfreader.Name("CIDFontType0") || cid["CIDToGIDMap"] != nil ||
+		stm.Dict["Subtype"] != pdfreader.Name("CIDFontType0C") {
+		t.Errorf("unexpected font %s %s", pdfreader.Format(cid), pdfreader.Format(stm.Dict))
+	}
+	cff, err := stm.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cffCompareGlyphs(t, otf, cff, "0Q中")
+
+	// A CID-keyed font, made of the subset of all the glyphs
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("cff", "", "font/CFFTest.otf")
+	pdf.SetFont("cff", "", 20)
+	pdf.AddPage()
+	pdf.Text(10, 20, "01Q中")
+	_, stm = cffEmbedded(t, pdf)
+	cff, _ = stm.Decode()
+	otf2 := otfWithCFF(otf, cff, 5)
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("cid", "", otf2)
+	pdf.SetFont("cid", "", 20)
+	pdf.AddPage()
+	pdf.Text(10, 20, "1中")
+	_, stm = cffEmbedded(t, pdf)
+	cff, _ = stm.Decode()
+	cffCompareGlyphs(t, otf2, cff, "1中")
+	if !bytes.Contains(cff, []byte("Identity")) {
+		t.Errorf("subset is not CID-keyed")
+	}
+}
+
+func TestCFFMakeFont(t *testing.T) {
+	otf, err := ioutil.ReadFile("font/CFFTest.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "gofpdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = gofpdf.MakeFont("font/CFFTest.otf", "font/cp1252.map", dir, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", dir)
+	pdf.AddFont("cff", "", "CFFTest.json")
+	pdf.SetFont("cff", "", 20)
+	pdf.AddPage()
+	pdf.Text(10, 20, "01Q")
+	var buf bytes.Buffer
+	if err = pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.6")) {
+		t.Errorf("unexpected header %q", buf.Bytes()[:8])
+	}
+	r, err := pdfreader.NewReaderBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	fonts, _ := r.ResolveDict(res["Font"])
+	if len(fonts) != 1 {
+		t.Fatalf("unexpected fonts %s", pdfreader.Format(fonts))
+	}
+	for _, ref := range fonts {
+		fd, _ := r.ResolveDict(ref)
+		desc, _ := r.ResolveDict(fd["FontDescriptor"])
+		obj, _ := r.Resolve(desc["FontFile3"])
+		stm, ok := obj.(*pdfreader.Stream)
+		if fd["Subtype"] != pdfreader.Name("Type1") || !ok || stm.Dict["Subtype"] != pdfreader.Name("OpenType") {
+			t.Fatalf("unexpected font %s %s", pdfreader.Format(fd), pdfreader.Format(desc))
+		}
+		if data, err := stm.Decode(); err != nil || !bytes.Equal(data, otf) {
+			t.Errorf("embedded font differs from font file (%v)", err)
+		}
+	}
+}
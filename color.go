@@ -0,0 +1,176 @@
+package gofpdf
+
+// CMYKType holds fields for cyan, magenta, yellow and black color components,
+// each ranging from 0 to 100 percent
+type CMYKType struct {
+	// C is the cyan component (0-100)
+	C byte
+	// M is the magenta component (0-100)
+	M byte
+	// Y is the yellow component (0-100)
+	Y byte
+	// K is the black component (0-100)
+	K byte
+}
+
+// setCMYK makes clr a DeviceCMYK color set with the operator opStr. The
+// other components of clr are retained.
+func (clr *colorType) setCMYK(c, m, y, k byte, opStr string) {
+	clr.mode = colorModeCMYK
+	clr.cmyk = cmykColorType{c: byteBound(c), m: byteBound(m), y: byteBound(y), k: byteBound(k)}
+	clr.str = clr.components() + " " + opStr
+}
+
+// setGray makes clr a DeviceGray color set with the operator opStr. The
+// other components of clr are retained.
+func (clr *colorType) setGray(level int, opStr string) {
+	clr.mode = colorModeGray
+	clr.level, _ = colorComp(level)
+	clr.str = clr.components() + " " + opStr
+}
+
+// components returns the components of clr, separated by spaces, in the
+// color space of its mode. Spot colors are represented by their RGB
+// components.
+func (clr colorType) components() string {
+	switch {
+	case clr.mode == colorModeCMYK:
+		return sprintf("%.3f %.3f %.3f %.3f", float64(clr.cmyk.c)/100, float64(clr.cmyk.m)/100,
+			float64(clr.cmyk.y)/100, float64(clr.cmyk.k)/100)
+	case clr.mode == colorModeGray:
+		return sprintf("%.3f", float64(clr.level)/255)
+	case clr.gray:
+		return sprintf("%.3f", clr.r)
+	}
+	return sprintf("%.3f %.3f %.3f", clr.r, clr.g, clr.b)
+}
+
+// strokeStr returns the operator that sets clr, a fill or text color, as the
+// stroke color
+func (clr colorType) strokeStr() string {
+	switch clr.mode {
+	case colorModeCMYK:
+		return clr.components() + " K"
+	case colorModeGray:
+		return clr.components() + " G"
+	}
+	return rgbColorValue(clr.ir, clr.ig, clr.ib, "G", "RG").str
+}
+
+// fillStr returns the operator that sets clr, a draw color, as the fill color
+func (clr colorType) fillStr() string {
+	switch clr.mode {
+	case colorModeCMYK:
+		return clr.components() + " k"
+	case colorModeGray:
+		return clr.components() + " g"
+	}
+	return rgbColorValue(clr.ir, clr.ig, clr.ib, "g", "rg").str
+}
+
+// SetDrawColorCMYK defines the color used for all drawing operations as a
+// process color of the DeviceCMYK color space. The components are
+// percentages ranging from 0 to 100; values above this are quietly capped to
+// 100. As with SetDrawColor(), the method can be called before the first page
+// is created and the value is retained from page to page.
+func (f *Fpdf) SetDrawColorCMYK(c, m, y, k byte) {
+	f.color.draw.setCMYK(c, m, y, k, "K")
+	f.color.cmykUsed = true
+	if f.page > 0 {
+		f.out(f.color.draw.str)
+	}
+}
+
+// SetFillColorCMYK defines the color used for all filling operations as a
+// process color of the DeviceCMYK color space. See SetDrawColorCMYK() for the
+// range of the components.
+func (f *Fpdf) SetFillColorCMYK(c, m, y, k byte) {
+	f.color.fill.setCMYK(c, m, y, k, "k")
+	f.color.cmykUsed = true
+	f.colorFlag = f.color.fill.str != f.color.text.str
+	if f.page > 0 {
+		f.out(f.color.fill.str)
+	}
+}
+
+// SetTextColorCMYK defines the color used for text as a process color of the
+// DeviceCMYK color space. See SetDrawColorCMYK() for the range of the
+// components.
+func (f *Fpdf) SetTextColorCMYK(c, m, y, k byte) {
+	f.color.text.setCMYK(c, m, y, k, "k")
+	f.color.cmykUsed = true
+	f.colorFlag = f.color.fill.str != f.color.text.str
+}
+
+// GetDrawColorCMYK returns the most recently set CMYK draw color. This will
+// not be the current value if a draw color of some other type (for example,
+// RGB) has been more recently set.
+func (f *Fpdf) GetDrawColorCMYK() (c, m, y, k byte) {
+	clr := f.color.draw.cmyk
+	return clr.c, clr.m, clr.y, clr.k
+}
+
+// GetFillColorCMYK returns the most recently set CMYK fill color. This will
+// not be the current value if a fill color of some other type has been more
+// recently set.
+func (f *Fpdf) GetFillColorCMYK() (c, m, y, k byte) {
+	clr := f.color.fill.cmyk
+	return clr.c, clr.m, clr.y, clr.k
+}
+
+// GetTextColorCMYK returns the most recently set CMYK text color. This will
+// not be the current value if a text color of some other type has been more
+// recently set.
+func (f *Fpdf) GetTextColorCMYK() (c, m, y, k byte) {
+	clr := f.color.text.cmyk
+	return clr.c, clr.m, clr.y, clr.k
+}
+
+// SetDrawColorGray defines the color used for all drawing operations as a
+// level of the DeviceGray color space, from 0 (black) to 255 (white). As with
+// SetDrawColor(), the method can be called before the first page is created
+// and the value is retained from page to page.
+func (f *Fpdf) SetDrawColorGray(level int) {
+	f.color.draw.setGray(level, "G")
+	if f.page > 0 {
+		f.out(f.color.draw.str)
+	}
+}
+
+// SetFillColorGray defines the color used for all filling operations as a
+// level of the DeviceGray color space, from 0 (black) to 255 (white).
+func (f *Fpdf) SetFillColorGray(level int) {
+	f.color.fill.setGray(level, "g")
+	f.colorFlag = f.color.fill.str != f.color.text.str
+	if f.page > 0 {
+		f.out(f.color.fill.str)
+	}
+}
+
+// SetTextColorGray defines the color used for text as a level of the
+// DeviceGray color space, from 0 (black) to 255 (white).
+func (f *Fpdf) SetTextColorGray(level int) {
+	f.color.text.setGray(level, "g")
+	f.colorFlag = f.color.fill.str != f.color.text.str
+}
+
+// GetDrawColorGray returns the most recently set gray level (0 - 255) of the
+// draw color. This will not be the current value if a draw color of some
+// other type has been more recently set.
+func (f *Fpdf) GetDrawColorGray() int {
+	return f.color.draw.level
+}
+
+// GetFillColorGray returns the most recently set gray level (0 - 255) of the
+// fill color. This will not be the current value if a fill color of some
+// other type has been more recently set.
+func (f *Fpdf) GetFillColorGray() int {
+	return f.color.fill.level
+}
+
+// GetTextColorGray returns the most recently set gray level (0 - 255) of the
+// text color. This will not be the current value if a text color of some
+// other type has been more recently set.
+func (f *Fpdf) GetTextColorGray() int {
+	return f.color.text.level
+}
@@ -0,0 +1,72 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+func TestColorCMYK(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetDrawColorCMYK(10, 20, 30, 40)
+	pdf.SetFillColorGray(128)
+	pdf.SetTextColorCMYK(0, 100, 200, 0)
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Magenta")
+	pdf.Rect(10, 20, 30, 30, "DF")
+	st := gofpdf.StateGet(pdf)
+	pdf.SetDrawColor(255, 0, 0)
+	pdf.SetFillColorCMYK(1, 2, 3, 4)
+	st.Put(pdf)
+	pdf.SetDrawColorGray(0)
+	pdf.LinearGradientCMYK(10, 60, 50, 20, gofpdf.CMYKType{C: 100}, gofpdf.CMYKType{K: 50}, 0, 0, 1, 0)
+	if c, m, y, k := pdf.GetTextColorCMYK(); c != 0 || m != 100 || y != 100 || k != 0 {
+		t.Errorf("unexpected text color %d %d %d %d", c, m, y, k)
+	}
+	if c, m, y, k := pdf.GetDrawColorCMYK(); c != 10 || m != 20 || y != 30 || k != 40 {
+		t.Errorf("unexpected draw color %d %d %d %d", c, m, y, k)
+	}
+	if level := pdf.GetFillColorGray(); level != 128 {
+		t.Errorf("unexpected gray level %d", level)
+	}
+	// The colors of the first page are set again on the next one
+	pdf.AddPage()
+	r := pagesRead(t, pdf)
+	s := updateContent(t, r, 1)
+	for _, op := range []string{
+		"0.100 0.200 0.300 0.400 K\n",
+		"0.502 g\n",
+		"0.000 1.000 1.000 0.000 k",
+		// Colors restored by StateType.Put
+		"0.010 0.020 0.030 0.040 k\n0.100 0.200 0.300 0.400 K\n0.502 g\n",
+	} {
+		if !strings.Contains(s, op) {
+			t.Errorf("%q not found in %q", op, s)
+		}
+	}
+	if s = updateContent(t, r, 2); !strings.Contains(s, "0.000 G\n0.502 g\n") {
+		t.Errorf("colors not restored on page 2: %q", s)
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	shadings, _ := r.ResolveDict(res["Shading"])
+	sh, _ := r.ResolveDict(shadings["Sh1"])
+	fn, _ := r.ResolveDict(sh["Function"])
+	if sh["ColorSpace"] != pdfreader.Name("DeviceCMYK") || pdfreader.Format(fn["C0"]) != "[1 0 0 0]" ||
+		pdfreader.Format(fn["C1"]) != "[0 0 0 0.5]" {
+		t.Errorf("unexpected shading %s %s", pdfreader.Format(sh), pdfreader.Format(fn))
+	}
+}
+
+func TestColorCMYKPDFA(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPDFA(gofpdf.PDFA2B)
+	pdf.SetTextColorCMYK(0, 0, 0, 100)
+	pdf.AddPage()
+	pdf.Close()
+	if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), "CMYK colors are not allowed") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/looksocial/gofpdf/pdfreader"
 )
 
 // Version of FPDF from which this package is derived
@@ -37,10 +39,15 @@ type blendModeType struct {
 }
 
 type gradientType struct {
-	tp                int // 2: linear, 3: radial
-	clr1Str, clr2Str  string
-	x1, y1, x2, y2, r float64
-	objNum            int
+	tp        int       // 2: linear, 3: radial
+	csStr     string    // color space of the colors
+	clrStrs   []string  // colors of the stops
+	bounds    []float64 // offsets of the stops between the first and the last
+	coordsStr string    // axis or circles of the gradient
+	extendStr string    // extension beyond the first and last stops
+	alpha     int       // gradient of the opacity, 0 if the gradient is opaque
+	mask      int       // soft mask of the opacity
+	objNum    int
 }
 
 const (
@@ -122,13 +129,19 @@ const (
 	colorModeRGB colorMode = iota
 	colorModeSpot
 	colorModeCMYK
+	colorModeGray
+	colorModePattern
 )
 
 type colorType struct {
 	r, g, b    float64
 	ir, ig, ib int
 	mode       colorMode
-	spotStr    string // name of current spot color
+	spotStr    string        // name of current spot color
+	cmyk       cmykColorType // components of a process CMYK color
+	level      int           // level of a DeviceGray color, 0 - 255
+	pattern    int           // tiling pattern, 1-based
+	tint       colorMode     // color space of the tint of an uncolored pattern
 	gray       bool
 	str        string
 }
@@ -185,6 +198,8 @@ type ImageInfoType struct {
 	trns  []int   // Transparency mask
 	scale float64 // Document scale factor
 	dpi   float64 // Dots-per-inch found from image file (png only)
+	icc   []byte  // ICC color profile
+	mask  string  // SHA-1 checksum of the image used as soft mask
 	i     string  // SHA-1 checksum of the above values.
 }
 
@@ -196,7 +211,7 @@ func generateImageID(info *ImageInfoType) (string, error) {
 // GobEncode encodes the receiving image to a byte slice.
 func (info *ImageInfoType) GobEncode() (buf []byte, err error) {
 	fields := []interface{}{info.data, info.smask, info.n, info.w, info.h, info.cs,
-		info.pal, info.bpc, info.f, info.dp, info.trns, info.scale, info.dpi}
+		info.pal, info.bpc, info.f, info.dp, info.trns, info.scale, info.dpi, info.icc, info.mask}
 	w := new(bytes.Buffer)
 	encoder := gob.NewEncoder(w)
 	for j := 0; j < len(fields) && err == nil; j++ {
@@ -212,7 +227,8 @@ func (info *ImageInfoType) GobEncode() (buf []byte, err error) {
 // the receiving image.
 func (info *ImageInfoType) GobDecode(buf []byte) (err error) {
 	fields := []interface{}{&info.data, &info.smask, &info.n, &info.w, &info.h,
-		&info.cs, &info.pal, &info.bpc, &info.f, &info.dp, &info.trns, &info.scale, &info.dpi}
+		&info.cs, &info.pal, &info.bpc, &info.f, &info.dp, &info.trns, &info.scale, &info.dpi,
+		&info.icc, &info.mask}
 	r := bytes.NewBuffer(buf)
 	decoder := gob.NewDecoder(r)
 	for j := 0; j < len(fields) && err == nil; j++ {
@@ -283,8 +299,13 @@ type linkType struct {
 }
 
 type intLinkType struct {
-	page int
-	y    float64
+	page   int
+	y      float64
+	fitStr string    // destination type, empty for /XYZ at y
+	params []float64 // destination parameters in user units
+	dest   string    // named destination
+	file   string    // target file of a remote go-to or launch action
+	launch bool      // launch file rather than open it as a PDF
 }
 
 // outlineType is used for a sidebar outline of bookmarks
@@ -293,6 +314,12 @@ type outlineType struct {
 	level, parent, first, last, next, prev int
 	y                                      float64
 	p                                      int
+	dest                                   string // named destination
+	id                                     int    // identifier, the creation order
+	fitStr                                 string // destination type, empty for /XYZ at y
+	color                                  string // /C array, empty for the default
+	style                                  int    // /F flags: italic 1, bold 2
+	open                                   bool   // children are visible
 }
 
 // InitType is used with NewCustom() to customize an Fpdf instance.
@@ -325,20 +352,43 @@ type FontLoader interface {
 // Pdf defines the interface used for various methods. It is implemented by the
 // main FPDF instance as well as templates.
 type Pdf interface {
+	AddCheckBox(x, y, size float64, checked bool, opt FormFieldOptions)
+	AddChoiceField(x, y, w, h float64, options []string, combo bool, opt FormFieldOptions)
+	AddCircleAnnotation(x, y, w, h float64, opt AnnotationOptions)
 	AddFont(familyStr, styleStr, fileStr string)
 	AddFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFileBytes []byte)
 	AddFontFromReader(familyStr, styleStr string, r io.Reader)
+	AddFreeTextAnnotation(x, y, w, h float64, text string, opt AnnotationOptions)
+	AddGradient(g Gradient) int
+	AddInkAnnotation(strokes [][]PointType, opt AnnotationOptions)
 	AddLayer(name string, visible bool) (layerID int)
+	AddLineAnnotation(x1, y1, x2, y2 float64, opt AnnotationOptions)
 	AddLink() int
+	AddNamedDest(name string, page int, y float64, fitStr string)
 	AddPage()
 	AddPageFormat(orientationStr string, size SizeType)
+	AddPolygonAnnotation(points []PointType, opt AnnotationOptions)
+	AddPushButton(x, y, w, h float64, caption string, opt FormFieldOptions)
+	AddRadioGroup(buttons []RadioButton, opt FormFieldOptions)
 	AddSpotColor(nameStr string, c, m, y, k byte)
+	AddSquareAnnotation(x, y, w, h float64, opt AnnotationOptions)
+	AddStampAnnotation(x, y, w, h float64, name string, opt AnnotationOptions)
+	AddTextAnnotation(x, y float64, icon string, opt AnnotationOptions)
+	AddTextField(x, y, w, h float64, opt FormFieldOptions)
+	AddTextMarkupAnnotation(subtype string, x, y, w, h float64, opt AnnotationOptions)
 	AliasNbPages(aliasStr string)
+	AliasPageLabel(aliasStr string)
 	ArcTo(x, y, rx, ry, degRotate, degStart, degEnd float64)
 	Arc(x, y, rx, ry, degRotate, degStart, degEnd float64, styleStr string)
+	BeginArtifact()
 	BeginLayer(id int)
+	BeginMask(maskStr string, fn func())
+	BeginTag(role string)
+	BeginTagOptions(role string, opt TagOptions)
 	Beziergon(points []PointType, styleStr string)
 	Bookmark(txtStr string, level int, y float64)
+	BookmarkDest(txtStr string, level int, name string)
+	BookmarkOptions(txtStr string, level int, opt BookmarkOptions) (id int)
 	CellFormat(w, h float64, txtStr, borderStr string, ln int, alignStr string, fill bool, link int, linkStr string)
 	Cellf(w, h float64, fmtStr string, args ...interface{})
 	Cell(w, h float64, txtStr string)
@@ -353,6 +403,8 @@ type Pdf interface {
 	ClipText(x, y float64, txtStr string, outline bool)
 	Close()
 	ClosePath()
+	CreateHatchPattern(styleStr string, spacing, lineWidth float64) int
+	CreatePattern(wd, ht float64, uncolored bool, fn func(*Tpl)) int
 	CreateTemplateCustom(corner PointType, size SizeType, fn func(*Tpl)) Template
 	CreateTemplate(fn func(*Tpl)) Template
 	CurveBezierCubicTo(cx0, cy0, cx1, cy1, x, y float64)
@@ -360,9 +412,14 @@ type Pdf interface {
 	CurveCubic(x0, y0, cx0, cy0, x1, y1, cx1, cy1 float64, styleStr string)
 	CurveTo(cx, cy, x, y float64)
 	Curve(x0, y0, cx, cy, x1, y1 float64, styleStr string)
+	DeletePage(n int)
 	DrawPath(styleStr string)
+	DuplicatePage(n int)
 	Ellipse(x, y, rx, ry, degRotate float64, styleStr string)
+	EndArtifact()
 	EndLayer()
+	EndMask()
+	EndTag()
 	Err() bool
 	Error() error
 	GetAlpha() (alpha float64, blendModeStr string)
@@ -370,8 +427,12 @@ type Pdf interface {
 	GetCellMargin() float64
 	GetConversionRatio() float64
 	GetDrawColor() (int, int, int)
+	GetDrawColorCMYK() (c, m, y, k byte)
+	GetDrawColorGray() int
 	GetDrawSpotColor() (name string, c, m, y, k byte)
 	GetFillColor() (int, int, int)
+	GetFillColorCMYK() (c, m, y, k byte)
+	GetFillColorGray() int
 	GetFillSpotColor() (name string, c, m, y, k byte)
 	GetFontDesc(familyStr, styleStr string) FontDescType
 	GetFontSize() (ptSize, unitSize float64)
@@ -379,9 +440,13 @@ type Pdf interface {
 	GetLineWidth() float64
 	GetMargins() (left, top, right, bottom float64)
 	GetPageSizeStr(sizeStr string) (size SizeType)
+	GetPageLabel(pageNum int) string
 	GetPageSize() (width, height float64)
 	GetStringWidth(s string) float64
+	GetTagged() bool
 	GetTextColor() (int, int, int)
+	GetTextColorCMYK() (c, m, y, k byte)
+	GetTextColorGray() int
 	GetTextSpotColor() (name string, c, m, y, k byte)
 	GetX() float64
 	GetXY() (float64, float64)
@@ -390,26 +455,36 @@ type Pdf interface {
 	Image(imageNameStr string, x, y, w, h float64, flow bool, tp string, link int, linkStr string)
 	ImageOptions(imageNameStr string, x, y, w, h float64, flow bool, options ImageOptions, link int, linkStr string)
 	ImageTypeFromMime(mimeStr string) (tp string)
+	ImportPage(r *pdfreader.Reader, pageNum int, boxName string) Template
+	InsertBookmark(beforeID int, txtStr string, level int, opt BookmarkOptions) (id int)
+	InsertPageAt(n int)
 	LinearGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2 float64)
+	LinearGradientCMYK(x, y, w, h float64, clr1, clr2 CMYKType, x1, y1, x2, y2 float64)
 	LineTo(x, y float64)
 	Line(x1, y1, x2, y2 float64)
 	LinkString(x, y, w, h float64, linkStr string)
 	Link(x, y, w, h float64, link int)
 	Ln(h float64)
+	MoveBookmark(id, beforeID, level int)
+	MovePage(from, to int)
 	MoveTo(x, y float64)
 	MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill bool)
 	Ok() bool
 	OpenLayerPane()
+	OpenUpdate(rs io.ReadSeeker)
+	OpenUpdateFile(fileStr string)
 	OutputAndClose(w io.WriteCloser) error
 	OutputFileAndClose(fileStr string) error
 	Output(w io.Writer) error
 	PageCount() int
 	PageNo() int
 	PageSize(pageNum int) (wd, ht float64, unitStr string)
+	PaintGradient(grad int)
 	PointConvert(pt float64) (u float64)
 	PointToUnitConvert(pt float64) (u float64)
 	Polygon(points []PointType, styleStr string)
 	RadialGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float64)
+	RadialGradientCMYK(x, y, w, h float64, clr1, clr2 CMYKType, x1, y1, x2, y2, r float64)
 	RawWriteBuf(r io.Reader)
 	RawWriteStr(str string)
 	Rect(x, y, w, h float64, styleStr string)
@@ -430,12 +505,20 @@ type Pdf interface {
 	SetDashPattern(dashArray []float64, dashPhase float64)
 	SetDisplayMode(zoomStr, layoutStr string)
 	SetDrawColor(r, g, b int)
+	SetDrawColorCMYK(c, m, y, k byte)
+	SetDrawColorGray(level int)
+	SetDrawPattern(pat int)
 	SetDrawSpotColor(nameStr string, tint byte)
 	SetError(err error)
 	SetErrorf(fmtStr string, args ...interface{})
 	SetFillColor(r, g, b int)
+	SetFillColorCMYK(c, m, y, k byte)
+	SetFillColorGray(level int)
+	SetFillPattern(pat int)
 	SetFillSpotColor(nameStr string, tint byte)
 	SetFont(familyStr, styleStr string, size float64)
+	SetFontFallback(familyStr string, fallbacks ...string)
+	SetFontKerning(familyStr, styleStr string, kerning bool)
 	SetFontLoader(loader FontLoader)
 	SetFontLocation(fontDirStr string)
 	SetFontSize(size float64)
@@ -443,28 +526,50 @@ type Pdf interface {
 	SetFontUnitSize(size float64)
 	SetFooterFunc(fnc func())
 	SetFooterFuncLpi(fnc func(lastPage bool))
+	SetFormValue(fieldName, value string)
 	SetHeaderFunc(fnc func())
 	SetHeaderFuncMode(fnc func(), homeMode bool)
 	SetHomeXY()
 	SetJavascript(script string)
 	SetKeywords(keywordsStr string, isUTF8 bool)
+	SetLang(lang string)
 	SetLeftMargin(margin float64)
 	SetLineCapStyle(styleStr string)
 	SetLineJoinStyle(styleStr string)
 	SetLineWidth(width float64)
 	SetLink(link int, y float64, page int)
+	SetLinkFit(link int, page int, fitStr string, params ...float64)
+	SetLinkLaunch(link int, fileStr string)
+	SetLinkNamedDest(link int, name string)
+	SetLinkRemote(link int, fileStr string, page int, destName string)
 	SetMargins(left, top, right float64)
+	SetMissingGlyphFunc(fnc func(r rune, familyStr, styleStr string))
+	SetObjectStreams(on bool)
+	SetOpenAction(pageNum int, fitStr string, params ...float64)
+	SetOutputStream(w io.Writer)
 	SetPageBoxRec(t string, pb PageBox)
 	SetPageBox(t string, x, y, wd, ht float64)
+	SetPageLabel(startPage int, style string, prefix string, firstNumber int)
+	SetPageMode(modeStr string)
+	SetPDFA(level PDFAConformance)
+	SetPDFUA(enabled bool)
 	SetPage(pageNum int)
 	SetProtection(actionFlag byte, userPassStr, ownerPassStr string)
+	SetProtectionAlgorithm(alg EncryptionType, actionFlag int, userPassStr, ownerPassStr string)
 	SetRightMargin(margin float64)
+	SetSignature(opt SignatureOptions)
 	SetSubject(subjectStr string, isUTF8 bool)
+	SetTagged(enabled bool)
 	SetTextColor(r, g, b int)
+	SetTextColorCMYK(c, m, y, k byte)
+	SetTextColorGray(level int)
+	SetTextDirection(dirStr string)
+	SetTextPattern(pat int)
 	SetTextSpotColor(nameStr string, tint byte)
 	SetTitle(titleStr string, isUTF8 bool)
 	SetTopMargin(margin float64)
 	SetUnderlineThickness(thickness float64)
+	SetViewerPreferences(prefs ViewerPreferences)
 	SetXmpMetadata(xmpStream []byte)
 	SetX(x float64)
 	SetXY(x, y float64)
@@ -514,8 +619,13 @@ type PageBox struct {
 type Fpdf struct {
 	isCurrentUTF8    bool                       // is current font used in utf-8 mode
 	isRTL            bool                       // is is right to left mode enabled
+	isAutoDir        bool                       // base direction of paragraphs is that of their first strong character
+	visualLine       string                     // visual order of the next line output by CellFormat
+	hasVisualLine    bool                       // visualLine is set
 	page             int                        // current page number
+	openPage         int                        // page whose footer is not yet written, 0 if none
 	n                int                        // current object number
+	firstPageObj     int                        // object number of the first page
 	offsets          []int                      // array of object offsets
 	templates        map[string]Template        // templates used in this document
 	templateObjects  map[string]int             // template object IDs within this document
@@ -523,6 +633,8 @@ type Fpdf struct {
 	importedObjPos   map[string]map[int]string  // imported template objects hashes and their positions (gofpdi)
 	importedTplObjs  map[string]string          // imported template names and IDs (hashed) (gofpdi)
 	importedTplIDs   map[string]int             // imported template ids hash to object id int (gofpdi)
+	importRefs       importRefsType             // objects copied from the documents of imported pages
+	importDocs       importDocsType             // sequence numbers of the documents of imported pages
 	buffer           fmtBuffer                  // buffer holding in-memory PDF
 	pages            []*bytes.Buffer            // slice[page] of page content; 1-based
 	state            int                        // current document state
@@ -552,6 +664,8 @@ type Fpdf struct {
 	coreFonts        map[string]bool            // array of core font names
 	fonts            map[string]fontDefType     // array of used fonts
 	fontFiles        map[string]fontFileType    // array of font files
+	fontFallbacks    map[string][]string        // fallback font families of UTF-8 font families
+	missingGlyphFnc  func(rune, string, string) // called for runes missing from the current font and its fallbacks
 	diffs            []string                   // array of encoding differences
 	fontFamily       string                     // current font family
 	fontStyle        string                     // current font style
@@ -562,11 +676,20 @@ type Fpdf struct {
 	fontSize         float64                    // current font size in user unit
 	ws               float64                    // word spacing
 	images           map[string]*ImageInfoType  // array of used images
+	iccProfiles      map[string]int             // ICC profile object numbers by SHA-1 checksum
 	aliasMap         map[string]string          // map of alias->replacement
 	pageLinks        [][]linkType               // pageLinks[page][link], both 1-based
 	links            []intLinkType              // array of internal links
+	namedDests       map[string]namedDestType   // named destinations
 	attachments      []Attachment               // slice of content to embed globally
 	pageAttachments  [][]annotationAttach       // 1-based array of annotation for file attachments (per page)
+	pageWidgets      [][]*formWidget            // 1-based array of form field widgets (per page)
+	pageAnnots       [][]*annotType             // 1-based array of markup annotations (per page)
+	form             formRecType                // interactive form fields
+	sign             *signatureType             // digital signature, nil if document is not signed
+	objStm           objStmRecType              // object stream output mode
+	stream           *streamType                // streaming output, nil if document is buffered
+	update           *updateType                // incremental update, nil for a new document
 	outlines         []outlineType              // array of outlines
 	outlineRoot      int                        // root of outlines
 	autoPageBreak    bool                       // automatic page breaking
@@ -580,7 +703,12 @@ type Fpdf struct {
 	footerFncLpi     func(bool)                 // function provided by app and called to write footer with last page flag
 	zoomMode         string                     // zoom display mode
 	layoutMode       string                     // layout display mode
+	pageMode         string                     // page mode of the document window
+	viewerPrefs      ViewerPreferences          // viewer and print dialog preferences
+	openAction       openActionType             // destination displayed on opening
 	xmp              []byte                     // XMP metadata
+	pdfa             pdfaRecType                // PDF/A conformance state
+	tag              tagRecType                 // structure tree of a tagged document
 	producer         string                     // producer
 	title            string                     // title
 	subject          string                     // subject
@@ -590,6 +718,8 @@ type Fpdf struct {
 	creationDate     time.Time                  // override for document CreationDate value
 	modDate          time.Time                  // override for document ModDate value
 	aliasNbPagesStr  string                     // alias for total number of pages
+	aliasLabelStr    string                     // alias for the label of the current page
+	pageLabels       []pageLabelType            // page label ranges, sorted by first page
 	pdfVersion       string                     // PDF version number
 	fontDirStr       string                     // location of font definition files
 	capStyle         int                        // line cap style: butt 0, round 1, square 2
@@ -601,6 +731,9 @@ type Fpdf struct {
 	blendMode        string                     // current blend mode
 	alpha            float64                    // current transpacency
 	gradientList     []gradientType             // slice[idx] of gradient records
+	patternList      []patternType              // slice[idx] of tiling patterns
+	maskList         []maskType                 // slice[idx] of soft masks
+	maskNest         int                        // number of active soft masks
 	clipNest         int                        // Number of active clipping contexts
 	transformNest    int                        // Number of active transformation contexts
 	err              error                      // Set if error occurs during life cycle of instance
@@ -613,6 +746,7 @@ type Fpdf struct {
 	color            struct {
 		// Composite values of colors
 		draw, fill, text colorType
+		cmykUsed         bool // a process CMYK color has been set
 	}
 	spotColorMap           map[string]spotColorType // Map of named ink-based colors
 	userUnderlineThickness float64                  // A custom user underline thickness multiplier.
@@ -727,6 +861,7 @@ type fontDefType struct {
 	i            string        // 1-based position in font list, set by font loader, not this program
 	utf8File     *utf8FontFile // UTF-8 font
 	usedRunes    map[int]int   // Array of used runes
+	kern         bool          // Pair kerning applied to text
 }
 
 // generateFontID generates a font Id from the font definition
@@ -749,4 +884,5 @@ type fontInfoType struct {
 	Widths             []int
 	Size1, Size2       uint32
 	Desc               FontDescType
+	PostScriptOutlines bool
 }
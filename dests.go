@@ -0,0 +1,228 @@
+package gofpdf
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// namedDestType holds a named destination
+type namedDestType struct {
+	page   int
+	y      float64
+	fitStr string
+}
+
+// AddNamedDest defines a named destination, a location in the document
+// that links and bookmarks of this document, and links of other documents,
+// can refer to by name. page is the 1-based number of the page of the
+// destination and y is its vertical position; -1 indicates the current
+// page or position. fitStr is "XYZ" (or "") to display the page at its
+// current magnification with y at the top of the window, "FitH" or "FitBH"
+// to fit the width of the page or of its contents with y at the top of the
+// window, and "Fit" or "FitB" to fit the page or its contents in the
+// window. Destinations are written to the /Dests name tree of the document.
+func (f *Fpdf) AddNamedDest(name string, page int, y float64, fitStr string) {
+	if f.err != nil {
+		return
+	}
+	if name == "" {
+		f.err = fmt.Errorf("named destination requires a name")
+		return
+	}
+	switch fitStr {
+	case "":
+		fitStr = "XYZ"
+	case "XYZ", "Fit", "FitH", "FitB", "FitBH":
+	default:
+		f.err = fmt.Errorf("incorrect named destination type: %s", fitStr)
+		return
+	}
+	if y == -1 {
+		y = f.y
+	}
+	if page == -1 {
+		page = f.page
+	}
+	if page < 1 {
+		f.err = fmt.Errorf("incorrect named destination page: %d", page)
+		return
+	}
+	if f.namedDests == nil {
+		f.namedDests = make(map[string]namedDestType)
+	}
+	f.namedDests[name] = namedDestType{page: page, y: y, fitStr: fitStr}
+}
+
+// SetLinkFit defines the page a link points to and the way the page is
+// displayed. page is 1-based; -1 indicates the current page. fitStr and
+// params specify the destination as for SetOpenAction; for example
+// SetLinkFit(link, 3, "Fit") displays the entire third page and
+// SetLinkFit(link, 3, "FitR", x1, y1, x2, y2) zooms on a rectangle of it.
+// See AddLink().
+func (f *Fpdf) SetLinkFit(link int, page int, fitStr string, params ...float64) {
+	if f.err != nil {
+		return
+	}
+	count, ok := destParamCount[fitStr]
+	if !ok {
+		f.err = fmt.Errorf("incorrect link destination type: %s", fitStr)
+		return
+	}
+	if len(params) != count {
+		f.err = fmt.Errorf("link destination %s requires %d parameters", fitStr, count)
+		return
+	}
+	if page == -1 {
+		page = f.page
+	}
+	f.links[link] = intLinkType{page: page, fitStr: fitStr, params: params}
+}
+
+// SetLinkNamedDest makes a link point to the named destination defined
+// with AddNamedDest. The destination may be defined after this call. See
+// AddLink().
+func (f *Fpdf) SetLinkNamedDest(link int, name string) {
+	f.links[link] = intLinkType{dest: name}
+}
+
+// SetLinkRemote makes a link point to another PDF file, specified by its
+// path fileStr relative to the current document. The link opens the named
+// destination destName of the file if it is not empty, and otherwise the
+// 1-based page page, fitted in the window. See AddLink().
+func (f *Fpdf) SetLinkRemote(link int, fileStr string, page int, destName string) {
+	if f.err != nil {
+		return
+	}
+	if destName == "" && page < 1 {
+		f.err = fmt.Errorf("incorrect remote link page: %d", page)
+		return
+	}
+	f.links[link] = intLinkType{page: page, dest: destName, file: fileStr}
+}
+
+// SetLinkLaunch makes a link launch the local file fileStr, which is
+// opened by the application associated with it. Viewers usually ask the
+// user for confirmation, and some do not support launch actions at all.
+// See AddLink().
+func (f *Fpdf) SetLinkLaunch(link int, fileStr string) {
+	f.links[link] = intLinkType{file: fileStr, launch: true}
+}
+
+// BookmarkDest sets a bookmark that will be displayed in a sidebar outline
+// and points to the named destination defined with AddNamedDest. txtStr
+// is the title of the bookmark and level specifies its level in the
+// outline, as for Bookmark().
+func (f *Fpdf) BookmarkDest(txtStr string, level int, name string) {
+	f.Bookmark(txtStr, level, 0)
+	f.outlines[len(f.outlines)-1].dest = name
+}
+
+// MailtoURL returns a mailto URL that opens a new message to address, with
+// the specified subject and body if not empty. The URL can be passed to
+// LinkString(), CellFormat() and the other functions that accept an
+// external link.
+func MailtoURL(address, subject, body string) string {
+	escape := func(s string) string {
+		return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+	}
+	var query []string
+	if subject != "" {
+		query = append(query, "subject="+escape(subject))
+	}
+	if body != "" {
+		query = append(query, "body="+escape(body))
+	}
+	s := "mailto:" + address
+	if len(query) > 0 {
+		s += "?" + strings.Join(query, "&")
+	}
+	return s
+}
+
+// linkAction returns the destination or action entry of the annotation of
+// an internal link
+func (f *Fpdf) linkAction(l intLinkType) string {
+	switch {
+	case l.launch:
+		return sprintf("/A <</S /Launch /F %s>>", f.textstring(l.file))
+	case l.file != "":
+		dest := sprintf("[%d /Fit]", l.page-1)
+		if l.dest != "" {
+			dest = f.textstring(l.dest)
+		}
+		return sprintf("/A <</S /GoToR /F %s /D %s>>", f.textstring(l.file), dest)
+	case l.dest != "":
+		return "/Dest " + f.textstring(l.dest)
+	case l.fitStr != "":
+		return "/Dest " + f.destArray(l.page, l.fitStr, l.params)
+	}
+	_, h, _ := f.PageSize(l.page)
+	return sprintf("/Dest [%d 0 R /XYZ 0 %.2f null]", f.pageObj(l.page), (h-l.y)*f.k)
+}
+
+// fitParams returns the parameters of a destination of the specified type
+// that displays the vertical position y at the top of the window
+func fitParams(fitStr string, y float64) []float64 {
+	switch fitStr {
+	case "XYZ":
+		return []float64{0, y, 0}
+	case "FitH", "FitBH":
+		return []float64{y}
+	}
+	return nil
+}
+
+// destEndDoc checks that the named destinations refer to existing pages and
+// that the links and bookmarks refer to defined destinations
+func (f *Fpdf) destEndDoc() {
+	for _, name := range f.destNames() {
+		if d := f.namedDests[name]; d.page > f.page {
+			f.SetErrorf("page %d of named destination %s does not exist", d.page, name)
+		}
+	}
+	for _, l := range f.links {
+		if l.fitStr != "" && (l.page < 1 || l.page > f.page) {
+			f.SetErrorf("link destination page %d does not exist", l.page)
+		}
+		if l.dest != "" && l.file == "" {
+			if _, ok := f.namedDests[l.dest]; !ok {
+				f.SetErrorf("named destination %s of link is not defined", l.dest)
+			}
+		}
+	}
+	for _, o := range f.outlines {
+		if o.dest != "" {
+			if _, ok := f.namedDests[o.dest]; !ok {
+				f.SetErrorf("named destination %s of bookmark is not defined", o.dest)
+			}
+		}
+	}
+}
+
+// destNames returns the names of the named destinations in the order of
+// the name tree
+func (f *Fpdf) destNames() []string {
+	names := make([]string, 0, len(f.namedDests))
+	for name := range f.namedDests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// destPutNames writes the /Dests name tree of the name dictionary
+func (f *Fpdf) destPutNames() {
+	if len(f.namedDests) == 0 {
+		return
+	}
+	var b fmtBuffer
+	b.printf("/Dests <</Names [")
+	for _, name := range f.destNames() {
+		d := f.namedDests[name]
+		b.printf("%s %s ", f.textstring(name), f.destArray(d.page, d.fitStr, fitParams(d.fitStr, d.y)))
+	}
+	b.printf("]>>")
+	f.out(b.String())
+}
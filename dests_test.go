@@ -0,0 +1,73 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+func TestNamedDests(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	toc := pdf.AddLink()
+	pdf.SetLinkNamedDest(toc, "chapter2")
+	pdf.CellFormat(100, 20, "Chapter 2", "", 1, "", false, toc, "")
+	fit := pdf.AddLink()
+	pdf.SetLinkFit(fit, 2, "FitR", 10, 20, 110, 70)
+	pdf.CellFormat(100, 20, "Zoom", "", 1, "", false, fit, "")
+	remote := pdf.AddLink()
+	pdf.SetLinkRemote(remote, "other.pdf", 3, "")
+	pdf.CellFormat(100, 20, "Other", "", 1, "", false, remote, "")
+	named := pdf.AddLink()
+	pdf.SetLinkRemote(named, "other.pdf", 0, "intro")
+	pdf.CellFormat(100, 20, "Intro", "", 1, "", false, named, "")
+	launch := pdf.AddLink()
+	pdf.SetLinkLaunch(launch, "data.xlsx")
+	pdf.CellFormat(100, 20, "Data", "", 1, "", false, launch, "")
+	pdf.CellFormat(100, 20, "Mail", "", 1, "", false, 0,
+		gofpdf.MailtoURL("qa@example.com", "Report & review", "Hello there"))
+	pdf.AddPage()
+	pdf.AddNamedDest("chapter2", -1, 100, "")
+	pdf.AddNamedDest("appendix", 2, 0, "Fit")
+	pdf.BookmarkDest("Chapter 2", 0, "chapter2")
+	s := string(formOutput(t, pdf))
+	for _, want := range []string{
+		"/Dest (chapter2)>>",
+		"/Dest [5 0 R /FitR 10.00 722.00 110.00 772.00]>>",
+		"/A <</S /GoToR /F (other.pdf) /D [2 /Fit]>>>>",
+		"/A <</S /GoToR /F (other.pdf) /D (intro)>>>>",
+		"/A <</S /Launch /F (data.xlsx)>>>>",
+		"/URI (mailto:qa@example.com?subject=Report%20%26%20review&body=Hello%20there)",
+		"/Dests <</Names [(appendix) [5 0 R /Fit] (chapter2) [5 0 R /XYZ 0.00 692.00 null] ]>>",
+		"/Dest (chapter2)\n/Count 0>>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestNamedDestErrors(t *testing.T) {
+	for _, fn := range []func(pdf *gofpdf.Fpdf){
+		func(pdf *gofpdf.Fpdf) { pdf.AddNamedDest("a", 1, 0, "FitR") },
+		func(pdf *gofpdf.Fpdf) { pdf.AddNamedDest("a", 2, 0, "") },
+		func(pdf *gofpdf.Fpdf) {
+			link := pdf.AddLink()
+			pdf.SetLinkNamedDest(link, "missing")
+			pdf.Link(0, 0, 10, 10, link)
+		},
+		func(pdf *gofpdf.Fpdf) { pdf.BookmarkDest("Missing", 0, "missing") },
+		func(pdf *gofpdf.Fpdf) { pdf.SetLinkFit(pdf.AddLink(), 1, "FitH") },
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		fn(pdf)
+		pdf.Close()
+		if !pdf.Err() {
+			t.Errorf("expected error")
+		}
+	}
+}
@@ -161,7 +161,13 @@ SetFont().
 
 You should use AddUTF8Font() or AddUTF8FontFromBytes() to add a TrueType
 UTF-8 encoded font. Use RTL() and LTR() methods switch between
-“right-to-left” and “left-to-right” mode.
+“right-to-left” and “left-to-right” mode, or SetTextDirection() to let the
+direction of each paragraph follow its first strong character. Text that
+mixes both directions is displayed according to the Unicode bidirectional
+algorithm. Arabic letters are shown in the initial, medial, final or
+isolated form that fits their context, and lam followed by alef as their
+ligature, with the glyphs that the GSUB table of the font selects or, failing
+that, those of the Arabic presentation forms of its cmap table.
 
 In order to use a different non-UTF-8 TrueType or Type1 font, you will
 need to generate a font definition file and, if the font will be
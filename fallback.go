@@ -0,0 +1,159 @@
+package gofpdf
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// fontRunType is a run of text that is shown with a single font
+type fontRunType struct {
+	font fontDefType
+	txt  string
+}
+
+// SetFontFallback sets the font families used to show the runes of text that
+// the UTF-8 font family familyStr lacks. When a rune is missing from the
+// current font, it is shown with the first of the fallbacks families that has
+// it, in the style of the current font if the family has been added in that
+// style or else in its regular style. Text is split into runs of runes shown
+// with the same font.
+//
+// familyStr and each fallback family must have been added with AddUTF8Font()
+// or AddUTF8FontFromBytes() in at least one style. Calling this method without
+// fallbacks removes the fallbacks of familyStr.
+//
+// Fallback fonts are taken into account by GetStringWidth(), SplitLines(),
+// SplitText(), MultiCell() and Write(), as well as by Text() and CellFormat().
+// Runes that none of the fonts has are shown with the current font and can be
+// reported with SetMissingGlyphFunc().
+func (f *Fpdf) SetFontFallback(familyStr string, fallbacks ...string) {
+	if f.err != nil {
+		return
+	}
+	family := getFontKey(fontFamilyEscape(familyStr), "")
+	var families []string
+	for _, str := range append([]string{familyStr}, fallbacks...) {
+		fallback := getFontKey(fontFamilyEscape(str), "")
+		if !f.isUTF8Family(fallback) {
+			f.err = fmt.Errorf("undefined UTF-8 font family: %s", str)
+			return
+		}
+		families = append(families, fallback)
+	}
+	if len(fallbacks) == 0 {
+		delete(f.fontFallbacks, family)
+	} else {
+		f.fontFallbacks[family] = families[1:]
+	}
+}
+
+// SetMissingGlyphFunc sets the function that is called when a rune of text
+// shown with a UTF-8 font is missing from the font and from its fallbacks, if
+// any were set with SetFontFallback(). fnc is called with the rune and the
+// family and style of the current font each time such a rune is output, for
+// example to log data problems. The rune is shown with the .notdef glyph of
+// the current font. Specify nil to remove the function.
+func (f *Fpdf) SetMissingGlyphFunc(fnc func(r rune, familyStr, styleStr string)) {
+	f.missingGlyphFnc = fnc
+}
+
+// isUTF8Family returns true if the font family, as a font key without style,
+// has been added in at least one style as a UTF-8 font
+func (f *Fpdf) isUTF8Family(family string) bool {
+	for _, style := range []string{"", "B", "I", "BI"} {
+		if font, ok := f.fonts[family+style]; ok && font.Tp == "UTF8" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRune returns true if the font has a glyph for the rune r
+func (utf *utf8FontFile) hasRune(r rune) bool {
+	return utf != nil && utf.charSymbolDictionary[int(r)] != 0
+}
+
+// fallbackFont returns the font with which the rune r is shown if the current
+// font lacks it. ok is false if r is shown with the current font.
+func (f *Fpdf) fallbackFont(r rune) (font fontDefType, ok bool) {
+	families := f.fontFallbacks[f.fontFamily]
+	if len(families) == 0 || !f.isCurrentUTF8 || f.currentFont.utf8File.hasRune(r) {
+		return
+	}
+	for _, family := range families {
+		fb, found := f.fonts[family+f.fontStyle]
+		if !found {
+			fb, found = f.fonts[family]
+		}
+		if found && fb.utf8File.hasRune(r) {
+			return fb, true
+		}
+	}
+	return
+}
+
+// fallbackWidth returns the width of the rune r in thousandths of the em if
+// it is shown with a fallback font. ok is false if r is shown with the current
+// font.
+func (f *Fpdf) fallbackWidth(r rune) (w int, ok bool) {
+	var font fontDefType
+	if font, ok = f.fallbackFont(r); ok && int(r) < len(font.Cw) && font.Cw[r] != 65535 {
+		w = font.Cw[r]
+	}
+	return
+}
+
+// fontRuns splits the UTF-8 string txtStr into runs of runes that are shown
+// with the same font and records the runes used in fallback fonts. Runes that
+// neither the current font nor its fallbacks have are reported to the
+// function set with SetMissingGlyphFunc().
+func (f *Fpdf) fontRuns(txtStr string) (runs []fontRunType) {
+	font, start := f.currentFont, 0
+	for pos, r := range txtStr {
+		fb, ok := f.fallbackFont(r)
+		if ok {
+			fb.usedRunes[int(r)] = int(r)
+		} else {
+			fb = f.currentFont
+			if f.missingGlyphFnc != nil && !unicode.IsControl(r) && !fb.utf8File.hasRune(r) {
+				f.missingGlyphFnc(r, f.fontFamily, f.fontStyle)
+			}
+		}
+		if fb.i != font.i && pos > start {
+			runs = append(runs, fontRunType{font, txtStr[start:pos]})
+			start = pos
+		}
+		font = fb
+	}
+	return append(runs, fontRunType{font, txtStr[start:]})
+}
+
+// showString returns the operator that shows the UTF-8 string txtStr, in
+// visual order, with the current font: a TJ operator if it is kerned or else
+// the Tj operator formatted with tjFmt
+func (f *Fpdf) showString(txtStr, tjFmt string) string {
+	if kt := f.kernText(txtStr); kt != "" {
+		return sprintf("[%s] TJ", kt)
+	}
+	return sprintf(tjFmt, f.escape(utf8toutf16(txtStr, false)))
+}
+
+// showText returns the operators that show the UTF-8 string txtStr, in
+// visual order, with the current font and its fallbacks. show returns the
+// operators that show a run of text with the current font, which is switched
+// to the font of each run and restored afterwards.
+func (f *Fpdf) showText(txtStr string, show func(txt string) string) string {
+	runs := f.fontRuns(txtStr)
+	if len(runs) == 1 && runs[0].font.i == f.currentFont.i {
+		return show(txtStr)
+	}
+	current := f.currentFont
+	var s fmtBuffer
+	for _, run := range runs {
+		f.currentFont = run.font
+		s.printf("/F%s %.2f Tf %s ", run.font.i, f.fontSizePt, show(run.txt))
+	}
+	f.currentFont = current
+	s.printf("/F%s %.2f Tf", current.i, f.fontSizePt)
+	return s.String()
+}
@@ -0,0 +1,125 @@
+package gofpdf_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// fallbackPdf returns a document whose current font, calligra, lacks the
+// Cyrillic runes of dejavu and the ideograph 中 of cfftest, its fallbacks
+func fallbackPdf() *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "pt", "A4", "font")
+	pdf.AddUTF8Font("calligra", "", "calligra.ttf")
+	pdf.AddUTF8Font("dejavu", "", "DejaVuSansCondensed.ttf")
+	pdf.AddUTF8Font("cfftest", "", "CFFTest.otf")
+	pdf.SetFontFallback("calligra", "dejavu", "cfftest")
+	pdf.SetFont("calligra", "", 12)
+	return pdf
+}
+
+func TestFontFallback(t *testing.T) {
+	pdf := fallbackPdf()
+	var missing []rune
+	pdf.SetMissingGlyphFunc(func(r rune, familyStr, styleStr string) {
+		if familyStr != "calligra" || styleStr != "" {
+			t.Errorf("unexpected font %s %s", familyStr, styleStr)
+		}
+		missing = append(missing, r)
+	})
+	pdf.AddPage()
+	txt := "Jo Мир 中\ue000"
+	var want float64
+	for _, run := range []struct{ family, txt string }{
+		{"calligra", "Jo "}, {"dejavu", "Мир"}, {"calligra", " "}, {"cfftest", "中"}, {"calligra", "\ue000"},
+	} {
+		pdf.SetFont(run.family, "", 12)
+		want += pdf.GetStringWidth(run.txt)
+	}
+	pdf.SetFont("calligra", "", 12)
+	if w := pdf.GetStringWidth(txt); math.Abs(w-want) > 1e-9 {
+		t.Errorf("width %.3f, expected %.3f", w, want)
+	}
+	pdf.Text(10, 20, txt)
+	pdf.SetXY(10, 40)
+	pdf.CellFormat(0, 20, txt, "", 1, "J", false, 0, "")
+	pdf.Text(10, 80, "Jo")
+	if err := pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if string(missing) != "\ue000\ue000" {
+		t.Errorf("unexpected missing runes %q", missing)
+	}
+	r := pagesRead(t, pdf)
+	s := updateContent(t, r, 1)
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.Contains(line, " Td ") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 3 || strings.Count(lines[0], " Tf ") != 6 || strings.Count(lines[1], " Tf ") != 6 ||
+		strings.Contains(lines[2], " Tf ") || strings.Count(lines[1], "] TJ") != 5 {
+		t.Fatalf("unexpected content %q", s)
+	}
+	// The runs are shown with calligra, dejavu, calligra, cfftest and calligra,
+	// whose font is then restored
+	names := strings.Fields(lines[0])
+	var fonts []string
+	for j, name := range names {
+		if name == "Tf" {
+			fonts = append(fonts, names[j-2])
+		}
+	}
+	if fonts[0] != fonts[2] || fonts[0] != fonts[4] || fonts[0] != fonts[5] ||
+		fonts[1] == fonts[0] || fonts[3] == fonts[0] || fonts[1] == fonts[3] {
+		t.Errorf("unexpected fonts %q", fonts)
+	}
+	// The Cyrillic runes are part of the subset of dejavu
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	resFonts, _ := r.ResolveDict(res["Font"])
+	font, _ := r.ResolveDict(resFonts[pdfreader.Name(fonts[1][1:])])
+	descendants, _ := r.ResolveArray(font["DescendantFonts"])
+	desc, _ := r.ResolveDict(descendants[0])
+	widths, _ := r.ResolveArray(desc["W"])
+	if !strings.Contains(pdfreader.Format(widths), "1052 ") {
+		t.Errorf("unexpected widths %s", pdfreader.Format(widths))
+	}
+}
+
+func TestFontFallbackSplit(t *testing.T) {
+	txt := "Мир Мир"
+	for _, fallback := range []bool{true, false} {
+		pdf := fallbackPdf()
+		w := pdf.GetStringWidth(txt) + 2*pdf.GetCellMargin()
+		count := 1
+		if !fallback {
+			// The widths of missing runes are larger
+			pdf.SetFontFallback("calligra")
+			count = 2
+		}
+		if lines := pdf.SplitText(txt, w); len(lines) != count {
+			t.Errorf("fallback %v: SplitText returned %q", fallback, lines)
+		}
+		if lines := pdf.SplitLines([]byte(txt), w); len(lines) != count {
+			t.Errorf("fallback %v: SplitLines returned %q", fallback, lines)
+		}
+		pdf.AddPage()
+		pdf.MultiCell(w, 12, txt, "", "L", false)
+		_, top, _, _ := pdf.GetMargins()
+		if lines := (pdf.GetY() - top) / 12; math.Abs(lines-float64(count)) > 1e-9 {
+			t.Errorf("fallback %v: MultiCell output %.2f lines", fallback, lines)
+		}
+		if err := pdf.Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := fallbackPdf()
+	pdf.SetFontFallback("calligra", "helvetica")
+	if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), "undefined UTF-8 font family: helvetica") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -102,6 +102,7 @@ func getInfoFromTrueType(fileStr string, msgWriter io.Writer, embed bool, encLis
 	}
 	k := 1000.0 / float64(ttf.UnitsPerEm)
 	info.FontName = ttf.PostScriptName
+	info.PostScriptOutlines = ttf.PostScriptOutlines
 	info.Bold = ttf.Bold
 	info.Desc.ItalicAngle = int(ttf.ItalicAngle)
 	info.IsFixedPitch = ttf.IsFixedPitch
@@ -387,11 +388,10 @@ func makeDefinitionFile(fileStr, tpStr, encodingFileStr string, embed bool, encL
 //
 // fontFileStr is the name of the TrueType file (extension .ttf), OpenType file
 // (extension .otf) or binary Type1 file (extension .pfb) from which to
-// generate a definition file. If an OpenType file is specified, it must be one
-// that is based on TrueType outlines, not PostScript outlines; this cannot be
-// determined from the file extension alone. If a Type1 file is specified, a
-// metric file with the same pathname except with the extension .afm must be
-// present.
+// generate a definition file. An OpenType file may be based on TrueType
+// outlines or on PostScript outlines; the latter is embedded as is (FontFile3)
+// and requires PDF 1.6. If a Type1 file is specified, a metric file with the
+// same pathname except with the extension .afm must be present.
 //
 // encodingFileStr is the name of the encoding file that corresponds to the
 // font.
@@ -436,6 +436,9 @@ func MakeFont(fontFileStr, encodingFileStr, dstDirStr string, msgWriter io.Write
 		if err != nil {
 			return err
 		}
+		if info.PostScriptOutlines {
+			tpStr = "OpenType"
+		}
 	} else {
 		info, err = getInfoFromType1(fontFileStr, msgWriter, embed, encList)
 		if err != nil {
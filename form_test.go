@@ -0,0 +1,115 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+func formOutput(t *testing.T, pdf *gofpdf.Fpdf) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAddTextField(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddTextField(20, 20, 60, 8, gofpdf.FormFieldOptions{
+		Name:     "customer",
+		Value:    "Jane Doe",
+		Default:  "Name",
+		Tooltip:  "Customer name",
+		Required: true,
+		MaxLen:   40,
+		Border:   true,
+	})
+	pdf.AddTextField(20, 32, 60, 20, gofpdf.FormFieldOptions{
+		Name:      "notes",
+		Multiline: true,
+		ReadOnly:  true,
+	})
+	b := formOutput(t, pdf)
+	for _, s := range []string{
+		"/Subtype /Widget",
+		"/FT /Tx /T (customer)",
+		"/TU (Customer name)",
+		"/Ff 2",
+		"/V (Jane Doe)",
+		"/DV (Name)",
+		"/MaxLen 40",
+		"/Ff 4097",
+		"(Jane Doe) Tj",
+		"/AcroForm <</Fields [",
+	} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+}
+
+func TestFormFieldErrors(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.AddTextField(10, 10, 40, 8, gofpdf.FormFieldOptions{Name: "a"})
+	if pdf.Error() == nil {
+		t.Errorf("expecting error for text field without font")
+	}
+
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.AddCheckBox(10, 10, 5, true, gofpdf.FormFieldOptions{Name: "a"})
+	pdf.AddCheckBox(10, 20, 5, false, gofpdf.FormFieldOptions{Name: "a"})
+	if pdf.Error() == nil {
+		t.Errorf("expecting error for duplicate field name")
+	}
+
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.AddRadioGroup(nil, gofpdf.FormFieldOptions{Name: "a"})
+	if err := pdf.Output(&bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "at least one button") {
+		t.Errorf("expecting error for radio group without buttons, got %v", err)
+	}
+}
+
+func TestFormButtonsAndChoices(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddCheckBox(10, 10, 5, true, gofpdf.FormFieldOptions{Name: "agree"})
+	pdf.AddRadioGroup([]gofpdf.RadioButton{
+		{X: 10, Y: 20, Size: 5, Value: "Air"},
+		{X: 30, Y: 20, Size: 5, Value: "Sea"},
+	}, gofpdf.FormFieldOptions{Name: "transport", Value: "Sea"})
+	pdf.AddChoiceField(10, 30, 40, 8, []string{"Red", "Green"}, true,
+		gofpdf.FormFieldOptions{Name: "color", Value: "Green", TabOrder: 1})
+	pdf.AddPushButton(10, 45, 30, 10, "Print", gofpdf.FormFieldOptions{
+		Name: "print", JavaScript: "print();", TabOrder: -1})
+	b := formOutput(t, pdf)
+	for _, s := range []string{
+		"/FT /Btn /T (agree)",
+		"/V /Yes",
+		"/AS /Yes",
+		"/Ff 49152",
+		"/V /Sea",
+		"/AS /Off",
+		"/Kids [",
+		"/FT /Ch /T (color)",
+		"/Ff 131072",
+		"/Opt [(Red) (Green) ]",
+		"/Ff 65536",
+		"/S /JavaScript /JS (print\\(\\);)",
+	} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+}
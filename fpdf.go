@@ -23,6 +23,7 @@ package gofpdf
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -40,6 +41,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var gl struct {
@@ -82,21 +84,30 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	f.defPageBoxes = make(map[string]PageBox)
 	f.state = 0
 	f.fonts = make(map[string]fontDefType)
+	f.fontFallbacks = make(map[string][]string)
 	f.fontFiles = make(map[string]fontFileType)
 	f.diffs = make([]string, 0, 8)
 	f.templates = make(map[string]Template)
 	f.templateObjects = make(map[string]int)
+	f.importRefs = make(importRefsType)
+	f.importDocs = make(importDocsType)
 	f.importedObjs = make(map[string][]byte, 0)
 	f.importedObjPos = make(map[string]map[int]string, 0)
 	f.importedTplObjs = make(map[string]string)
 	f.importedTplIDs = make(map[string]int, 0)
 	f.images = make(map[string]*ImageInfoType)
+	f.iccProfiles = make(map[string]int)
 	f.pageLinks = make([][]linkType, 0, 8)
 	f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0)) // pageLinks[0] is unused (1-based)
 	f.links = make([]intLinkType, 0, 8)
 	f.links = append(f.links, intLinkType{}) // links[0] is unused (1-based)
 	f.pageAttachments = make([][]annotationAttach, 0, 8)
 	f.pageAttachments = append(f.pageAttachments, []annotationAttach{}) //
+	f.pageWidgets = make([][]*formWidget, 0, 8)
+	f.pageWidgets = append(f.pageWidgets, []*formWidget{}) // pageWidgets[0] is unused (1-based)
+	f.pageAnnots = make([][]*annotType, 0, 8)
+	f.pageAnnots = append(f.pageAnnots, []*annotType{}) // pageAnnots[0] is unused (1-based)
+	f.tag.pageMCIDs = make([][]*structElem, 1, 8)       // pageMCIDs[0] is unused (1-based)
 	f.aliasMap = make(map[string]string)
 	f.inHeader = false
 	f.inFooter = false
@@ -200,6 +211,8 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	f.alpha = 1
 	f.gradientList = make([]gradientType, 0, 8)
 	f.gradientList = append(f.gradientList, gradientType{}) // gradientList[0] is unused
+	f.patternList = append(f.patternList, patternType{})    // patternList[0] is unused
+	f.maskList = append(f.maskList, maskType{})             // maskList[0] is unused
 	// Set default PDF version number
 	f.pdfVersion = "1.3"
 	f.SetProducer("FPDF "+cnFpdfVersion, true)
@@ -399,7 +412,12 @@ func (f *Fpdf) SetPageBox(t string, x, y, wd, ht float64) {
 // pageNum is one-based. The SetPage() example demonstrates this method.
 func (f *Fpdf) SetPage(pageNum int) {
 	if (pageNum > 0) && (pageNum < len(f.pages)) {
+		if f.stream != nil && pageNum != f.page {
+			f.err = fmt.Errorf("page %d has been written to the output stream", pageNum)
+			return
+		}
 		f.page = pageNum
+		f.updateSetPage()
 	}
 }
 
@@ -637,16 +655,19 @@ func (f *Fpdf) AliasNbPages(aliasStr string) {
 		aliasStr = "{nb}"
 	}
 	f.aliasNbPagesStr = aliasStr
+	f.streamCheck()
 }
 
-// RTL enables right-to-left mode
+// RTL enables right-to-left mode, in which paragraphs of text have a
+// right-to-left base direction. See SetTextDirection() for details.
 func (f *Fpdf) RTL() {
-	f.isRTL = true
+	f.SetTextDirection("rtl")
 }
 
-// LTR disables right-to-left mode
+// LTR disables right-to-left mode, so that paragraphs of text have a
+// left-to-right base direction. See SetTextDirection() for details.
 func (f *Fpdf) LTR() {
-	f.isRTL = false
+	f.SetTextDirection("ltr")
 }
 
 // open begins a document
@@ -664,6 +685,8 @@ func (f *Fpdf) Close() {
 			f.err = fmt.Errorf("clip procedure must be explicitly ended")
 		} else if f.transformNest > 0 {
 			f.err = fmt.Errorf("transformation procedure must be explicitly ended")
+		} else if f.maskNest > 0 {
+			f.err = fmt.Errorf("mask procedure must be explicitly ended")
 		}
 	}
 	if f.err != nil {
@@ -678,17 +701,25 @@ func (f *Fpdf) Close() {
 			return
 		}
 	}
-	// Page footer
-	f.inFooter = true
-	if f.footerFnc != nil {
-		f.footerFnc()
-	} else if f.footerFncLpi != nil {
-		f.footerFncLpi(true)
+	// Page footer, which is pending on the last page added unless it has
+	// been deleted
+	if f.openPage > 0 {
+		f.page = f.openPage
+		f.inFooter = true
+		f.tagBeginArtifact("Footer")
+		if f.footerFnc != nil && !f.updatePage(f.page) {
+			f.footerFnc()
+		} else if f.footerFncLpi != nil && !f.updatePage(f.page) {
+			f.footerFncLpi(f.page == len(f.pages)-1)
+		}
+		f.tagEndArtifact()
+		f.inFooter = false
 	}
-	f.inFooter = false
 
 	// Close page
 	f.endpage()
+	f.openPage = 0
+	f.page = len(f.pages) - 1
 	// Close document
 	f.enddoc()
 	return
@@ -721,9 +752,6 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	if f.err != nil {
 		return
 	}
-	if f.page != len(f.pages)-1 {
-		f.page = len(f.pages) - 1
-	}
 	if f.state == 0 {
 		f.open()
 	}
@@ -742,21 +770,28 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	tc := f.color.text
 	cf := f.colorFlag
 
-	if f.page > 0 {
+	if f.openPage > 0 {
+		f.page = f.openPage
 		f.inFooter = true
+		f.tagBeginArtifact("Footer")
 		// Page footer avoid double call on footer.
-		if f.footerFnc != nil {
+		if f.footerFnc != nil && !f.updatePage(f.page) {
 			f.footerFnc()
 
-		} else if f.footerFncLpi != nil {
+		} else if f.footerFncLpi != nil && !f.updatePage(f.page) {
 			f.footerFncLpi(false) // not last page.
 		}
+		f.tagEndArtifact()
 		f.inFooter = false
 		// Close page
 		f.endpage()
 	}
-	// Start new page
+	// Start new page at the end of the document
+	f.page = len(f.pages) - 1
 	f.beginpage(orientationStr, size)
+	// Page settings and header are not part of the document structure
+	f.tag.hold++
+	defer func() { f.tag.hold-- }()
 	// 	Set line cap style to current value
 	// f.out("2 J")
 	f.outf("%d J", f.capStyle)
@@ -790,7 +825,9 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	// 	Page header
 	if f.headerFnc != nil {
 		f.inHeader = true
+		f.tagBeginArtifact("Header")
 		f.headerFnc()
+		f.tagEndArtifact()
 		f.inHeader = false
 		if f.headerHomeMode {
 			f.SetHomeXY()
@@ -961,10 +998,15 @@ func (f *Fpdf) GetStringSymbolWidth(s string) int {
 	}
 	w := 0
 	if f.isCurrentUTF8 {
-		unicode := []rune(s)
-		for _, char := range unicode {
+		unicode := f.shapeRunes([]rune(s))
+		for i, char := range unicode {
+			if i > 0 {
+				w += f.kern(unicode[i-1], char)
+			}
 			intChar := int(char)
-			if len(f.currentFont.Cw) >= intChar && f.currentFont.Cw[intChar] > 0 {
+			if fw, ok := f.fallbackWidth(char); ok {
+				w += fw
+			} else if len(f.currentFont.Cw) >= intChar && f.currentFont.Cw[intChar] > 0 {
 				if f.currentFont.Cw[intChar] != 65535 {
 					w += f.currentFont.Cw[intChar]
 				}
@@ -1387,13 +1429,32 @@ func (f *Fpdf) gradientClipEnd() {
 	f.out("Q")
 }
 
-func (f *Fpdf) gradient(tp, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float64) {
+func (f *Fpdf) gradient(tp int, csStr, clr1Str, clr2Str string, x1, y1, x2, y2, r float64) {
 	pos := len(f.gradientList)
+	gr := gradientType{tp: tp, csStr: csStr, clrStrs: []string{clr1Str, clr2Str}, extendStr: "true true"}
+	if tp == 2 {
+		gr.coordsStr = sprintf("%.5f %.5f %.5f %.5f", x1, y1, x2, y2)
+	} else {
+		gr.coordsStr = sprintf("%.5f %.5f 0 %.5f %.5f %.5f", x1, y1, x2, y2, r)
+	}
+	f.gradientList = append(f.gradientList, gr)
+	f.outf("/Sh%d sh", pos)
+}
+
+// rgbGradient draws a gradient between two RGB colors
+func (f *Fpdf) rgbGradient(tp, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float64) {
 	clr1 := rgbColorValue(r1, g1, b1, "", "")
 	clr2 := rgbColorValue(r2, g2, b2, "", "")
-	f.gradientList = append(f.gradientList, gradientType{tp, clr1.str, clr2.str,
-		x1, y1, x2, y2, r, 0})
-	f.outf("/Sh%d sh", pos)
+	f.gradient(tp, "DeviceRGB", clr1.str, clr2.str, x1, y1, x2, y2, r)
+}
+
+// cmykGradient draws a gradient between two CMYK colors
+func (f *Fpdf) cmykGradient(tp int, clr1, clr2 CMYKType, x1, y1, x2, y2, r float64) {
+	var c1, c2 colorType
+	c1.setCMYK(clr1.C, clr1.M, clr1.Y, clr1.K, "")
+	c2.setCMYK(clr2.C, clr2.M, clr2.Y, clr2.K, "")
+	f.color.cmykUsed = true
+	f.gradient(tp, "DeviceCMYK", c1.components(), c2.components(), x1, y1, x2, y2, r)
 }
 
 // LinearGradient draws a rectangular area with a blending of one color to
@@ -1414,7 +1475,16 @@ func (f *Fpdf) gradient(tp, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float6
 // the colors are gradually blended.
 func (f *Fpdf) LinearGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2 float64) {
 	f.gradientClipStart(x, y, w, h)
-	f.gradient(2, r1, g1, b1, r2, g2, b2, x1, y1, x2, y2, 0)
+	f.rgbGradient(2, r1, g1, b1, r2, g2, b2, x1, y1, x2, y2, 0)
+	f.gradientClipEnd()
+}
+
+// LinearGradientCMYK draws a linear gradient between two process colors of
+// the DeviceCMYK color space, whose components are percentages ranging from
+// 0 to 100. See LinearGradient() for a description of the other parameters.
+func (f *Fpdf) LinearGradientCMYK(x, y, w, h float64, clr1, clr2 CMYKType, x1, y1, x2, y2 float64) {
+	f.gradientClipStart(x, y, w, h)
+	f.cmykGradient(2, clr1, clr2, x1, y1, x2, y2, 0)
 	f.gradientClipEnd()
 }
 
@@ -1438,7 +1508,16 @@ func (f *Fpdf) LinearGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1
 // The LinearGradient() example demonstrates this method.
 func (f *Fpdf) RadialGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float64) {
 	f.gradientClipStart(x, y, w, h)
-	f.gradient(3, r1, g1, b1, r2, g2, b2, x1, y1, x2, y2, r)
+	f.rgbGradient(3, r1, g1, b1, r2, g2, b2, x1, y1, x2, y2, r)
+	f.gradientClipEnd()
+}
+
+// RadialGradientCMYK draws a radial gradient between two process colors of
+// the DeviceCMYK color space, whose components are percentages ranging from
+// 0 to 100. See RadialGradient() for a description of the other parameters.
+func (f *Fpdf) RadialGradientCMYK(x, y, w, h float64, clr1, clr2 CMYKType, x1, y1, x2, y2, r float64) {
+	f.gradientClipStart(x, y, w, h)
+	f.cmykGradient(3, clr1, clr2, x1, y1, x2, y2, r)
 	f.gradientClipEnd()
 }
 
@@ -1646,6 +1725,8 @@ func (f *Fpdf) AddFont(familyStr, styleStr, fileStr string) {
 }
 
 // AddUTF8Font imports a TrueType font with utf-8 symbols and makes it available.
+// OpenType fonts with PostScript (CFF) outlines are supported as well; they
+// are subset and embedded as CID-keyed CFF fonts.
 // It is necessary to generate a font definition file first with the makefont
 // utility. It is not necessary to call this function for the core PDF fonts
 // (courier, helvetica, times, zapfdingbats).
@@ -1933,11 +2014,16 @@ func (f *Fpdf) addFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFile
 
 		// embed font
 		if len(info.File) > 0 {
-			if info.Tp == "TrueType" {
+			if info.Tp == "TrueType" || info.Tp == "OpenType" {
 				f.fontFiles[info.File] = fontFileType{
 					length1:  int64(info.OriginalSize),
 					embedded: true,
 					content:  zFileBytes,
+					fontType: info.Tp,
+				}
+				if info.Tp == "OpenType" && f.pdfVersion < "1.6" {
+					// FontFile3 with subtype OpenType
+					f.pdfVersion = "1.6"
 				}
 			} else {
 				f.fontFiles[info.File] = fontFileType{
@@ -2001,8 +2087,12 @@ func (f *Fpdf) AddFontFromReader(familyStr, styleStr string, r io.Reader) {
 	// dbg("font [%s], type [%s]", info.File, info.Tp)
 	if len(info.File) > 0 {
 		// Embedded font
-		if info.Tp == "TrueType" {
-			f.fontFiles[info.File] = fontFileType{length1: int64(info.OriginalSize)}
+		if info.Tp == "TrueType" || info.Tp == "OpenType" {
+			f.fontFiles[info.File] = fontFileType{length1: int64(info.OriginalSize), fontType: info.Tp}
+			if info.Tp == "OpenType" && f.pdfVersion < "1.6" {
+				// FontFile3 with subtype OpenType
+				f.pdfVersion = "1.6"
+			}
 		} else {
 			f.fontFiles[info.File] = fontFileType{length1: int64(info.Size1), length2: int64(info.Size2)}
 		}
@@ -2289,7 +2379,7 @@ func (f *Fpdf) SetLink(link int, y float64, page int) {
 	if page == -1 {
 		page = f.page
 	}
-	f.links[link] = intLinkType{page, y}
+	f.links[link] = intLinkType{page: page, y: y}
 }
 
 // newLink adds a new clickable link on current page
@@ -2323,7 +2413,8 @@ func (f *Fpdf) LinkString(x, y, w, h float64, linkStr string) {
 // is the title of the bookmark. level specifies the level of the bookmark in
 // the outline; 0 is the top level, 1 is just below, and so on. y specifies the
 // vertical position of the bookmark destination in the current page; -1
-// indicates the current position.
+// indicates the current position. A bookmark set before the first page is
+// added refers to the first page.
 func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 	if y == -1 {
 		y = f.y
@@ -2331,7 +2422,7 @@ func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 	if f.isCurrentUTF8 {
 		txtStr = utf8toutf16(txtStr)
 	}
-	f.outlines = append(f.outlines, outlineType{text: txtStr, level: level, y: y, p: f.PageNo(), prev: -1, last: -1, next: -1, first: -1})
+	f.outlines = append(f.outlines, outlineType{text: txtStr, level: level, y: y, p: f.PageNo(), prev: -1, last: -1, next: -1, first: -1, id: len(f.outlines)})
 }
 
 // Text prints a character string. The origin (x, y) is on the left of the
@@ -2339,20 +2430,23 @@ func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 // precisely on the page, but it is usually easier to use Cell(), MultiCell()
 // or Write() which are the standard methods to print text.
 func (f *Fpdf) Text(x, y float64, txtStr string) {
-	var txt2 string
+	var show string
 	if f.isCurrentUTF8 {
+		txtStr = f.shapeText(txtStr)
 		if f.isRTL {
-			txtStr = reverseText(txtStr)
 			x -= f.GetStringWidth(txtStr)
 		}
-		txt2 = f.escape(utf8toutf16(txtStr, false))
+		txtStr = f.visualText(txtStr)
 		for _, uni := range []rune(txtStr) {
 			f.currentFont.usedRunes[int(uni)] = int(uni)
 		}
+		show = f.showText(txtStr, func(txt string) string {
+			return f.showString(txt, "(%s) Tj")
+		})
 	} else {
-		txt2 = f.escape(txtStr)
+		show = sprintf("(%s) Tj", f.escape(txtStr))
 	}
-	s := sprintf("BT %.2f %.2f Td (%s) Tj ET", x*f.k, (f.h-y)*f.k, txt2)
+	s := sprintf("BT %.2f %.2f Td %s ET", x*f.k, (f.h-y)*f.k, show)
 	if f.underline && txtStr != "" {
 		s += " " + f.dounderline(x, y, txtStr)
 	}
@@ -2445,9 +2539,12 @@ func (f *Fpdf) SetAcceptPageBreakFunc(fnc func() bool) {
 func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 	alignStr string, fill bool, link int, linkStr string) {
 	// dbg("CellFormat. h = %.2f, borderStr = %s", h, borderStr)
+	visualStr, visual := f.visualLine, f.hasVisualLine
+	f.visualLine, f.hasVisualLine = "", false
 	if f.err != nil {
 		return
 	}
+	txtStr = f.shapeText(txtStr)
 
 	if f.currentFont.Name == "" {
 		f.err = fmt.Errorf("font has not been set; unable to render text")
@@ -2551,49 +2648,76 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 		if f.colorFlag {
 			s.printf("q %s ", f.color.text.str)
 		}
+		if f.isCurrentUTF8 {
+			// Visual order of the text, unless it is a line of a paragraph
+			// whose order has been resolved
+			if visual {
+				txtStr = visualStr
+			} else {
+				txtStr = f.visualText(txtStr)
+			}
+		}
 		//If multibyte, Tw has no effect - do word spacing using an adjustment before each space
 		if (f.ws != 0 || alignStr == "J") && f.isCurrentUTF8 { // && f.ws != 0
-			if f.isRTL {
-				txtStr = reverseText(txtStr)
-			}
 			wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
 			for _, uni := range []rune(txtStr) {
 				f.currentFont.usedRunes[int(uni)] = int(uni)
 			}
 			space := f.escape(utf8toutf16(" ", false))
 			strSize := f.GetStringSymbolWidth(txtStr)
-			s.printf("BT 0 Tw %.2f %.2f Td [", (f.x+dx)*k, (f.h-(f.y+.5*h+.3*f.fontSize))*k)
-			t := strings.Split(txtStr, " ")
-			shift := float64((wmax - strSize)) / float64(len(t)-1)
-			numt := len(t)
-			for i := 0; i < numt; i++ {
-				tx := t[i]
-				tx = "(" + f.escape(utf8toutf16(tx, false)) + ")"
-				s.printf("%s ", tx)
-				if (i + 1) < numt {
-					s.printf("%.3f(%s) ", -shift, space)
+			shift := float64((wmax - strSize)) / float64(strings.Count(txtStr, " "))
+			justify := func(txt string) string {
+				var tj fmtBuffer
+				tj.WriteString("[")
+				t := strings.Split(txt, " ")
+				numt := len(t)
+				for i := 0; i < numt; i++ {
+					tx := t[i]
+					if kt := f.kernText(tx); kt != "" {
+						tx = kt
+					} else {
+						tx = "(" + f.escape(utf8toutf16(tx, false)) + ")"
+					}
+					tj.printf("%s ", tx)
+					if (i + 1) < numt {
+						// Kerning with the space on either side of it
+						var before, after int
+						if r := []rune(t[i]); len(r) > 0 {
+							before = f.kernVisual(r[len(r)-1], ' ')
+						}
+						if r := []rune(t[i+1]); len(r) > 0 {
+							after = f.kernVisual(' ', r[0])
+						}
+						tj.printf("%.3f(%s) ", -shift-float64(before), space)
+						if after != 0 {
+							tj.printf("%d ", -after)
+						}
+					}
 				}
+				tj.WriteString("] TJ")
+				return tj.String()
 			}
-			s.printf("] TJ ET")
+			s.printf("BT 0 Tw %.2f %.2f Td %s ET", (f.x+dx)*k, (f.h-(f.y+.5*h+.3*f.fontSize))*k,
+				f.showText(txtStr, justify))
 		} else {
-			var txt2 string
+			var show string
 			if f.isCurrentUTF8 {
-				if f.isRTL {
-					txtStr = reverseText(txtStr)
-				}
-				txt2 = f.escape(utf8toutf16(txtStr, false))
 				for _, uni := range []rune(txtStr) {
 					f.currentFont.usedRunes[int(uni)] = int(uni)
 				}
+				show = f.showText(txtStr, func(txt string) string {
+					return f.showString(txt, "(%s)Tj")
+				})
 			} else {
 
-				txt2 = strings.Replace(txtStr, "\\", "\\\\", -1)
+				txt2 := strings.Replace(txtStr, "\\", "\\\\", -1)
 				txt2 = strings.Replace(txt2, "(", "\\(", -1)
 				txt2 = strings.Replace(txt2, ")", "\\)", -1)
+				show = sprintf("(%s)Tj", txt2)
 			}
 			bt := (f.x + dx) * k
 			td := (f.h - (f.y + dy + .5*h + .3*f.fontSize)) * k
-			s.printf("BT %.2f %.2f Td (%s)Tj ET", bt, td, txt2)
+			s.printf("BT %.2f %.2f Td %s ET", bt, td, show)
 			//BT %.2F %.2F Td (%s) Tj ET',(f.x+dx)*k,(f.h-(f.y+.5*h+.3*f.FontSize))*k,txt2);
 		}
 
@@ -2627,17 +2751,6 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 	return
 }
 
-// Revert string to use in RTL languages
-func reverseText(text string) string {
-	oldText := []rune(text)
-	newText := make([]rune, len(oldText))
-	length := len(oldText) - 1
-	for i, r := range oldText {
-		newText[length-i] = r
-	}
-	return string(newText)
-}
-
 // Cell is a simpler version of CellFormat with no fill, border, links or
 // special alignment. The Cell_strikeout() example demonstrates this method.
 func (f *Fpdf) Cell(w, h float64, txtStr string) {
@@ -2656,8 +2769,10 @@ func (f *Fpdf) Cellf(w, h float64, fmtStr string, args ...interface{}) {
 // used to determine the total height of wrapped text for vertical placement
 // purposes.
 //
-// This method is useful for codepage-based fonts only. For UTF-8 encoded text,
-// use SplitText().
+// With a codepage-based font, each byte of txt is a character. With a UTF-8
+// font, txt is decoded as UTF-8 text and the kerning of the font, if enabled
+// with SetFontKerning(), is taken into account. Arabic letters are shaped as
+// described for SplitText(), which does the same for strings.
 //
 // You can use MultiCell if you want to print a text on several lines in a
 // simple way.
@@ -2671,6 +2786,9 @@ func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 	}
 	wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
 	s := bytes.Replace(txt, []byte("\r"), []byte{}, -1)
+	if f.isCurrentUTF8 {
+		s = []byte(f.shapeText(string(s)))
+	}
 	nb := len(s)
 	for nb > 0 && s[nb-1] == '\n' {
 		nb--
@@ -2680,23 +2798,35 @@ func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 	i := 0
 	j := 0
 	l := 0
+	var prev rune
 	for i < nb {
-		c := s[i]
+		c, size := rune(s[i]), 1
+		if f.isCurrentUTF8 {
+			c, size = utf8.DecodeRune(s[i:])
+		}
 		// Add bounds check for character width access
-		if int(c) < len(cw) {
-			l += cw[c]
+		if fw, ok := f.fallbackWidth(c); ok {
+			l += fw
+		} else if int(c) < len(cw) {
+			if cw[c] != 65535 { //Marker width 65535 used for zero width symbols
+				l += cw[c]
+			}
 		} else if f.currentFont.Desc.MissingWidth != 0 {
 			l += f.currentFont.Desc.MissingWidth
 		} else {
 			l += 500 // Default fallback width
 		}
+		if i > j {
+			l += f.kern(prev, c)
+		}
+		prev = c
 		if c == ' ' || c == '\t' || c == '\n' {
 			sep = i
 		}
 		if c == '\n' || l > wmax {
 			if sep == -1 {
 				if i == j {
-					i++
+					i += size
 				}
 				sep = i
 			} else {
@@ -2707,7 +2837,7 @@ func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 			j = i
 			l = 0
 		} else {
-			i++
+			i += size
 		}
 	}
 	if i != j {
@@ -2753,16 +2883,18 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 	}
 	wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
 	s := strings.Replace(txtStr, "\r", "", -1)
-	srune := []rune(s)
+	srune := f.shapeRunes([]rune(s))
 
 	// remove extra line breaks
 	var nb int
+	var para *bidiParagraph
 	if f.isCurrentUTF8 {
 		nb = len(srune)
 		for nb > 0 && srune[nb-1] == '\n' {
 			nb--
 		}
 		srune = srune[0:nb]
+		para = f.bidiParagraph(srune)
 	} else {
 		nb = len(s)
 		bytes2 := []byte(s)
@@ -2827,12 +2959,13 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			if f.isCurrentUTF8 {
 				newAlignStr := alignStr
 				if newAlignStr == "J" {
-					if f.isRTL {
+					if f.isRTLParagraph(para, j) {
 						newAlignStr = "R"
 					} else {
 						newAlignStr = "L"
 					}
 				}
+				f.bidiLine(para, j, i)
 				f.CellFormat(w, h, string(srune[j:i]), b, 2, newAlignStr, fill, 0, "")
 			} else {
 				f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
@@ -2853,15 +2986,19 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			ls = l
 			ns++
 		}
-		if int(c) >= len(cw) {
+		if fw, ok := f.fallbackWidth(c); ok {
+			l += fw
+		} else if int(c) >= len(cw) {
 			f.err = fmt.Errorf("character outside the supported range: %s", string(c))
 			return
-		}
-		if cw[int(c)] == 0 { //Marker width 0 used for missing symbols
+		} else if cw[int(c)] == 0 { //Marker width 0 used for missing symbols
 			l += f.currentFont.Desc.MissingWidth
 		} else if cw[int(c)] != 65535 { //Marker width 65535 used for zero width symbols
 			l += cw[int(c)]
 		}
+		if f.isCurrentUTF8 && i > j {
+			l += f.kern(srune[i-1], c)
+		}
 		if l > wmax {
 			// Automatic line break
 			if sep == -1 {
@@ -2873,6 +3010,7 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 					f.out("0 Tw")
 				}
 				if f.isCurrentUTF8 {
+					f.bidiLine(para, j, i)
 					f.CellFormat(w, h, string(srune[j:i]), b, 2, alignStr, fill, 0, "")
 				} else {
 					f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
@@ -2887,6 +3025,7 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 					f.outf("%.3f Tw", f.ws*f.k)
 				}
 				if f.isCurrentUTF8 {
+					f.bidiLine(para, j, sep)
 					f.CellFormat(w, h, string(srune[j:sep]), b, 2, alignStr, fill, 0, "")
 				} else {
 					f.CellFormat(w, h, s[j:sep], b, 2, alignStr, fill, 0, "")
@@ -2915,12 +3054,13 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 	}
 	if f.isCurrentUTF8 {
 		if alignStr == "J" {
-			if f.isRTL {
+			if f.isRTLParagraph(para, j) {
 				alignStr = "R"
 			} else {
 				alignStr = ""
 			}
 		}
+		f.bidiLine(para, j, i)
 		f.CellFormat(w, h, string(srune[j:i]), b, 2, alignStr, fill, 0, "")
 	} else {
 		f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
@@ -2934,14 +3074,16 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 	cw := f.currentFont.Cw
 	w := f.w - f.rMargin - f.x
 	wmax := (w - 2*f.cMargin) * 1000 / f.fontSize
-	s := strings.Replace(txtStr, "\r", "", -1)
+	s := f.shapeText(strings.Replace(txtStr, "\r", "", -1))
 	var nb int
+	var para *bidiParagraph
 	if f.isCurrentUTF8 {
 		nb = len([]rune(s))
 		if nb == 1 && s == " " {
 			f.x += f.GetStringWidth(s)
 			return
 		}
+		para = f.bidiParagraph([]rune(s))
 	} else {
 		nb = len(s)
 	}
@@ -2950,6 +3092,7 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 	j := 0
 	l := 0.0
 	nl := 1
+	var prev rune
 	for i < nb {
 		// Get next character
 		var c rune
@@ -2961,6 +3104,7 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 		if c == '\n' {
 			// Explicit line break
 			if f.isCurrentUTF8 {
+				f.bidiLine(para, j, i)
 				f.CellFormat(w, h, string([]rune(s)[j:i]), "", 2, "", false, link, linkStr)
 			} else {
 				f.CellFormat(w, h, s[j:i], "", 2, "", false, link, linkStr)
@@ -2980,7 +3124,15 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 		if c == ' ' {
 			sep = i
 		}
-		l += float64(cw[int(c)])
+		if fw, ok := f.fallbackWidth(c); ok {
+			l += float64(fw)
+		} else {
+			l += float64(cw[int(c)])
+		}
+		if f.isCurrentUTF8 && i > j {
+			l += float64(f.kern(prev, c))
+		}
+		prev = c
 		if l > wmax {
 			// Automatic line break
 			if sep == -1 {
@@ -2998,12 +3150,14 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 					i++
 				}
 				if f.isCurrentUTF8 {
+					f.bidiLine(para, j, i)
 					f.CellFormat(w, h, string([]rune(s)[j:i]), "", 2, "", false, link, linkStr)
 				} else {
 					f.CellFormat(w, h, s[j:i], "", 2, "", false, link, linkStr)
 				}
 			} else {
 				if f.isCurrentUTF8 {
+					f.bidiLine(para, j, sep)
 					f.CellFormat(w, h, string([]rune(s)[j:sep]), "", 2, "", false, link, linkStr)
 				} else {
 					f.CellFormat(w, h, s[j:sep], "", 2, "", false, link, linkStr)
@@ -3026,6 +3180,7 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 	// Last chunk
 	if i != j {
 		if f.isCurrentUTF8 {
+			f.bidiLine(para, j, nb)
 			f.CellFormat(l/1000*f.fontSize, h, string([]rune(s)[j:]), "", 0, "", false, link, linkStr)
 		} else {
 			f.CellFormat(l/1000*f.fontSize, h, s[j:], "", 0, "", false, link, linkStr)
@@ -3149,7 +3304,7 @@ func (f *Fpdf) ImageTypeFromMime(mimeStr string) (tp string) {
 	return
 }
 
-func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, allowNegativeX, flow bool, link int, linkStr string) {
+func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, options ImageOptions, flow bool, link int, linkStr string) {
 	// Automatic width and height calculation if needed
 	if w == 0 && h == 0 {
 		// Put image at 96 dpi
@@ -3192,14 +3347,16 @@ func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, allowNegativeX,
 		y = f.y
 		f.y += h
 	}
-	if !allowNegativeX {
+	if !options.AllowNegativePosition {
 		if x < 0 {
 			x = f.x
 		}
 	}
 	// dbg("h %.2f", h)
 	// q 85.04 0 0 NaN 28.35 NaN cm /I2 Do Q
+	f.tagBeginImage(options.AltText, x, y, w, h)
 	f.outf("q %.5f 0 0 %.5f %.5f %.5f cm /I%s Do Q", w*f.k, h*f.k, x*f.k, (f.h-(y+h))*f.k, info.i)
+	f.tagEndImage(options.AltText)
 	if link > 0 || len(linkStr) > 0 {
 		f.newLink(x, y, w, h, link, linkStr)
 	}
@@ -3261,7 +3418,7 @@ func (f *Fpdf) ImageOptions(imageNameStr string, x, y, w, h float64, flow bool,
 	if f.err != nil {
 		return
 	}
-	f.imageOut(info, x, y, w, h, options.AllowNegativePosition, flow, link, linkStr)
+	f.imageOut(info, x, y, w, h, options, flow, link, linkStr)
 	return
 }
 
@@ -3302,6 +3459,21 @@ type ImageOptions struct {
 	// AllowNegativePosition, when true, prevents automatic coercion of negative
 	// x coordinate values to the current x position.
 	AllowNegativePosition bool
+	// AltText is the alternate description of the image in a tagged document.
+	// Images without alternate text are marked as artifacts. See SetTagged.
+	AltText string
+	// ICCProfile is an ICC color profile that describes the colors of the
+	// image. It replaces the profile embedded in a JPEG or PNG image, if any,
+	// and must have as many color components as the image (or its palette).
+	// Images are written with an ICCBased color space when they have a
+	// profile; identical profiles are written only once.
+	ICCProfile []byte
+	// MaskImage is the name of a registered grayscale image that is used as
+	// the soft mask of the image: the image is opaque where the mask is white
+	// and transparent where it is black. The mask replaces the transparency of
+	// the image, if any, and is stretched to the size of the image. The mask
+	// image must not itself have transparency or an ICC profile.
+	MaskImage string
 }
 
 // RegisterImageOptionsReader registers an image, reading it from Reader r, adding it
@@ -3342,6 +3514,29 @@ func (f *Fpdf) RegisterImageOptionsReader(imgName string, options ImageOptions,
 	if f.err != nil {
 		return
 	}
+	if options.ICCProfile != nil {
+		info.icc = options.ICCProfile
+		f.imageICCCheck(info)
+		if f.err != nil {
+			return
+		}
+	}
+	if options.MaskImage != "" {
+		mask, ok := f.images[options.MaskImage]
+		if !ok {
+			f.err = fmt.Errorf("mask image %s is not registered", options.MaskImage)
+			return
+		}
+		if mask.cs != "DeviceGray" || len(mask.smask) > 0 || mask.mask != "" || len(mask.icc) > 0 {
+			f.err = fmt.Errorf("mask image %s is not a plain grayscale image", options.MaskImage)
+			return
+		}
+		info.smask = nil
+		info.mask = mask.i
+		if f.pdfVersion < "1.4" {
+			f.pdfVersion = "1.4"
+		}
+	}
 
 	if info.i, f.err = generateImageID(info); f.err != nil {
 		return
@@ -3579,6 +3774,24 @@ func (f *Fpdf) SetProtection(actionFlag byte, userPassStr, ownerPassStr string)
 	f.protect.setProtection(actionFlag, userPassStr, ownerPassStr)
 }
 
+// SetProtectionAlgorithm is like SetProtection but lets the encryption
+// algorithm be chosen. EncryptRC4Bits40 is equivalent to SetProtection;
+// EncryptRC4Bits128, EncryptAESBits128 and EncryptAESBits256 provide
+// stronger protection and honor the additional permission flags
+// CnProtectFillForms, CnProtectExtract, CnProtectAssemble and
+// CnProtectPrintHighRes. AES-256 encryption produces a PDF 2.0 document,
+// which older readers may not be able to open.
+func (f *Fpdf) SetProtectionAlgorithm(alg EncryptionType, actionFlag int, userPassStr, ownerPassStr string) {
+	if f.err != nil {
+		return
+	}
+	if alg < EncryptRC4Bits40 || alg > EncryptAESBits256 {
+		f.err = fmt.Errorf("unknown encryption algorithm %d", alg)
+		return
+	}
+	f.protect.setProtectionAlgorithm(alg, actionFlag, userPassStr, ownerPassStr)
+}
+
 // OutputAndClose sends the PDF document to the writer specified by w. This
 // method will close both f and w, even if an error is detected and no document
 // is produced.
@@ -3614,6 +3827,10 @@ func (f *Fpdf) Output(w io.Writer) error {
 	if f.err != nil {
 		return f.err
 	}
+	if f.stream != nil {
+		f.err = fmt.Errorf("document is written to the output stream and must be completed with Close")
+		return f.err
+	}
 	// dbg("Output")
 	if f.state < 3 {
 		f.Close()
@@ -3660,7 +3877,10 @@ func (f *Fpdf) beginpage(orientationStr string, size SizeType) {
 	if f.err != nil {
 		return
 	}
+	// In streaming mode, the previous page is complete
+	f.streamPutPage()
 	f.page++
+	f.openPage = f.page
 	// add the default page boxes, if any exist, to the page
 	f.pageBoxes[f.page] = make(map[string]PageBox)
 	for box, pb := range f.defPageBoxes {
@@ -3669,6 +3889,9 @@ func (f *Fpdf) beginpage(orientationStr string, size SizeType) {
 	f.pages = append(f.pages, bytes.NewBufferString(""))
 	f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0))
 	f.pageAttachments = append(f.pageAttachments, []annotationAttach{})
+	f.pageWidgets = append(f.pageWidgets, []*formWidget{})
+	f.pageAnnots = append(f.pageAnnots, []*annotType{})
+	f.tag.pageMCIDs = append(f.tag.pageMCIDs, nil)
 	f.state = 2
 	f.x = f.lMargin
 	f.y = f.tMargin
@@ -3701,6 +3924,7 @@ func (f *Fpdf) beginpage(orientationStr string, size SizeType) {
 }
 
 func (f *Fpdf) endpage() {
+	f.tagEndContent()
 	f.EndLayer()
 	f.state = 1
 }
@@ -3742,9 +3966,11 @@ func (f *Fpdf) escape(s string) string {
 // textstring formats a text string
 func (f *Fpdf) textstring(s string) string {
 	if f.protect.encrypted {
-		b := []byte(s)
-		f.protect.rc4(uint32(f.n), &b)
-		s = string(b)
+		enc := "(" + f.escape(string(f.protect.encrypt(f.n, []byte(s)))) + ")"
+		if f.objStm.enabled {
+			f.objStmRecord(f.n, enc, "("+f.escape(s)+")")
+		}
+		return enc
 	}
 	return "(" + f.escape(s) + ")"
 }
@@ -3830,6 +4056,10 @@ func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
 		f.err = fmt.Errorf("image JPEG buffer has unsupported color space (%v)", config.ColorModel)
 		return
 	}
+	// An embedded profile that does not match the image is ignored
+	if icc := jpegICCProfile(info.data); iccComponents(icc) == info.imageComponents() {
+		info.icc = icc
+	}
 	return
 }
 
@@ -3881,6 +4111,40 @@ func (f *Fpdf) parsegif(r io.Reader) (info *ImageInfoType) {
 	return f.parsepngstream(pngBuf, false)
 }
 
+// pageObj returns the object number of page n. Each page is written as two
+// consecutive objects, the page dictionary followed by its content stream,
+// except in streaming mode, where the content streams are written earlier.
+// The pages of an updated document keep their object numbers.
+func (f *Fpdf) pageObj(n int) int {
+	if f.update != nil {
+		if n <= len(f.update.pages) {
+			return f.update.pages[n-1].ref.Num
+		}
+		n -= len(f.update.pages)
+	}
+	if f.stream != nil {
+		return f.firstPageObj + n - 1
+	}
+	return f.firstPageObj + 2*(n-1)
+}
+
+// pagesObj returns the object number of the root of the page tree
+func (f *Fpdf) pagesObj() int {
+	if f.update != nil {
+		return f.update.pagesRef.Num
+	}
+	return 1
+}
+
+// resourcesObj returns the object number of the resource dictionary shared
+// by the pages and form XObjects
+func (f *Fpdf) resourcesObj() int {
+	if f.update != nil {
+		return f.update.resObj
+	}
+	return 2
+}
+
 // newobj begins a new object
 func (f *Fpdf) newobj() {
 	// dbg("newobj")
@@ -3888,14 +4152,14 @@ func (f *Fpdf) newobj() {
 	for j := len(f.offsets); j <= f.n; j++ {
 		f.offsets = append(f.offsets, 0)
 	}
-	f.offsets[f.n] = f.buffer.Len()
+	f.offsets[f.n] = f.offset()
 	f.outf("%d 0 obj", f.n)
 }
 
 func (f *Fpdf) putstream(b []byte) {
 	// dbg("putstream")
 	if f.protect.encrypted {
-		f.protect.rc4(uint32(f.n), &b)
+		b = f.protect.encrypt(f.n, b)
 	}
 	f.out("stream")
 	f.out(string(b))
@@ -3905,6 +4169,9 @@ func (f *Fpdf) putstream(b []byte) {
 // out; Add a line to the document
 func (f *Fpdf) out(s string) {
 	if f.state == 2 {
+		if f.tagContent() {
+			f.tagBeginContent()
+		}
 		f.pages[f.page].WriteString(s)
 		f.pages[f.page].WriteString("\n")
 	} else {
@@ -3916,6 +4183,9 @@ func (f *Fpdf) out(s string) {
 // outbuf adds a buffered line to the document
 func (f *Fpdf) outbuf(r io.Reader) {
 	if f.state == 2 {
+		if f.tagContent() {
+			f.tagBeginContent()
+		}
 		f.pages[f.page].ReadFrom(r)
 		f.pages[f.page].WriteString("\n")
 	} else {
@@ -4009,19 +4279,24 @@ func (f *Fpdf) RegisterAlias(alias, replacement string) {
 }
 
 func (f *Fpdf) replaceAliases() {
+	for n := 1; n <= f.page; n++ {
+		f.replacePageAliases(n)
+	}
+}
+
+// replacePageAliases substitutes the registered aliases on page n
+func (f *Fpdf) replacePageAliases(n int) {
 	for mode := 0; mode < 2; mode++ {
 		for alias, replacement := range f.aliasMap {
 			if mode == 1 {
 				alias = utf8toutf16(alias, false)
 				replacement = utf8toutf16(replacement, false)
 			}
-			for n := 1; n <= f.page; n++ {
-				s := f.pages[n].String()
-				if strings.Contains(s, alias) {
-					s = strings.Replace(s, alias, replacement, -1)
-					f.pages[n].Truncate(0)
-					f.pages[n].WriteString(s)
-				}
+			s := f.pages[n].String()
+			if strings.Contains(s, alias) {
+				s = strings.Replace(s, alias, replacement, -1)
+				f.pages[n].Truncate(0)
+				f.pages[n].WriteString(s)
 			}
 		}
 	}
@@ -4029,14 +4304,15 @@ func (f *Fpdf) replaceAliases() {
 
 func (f *Fpdf) putpages() {
 	var wPt, hPt float64
-	var pageSize SizeType
-	var ok bool
 	nb := f.page
+	// In streaming mode, the last page is written like the previous ones
+	f.streamPutPage()
 	if len(f.aliasNbPagesStr) > 0 {
 		// Replace number of pages
 		f.RegisterAlias(f.aliasNbPagesStr, sprintf("%d", nb))
 	}
 	f.replaceAliases()
+	f.replacePageLabelAliases()
 	if f.defOrientation == "P" {
 		wPt = f.defPageSize.Wd * f.k
 		hPt = f.defPageSize.Ht * f.k
@@ -4044,73 +4320,29 @@ func (f *Fpdf) putpages() {
 		wPt = f.defPageSize.Ht * f.k
 		hPt = f.defPageSize.Wd * f.k
 	}
-	pagesObjectNumbers := make([]int, nb+1) // 1-based
+	f.firstPageObj = f.n + 1
+	// Form field widgets and markup annotations are written after the pages
+	f.annotNumberObjects(f.formNumberObjects(f.pageObj(nb + 1)))
 	for n := 1; n <= nb; n++ {
-		// Page
-		f.newobj()
-		pagesObjectNumbers[n] = f.n // save for /Kids
-		f.out("<</Type /Page")
-		f.out("/Parent 1 0 R")
-		pageSize, ok = f.pageSizes[n]
-		if ok {
-			f.outf("/MediaBox [0 0 %.2f %.2f]", pageSize.Wd, pageSize.Ht)
-		}
-		for t, pb := range f.pageBoxes[n] {
-			f.outf("/%s [%.2f %.2f %.2f %.2f]", t, pb.X, pb.Y, pb.Wd, pb.Ht)
-		}
-		f.out("/Resources 2 0 R")
-		// Links
-		if len(f.pageLinks[n])+len(f.pageAttachments[n]) > 0 {
-			var annots fmtBuffer
-			annots.printf("/Annots [")
-			for _, pl := range f.pageLinks[n] {
-				annots.printf("<</Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] ",
-					pl.x, pl.y, pl.x+pl.wd, pl.y-pl.ht)
-				if pl.link == 0 {
-					annots.printf("/A <</S /URI /URI %s>>>>", f.textstring(pl.linkStr))
-				} else {
-					l := f.links[pl.link]
-					var sz SizeType
-					var h float64
-					sz, ok = f.pageSizes[l.page]
-					if ok {
-						h = sz.Ht
-					} else {
-						h = hPt
-					}
-					// dbg("h [%.2f], l.y [%.2f] f.k [%.2f]\n", h, l.y, f.k)
-					annots.printf("/Dest [%d 0 R /XYZ 0 %.2f null]>>", 1+2*l.page, h-l.y*f.k)
-				}
-			}
-			f.putAttachmentAnnotationLinks(&annots, n)
-			annots.printf("]")
-			f.out(annots.String())
-		}
-		if f.pdfVersion > "1.3" {
-			f.out("/Group <</Type /Group /S /Transparency /CS /DeviceRGB>>")
+		if !f.updatePage(n) {
+			f.putpage(n)
 		}
-		f.outf("/Contents %d 0 R>>", f.n+1)
-		f.out("endobj")
-		// Page content
-		f.newobj()
-		if f.compress {
-			data := sliceCompress(f.pages[n].Bytes())
-			f.outf("<</Filter /FlateDecode /Length %d>>", len(data))
-			f.putstream(data)
-		} else {
-			f.outf("<</Length %d>>", f.pages[n].Len())
-			f.putstream(f.pages[n].Bytes())
-		}
-		f.out("endobj")
+	}
+	f.formPutObjects()
+	f.annotPutObjects()
+	if f.update != nil {
+		// Pages of the updated document and its page tree
+		f.updatePutPages()
+		return
 	}
 	// Pages root
-	f.offsets[1] = f.buffer.Len()
+	f.offsets[1] = f.offset()
 	f.out("1 0 obj")
 	f.out("<</Type /Pages")
 	var kids fmtBuffer
 	kids.printf("/Kids [")
 	for i := 1; i <= nb; i++ {
-		kids.printf("%d 0 R ", pagesObjectNumbers[i])
+		kids.printf("%d 0 R ", f.pageObj(i))
 	}
 	kids.printf("]")
 	f.out(kids.String())
@@ -4120,6 +4352,87 @@ func (f *Fpdf) putpages() {
 	f.out("endobj")
 }
 
+// putpage writes the dictionary of page n, followed by its content stream
+// unless it has been written to the output stream
+func (f *Fpdf) putpage(n int) {
+	f.newobj()
+	f.out("<</Type /Page")
+	f.outf("/Parent %d 0 R", f.pagesObj())
+	if pageSize, ok := f.pageSizes[n]; ok {
+		f.outf("/MediaBox [0 0 %.2f %.2f]", pageSize.Wd, pageSize.Ht)
+	} else if f.update != nil {
+		// Added pages do not inherit the media box of the updated document
+		pageSize = f.updateDefaultSize()
+		f.outf("/MediaBox [0 0 %.2f %.2f]", pageSize.Wd*f.k, pageSize.Ht*f.k)
+	}
+	for t, pb := range f.pageBoxes[n] {
+		f.outf("/%s [%.2f %.2f %.2f %.2f]", t, pb.X, pb.Y, pb.Wd, pb.Ht)
+	}
+	f.outf("/Resources %d 0 R", f.resourcesObj())
+	if f.tag.enabled {
+		f.outf("/StructParents %d", n-1)
+	}
+	// Links
+	if f.pageHasAnnots(n) {
+		var annots fmtBuffer
+		annots.printf("/Annots [")
+		f.putPageAnnots(&annots, n)
+		annots.printf("]")
+		f.out(annots.String())
+	}
+	if f.pdfVersion > "1.3" && f.pdfaPart() != 1 {
+		f.out("/Group <</Type /Group /S /Transparency /CS /DeviceRGB>>")
+	}
+	if f.stream != nil {
+		f.outf("/Contents %d 0 R>>", f.stream.contents[n])
+		f.out("endobj")
+		return
+	}
+	f.outf("/Contents %d 0 R>>", f.n+1)
+	f.out("endobj")
+	// Page content
+	f.putpagecontent(n)
+}
+
+// pageHasAnnots reports whether page n has links or other annotations
+func (f *Fpdf) pageHasAnnots(n int) bool {
+	return len(f.pageLinks[n])+len(f.pageAttachments[n])+len(f.pageWidgets[n])+len(f.pageAnnots[n]) > 0
+}
+
+// putPageAnnots appends the links and other annotations of page n to an
+// /Annots array
+func (f *Fpdf) putPageAnnots(annots *fmtBuffer, n int) {
+	for _, pl := range f.pageLinks[n] {
+		annots.printf("<</Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] ",
+			pl.x, pl.y, pl.x+pl.wd, pl.y-pl.ht)
+		if f.pdfaPart() > 0 {
+			annots.printf("/F 4 ")
+		}
+		if pl.link == 0 {
+			annots.printf("/A <</S /URI /URI %s>>>>", f.textstring(pl.linkStr))
+		} else {
+			annots.printf("%s>>", f.linkAction(f.links[pl.link]))
+		}
+	}
+	f.putAttachmentAnnotationLinks(annots, n)
+	f.formPutAnnots(annots, n)
+	f.annotPutAnnots(annots, n)
+}
+
+// putpagecontent writes the content stream of page n
+func (f *Fpdf) putpagecontent(n int) {
+	f.newobj()
+	if f.compress {
+		data := sliceCompress(f.pages[n].Bytes())
+		f.outf("<</Filter /FlateDecode /Length %d>>", f.protect.streamLen(len(data)))
+		f.putstream(data)
+	} else {
+		f.outf("<</Length %d>>", f.protect.streamLen(f.pages[n].Len()))
+		f.putstream(f.pages[n].Bytes())
+	}
+	f.out("endobj")
+}
+
 func (f *Fpdf) putfonts() {
 	if f.err != nil {
 		return
@@ -4166,11 +4479,15 @@ func (f *Fpdf) putfonts() {
 					buf = append(buf, font[6+info.length1+6:info.length2]...)
 					font = buf
 				}
-				f.outf("<</Length %d", len(font))
+				f.outf("<</Length %d", f.protect.streamLen(len(font)))
 				if compressed {
 					f.out("/Filter /FlateDecode")
 				}
-				f.outf("/Length1 %d", info.length1)
+				if info.fontType == "OpenType" {
+					f.out("/Subtype /OpenType")
+				} else {
+					f.outf("/Length1 %d", info.length1)
+				}
 				if info.length2 > 0 {
 					f.outf("/Length2 %d /Length3 0", info.length2)
 				}
@@ -4209,14 +4526,18 @@ func (f *Fpdf) putfonts() {
 				}
 				f.out(">>")
 				f.out("endobj")
-			case "Type1":
-				fallthrough
-			case "TrueType":
-				// Additional Type1 or TrueType/OpenType font
+			case "Type1", "TrueType", "OpenType":
+				// Additional Type1 or TrueType/OpenType font. An OpenType
+				// font with PostScript outlines is a Type1 font whose
+				// program is embedded with FontFile3.
 				f.newobj()
 				f.out("<</Type /Font")
 				f.outf("/BaseFont /%s", name)
-				f.outf("/Subtype /%s", tp)
+				if tp == "OpenType" {
+					f.out("/Subtype /Type1")
+				} else {
+					f.outf("/Subtype /%s", tp)
+				}
 				f.out("/FirstChar 32 /LastChar 255")
 				f.outf("/Widths %d 0 R", f.n+1)
 				f.outf("/FontDescriptor %d 0 R", f.n+2)
@@ -4251,8 +4572,11 @@ func (f *Fpdf) putfonts() {
 				s.printf("/StemV %d ", font.Desc.StemV)
 				s.printf("/MissingWidth %d ", font.Desc.MissingWidth)
 				var suffix string
-				if tp != "Type1" {
+				switch tp {
+				case "TrueType":
 					suffix = "2"
+				case "OpenType":
+					suffix = "3"
 				}
 				s.printf("/FontFile%s %d 0 R>>", suffix, f.fontFiles[font.File].n)
 				f.out(s.String())
@@ -4266,22 +4590,38 @@ func (f *Fpdf) putfonts() {
 				compressedFontStream := sliceCompress(utf8FontStream)
 				CodeSignDictionary := font.utf8File.CodeSymbolDictionary
 				delete(CodeSignDictionary, 0)
+				// A font with PostScript outlines is embedded as a CID-keyed
+				// CFF font whose CIDs are the codes of the text, so it needs
+				// no CIDToGIDMap
+				cff := font.utf8File.cffFont != nil
+				// PDF/A-1 requires the CIDs of a subset font to be listed in
+				// a CIDSet stream. Other fonts are embedded whole and need no
+				// CharSet.
+				cidSet := f.pdfaPart() == 1
 
 				f.newobj()
 				f.out(fmt.Sprintf("<</Type /Font\n/Subtype /Type0\n/BaseFont /%s\n/Encoding /Identity-H\n/DescendantFonts [%d 0 R]\n/ToUnicode %d 0 R>>\n"+"endobj", fontName, f.n+1, f.n+2))
 
 				f.newobj()
-				f.out("<</Type /Font\n/Subtype /CIDFontType2\n/BaseFont /" + fontName + "\n" +
+				subtype := "CIDFontType2"
+				if cff {
+					subtype = "CIDFontType0"
+				}
+				f.out("<</Type /Font\n/Subtype /" + subtype + "\n/BaseFont /" + fontName + "\n" +
 					"/CIDSystemInfo " + strconv.Itoa(f.n+2) + " 0 R\n/FontDescriptor " + strconv.Itoa(f.n+3) + " 0 R")
 				if font.Desc.MissingWidth != 0 {
 					f.out("/DW " + strconv.Itoa(font.Desc.MissingWidth) + "")
 				}
 				f.generateCIDFontMap(&font, font.utf8File.LastRune)
-				f.out("/CIDToGIDMap " + strconv.Itoa(f.n+4) + " 0 R>>")
+				if cff {
+					f.out(">>")
+				} else {
+					f.out("/CIDToGIDMap " + strconv.Itoa(f.n+4) + " 0 R>>")
+				}
 				f.out("endobj")
 
 				f.newobj()
-				f.out("<</Length " + strconv.Itoa(len(toUnicode)) + ">>")
+				f.out("<</Length " + strconv.Itoa(f.protect.streamLen(len(toUnicode))) + ">>")
 				f.putstream([]byte(toUnicode))
 				f.out("endobj")
 
@@ -4305,33 +4645,66 @@ func (f *Fpdf) putfonts() {
 				s.printf(" /ItalicAngle %d", font.Desc.ItalicAngle)
 				s.printf(" /StemV %d", font.Desc.StemV)
 				s.printf(" /MissingWidth %d", font.Desc.MissingWidth)
-				s.printf("/FontFile2 %d 0 R", f.n+2)
+				if cff {
+					s.printf("/FontFile3 %d 0 R", f.n+1)
+				} else {
+					s.printf("/FontFile2 %d 0 R", f.n+2)
+				}
+				if cidSet {
+					if cff {
+						s.printf("/CIDSet %d 0 R", f.n+2)
+					} else {
+						s.printf("/CIDSet %d 0 R", f.n+3)
+					}
+				}
 				s.printf(">>")
 				f.out(s.String())
 				f.out("endobj")
 
-				// Embed CIDToGIDMap
-				cidToGidMap := make([]byte, 256*256*2)
+				if !cff {
+					// Embed CIDToGIDMap
+					cidToGidMap := make([]byte, 256*256*2)
 
-				for cc, glyph := range CodeSignDictionary {
-					cidToGidMap[cc*2] = byte(glyph >> 8)
-					cidToGidMap[cc*2+1] = byte(glyph & 0xFF)
-				}
+					for cc, glyph := range CodeSignDictionary {
+						cidToGidMap[cc*2] = byte(glyph >> 8)
+						cidToGidMap[cc*2+1] = byte(glyph & 0xFF)
+					}
 
-				cidToGidMap = sliceCompress(cidToGidMap)
-				f.newobj()
-				f.out("<</Length " + strconv.Itoa(len(cidToGidMap)) + "/Filter /FlateDecode>>")
-				f.putstream(cidToGidMap)
-				f.out("endobj")
+					cidToGidMap = sliceCompress(cidToGidMap)
+					f.newobj()
+					f.out("<</Length " + strconv.Itoa(f.protect.streamLen(len(cidToGidMap))) + "/Filter /FlateDecode>>")
+					f.putstream(cidToGidMap)
+					f.out("endobj")
+				}
 
 				//Font file
 				f.newobj()
-				f.out("<</Length " + strconv.Itoa(len(compressedFontStream)))
+				f.out("<</Length " + strconv.Itoa(f.protect.streamLen(len(compressedFontStream))))
 				f.out("/Filter /FlateDecode")
-				f.out("/Length1 " + strconv.Itoa(utf8FontSize))
+				if cff {
+					f.out("/Subtype /CIDFontType0C")
+				} else {
+					f.out("/Length1 " + strconv.Itoa(utf8FontSize))
+				}
 				f.out(">>")
 				f.putstream(compressedFontStream)
 				f.out("endobj")
+
+				if cidSet {
+					// One bit per CID, set if the font program has its glyph
+					set := make([]byte, font.utf8File.LastRune/8+1)
+					set[0] = 0x80
+					for _, r := range usedRunes {
+						if font.utf8File.hasRune(rune(r)) && r/8 < len(set) {
+							set[r/8] |= 0x80 >> uint(r%8)
+						}
+					}
+					set = sliceCompress(set)
+					f.newobj()
+					f.out("<</Length " + strconv.Itoa(f.protect.streamLen(len(set))) + "/Filter /FlateDecode>>")
+					f.putstream(set)
+					f.out("endobj")
+				}
 			default:
 				f.err = fmt.Errorf("unsupported font type: %s", tp)
 				return
@@ -4508,9 +4881,30 @@ func (f *Fpdf) putimages() {
 	// Maintain a list of inserted image SHA-1 hashes, with their
 	// corresponding object ID number.
 	insertedImages := map[string]int{}
+	for _, image := range f.images {
+		if image.n > 0 {
+			// Already written to the output stream
+			insertedImages[image.i] = image.n
+		}
+	}
+
+	// Images used as soft masks are written first, so that the images they
+	// mask can refer to them
+	masks := make(map[string]bool)
+	for _, image := range f.images {
+		if image.mask != "" {
+			masks[image.mask] = true
+		}
+	}
+	sort.SliceStable(keyList, func(i, j int) bool {
+		return masks[f.images[keyList[i]].i] && !masks[f.images[keyList[j]].i]
+	})
 
 	for _, key = range keyList {
 		image := f.images[key]
+		if image.n > 0 {
+			continue
+		}
 
 		// Check if this image has already been inserted using it's SHA-1 hash.
 		insertedImageObjN, isFound := insertedImages[image.i]
@@ -4523,11 +4917,16 @@ func (f *Fpdf) putimages() {
 		} else {
 			f.putimage(image)
 			insertedImages[image.i] = image.n
+			if f.stream != nil {
+				// The image data is no longer needed
+				image.data = nil
+			}
 		}
 	}
 }
 
 func (f *Fpdf) putimage(info *ImageInfoType) {
+	cs := f.imageColorSpace(info)
 	f.newobj()
 	info.n = f.n
 	f.out("<</Type /XObject")
@@ -4535,9 +4934,9 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 	f.outf("/Width %d", int(info.w))
 	f.outf("/Height %d", int(info.h))
 	if info.cs == "Indexed" {
-		f.outf("/ColorSpace [/Indexed /DeviceRGB %d %d 0 R]", len(info.pal)/3-1, f.n+1)
+		f.outf("/ColorSpace [/Indexed %s %d %d 0 R]", cs, len(info.pal)/3-1, f.n+1)
 	} else {
-		f.outf("/ColorSpace /%s", info.cs)
+		f.outf("/ColorSpace %s", cs)
 		if info.cs == "DeviceCMYK" {
 			f.out("/Decode [1 0 1 0 1 0 1 0]")
 		}
@@ -4556,10 +4955,17 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 		}
 		f.outf("/Mask [%s]", trns.String())
 	}
-	if info.smask != nil {
+	if info.mask != "" {
+		for _, mask := range f.images {
+			if mask.i == info.mask && mask.n > 0 {
+				f.outf("/SMask %d 0 R", mask.n)
+				break
+			}
+		}
+	} else if info.smask != nil {
 		f.outf("/SMask %d 0 R", f.n+1)
 	}
-	f.outf("/Length %d>>", len(info.data))
+	f.outf("/Length %d>>", f.protect.streamLen(len(info.data)))
 	f.putstream(info.data)
 	f.out("endobj")
 	// 	Soft mask
@@ -4581,10 +4987,10 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 		f.newobj()
 		if f.compress {
 			pal := sliceCompress(info.pal)
-			f.outf("<</Filter /FlateDecode /Length %d>>", len(pal))
+			f.outf("<</Filter /FlateDecode /Length %d>>", f.protect.streamLen(len(pal)))
 			f.putstream(pal)
 		} else {
-			f.outf("<</Length %d>>", len(info.pal))
+			f.outf("<</Length %d>>", f.protect.streamLen(len(info.pal)))
 			f.putstream(info.pal)
 		}
 		f.out("endobj")
@@ -4652,11 +5058,12 @@ func (f *Fpdf) putresourcedict() {
 	f.putxobjectdict()
 	f.out(">>")
 	count := len(f.blendList)
-	if count > 1 {
+	if count > 1 || len(f.maskList) > 1 {
 		f.out("/ExtGState <<")
 		for j := 1; j < count; j++ {
 			f.outf("/GS%d %d 0 R", j, f.blendList[j].objNum)
 		}
+		f.maskPutExtGStates()
 		f.out(">>")
 	}
 	count = len(f.gradientList)
@@ -4667,6 +5074,7 @@ func (f *Fpdf) putresourcedict() {
 		}
 		f.out(">>")
 	}
+	f.patternPutResourceDict()
 	// Layers
 	f.layerPutResourceDict()
 	f.spotColorPutResourceDict()
@@ -4687,23 +5095,37 @@ func (f *Fpdf) putBlendModes() {
 func (f *Fpdf) putGradients() {
 	count := len(f.gradientList)
 	for j := 1; j < count; j++ {
-		var f1 int
 		gr := f.gradientList[j]
-		if gr.tp == 2 || gr.tp == 3 {
+		// Gradients of more than two colors stitch a function for each pair
+		// of consecutive stops
+		var fns fmtBuffer
+		for k := 1; k < len(gr.clrStrs); k++ {
+			f.newobj()
+			f.outf("<</FunctionType 2 /Domain [0.0 1.0] /C0 [%s] /C1 [%s] /N 1>>", gr.clrStrs[k-1], gr.clrStrs[k])
+			f.out("endobj")
+			fns.printf("%d 0 R ", f.n)
+		}
+		fn := f.n
+		if len(gr.clrStrs) > 2 {
+			var bounds, encode fmtBuffer
+			for k, b := range gr.bounds {
+				if k > 0 {
+					bounds.printf(" ")
+				}
+				bounds.printf("%.5f", b)
+			}
+			for k := 1; k < len(gr.clrStrs); k++ {
+				encode.printf("0 1 ")
+			}
 			f.newobj()
-			f.outf("<</FunctionType 2 /Domain [0.0 1.0] /C0 [%s] /C1 [%s] /N 1>>", gr.clr1Str, gr.clr2Str)
+			f.outf("<</FunctionType 3 /Domain [0.0 1.0] /Functions [%s] /Bounds [%s] /Encode [%s]>>",
+				strings.TrimSpace(fns.String()), bounds.String(), strings.TrimSpace(encode.String()))
 			f.out("endobj")
-			f1 = f.n
+			fn = f.n
 		}
 		f.newobj()
-		f.outf("<</ShadingType %d /ColorSpace /DeviceRGB", gr.tp)
-		if gr.tp == 2 {
-			f.outf("/Coords [%.5f %.5f %.5f %.5f] /Function %d 0 R /Extend [true true]>>",
-				gr.x1, gr.y1, gr.x2, gr.y2, f1)
-		} else if gr.tp == 3 {
-			f.outf("/Coords [%.5f %.5f 0 %.5f %.5f %.5f] /Function %d 0 R /Extend [true true]>>",
-				gr.x1, gr.y1, gr.x2, gr.y2, gr.r, f1)
-		}
+		f.outf("<</ShadingType %d /ColorSpace /%s", gr.tp, gr.csStr)
+		f.outf("/Coords [%s] /Function %d 0 R /Extend [%s]>>", gr.coordsStr, fn, gr.extendStr)
 		f.out("endobj")
 		f.gradientList[j].objNum = f.n
 	}
@@ -4735,6 +5157,7 @@ func (f *Fpdf) putresources() {
 	f.layerPutLayers()
 	f.putBlendModes()
 	f.putGradients()
+	f.putMasks()
 	f.putSpotColors()
 	f.putfonts()
 	if f.err != nil {
@@ -4742,27 +5165,18 @@ func (f *Fpdf) putresources() {
 	}
 	f.putimages()
 	f.putTemplates()
+	f.putPatterns()
 	f.putImportedTemplates() // gofpdi
 	// 	Resource dictionary
-	f.offsets[2] = f.buffer.Len()
-	f.out("2 0 obj")
+	f.offsets[f.resourcesObj()] = f.offset()
+	f.outf("%d 0 obj", f.resourcesObj())
 	f.out("<<")
 	f.putresourcedict()
 	f.out(">>")
 	f.out("endobj")
 	f.putjavascript()
 	if f.protect.encrypted {
-		f.newobj()
-		f.protect.objNum = f.n
-		f.out("<<")
-		f.out("/Filter /Standard")
-		f.out("/V 1")
-		f.out("/R 2")
-		f.outf("/O (%s)", f.escape(string(f.protect.oValue)))
-		f.outf("/U (%s)", f.escape(string(f.protect.uValue)))
-		f.outf("/P %d", f.protect.pValue)
-		f.out(">>")
-		f.out("endobj")
+		f.putencryption()
 	}
 	return
 }
@@ -4795,24 +5209,16 @@ func (f *Fpdf) putinfo() {
 		f.outf("/Creator %s", f.textstring(f.creator))
 	}
 	creation := timeOrNow(f.creationDate)
-	f.outf("/CreationDate %s", f.textstring("D:"+creation.Format("20060102150405")))
+	f.outf("/CreationDate %s", f.textstring(f.pdfDate(creation)))
 	mod := timeOrNow(f.modDate)
-	f.outf("/ModDate %s", f.textstring("D:"+mod.Format("20060102150405")))
+	f.outf("/ModDate %s", f.textstring(f.pdfDate(mod)))
 }
 
 func (f *Fpdf) putcatalog() {
 	f.out("/Type /Catalog")
 	f.out("/Pages 1 0 R")
-	switch f.zoomMode {
-	case "fullpage":
-		f.out("/OpenAction [3 0 R /Fit]")
-	case "fullwidth":
-		f.out("/OpenAction [3 0 R /FitH null]")
-	case "real":
-		f.out("/OpenAction [3 0 R /XYZ null null 1]")
-	}
-	// } 	else if !is_string($this->zoomMode))
-	// 		$this->out('/OpenAction [3 0 R /XYZ null null '.sprintf('%.2f',$this->zoomMode/100).']');
+	// Open action and page layout
+	f.viewerPutOpenAction()
 	switch f.layoutMode {
 	case "single", "SinglePage":
 		f.out("/PageLayout /SinglePage")
@@ -4831,47 +5237,119 @@ func (f *Fpdf) putcatalog() {
 	// Bookmarks
 	if len(f.outlines) > 0 {
 		f.outf("/Outlines %d 0 R", f.outlineRoot)
-		f.out("/PageMode /UseOutlines")
 	}
+	// Page mode and viewer preferences
+	f.viewerPutCatalog()
+	// Page labels
+	f.putPageLabels()
 	// Layers
 	f.layerPutCatalog()
+	// Interactive form
+	f.formPutCatalog()
+	// Metadata and PDF/A output intent
+	f.pdfaPutCatalog()
+	// Accessibility
+	f.tagPutCatalog()
 	// Name dictionary :
 	//	-> Javascript
 	//	-> Embedded files
+	//	-> Named destinations
 	f.out("/Names <<")
 	// JavaScript
 	if f.javascript != nil {
 		f.outf("/JavaScript %d 0 R", f.nJs)
 	}
 	// Embedded files
-	f.outf("/EmbeddedFiles %s", f.getEmbeddedFiles())
+	if f.pdfaPart() == 0 || len(f.attachments) > 0 {
+		f.outf("/EmbeddedFiles %s", f.getEmbeddedFiles())
+	}
+	// Named destinations
+	f.destPutNames()
 	f.out(">>")
+	// Version required by features used after the header was streamed
+	f.streamPutVersion()
 }
 
 func (f *Fpdf) putheader() {
-	if len(f.blendMap) > 0 && f.pdfVersion < "1.4" {
+	if f.transparencyUsed() && f.pdfVersion < "1.4" {
 		f.pdfVersion = "1.4"
 	}
+	if f.protect.encrypted {
+		switch f.protect.algorithm {
+		case EncryptRC4Bits128:
+			if f.pdfVersion < "1.4" {
+				f.pdfVersion = "1.4"
+			}
+		case EncryptAESBits128:
+			if f.pdfVersion < "1.6" {
+				f.pdfVersion = "1.6"
+			}
+		case EncryptAESBits256:
+			f.pdfVersion = "2.0"
+		}
+	}
+	switch f.pdfaPart() {
+	case 1:
+		if f.pdfVersion < "1.4" {
+			f.pdfVersion = "1.4"
+		}
+	case 2, 3:
+		if f.pdfVersion < "1.7" {
+			f.pdfVersion = "1.7"
+		}
+	}
+	if f.objStm.enabled && f.pdfVersion < "1.5" {
+		f.pdfVersion = "1.5"
+	}
+	if f.stream != nil {
+		if f.stream.version != "" {
+			// The header has been written to the output stream
+			return
+		}
+		f.stream.version = f.pdfVersion
+	}
 	f.outf("%%PDF-%s", f.pdfVersion)
+	if f.pdfaPart() > 0 {
+		// Binary comment marking the file as binary, required by PDF/A
+		f.out("%\xe2\xe3\xcf\xd3")
+	}
 }
 
 func (f *Fpdf) puttrailer() {
 	f.outf("/Size %d", f.n+1)
 	f.outf("/Root %d 0 R", f.n)
 	f.outf("/Info %d 0 R", f.n-1)
+	f.puttrailerID()
+}
+
+// puttrailerID writes the encryption dictionary and file identifier entries
+// of the trailer
+func (f *Fpdf) puttrailerID() {
 	if f.protect.encrypted {
 		f.outf("/Encrypt %d 0 R", f.protect.objNum)
-		f.out("/ID [()()]")
+		if len(f.protect.id) > 0 {
+			f.outf("/ID [<%x><%x>]", f.protect.id, f.protect.id)
+		} else {
+			f.out("/ID [()()]")
+		}
+	} else if f.pdfaPart() > 0 {
+		id := md5.Sum(f.buffer.Bytes())
+		f.outf("/ID [<%x><%x>]", id, id)
 	}
 }
 
 func (f *Fpdf) putxmp() {
-	if len(f.xmp) == 0 {
+	xmp := f.xmp
+	if f.xmpGenerated() {
+		xmp = f.documentXmp()
+	}
+	if len(xmp) == 0 {
 		return
 	}
 	f.newobj()
-	f.outf("<< /Type /Metadata /Subtype /XML /Length %d >>", len(f.xmp))
-	f.putstream(f.xmp)
+	f.pdfa.objXmp = f.n
+	f.outf("<< /Type /Metadata /Subtype /XML /Length %d >>", f.protect.streamLen(len(xmp)))
+	f.putstream(xmp)
 	f.out("endobj")
 }
 
@@ -4899,8 +5377,17 @@ func (f *Fpdf) putbookmarks() {
 			lru[o.level] = i
 			level = o.level
 		}
+		// Number of visible descendants of each bookmark
+		visible := make([]int, nb+1)
+		for i := nb - 1; i >= 0; i-- {
+			o := f.outlines[i]
+			visible[o.parent]++
+			if o.open {
+				visible[o.parent] += visible[i]
+			}
+		}
 		n := f.n + 1
-		for _, o := range f.outlines {
+		for i, o := range f.outlines {
 			f.newobj()
 			f.outf("<</Title %s", f.textstring(o.text))
 			f.outf("/Parent %d 0 R", n+o.parent)
@@ -4916,14 +5403,24 @@ func (f *Fpdf) putbookmarks() {
 			if o.last != -1 {
 				f.outf("/Last %d 0 R", n+o.last)
 			}
-			f.outf("/Dest [%d 0 R /XYZ 0 %.2f null]", 1+2*o.p, (f.h-o.y)*f.k)
-			f.out("/Count 0>>")
+			f.outf("/Dest %s", f.outlineDest(o))
+			if o.color != "" {
+				f.outf("/C %s", o.color)
+			}
+			if o.style != 0 {
+				f.outf("/F %d", o.style)
+			}
+			count := visible[i]
+			if !o.open {
+				count = -count
+			}
+			f.outf("/Count %d>>", count)
 			f.out("endobj")
 		}
 		f.newobj()
 		f.outlineRoot = f.n
 		f.outf("<</Type /Outlines /First %d 0 R", n)
-		f.outf("/Last %d 0 R>>", n+lru[0])
+		f.outf("/Last %d 0 R /Count %d>>", n+lru[0], visible[nb])
 		f.out("endobj")
 	}
 }
@@ -4933,10 +5430,29 @@ func (f *Fpdf) enddoc() {
 		return
 	}
 	f.layerEndDoc()
+	f.viewerEndDoc()
+	f.destEndDoc()
+	f.outlineEndDoc()
+	f.streamCheck()
+	f.pdfaBeginDoc()
+	f.tagEndDoc()
+	if f.err != nil {
+		return
+	}
+	if f.update != nil {
+		// Incremental update of an existing document
+		f.updateEndDoc()
+		return
+	}
 	f.putheader()
 	// Embedded files
 	f.putAttachments()
 	f.putAnnotationsAttachments()
+	// Digital signature
+	f.signPutDict()
+	if f.err != nil {
+		return
+	}
 	f.putpages()
 	f.putresources()
 	if f.err != nil {
@@ -4944,8 +5460,12 @@ func (f *Fpdf) enddoc() {
 	}
 	// Bookmarks
 	f.putbookmarks()
+	// Structure tree
+	f.tagPutStructTree()
 	// Metadata
 	f.putxmp()
+	// PDF/A output intent
+	f.pdfaPutObjects()
 	// 	Info
 	f.newobj()
 	f.out("<<")
@@ -4958,8 +5478,15 @@ func (f *Fpdf) enddoc() {
 	f.putcatalog()
 	f.out(">>")
 	f.out("endobj")
+	if f.objStm.enabled {
+		// Object streams and cross-reference stream
+		f.putObjStreams()
+		f.state = 3
+		f.signDocument()
+		return
+	}
 	// Cross-ref
-	o := f.buffer.Len()
+	o := f.offset()
 	f.out("xref")
 	f.outf("0 %d", f.n+1)
 	f.out("0000000000 65535 f ")
@@ -4975,6 +5502,8 @@ func (f *Fpdf) enddoc() {
 	f.outf("%d", o)
 	f.out("%%EOF")
 	f.state = 3
+	f.signDocument()
+	f.streamFlush()
 	return
 }
 
@@ -35,6 +35,7 @@ import (
 	gofpdf "github.com/looksocial/gofpdf"
 	"github.com/looksocial/gofpdf/internal/example"
 	"github.com/looksocial/gofpdf/internal/files"
+	"github.com/looksocial/gofpdf/pdfreader"
 )
 
 func init() {
@@ -1682,6 +1683,22 @@ func ExampleFpdf_SetProtection() {
 	// Successfully generated pdf/Fpdf_SetProtection.pdf
 }
 
+// ExampleFpdf_SetProtectionAlgorithm demonstrates AES-256 encryption, which
+// is preferable to the 40-bit RC4 encryption of SetProtection.
+func ExampleFpdf_SetProtectionAlgorithm() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtectionAlgorithm(gofpdf.EncryptAESBits256,
+		gofpdf.CnProtectPrint|gofpdf.CnProtectPrintHighRes|gofpdf.CnProtectExtract, "123", "abc")
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 12)
+	pdf.Write(10, "Password-protected with AES-256.")
+	fileStr := example.Filename("Fpdf_SetProtectionAlgorithm")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetProtectionAlgorithm.pdf
+}
+
 // ExampleFpdf_Polygon displays equilateral polygons in a demonstration of the Polygon
 // function.
 func ExampleFpdf_Polygon() {
@@ -2927,3 +2944,719 @@ func ExampleFpdf_SetModificationDate() {
 	// Output:
 	// Successfully generated pdf/Fpdf_SetModificationDate.pdf
 }
+
+// ExampleFpdf_AddTextField demonstrates the interactive form fields that a
+// reader can fill in on screen.
+func ExampleFpdf_AddTextField() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetDrawColor(64, 64, 128)
+	pdf.SetFillColor(240, 240, 255)
+	pdf.Text(20, 26, "Name")
+	pdf.AddTextField(60, 20, 80, 8, gofpdf.FormFieldOptions{
+		Name: "name", Tooltip: "Full name", Required: true, Border: true, Fill: true})
+	pdf.Text(20, 38, "Comments")
+	pdf.AddTextField(60, 32, 80, 24, gofpdf.FormFieldOptions{
+		Name: "comments", Multiline: true, MaxLen: 500, Border: true, Fill: true})
+	pdf.Text(20, 67, "Newsletter")
+	pdf.AddCheckBox(60, 62, 6, true, gofpdf.FormFieldOptions{Name: "newsletter", Border: true})
+	pdf.Text(20, 79, "Shipping")
+	pdf.AddRadioGroup([]gofpdf.RadioButton{
+		{X: 60, Y: 74, Size: 6, Value: "Air"},
+		{X: 90, Y: 74, Size: 6, Value: "Sea"},
+	}, gofpdf.FormFieldOptions{Name: "shipping", Value: "Air", Default: "Air", Border: true})
+	pdf.Text(68, 79, "Air")
+	pdf.Text(98, 79, "Sea")
+	pdf.Text(20, 91, "Country")
+	pdf.AddChoiceField(60, 86, 80, 8, []string{"Canada", "France", "Japan"}, true,
+		gofpdf.FormFieldOptions{Name: "country", Value: "France", Border: true, Fill: true})
+	pdf.SetFillColor(200, 200, 220)
+	pdf.AddPushButton(60, 100, 30, 10, "Print", gofpdf.FormFieldOptions{
+		Name: "print", JavaScript: "this.print();", Border: true, Fill: true})
+	fileStr := example.Filename("Fpdf_AddTextField")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddTextField.pdf
+}
+
+// ExampleFpdf_SetPDFA demonstrates the generation of a PDF/A-3b document
+// with an embedded data file. Only embedded fonts may be used.
+func ExampleFpdf_SetPDFA() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPDFA(gofpdf.PDFA3B)
+	pdf.SetTitle("Invoice 2024-0042", true)
+	pdf.SetAuthor("Example Supplies Ltd.", true)
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddPage()
+	pdf.SetFont("dejavu", "", 14)
+	pdf.Write(8, "Invoice 2024-0042\n")
+	pdf.SetFont("dejavu", "", 11)
+	pdf.Write(6, "The machine-readable invoice data is attached to this document.")
+	pdf.SetAttachments([]gofpdf.Attachment{{
+		Content:      []byte("<Invoice><ID>2024-0042</ID></Invoice>"),
+		Filename:     "invoice.xml",
+		Description:  "Invoice data",
+		MimeType:     "text/xml",
+		Relationship: "Data",
+	}})
+	fileStr := example.Filename("Fpdf_SetPDFA")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetPDFA.pdf
+}
+
+// ExampleFpdf_SetPDFUA demonstrates the generation of an accessible tagged
+// document with headings, paragraphs and a figure.
+func ExampleFpdf_SetPDFUA() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPDFUA(true)
+	pdf.SetLang("en-US")
+	pdf.SetTitle("Annual report", true)
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("dejavu", "B", example.FontFile("DejaVuSansCondensed-Bold.ttf"))
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("dejavu", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	pdf.BeginTag("Document")
+	pdf.BeginTag("H1")
+	pdf.SetFont("dejavu", "B", 18)
+	pdf.Write(10, "Annual report")
+	pdf.EndTag()
+	pdf.Ln(14)
+	pdf.BeginTag("P")
+	pdf.SetFont("dejavu", "", 11)
+	pdf.MultiCell(0, 6, "This document is tagged: its headings, paragraphs and "+
+		"figures are identified for assistive technologies, and the page "+
+		"footers are marked as artifacts.", "", "", false)
+	pdf.EndTag()
+	pdf.ImageOptions(example.ImageFile("logo.png"), 10, 50, 30, 0, false,
+		gofpdf.ImageOptions{AltText: "gofpdf logo"}, 0, "")
+	pdf.EndTag()
+	fileStr := example.Filename("Fpdf_SetPDFUA")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetPDFUA.pdf
+}
+
+// ExampleFpdf_SetPageLabel demonstrates page labels: the front matter is
+// numbered with lowercase roman numerals, the body with decimal numbers
+// starting at 1 and the appendix with prefixed numbers. The footer prints
+// the label of each page.
+func ExampleFpdf_SetPageLabel() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPageLabel(1, "r", "", 1)
+	pdf.SetPageLabel(3, "D", "", 1)
+	pdf.SetPageLabel(6, "D", "A-", 1)
+	pdf.AliasPageLabel("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, "Page {pl}", "", 0, "C", false, 0, "")
+	})
+	titles := []string{"Title page", "Contents", "Introduction", "Results",
+		"Discussion", "Appendix: data", "Appendix: methods"}
+	pdf.SetFont("Helvetica", "B", 16)
+	for _, title := range titles {
+		pdf.AddPage()
+		pdf.Cell(0, 10, title)
+	}
+	fileStr := example.Filename("Fpdf_SetPageLabel")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetPageLabel.pdf
+}
+
+// ExampleFpdf_SetViewerPreferences demonstrates the viewer controls of the
+// document catalog: the window shows the document title and fits the
+// first page, the bookmarks panel is hidden in favor of page thumbnails and
+// the document opens on its second page fitted to the window width.
+func ExampleFpdf_SetViewerPreferences() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Viewer preferences", true)
+	pdf.SetViewerPreferences(gofpdf.ViewerPreferences{
+		FitWindow:       true,
+		CenterWindow:    true,
+		DisplayDocTitle: true,
+		PrintScaling:    "None",
+		Duplex:          "DuplexFlipLongEdge",
+	})
+	pdf.SetPageMode("UseThumbs")
+	pdf.SetFont("Helvetica", "", 16)
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		pdf.Bookmark(fmt.Sprintf("Page %d", j), 0, 0)
+		pdf.Cell(0, 10, fmt.Sprintf("Page %d", j))
+	}
+	pdf.SetOpenAction(2, "FitH", 0)
+	fileStr := example.Filename("Fpdf_SetViewerPreferences")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetViewerPreferences.pdf
+}
+
+// ExampleFpdf_AddTextAnnotation demonstrates markup annotations of the kind
+// added by reviewers: a sticky note, highlighted and struck out text, a
+// shape around a figure and an approval stamp.
+func ExampleFpdf_AddTextAnnotation() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	opt := gofpdf.AnnotationOptions{Author: "QA", Subject: "Review"}
+	pdf.SetXY(20, 20)
+	pdf.Cell(80, 8, "Total amount due: 1,250.00")
+	pdf.SetDrawColor(255, 230, 0)
+	opt.Contents = "Please confirm the amount with accounting."
+	pdf.AddTextMarkupAnnotation("Highlight", 20, 20, 80, 8, opt)
+	pdf.AddTextAnnotation(102, 20, "Comment", opt)
+	pdf.SetXY(20, 32)
+	pdf.Cell(80, 8, "Payment within 60 days")
+	pdf.SetDrawColor(200, 0, 0)
+	opt.Contents = "Terms changed to 30 days."
+	pdf.AddTextMarkupAnnotation("StrikeOut", 20, 32, 80, 8, opt)
+	pdf.SetLineWidth(0.8)
+	pdf.Rect(30, 50, 60, 40, "D")
+	opt.Contents = "Replace this chart with the updated one."
+	pdf.AddCircleAnnotation(25, 45, 70, 50, opt)
+	pdf.SetDrawColor(0, 128, 0)
+	opt.Contents = ""
+	pdf.AddStampAnnotation(120, 60, 60, 20, "Approved", opt)
+	fileStr := example.Filename("Fpdf_AddTextAnnotation")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddTextAnnotation.pdf
+}
+
+// ExampleFpdf_AddNamedDest demonstrates named destinations: the table of
+// contents and the bookmarks refer to the chapters by name, one link
+// zooms on a figure and another opens a mail message.
+func ExampleFpdf_AddNamedDest() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 14)
+	chapters := []string{"Introduction", "Methods", "Results"}
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Contents")
+	pdf.Ln(12)
+	for j, title := range chapters {
+		link := pdf.AddLink()
+		pdf.SetLinkNamedDest(link, fmt.Sprintf("chapter%d", j+1))
+		pdf.CellFormat(0, 8, title, "", 1, "", false, link, "")
+	}
+	figure := pdf.AddLink()
+	pdf.SetLinkFit(figure, 4, "FitR", 20, 40, 120, 100)
+	pdf.CellFormat(0, 8, "Figure 1", "", 1, "", false, figure, "")
+	pdf.CellFormat(0, 8, "Send comments", "", 1, "", false, 0,
+		gofpdf.MailtoURL("review@example.com", "Comments on the report", ""))
+	for j, title := range chapters {
+		pdf.AddPage()
+		name := fmt.Sprintf("chapter%d", j+1)
+		pdf.AddNamedDest(name, -1, -1, "FitH")
+		pdf.BookmarkDest(title, 0, name)
+		pdf.Cell(0, 10, title)
+	}
+	pdf.Rect(20, 40, 100, 60, "D")
+	fileStr := example.Filename("Fpdf_AddNamedDest")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddNamedDest.pdf
+}
+
+// ExampleFpdf_BookmarkOptions demonstrates styled bookmarks and an outline
+// edited after the pages are written: the chapters are expanded and
+// colored, and the bookmark of the summary, which is written last, is
+// inserted at the top of the outline and points to the first page.
+func ExampleFpdf_BookmarkOptions() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Summary")
+	first := -1
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		pdf.Cell(0, 10, fmt.Sprintf("Chapter %d", j))
+		id := pdf.BookmarkOptions(fmt.Sprintf("Chapter %d", j), 0, gofpdf.BookmarkOptions{
+			Color: []int{0, 0, 160}, Bold: true, Open: true, Fit: "FitH",
+		})
+		if first < 0 {
+			first = id
+		}
+		for k := 1; k <= 2; k++ {
+			pdf.Ln(20)
+			pdf.Cell(0, 10, fmt.Sprintf("Section %d.%d", j, k))
+			pdf.BookmarkOptions(fmt.Sprintf("Section %d.%d", j, k), 1, gofpdf.BookmarkOptions{
+				Italic: true, Y: pdf.GetY(),
+			})
+		}
+	}
+	pdf.InsertBookmark(first, "Summary", 0, gofpdf.BookmarkOptions{Page: 1, Fit: "Fit"})
+	fileStr := example.Filename("Fpdf_BookmarkOptions")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_BookmarkOptions.pdf
+}
+
+// ExampleFpdf_SetObjectStreams demonstrates compact output of a long
+// document. The page, font and outline dictionaries are packed into
+// compressed object streams and the cross-reference table is written as a
+// compressed stream.
+func ExampleFpdf_SetObjectStreams() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetObjectStreams(true)
+	pdf.SetFont("Helvetica", "", 16)
+	for j := 1; j <= 50; j++ {
+		pdf.AddPage()
+		pdf.Cell(0, 10, fmt.Sprintf("Page %d", j))
+		pdf.Bookmark(fmt.Sprintf("Page %d", j), 0, 0)
+	}
+	fileStr := example.Filename("Fpdf_SetObjectStreams")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetObjectStreams.pdf
+}
+
+// ExampleFpdf_SetOutputStream demonstrates the generation of a long
+// document that is written to its file as it is produced, so that only the
+// current page is held in memory.
+func ExampleFpdf_SetOutputStream() {
+	fileStr := example.Filename("Fpdf_SetOutputStream")
+	fl, err := os.Create(fileStr)
+	if err != nil {
+		example.Summary(err, fileStr)
+		return
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetOutputStream(fl)
+	pdf.SetFont("Helvetica", "", 12)
+	for j := 1; j <= 500; j++ {
+		pdf.AddPage()
+		pdf.Image(example.ImageFile("logo.png"), 10, 10, 30, 0, false, "", 0, "")
+		pdf.SetY(40)
+		for k := 1; k <= 20; k++ {
+			pdf.CellFormat(0, 10, fmt.Sprintf("Statement %d, line %d", j, k), "B", 1, "", false, 0, "")
+		}
+	}
+	pdf.Close()
+	fl.Close()
+	example.Summary(pdf.Error(), fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetOutputStream.pdf
+}
+
+// ExampleFpdf_OpenUpdate demonstrates the incremental update of an existing
+// document. A page is stamped, a link is added to it and a page is appended.
+func ExampleFpdf_OpenUpdate() {
+	var orig bytes.Buffer
+	src := gofpdf.New("P", "mm", "A4", "")
+	src.SetFont("Helvetica", "", 16)
+	for j := 1; j <= 2; j++ {
+		src.AddPage()
+		src.Cell(40, 10, fmt.Sprintf("Original page %d", j))
+	}
+	err := src.Output(&orig)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "B", 24)
+	pdf.OpenUpdate(bytes.NewReader(orig.Bytes()))
+	pdf.SetPage(1)
+	pdf.SetTextColor(200, 0, 0)
+	pdf.TransformBegin()
+	pdf.TransformRotate(30, 105, 150)
+	pdf.Text(60, 150, "APPROVED")
+	pdf.TransformEnd()
+	pdf.SetFont("Helvetica", "U", 12)
+	pdf.SetTextColor(0, 0, 255)
+	pdf.SetXY(10, 30)
+	pdf.WriteLinkString(6, "github.com/looksocial/gofpdf", "https://github.com/looksocial/gofpdf")
+	pdf.SetPage(2)
+	pdf.AddPage()
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.Cell(40, 10, "Appended page")
+	fileStr := example.Filename("Fpdf_OpenUpdate")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_OpenUpdate.pdf
+}
+
+// ExampleFpdf_ImportPage demonstrates the import of the pages of an existing
+// document, which are placed two by two on landscape pages.
+func ExampleFpdf_ImportPage() {
+	var orig bytes.Buffer
+	src := gofpdf.New("P", "mm", "A4", "")
+	src.SetFont("Helvetica", "", 48)
+	for j := 1; j <= 4; j++ {
+		src.AddPage()
+		src.SetFillColor(60*j, 200, 255-60*j)
+		src.Rect(20, 20, 170, 257, "F")
+		src.CellFormat(170, 100, fmt.Sprintf("Page %d", j), "", 0, "C", false, 0, "")
+	}
+	err := src.Output(&orig)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	r, err := pdfreader.NewReaderBytes(orig.Bytes())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	for n := 1; n <= r.NumPages(); n++ {
+		if n%2 == 1 {
+			pdf.AddPage()
+		}
+		tpl := pdf.ImportPage(r, n, "media")
+		pdf.UseTemplateScaled(tpl, gofpdf.PointType{X: 10 + float64((n-1)%2)*143.5, Y: 5},
+			gofpdf.SizeType{Wd: 133.5, Ht: 200})
+	}
+	fileStr := example.Filename("Fpdf_ImportPage")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImportPage.pdf
+}
+
+// ExampleFpdf_MovePage demonstrates a table of contents that is written once
+// the chapters, and the pages on which they start, are known. It is then
+// moved to the front of the document, and a cover page is inserted before it.
+// The page numbers of the footers are written with an alias, which is
+// replaced with the final number of each page.
+func ExampleFpdf_MovePage() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AliasPageLabel("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, "Page {pl}", "", 0, "C", false, 0, "")
+	})
+	type entry struct {
+		title string
+		link  int
+	}
+	var contents []entry
+	for j := 1; j <= 3; j++ {
+		for k := 0; k < j; k++ {
+			pdf.AddPage()
+			if k == 0 {
+				e := entry{title: fmt.Sprintf("Chapter %d", j), link: pdf.AddLink()}
+				pdf.SetLink(e.link, 0, -1)
+				pdf.SetFont("Helvetica", "B", 16)
+				pdf.Cell(0, 10, e.title)
+				pdf.Ln(15)
+				contents = append(contents, e)
+			}
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.MultiCell(0, 6, strings.Repeat(lorem(), 2), "", "J", false)
+		}
+	}
+	// The chapters are shifted by two pages: the cover and the contents
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.Cell(0, 10, "Contents")
+	pdf.Ln(15)
+	pdf.SetFont("Helvetica", "", 12)
+	for j, e := range contents {
+		page := 3 + j*(j+1)/2
+		pdf.CellFormat(0, 8, fmt.Sprintf("%s ...... %d", e.title, page), "", 1, "L", false, e.link, "")
+	}
+	pdf.MovePage(pdf.PageCount(), 1)
+	pdf.InsertPageAt(1)
+	pdf.SetFont("Helvetica", "B", 32)
+	pdf.SetY(120)
+	pdf.CellFormat(0, 20, "Annual report", "", 0, "C", false, 0, "")
+	fileStr := example.Filename("Fpdf_MovePage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MovePage.pdf
+}
+
+// ExampleFpdf_SetFillColorCMYK demonstrates process colors of the DeviceCMYK
+// color space, as required by many print shops, and DeviceGray levels.
+func ExampleFpdf_SetFillColorCMYK() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColorCMYK(0, 0, 0, 100)
+	pdf.Cell(0, 10, "Process colors")
+	pdf.Ln(15)
+	inks := []struct {
+		name       string
+		c, m, y, k byte
+	}{
+		{"Cyan", 100, 0, 0, 0},
+		{"Magenta", 0, 100, 0, 0},
+		{"Yellow", 0, 0, 100, 0},
+		{"Black", 0, 0, 0, 100},
+		{"Rich black", 60, 40, 40, 100},
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetDrawColorGray(128)
+	for j, ink := range inks {
+		x := 10 + float64(j)*38
+		pdf.SetFillColorCMYK(ink.c, ink.m, ink.y, ink.k)
+		pdf.Rect(x, 30, 34, 34, "FD")
+		pdf.SetXY(x, 66)
+		pdf.CellFormat(34, 6, ink.name, "", 0, "C", false, 0, "")
+	}
+	pdf.SetXY(10, 80)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.Cell(0, 10, "Gray levels")
+	for j := 0; j <= 10; j++ {
+		pdf.SetFillColorGray(j * 255 / 10)
+		pdf.Rect(10+float64(j)*17, 95, 15, 15, "FD")
+	}
+	pdf.SetXY(10, 120)
+	pdf.Cell(0, 10, "Gradients")
+	pdf.LinearGradientCMYK(10, 135, 90, 40, gofpdf.CMYKType{C: 100}, gofpdf.CMYKType{M: 100, Y: 100}, 0, 0, 1, 0)
+	pdf.RadialGradientCMYK(110, 135, 90, 40, gofpdf.CMYKType{Y: 100}, gofpdf.CMYKType{C: 60, K: 80}, 0.5, 0.5, 0.5, 0.5, 0.8)
+	fileStr := example.Filename("Fpdf_SetFillColorCMYK")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFillColorCMYK.pdf
+}
+
+// This example demonstrates tiling patterns. The hatch patterns are
+// uncolored, so each one is painted in the fill color current when it is set.
+func ExampleFpdf_CreateHatchPattern() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddPage()
+	styles := []string{"horizontal", "vertical", "diagonal", "backdiagonal", "cross", "diagonalcross", "dots"}
+	for j, style := range styles {
+		x := 10 + float64(j%4)*48
+		y := 15 + float64(j/4)*50
+		pdf.SetFillColor(0, 0, 160)
+		pdf.SetFillPattern(pdf.CreateHatchPattern(style, 2.5, 0.3))
+		pdf.Rect(x, y, 40, 35, "FD")
+		pdf.SetXY(x, y+36)
+		pdf.CellFormat(40, 6, style, "", 0, "C", false, 0, "")
+	}
+	// A colored pattern drawn like a template
+	bricks := pdf.CreatePattern(12, 6, false, func(tpl *gofpdf.Tpl) {
+		tpl.SetFillColor(180, 60, 40)
+		tpl.SetDrawColor(230, 230, 230)
+		tpl.SetLineWidth(0.5)
+		tpl.Rect(0, 0, 12, 3, "FD")
+		tpl.Rect(-6, 3, 12, 3, "FD")
+		tpl.Rect(6, 3, 12, 3, "FD")
+	})
+	pdf.SetFillPattern(bricks)
+	pdf.Polygon([]gofpdf.PointType{{X: 10, Y: 175}, {X: 60, Y: 125}, {X: 110, Y: 175}}, "FD")
+	// Text painted with a hatch pattern in red
+	pdf.SetFont("Helvetica", "B", 60)
+	pdf.SetTextColor(200, 0, 0)
+	pdf.SetTextPattern(pdf.CreateHatchPattern("diagonal", 1.2, 0.4))
+	pdf.Text(10, 210, "Hatched")
+	fileStr := example.Filename("Fpdf_CreateHatchPattern")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_CreateHatchPattern.pdf
+}
+
+// This example demonstrates gradients of several colors used as fill colors
+// of shapes and text, and a gradient of the opacity painted over an image.
+func ExampleFpdf_AddGradient() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	rainbow := []gofpdf.GradientStop{
+		{Offset: 0, R: 228, G: 3, B: 3},
+		{Offset: 0.2, R: 255, G: 140},
+		{Offset: 0.4, R: 255, G: 237},
+		{Offset: 0.6, G: 128, B: 38},
+		{Offset: 0.8, G: 77, B: 255},
+		{Offset: 1, R: 117, G: 7, B: 135},
+	}
+	pdf.SetFillPattern(pdf.AddGradient(gofpdf.Gradient{X1: 10, Y1: 0, X2: 200, Y2: 0, Stops: rainbow}))
+	pdf.RoundedRect(10, 10, 190, 30, 8, "1234", "F")
+	pdf.SetFont("Helvetica", "B", 56)
+	pdf.SetTextPattern(pdf.AddGradient(gofpdf.Gradient{X1: 0, Y1: 50, X2: 0, Y2: 75, Stops: rainbow}))
+	pdf.Text(10, 70, "Gradient text")
+	pdf.SetFillPattern(pdf.AddGradient(gofpdf.Gradient{
+		Radial: true, X1: 60, Y1: 100, X2: 70, Y2: 110, R2: 40,
+		Stops: []gofpdf.GradientStop{
+			{Offset: 0, R: 255, G: 255, B: 255},
+			{Offset: 0.7, R: 0, G: 90, B: 160},
+			{Offset: 1, R: 0, G: 30, B: 60},
+		},
+		ExtendEnd: true,
+	}))
+	pdf.Polygon([]gofpdf.PointType{{X: 70, Y: 80}, {X: 110, Y: 150}, {X: 30, Y: 150}}, "F")
+	// The image fades out to the right
+	pdf.ImageOptions(example.ImageFile("logo.jpg"), 120, 90, 80, 0, false,
+		gofpdf.ImageOptions{}, 0, "")
+	fade := pdf.AddGradient(gofpdf.Gradient{X1: 120, Y1: 0, X2: 200, Y2: 0,
+		Stops: []gofpdf.GradientStop{
+			{Offset: 0, R: 255, G: 255, B: 255, Transparency: 1},
+			{Offset: 1, R: 255, G: 255, B: 255},
+		}})
+	pdf.ClipRect(120, 80, 80, 80, false)
+	pdf.PaintGradient(fade)
+	pdf.ClipEnd()
+	fileStr := example.Filename("Fpdf_AddGradient")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddGradient.pdf
+}
+
+// This example demonstrates soft masks. A photograph fades out through a
+// luminosity mask drawn with a radial gradient, and a rectangle fades in
+// through an alpha mask drawn with increasingly opaque strips.
+func ExampleFpdf_BeginMask() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	vignette := pdf.AddGradient(gofpdf.Gradient{
+		Radial: true, X1: 60, Y1: 50, X2: 60, Y2: 50, R2: 45,
+		Stops: []gofpdf.GradientStop{
+			{Offset: 0.5, R: 255, G: 255, B: 255},
+			{Offset: 1},
+		},
+	})
+	pdf.BeginMask("Luminosity", func() {
+		pdf.PaintGradient(vignette)
+	})
+	pdf.ImageOptions(example.ImageFile("logo.jpg"), 15, 15, 90, 0, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.EndMask()
+	pdf.BeginMask("Alpha", func() {
+		for j := 0; j < 10; j++ {
+			pdf.SetAlpha(float64(j+1)/10, "")
+			pdf.Rect(120+float64(j)*8, 20, 8, 60, "F")
+		}
+	})
+	pdf.SetFillColor(0, 90, 160)
+	pdf.Rect(120, 20, 80, 60, "F")
+	pdf.EndMask()
+	fileStr := example.Filename("Fpdf_BeginMask")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_BeginMask.pdf
+}
+
+// This example demonstrates pair kerning. The same headline is printed
+// without and with the kerning of the font, which tightens pairs such as
+// "AV" and "To".
+func ExampleFpdf_SetFontKerning() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "B", example.FontFile("DejaVuSansCondensed-Bold.ttf"))
+	pdf.SetFont("dejavu", "B", 36)
+	pdf.AddPage()
+	for _, kerning := range []bool{false, true} {
+		pdf.SetFontKerning("dejavu", "B", kerning)
+		pdf.CellFormat(0, 16, "AVAST! Tow Yachts", "1", 1, "C", false, 0, "")
+		pdf.Ln(4)
+	}
+	pdf.SetFont("dejavu", "B", 12)
+	pdf.MultiCell(80, 6, "WAVE VAT. Today, Tomorrow, Yesterday: LTA Paved Way", "1", "J", false)
+	fileStr := example.Filename("Fpdf_SetFontKerning")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFontKerning.pdf
+}
+
+// This example demonstrates the use of an OpenType font with PostScript
+// (CFF) outlines. The test font only has glyphs for the digits 0 and 1, the
+// letter Q and the ideograph 中.
+func ExampleFpdf_AddUTF8Font_cff() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("cfftest", "", example.FontFile("CFFTest.otf"))
+	pdf.AddPage()
+	for j, size := range []float64{12, 24, 48} {
+		pdf.SetFont("cfftest", "", size)
+		pdf.Text(20, 30+float64(j)*30, "0110Q中")
+	}
+	fileStr := example.Filename("Fpdf_AddUTF8Font_cff")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddUTF8Font_cff.pdf
+}
+
+// This example demonstrates font fallback. Calligrapher has no Cyrillic
+// letters, which are shown with DejaVu, and none of the fonts has the
+// ideograph 国, which is reported by the missing glyph function.
+func ExampleFpdf_SetFontFallback() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("calligra", "", example.FontFile("calligra.ttf"))
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("cfftest", "", example.FontFile("CFFTest.otf"))
+	pdf.SetFontFallback("calligra", "dejavu", "cfftest")
+	var missing []string
+	pdf.SetMissingGlyphFunc(func(r rune, familyStr, styleStr string) {
+		missing = append(missing, fmt.Sprintf("%U", r))
+	})
+	pdf.SetFont("calligra", "", 16)
+	pdf.AddPage()
+	pdf.MultiCell(80, 8, "Customers: Ivan Petrov (Иван Петров), Zhong (中) "+
+		"and Guo (国)", "1", "L", false)
+	pdf.Ln(4)
+	pdf.MultiCell(80, 8, "Missing glyphs: "+strings.Join(missing, ", "), "", "L", false)
+	fileStr := example.Filename("Fpdf_SetFontFallback")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFontFallback.pdf
+}
+
+// This example demonstrates bidirectional text. Hebrew words are displayed
+// from right to left, while the numbers and English words they contain keep
+// their order. Wrapped paragraphs are reordered line by line.
+func ExampleFpdf_SetTextDirection() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.SetFont("dejavu", "", 14)
+	pdf.AddPage()
+	txt := "הזמנה מספר 1024 (Model X-200): 3 יחידות, סה״כ 1,250.00 ₪."
+	for _, dirStr := range []string{"ltr", "rtl", "auto"} {
+		pdf.SetTextDirection(dirStr)
+		pdf.CellFormat(0, 8, dirStr+":", "", 1, "", false, 0, "")
+		pdf.MultiCell(90, 8, txt, "1", "R", false)
+		pdf.Ln(4)
+	}
+	fileStr := example.Filename("Fpdf_SetTextDirection")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetTextDirection.pdf
+}
+
+// This example demonstrates Arabic text, whose letters are joined in the
+// forms that fit their context and wrapped according to the widths of those
+// forms.
+func ExampleFpdf_MultiCell_arabic() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.SetFont("dejavu", "", 16)
+	pdf.AddPage()
+	pdf.RTL()
+	pdf.MultiCell(70, 10, "مرحبا بالعالم! رقم الطلب 1024 جاهز للشحن، "+
+		"والتسليم خلال ثلاثة أيام.", "1", "R", false)
+	fileStr := example.Filename("Fpdf_MultiCell_arabic")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MultiCell_arabic.pdf
+}
@@ -0,0 +1,170 @@
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// GradientStop specifies the color of a gradient at a position along the
+// gradient
+type GradientStop struct {
+	// Offset is the position of the stop, from 0 at the start of the gradient
+	// to 1 at its end
+	Offset float64
+	// R, G and B are the components of an RGB color, from 0 to 255
+	R, G, B int
+	// CMYK, if not nil, is a process color that is used instead of R, G and
+	// B. Either all stops of a gradient or none of them specify this color.
+	CMYK *CMYKType
+	// Transparency is the transparency of the color, from 0 (opaque) to 1
+	// (invisible), so that the zero value is opaque.
+	Transparency float64
+}
+
+// Gradient specifies a linear or radial gradient of any number of colors.
+// Its coordinates are in the unit of measure of the document, relative to the
+// upper left corner of the current page when the gradient is defined.
+type Gradient struct {
+	// Radial is true for a radial gradient between two circles, false for a
+	// linear gradient along an axis
+	Radial bool
+	// (X1, Y1) is the start point of the axis of a linear gradient or the
+	// center of the start circle of a radial gradient; (X2, Y2) is the end
+	// point or the center of the end circle
+	X1, Y1, X2, Y2 float64
+	// R1 and R2 are the radii of the start and end circles of a radial
+	// gradient
+	R1, R2 float64
+	// Stops are the colors of the gradient, in order of increasing offset.
+	// At least two stops are required. The first color is used up to the
+	// offset of the first stop and the last color beyond the offset of the
+	// last stop.
+	Stops []GradientStop
+	// ExtendStart and ExtendEnd extend the gradient with its first and last
+	// colors before its start and beyond its end
+	ExtendStart, ExtendEnd bool
+}
+
+// AddGradient defines a gradient of several colors and returns its
+// identifier, which is a pattern identifier as returned by CreatePattern().
+// The zero value is returned in case of error.
+//
+// A gradient is painted like a color by passing its identifier to
+// SetFillPattern(), SetDrawPattern() or SetTextPattern(), so that rectangles,
+// rounded rectangles, polygons, paths and text can be filled with it. It can
+// also be painted over the current clipping area, set for example with
+// ClipPolygon() or ClipText(), with PaintGradient(). Gradients with
+// transparent stops can only be painted with PaintGradient().
+//
+// The coordinates of the gradient are converted with the height of the
+// current page when AddGradient() is called, not when the gradient is used, so
+// a gradient should only be used on pages of that height. They are also
+// subject to the current transformation when the gradient is painted with
+// PaintGradient().
+func (f *Fpdf) AddGradient(g Gradient) int {
+	if f.err != nil {
+		return 0
+	}
+	stops := g.Stops
+	if len(stops) < 2 {
+		f.err = fmt.Errorf("a gradient requires at least two color stops")
+		return 0
+	}
+	opaque := true
+	for j, s := range stops {
+		if s.Offset < 0 || s.Offset > 1 || (j > 0 && s.Offset < stops[j-1].Offset) {
+			f.err = fmt.Errorf("gradient stop offsets must increase from 0 to 1")
+			return 0
+		}
+		if (s.CMYK == nil) != (stops[0].CMYK == nil) {
+			f.err = fmt.Errorf("gradient stops must all be RGB or all be CMYK colors")
+			return 0
+		}
+		if s.Transparency < 0 || s.Transparency > 1 {
+			f.err = fmt.Errorf("gradient stop transparency %.2f is out of range", s.Transparency)
+			return 0
+		}
+		if s.Transparency != 0 {
+			opaque = false
+		}
+	}
+	// Stops hidden by others at the same offset at either end are dropped,
+	// since the stitching function cannot have empty end segments
+	for len(stops) > 2 && stops[1].Offset == stops[0].Offset {
+		stops = stops[1:]
+	}
+	for n := len(stops); n > 2 && stops[n-2].Offset == stops[n-1].Offset; n-- {
+		stops = stops[:n-1]
+	}
+	gr := gradientType{tp: 2, csStr: "DeviceRGB"}
+	if g.Radial {
+		gr.tp = 3
+		gr.coordsStr = sprintf("%.5f %.5f %.5f %.5f %.5f %.5f", g.X1*f.k, (f.h-g.Y1)*f.k, g.R1*f.k,
+			g.X2*f.k, (f.h-g.Y2)*f.k, g.R2*f.k)
+	} else {
+		gr.coordsStr = sprintf("%.5f %.5f %.5f %.5f", g.X1*f.k, (f.h-g.Y1)*f.k, g.X2*f.k, (f.h-g.Y2)*f.k)
+	}
+	gr.extendStr = sprintf("%t %t", g.ExtendStart, g.ExtendEnd)
+	alpha := gr
+	alpha.csStr = "DeviceGray"
+	// The colors of the first and last stops extend to the ends
+	var offsets []float64
+	for _, s := range stops {
+		offsets = append(offsets, s.Offset)
+	}
+	if stops[0].Offset > 0 {
+		offsets = append([]float64{0}, offsets...)
+		stops = append([]GradientStop{stops[0]}, stops...)
+	}
+	if stops[len(stops)-1].Offset < 1 {
+		offsets = append(offsets, 1)
+		stops = append(stops, stops[len(stops)-1])
+	}
+	for j, s := range stops {
+		var clr colorType
+		if s.CMYK != nil {
+			gr.csStr = "DeviceCMYK"
+			clr.setCMYK(s.CMYK.C, s.CMYK.M, s.CMYK.Y, s.CMYK.K, "")
+			f.color.cmykUsed = true
+		} else {
+			clr = rgbColorValue(s.R, s.G, s.B, "", "")
+		}
+		if gr.csStr == "DeviceRGB" {
+			gr.clrStrs = append(gr.clrStrs, clr.str)
+		} else {
+			gr.clrStrs = append(gr.clrStrs, clr.components())
+		}
+		alpha.clrStrs = append(alpha.clrStrs, sprintf("%.3f", 1-s.Transparency))
+		if j > 0 && j < len(stops)-1 {
+			gr.bounds = append(gr.bounds, offsets[j])
+		}
+	}
+	alpha.bounds = gr.bounds
+	if !opaque {
+		gr.alpha = len(f.gradientList)
+		gr.mask = f.addMask("Luminosity", []byte(sprintf("/Sh%d sh", gr.alpha)))
+		f.gradientList = append(f.gradientList, alpha)
+	}
+	f.gradientList = append(f.gradientList, gr)
+	f.patternList = append(f.patternList, patternType{shading: len(f.gradientList) - 1})
+	return len(f.patternList) - 1
+}
+
+// PaintGradient paints the gradient grad, returned by AddGradient(), over
+// the current clipping area. This is typically done between a clipping
+// method such as ClipRoundedRect(), ClipPolygon() or ClipText() and
+// ClipEnd(). Without clipping, the gradient covers the entire page.
+func (f *Fpdf) PaintGradient(grad int) {
+	if !f.patternCheck(grad) {
+		return
+	}
+	sh := f.patternList[grad].shading
+	if sh == 0 {
+		f.err = fmt.Errorf("pattern %d is not a gradient", grad)
+		return
+	}
+	if gr := f.gradientList[sh]; gr.alpha > 0 {
+		f.outf("q /SM%d gs /Sh%d sh Q", gr.mask, sh)
+	} else {
+		f.outf("/Sh%d sh", sh)
+	}
+}
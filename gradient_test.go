@@ -0,0 +1,144 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+func TestGradientStops(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddPage()
+	grad := pdf.AddGradient(gofpdf.Gradient{
+		X1: 100, Y1: 100, X2: 300, Y2: 100,
+		Stops: []gofpdf.GradientStop{
+			{Offset: 0.2, R: 255},
+			{Offset: 0.5, G: 255},
+			{Offset: 0.5, B: 255},
+			{Offset: 1, R: 255, G: 255, B: 255},
+		},
+		ExtendStart: true,
+	})
+	pdf.SetFillPattern(grad)
+	pdf.RoundedRect(100, 100, 200, 50, 10, "1234", "F")
+	r := pagesRead(t, pdf)
+	if s := updateContent(t, r, 1); !strings.Contains(s, "/Pattern cs /P1 scn\n") {
+		t.Errorf("gradient not set as fill color in %q", s)
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	patterns, _ := r.ResolveDict(res["Pattern"])
+	pat, _ := r.ResolveDict(patterns["P1"])
+	sh, _ := r.ResolveDict(pat["Shading"])
+	if pat["PatternType"] != 2 || pdfreader.Format(sh["Coords"]) != "[100 741.89 300 741.89]" ||
+		pdfreader.Format(sh["Extend"]) != "[true false]" {
+		t.Errorf("unexpected pattern %s %s", pdfreader.Format(pat), pdfreader.Format(sh))
+	}
+	fn, _ := r.ResolveDict(sh["Function"])
+	if fn["FunctionType"] != 3 || pdfreader.Format(fn["Bounds"]) != "[0.2 0.5 0.5]" ||
+		pdfreader.Format(fn["Encode"]) != "[0 1 0 1 0 1 0 1]" {
+		t.Errorf("unexpected stitching function %s", pdfreader.Format(fn))
+	}
+	fns, _ := r.ResolveArray(fn["Functions"])
+	var colors []string
+	for _, ref := range fns {
+		d, _ := r.ResolveDict(ref)
+		colors = append(colors, pdfreader.Format(d["C0"])+"-"+pdfreader.Format(d["C1"]))
+	}
+	if s := strings.Join(colors, " "); s != "[1 0 0]-[1 0 0] [1 0 0]-[0 1 0] [0 1 0]-[0 0 1] [0 0 1]-[1 1 1]" {
+		t.Errorf("unexpected functions %s", s)
+	}
+}
+
+func TestGradientAlpha(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	grad := pdf.AddGradient(gofpdf.Gradient{
+		Radial: true,
+		X1:     50, Y1: 50, X2: 50, Y2: 50, R2: 30,
+		Stops: []gofpdf.GradientStop{
+			{Offset: 0, CMYK: &gofpdf.CMYKType{C: 100}},
+			{Offset: 1, CMYK: &gofpdf.CMYKType{C: 100}, Transparency: 1},
+		},
+	})
+	pdf.ClipCircle(50, 50, 30, false)
+	pdf.PaintGradient(grad)
+	pdf.ClipEnd()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4")) {
+		t.Errorf("unexpected header %q", buf.Bytes()[:8])
+	}
+	r, err := pdfreader.NewReaderBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := updateContent(t, r, 1); !strings.Contains(s, "q /SM1 gs /Sh2 sh Q") {
+		t.Errorf("gradient not painted in %q", s)
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	shadings, _ := r.ResolveDict(res["Shading"])
+	sh, _ := r.ResolveDict(shadings["Sh2"])
+	if sh["ColorSpace"] != pdfreader.Name("DeviceCMYK") || sh["ShadingType"] != 3 {
+		t.Errorf("unexpected shading %s", pdfreader.Format(sh))
+	}
+	gs, _ := r.ResolveDict(res["ExtGState"])
+	sm, _ := r.ResolveDict(gs["SM1"])
+	mask, _ := r.ResolveDict(sm["SMask"])
+	form, _ := r.Resolve(mask["G"])
+	stm, ok := form.(*pdfreader.Stream)
+	if !ok || mask["S"] != pdfreader.Name("Luminosity") {
+		t.Fatalf("unexpected soft mask %s", pdfreader.Format(mask))
+	}
+	fres, _ := r.ResolveDict(stm.Dict["Resources"])
+	fsh, _ := r.ResolveDict(fres["Shading"])
+	alpha, _ := r.ResolveDict(fsh["Sh1"])
+	fn, _ := r.ResolveDict(alpha["Function"])
+	if alpha["ColorSpace"] != pdfreader.Name("DeviceGray") || pdfreader.Format(fn["C0"]) != "[1]" ||
+		pdfreader.Format(fn["C1"]) != "[0]" {
+		t.Errorf("unexpected opacity %s %s", pdfreader.Format(alpha), pdfreader.Format(fn))
+	}
+}
+
+func TestGradientErrors(t *testing.T) {
+	stop := func(offset float64) gofpdf.GradientStop {
+		return gofpdf.GradientStop{Offset: offset}
+	}
+	for _, c := range []struct {
+		fn  func(pdf *gofpdf.Fpdf)
+		err string
+	}{
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddGradient(gofpdf.Gradient{Stops: []gofpdf.GradientStop{stop(0)}})
+		}, "at least two color stops"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddGradient(gofpdf.Gradient{Stops: []gofpdf.GradientStop{stop(0.5), stop(0.2)}})
+		}, "offsets must increase"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddGradient(gofpdf.Gradient{Stops: []gofpdf.GradientStop{stop(0),
+				{Offset: 1, CMYK: &gofpdf.CMYKType{}}}})
+		}, "all be RGB or all be CMYK"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.SetFillPattern(pdf.AddGradient(gofpdf.Gradient{Stops: []gofpdf.GradientStop{stop(0),
+				{Offset: 1, Transparency: 0.5}}}))
+		}, "can only be painted with PaintGradient()"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddGradient(gofpdf.Gradient{Stops: []gofpdf.GradientStop{stop(0),
+				{Offset: 1, Transparency: 2}}})
+		}, "transparency 2.00 is out of range"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.PaintGradient(pdf.CreateHatchPattern("dots", 2, 1))
+		}, "pattern 1 is not a gradient"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		c.fn(pdf)
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
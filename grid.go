@@ -34,12 +34,12 @@ type RGBAType struct {
 // StateType holds various commonly used drawing values for convenient
 // retrieval (StateGet()) and restore (Put) methods.
 type StateType struct {
-	// clrDraw is the current drawing color
-	clrDraw RGBType
+	// clrDraw is the current drawing color, in any color mode
+	clrDraw colorType
 	// clrText is the current text color
-	clrText RGBType
+	clrText colorType
 	// clrFill is the current fill color
-	clrFill RGBType
+	clrFill colorType
 	// lineWd is the current line width
 	lineWd float64
 	// fontSize is the current font size
@@ -54,9 +54,7 @@ type StateType struct {
 
 // StateGet returns a variable that contains common state values.
 func StateGet(pdf *Fpdf) (st StateType) {
-	st.clrDraw.R, st.clrDraw.G, st.clrDraw.B = pdf.GetDrawColor()
-	st.clrFill.R, st.clrFill.G, st.clrFill.B = pdf.GetFillColor()
-	st.clrText.R, st.clrText.G, st.clrText.B = pdf.GetTextColor()
+	st.clrDraw, st.clrFill, st.clrText = pdf.color.draw, pdf.color.fill, pdf.color.text
 	st.lineWd = pdf.GetLineWidth()
 	_, st.fontSize = pdf.GetFontSize()
 	st.alpha, st.blendStr = pdf.GetAlpha()
@@ -65,11 +63,15 @@ func StateGet(pdf *Fpdf) (st StateType) {
 }
 
 // Put sets the common state values contained in the state structure
-// specified by st.
+// specified by st. Colors are restored in their color mode, such as RGB, CMYK
+// or spot color.
 func (st StateType) Put(pdf *Fpdf) {
-	pdf.SetDrawColor(st.clrDraw.R, st.clrDraw.G, st.clrDraw.B)
-	pdf.SetFillColor(st.clrFill.R, st.clrFill.G, st.clrFill.B)
-	pdf.SetTextColor(st.clrText.R, st.clrText.G, st.clrText.B)
+	pdf.color.draw, pdf.color.fill, pdf.color.text = st.clrDraw, st.clrFill, st.clrText
+	pdf.colorFlag = pdf.color.fill.str != pdf.color.text.str
+	if pdf.page > 0 {
+		pdf.out(pdf.color.draw.str)
+		pdf.out(pdf.color.fill.str)
+	}
 	pdf.SetLineWidth(st.lineWd)
 	pdf.SetFontUnitSize(st.fontSize)
 	pdf.SetAlpha(st.alpha, st.blendStr)
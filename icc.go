@@ -0,0 +1,154 @@
+package gofpdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+)
+
+// jpegICCProfile returns the ICC profile embedded in the APP2 segments of the
+// JPEG data, or nil if there is none. A profile may be split across several
+// segments, each labeled with its sequence number and the segment count.
+func jpegICCProfile(data []byte) []byte {
+	const label = "ICC_PROFILE\x00"
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	var chunks [][]byte
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return nil
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// Fill byte
+			pos++
+			continue
+		}
+		if marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			// Markers without a segment
+			pos += 2
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image; no more metadata follows
+			break
+		}
+		n := be16(data[pos+2:])
+		if n < 2 || pos+2+n > len(data) {
+			return nil
+		}
+		seg := data[pos+4 : pos+2+n]
+		if marker == 0xe2 && len(seg) > len(label)+2 && string(seg[:len(label)]) == label {
+			seq, count := int(seg[len(label)]), int(seg[len(label)+1])
+			if chunks == nil {
+				chunks = make([][]byte, count)
+			}
+			if seq < 1 || seq > len(chunks) || count != len(chunks) {
+				return nil
+			}
+			chunks[seq-1] = seg[len(label)+2:]
+		}
+		pos += 2 + n
+	}
+	var profile []byte
+	for _, c := range chunks {
+		if c == nil {
+			// Incomplete profile
+			return nil
+		}
+		profile = append(profile, c...)
+	}
+	return profile
+}
+
+// pngICCProfile returns the ICC profile of the content of a PNG iCCP chunk,
+// which holds the profile name, a compression method and the compressed
+// profile
+func pngICCProfile(chunk []byte) []byte {
+	pos := bytes.IndexByte(chunk, 0)
+	if pos < 1 || pos+2 > len(chunk) || chunk[pos+1] != 0 {
+		return nil
+	}
+	profile, err := sliceUncompress(chunk[pos+2:])
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// iccComponents returns the number of color components of the ICC profile,
+// as indicated by the data color space field of its header, or zero if the
+// profile is not an RGB, CMYK or gray profile
+func iccComponents(profile []byte) int {
+	if len(profile) < 128 {
+		return 0
+	}
+	switch string(profile[16:20]) {
+	case "GRAY":
+		return 1
+	case "RGB ":
+		return 3
+	case "CMYK":
+		return 4
+	}
+	return 0
+}
+
+// imageComponents returns the number of color components of the image, or
+// of the base color space of its palette
+func (info *ImageInfoType) imageComponents() int {
+	switch info.cs {
+	case "DeviceGray":
+		return 1
+	case "DeviceCMYK":
+		return 4
+	}
+	return 3
+}
+
+// imageICCCheck reports an error if the ICC profile attached to the image
+// does not match the color space of the image
+func (f *Fpdf) imageICCCheck(info *ImageInfoType) {
+	if len(info.icc) == 0 {
+		return
+	}
+	n := iccComponents(info.icc)
+	if n == 0 {
+		f.err = fmt.Errorf("ICC profile is not an RGB, CMYK or gray profile")
+	} else if n != info.imageComponents() {
+		f.err = fmt.Errorf("ICC profile of %d components does not match image color space %s", n, info.cs)
+	}
+}
+
+// imageColorSpace returns the color space of the image, which is based on
+// its ICC profile if it has one. The profile is written on first use;
+// identical profiles of different images share one object.
+func (f *Fpdf) imageColorSpace(info *ImageInfoType) string {
+	base := "/DeviceRGB"
+	if info.cs != "Indexed" {
+		base = "/" + info.cs
+	}
+	if len(info.icc) == 0 {
+		return base
+	}
+	key := fmt.Sprintf("%x", sha1.Sum(info.icc))
+	n, ok := f.iccProfiles[key]
+	if !ok {
+		profile := info.icc
+		var filter string
+		if f.compress {
+			profile = sliceCompress(profile)
+			filter = "/Filter /FlateDecode "
+		}
+		f.newobj()
+		n = f.n
+		f.outf("<</N %d /Alternate %s %s/Length %d>>", info.imageComponents(), base,
+			filter, f.protect.streamLen(len(profile)))
+		f.putstream(profile)
+		f.out("endobj")
+		f.iccProfiles[key] = n
+	}
+	return sprintf("[/ICCBased %d 0 R]", n)
+}
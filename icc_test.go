@@ -0,0 +1,172 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// iccProfile returns a minimal ICC profile of the color space cs ("RGB ",
+// "CMYK" or "GRAY")
+func iccProfile(cs string) []byte {
+	profile := make([]byte, 200)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	copy(profile[12:], "mntr")
+	copy(profile[16:], cs)
+	copy(profile[36:], "acsp")
+	for j := 128; j < len(profile); j++ {
+		profile[j] = byte(j)
+	}
+	return profile
+}
+
+// iccJPEG returns a JPEG image of width w with profile embedded in two APP2
+// segments
+func iccJPEG(t *testing.T, w int, profile []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	half := len(profile) / 2
+	for j, part := range [][]byte{profile[:half], profile[half:]} {
+		seg := append([]byte("ICC_PROFILE\x00"), byte(j+1), 2)
+		seg = append(seg, part...)
+		out = append(out, 0xff, 0xe2, byte((len(seg)+2)>>8), byte(len(seg)+2))
+		out = append(out, seg...)
+	}
+	return append(out, data[2:]...)
+}
+
+// iccPNG returns a PNG image of width w, in gray levels if gray is true, with
+// profile embedded in an iCCP chunk unless it is nil
+func iccPNG(t *testing.T, w int, gray bool, profile []byte) []byte {
+	t.Helper()
+	var img image.Image = image.NewRGBA(image.Rect(0, 0, w, 4))
+	if gray {
+		g := image.NewGray(image.Rect(0, 0, w, 4))
+		g.Set(0, 0, color.Gray{Y: 128})
+		img = g
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if profile == nil {
+		return data
+	}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(profile)
+	zw.Close()
+	content := append([]byte("Profile\x00\x00"), z.Bytes()...)
+	chunk := make([]byte, 4, 12+len(content))
+	binary.BigEndian.PutUint32(chunk, uint32(len(content)))
+	chunk = append(append(chunk, "iCCP"...), content...)
+	// The CRC is not checked
+	chunk = append(chunk, 0, 0, 0, 0)
+	// The chunk follows the signature and the IHDR chunk
+	return append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+}
+
+func TestImageICCProfile(t *testing.T) {
+	rgb := iccProfile("RGB ")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	for _, img := range []struct {
+		name    string
+		tp      string
+		data    []byte
+		profile []byte
+	}{
+		{"jpeg", "JPG", iccJPEG(t, 10, rgb), nil},
+		{"png", "PNG", iccPNG(t, 20, false, rgb), nil},
+		{"gray", "PNG", iccPNG(t, 30, true, nil), iccProfile("GRAY")},
+		{"plain", "PNG", iccPNG(t, 40, false, nil), nil},
+	} {
+		opt := gofpdf.ImageOptions{ImageType: img.tp, ICCProfile: img.profile}
+		pdf.RegisterImageOptionsReader(img.name, opt, bytes.NewReader(img.data))
+		pdf.ImageOptions(img.name, 10, 10, 20, 0, true, opt, 0, "")
+	}
+	r := pagesRead(t, pdf)
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	xobjects, _ := r.ResolveDict(res["XObject"])
+	cs := make(map[int]pdfreader.Object)
+	for _, ref := range xobjects {
+		obj, _ := r.Resolve(ref)
+		if stm, ok := obj.(*pdfreader.Stream); ok {
+			w, _ := stm.Dict["Width"].(int)
+			cs[w] = stm.Dict["ColorSpace"]
+		}
+	}
+	if cs[40] != pdfreader.Name("DeviceRGB") {
+		t.Errorf("unexpected color space %s", pdfreader.Format(cs[40]))
+	}
+	// The profile shared by the JPEG and PNG images is written once
+	if pdfreader.Format(cs[10]) != pdfreader.Format(cs[20]) {
+		t.Errorf("profile not shared: %s %s", pdfreader.Format(cs[10]), pdfreader.Format(cs[20]))
+	}
+	for _, c := range []struct {
+		w         int
+		n         int
+		alternate string
+		profile   []byte
+	}{
+		{10, 3, "DeviceRGB", rgb},
+		{30, 1, "DeviceGray", iccProfile("GRAY")},
+	} {
+		a, _ := r.ResolveArray(cs[c.w])
+		if len(a) != 2 || a[0] != pdfreader.Name("ICCBased") {
+			t.Errorf("unexpected color space %s", pdfreader.Format(cs[c.w]))
+			continue
+		}
+		obj, _ := r.Resolve(a[1])
+		stm, ok := obj.(*pdfreader.Stream)
+		if !ok {
+			t.Fatalf("profile %s is not a stream", pdfreader.Format(a[1]))
+		}
+		data, err := stm.Decode()
+		if err != nil || !bytes.Equal(data, c.profile) {
+			t.Errorf("unexpected profile %q %v", data, err)
+		}
+		if stm.Dict["N"] != c.n || stm.Dict["Alternate"] != pdfreader.Name(c.alternate) {
+			t.Errorf("unexpected profile dictionary %s", pdfreader.Format(stm.Dict))
+		}
+	}
+}
+
+func TestImageICCProfileErrors(t *testing.T) {
+	for _, c := range []struct {
+		profile []byte
+		err     string
+	}{
+		{iccProfile("CMYK"), "ICC profile of 4 components does not match image color space DeviceRGB"},
+		{iccProfile("Lab "), "not an RGB, CMYK or gray profile"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.RegisterImageOptionsReader("png", gofpdf.ImageOptions{ImageType: "PNG", ICCProfile: c.profile},
+			bytes.NewReader(iccPNG(t, 10, false, nil)))
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+	// An embedded profile that does not match the image is ignored
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	info := pdf.RegisterImageOptionsReader("png", gofpdf.ImageOptions{ImageType: "PNG"},
+		bytes.NewReader(iccPNG(t, 10, true, iccProfile("RGB "))))
+	if info == nil || pdf.Error() != nil {
+		t.Errorf("image with mismatched profile not registered: %v", pdf.Error())
+	}
+}
@@ -0,0 +1,342 @@
+package gofpdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// importRefsType maps the objects of each imported document to the numbers of
+// their copies in the document
+type importRefsType map[*pdfreader.Reader]map[pdfreader.Ref]int
+
+// importDocsType numbers the imported documents in the order of their first
+// imported page
+type importDocsType map[*pdfreader.Reader]int
+
+// importedTpl is a template holding a page of an existing document. The
+// objects used by the page, such as fonts and images, are copied from the
+// document when the template is written.
+type importedTpl struct {
+	r         *pdfreader.Reader
+	id        string
+	content   []byte
+	size      SizeType   // size of the template in user units
+	bbox      [4]float64 // page box in points
+	matrix    [6]float64 // maps the page box to the template, accounting for the page rotation
+	resources pdfreader.Object
+	group     pdfreader.Object // transparency group of the page
+}
+
+// ImportPage returns a template with the content of page pageNum (1-based) of
+// the existing document read by r, which can be obtained with
+// pdfreader.Open() or, for encrypted documents, pdfreader.NewReaderPassword().
+// The template can be placed with UseTemplate() or UseTemplateScaled() like
+// templates created with CreateTemplate(). The fonts, images and other
+// resources of the page are copied to the document, once for all the pages
+// imported from the same Reader.
+//
+// boxName selects the page box that delimits the template: "media", "crop",
+// "bleed", "trim" or "art", optionally followed by "box"; the crop box is
+// used if boxName is empty. The template has the size of the box, and the
+// rotation of the page is applied so that the template appears as the page is
+// displayed. Annotations, such as links and form fields, are not imported.
+// Imported templates cannot be serialized.
+func (f *Fpdf) ImportPage(r *pdfreader.Reader, pageNum int, boxName string) Template {
+	if f.err != nil {
+		return nil
+	}
+	boxStr := strings.ToLower(strings.TrimPrefix(boxName, "/"))
+	boxStr = strings.TrimSuffix(boxStr, "box")
+	switch boxStr {
+	case "":
+		boxStr = "Crop"
+	case "media", "crop", "bleed", "trim", "art":
+		boxStr = strings.ToUpper(boxStr[:1]) + boxStr[1:]
+	default:
+		f.err = fmt.Errorf("%s is not a valid page box type", boxName)
+		return nil
+	}
+	_, page, err := r.Page(pageNum)
+	if err != nil {
+		f.err = err
+		return nil
+	}
+	t := &importedTpl{r: r, group: page["Group"]}
+	// The page box is clipped to the media box
+	mllx, mlly, murx, mury, err := r.PageBox(pageNum, "MediaBox")
+	llx, lly, urx, ury, err2 := r.PageBox(pageNum, pdfreader.Name(boxStr+"Box"))
+	if err == nil {
+		err = err2
+	}
+	if err == nil {
+		t.resources, err = r.PageAttr(pageNum, "Resources")
+	}
+	if err == nil {
+		t.content, err = importContent(r, page["Contents"])
+	}
+	if err != nil {
+		f.err = err
+		return nil
+	}
+	llx, lly = math.Max(llx, mllx), math.Max(lly, mlly)
+	urx, ury = math.Min(urx, murx), math.Min(ury, mury)
+	if urx <= llx || ury <= lly {
+		f.err = fmt.Errorf("page %d has an empty %s box", pageNum, strings.ToLower(boxStr))
+		return nil
+	}
+	t.bbox = [4]float64{llx, lly, urx, ury}
+	w, h := urx-llx, ury-lly
+	obj, _ := r.PageAttr(pageNum, "Rotate")
+	rotate, _ := obj.(int)
+	switch (rotate%360 + 360) % 360 {
+	case 90:
+		t.matrix = [6]float64{0, -1, 1, 0, -lly, urx}
+		w, h = h, w
+	case 180:
+		t.matrix = [6]float64{-1, 0, 0, -1, urx, ury}
+	case 270:
+		t.matrix = [6]float64{0, 1, -1, 0, ury, -llx}
+		w, h = h, w
+	default:
+		t.matrix = [6]float64{1, 0, 0, 1, -llx, -lly}
+	}
+	t.size = SizeType{Wd: w / f.k, Ht: h / f.k}
+	// The identifier depends on the order in which documents are imported
+	// rather than on addresses, so that the output is reproducible
+	doc, ok := f.importDocs[r]
+	if !ok {
+		doc = len(f.importDocs) + 1
+		f.importDocs[r] = doc
+	}
+	t.id = fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("import %d %d %s", doc, pageNum, boxStr))))
+	return t
+}
+
+// importContent returns the decoded content of a page, whose content streams
+// are concatenated
+func importContent(r *pdfreader.Reader, contents pdfreader.Object) ([]byte, error) {
+	obj, err := r.Resolve(contents)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := obj.(pdfreader.Array)
+	if !ok {
+		list = pdfreader.Array{obj}
+	}
+	var buf bytes.Buffer
+	for _, o := range list {
+		if o, err = r.Resolve(o); err != nil {
+			return nil, err
+		}
+		stm, ok := o.(*pdfreader.Stream)
+		if !ok {
+			continue
+		}
+		data, err := stm.Decode()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// ID returns the global template identifier
+func (t *importedTpl) ID() string {
+	return t.id
+}
+
+// Size gives the bounding dimensions of this template
+func (t *importedTpl) Size() (corner PointType, size SizeType) {
+	return PointType{}, t.size
+}
+
+// Bytes returns the content of the page, not including resources
+func (t *importedTpl) Bytes() []byte {
+	return t.content
+}
+
+// Images returns an empty map, since the images of the page are part of its
+// resources
+func (t *importedTpl) Images() map[string]*ImageInfoType {
+	return map[string]*ImageInfoType{}
+}
+
+// Templates returns an empty list
+func (t *importedTpl) Templates() []Template {
+	return nil
+}
+
+// NumPages returns 1
+func (t *importedTpl) NumPages() int {
+	return 1
+}
+
+// FromPage returns the template itself for page 1
+func (t *importedTpl) FromPage(page int) (Template, error) {
+	if page != 1 {
+		return nil, fmt.Errorf("The template does not have a page %d", page)
+	}
+	return t, nil
+}
+
+// FromPages returns a slice holding the template
+func (t *importedTpl) FromPages() []Template {
+	return []Template{t}
+}
+
+var errImportedTpl = errors.New("imported page templates cannot be serialized")
+
+// Serialize returns an error, since imported templates cannot be serialized
+func (t *importedTpl) Serialize() ([]byte, error) {
+	return nil, errImportedTpl
+}
+
+// GobEncode returns an error, since imported templates cannot be serialized
+func (t *importedTpl) GobEncode() ([]byte, error) {
+	return nil, errImportedTpl
+}
+
+// GobDecode returns an error, since imported templates cannot be serialized
+func (t *importedTpl) GobDecode(buf []byte) error {
+	return errImportedTpl
+}
+
+// putImportedTpl writes an imported page as a form XObject, preceded by the
+// objects of its document that have not yet been written
+func (f *Fpdf) putImportedTpl(t *importedTpl) {
+	refs := f.importRefs[t.r]
+	if refs == nil {
+		refs = make(map[pdfreader.Ref]int)
+		f.importRefs[t.r] = refs
+	}
+	// Number the objects used by the page before writing them
+	var queue []pdfreader.Ref
+	var walk func(obj pdfreader.Object)
+	walk = func(obj pdfreader.Object) {
+		switch v := obj.(type) {
+		case pdfreader.Ref:
+			if _, ok := refs[v]; ok {
+				return
+			}
+			o, err := t.r.Object(v)
+			if d, ok := o.(pdfreader.Dict); err != nil || o == nil || (ok && d["Type"] == pdfreader.Name("Page")) {
+				// Missing objects and pages are replaced with null
+				refs[v] = 0
+				return
+			}
+			refs[v] = f.n + 1 + len(queue)
+			queue = append(queue, v)
+			walk(o)
+		case pdfreader.Array:
+			for _, o := range v {
+				walk(o)
+			}
+		case pdfreader.Dict:
+			// Keys are sorted so that objects are numbered in the same order
+			// every time
+			for _, k := range importKeys(v) {
+				if k != "Parent" {
+					walk(v[k])
+				}
+			}
+		case *pdfreader.Stream:
+			for _, k := range importKeys(v.Dict) {
+				// The length is written directly
+				if k != "Length" {
+					walk(v.Dict[k])
+				}
+			}
+		}
+	}
+	walk(t.resources)
+	walk(t.group)
+	for _, ref := range queue {
+		obj, _ := t.r.Object(ref)
+		f.newobj()
+		if stm, ok := obj.(*pdfreader.Stream); ok {
+			dict := f.importObject(refs, stm.Dict).(pdfreader.Dict)
+			dict["Length"] = f.protect.streamLen(len(stm.Data))
+			f.out(pdfreader.Format(dict))
+			f.putstream(stm.Data)
+		} else {
+			f.out(pdfreader.Format(f.importObject(refs, obj)))
+		}
+		f.out("endobj")
+	}
+	f.newobj()
+	f.templateObjects[t.ID()] = f.n
+	f.out("<</Type /XObject /Subtype /Form")
+	f.outf("/BBox [%.2f %.2f %.2f %.2f]", t.bbox[0], t.bbox[1], t.bbox[2], t.bbox[3])
+	m := t.matrix
+	f.outf("/Matrix [%.5f %.5f %.5f %.5f %.5f %.5f]", m[0], m[1], m[2], m[3], m[4], m[5])
+	if t.resources != nil {
+		f.outf("/Resources %s", pdfreader.Format(f.importObject(refs, t.resources)))
+	}
+	if t.group != nil {
+		f.outf("/Group %s", pdfreader.Format(f.importObject(refs, t.group)))
+	}
+	buffer := t.content
+	if f.compress {
+		buffer = sliceCompress(buffer)
+		f.out("/Filter /FlateDecode")
+	}
+	f.outf("/Length %d >>", f.protect.streamLen(len(buffer)))
+	f.putstream(buffer)
+	f.out("endobj")
+}
+
+// importKeys returns the keys of d in sorted order
+func importKeys(d pdfreader.Dict) []pdfreader.Name {
+	keys := make([]pdfreader.Name, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// importObject returns a copy of obj, an object of an imported document, in
+// which references are replaced with the numbers of the copied objects and
+// strings are encrypted for the current object if the document is protected
+func (f *Fpdf) importObject(refs map[pdfreader.Ref]int, obj pdfreader.Object) pdfreader.Object {
+	switch v := obj.(type) {
+	case pdfreader.Ref:
+		if n := refs[v]; n > 0 {
+			return pdfreader.Ref{Num: n}
+		}
+		return nil
+	case pdfreader.String:
+		if !f.protect.encrypted {
+			return v
+		}
+		enc := pdfreader.String(f.protect.encrypt(f.n, []byte(v)))
+		if f.objStm.enabled {
+			f.objStmRecord(f.n, pdfreader.Format(enc), pdfreader.Format(v))
+		}
+		return enc
+	case pdfreader.Array:
+		a := make(pdfreader.Array, len(v))
+		for j, o := range v {
+			a[j] = f.importObject(refs, o)
+		}
+		return a
+	case pdfreader.Dict:
+		d := make(pdfreader.Dict, len(v))
+		for k, o := range v {
+			if k != "Parent" {
+				d[k] = f.importObject(refs, o)
+			}
+		}
+		return d
+	}
+	return obj
+}
@@ -0,0 +1,223 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// importSource returns a document of two pages with a crop box, configured
+// by fn
+func importSource(t *testing.T, fn func(pdf *gofpdf.Fpdf)) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	fn(pdf)
+	pdf.SetPageBox("crop", 50, 100, 300, 400)
+	for j := 1; j <= 2; j++ {
+		pdf.AddPage()
+		pdf.SetFont("Times", "", 12)
+		pdf.Text(60, 120, fmt.Sprintf("Imported %d", j))
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// importedForm returns the form XObject of template tpl on page n
+func importedForm(t *testing.T, r *pdfreader.Reader, n int, tpl gofpdf.Template) *pdfreader.Stream {
+	t.Helper()
+	res, _ := r.ResolveDict(mustAttr(t, r, n, "Resources"))
+	xobjects, _ := r.ResolveDict(res["XObject"])
+	obj, _ := r.Resolve(xobjects[pdfreader.Name("TPL"+tpl.ID())])
+	stm, ok := obj.(*pdfreader.Stream)
+	if !ok {
+		t.Fatalf("template %s not found on page %d", tpl.ID(), n)
+	}
+	return stm
+}
+
+func TestImportPage(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		password string
+		src      func(pdf *gofpdf.Fpdf)
+		dst      func(pdf *gofpdf.Fpdf)
+	}{
+		{"plain", "", func(pdf *gofpdf.Fpdf) {}, func(pdf *gofpdf.Fpdf) {}},
+		{"object streams", "", func(pdf *gofpdf.Fpdf) {
+			pdf.SetObjectStreams(true)
+		}, func(pdf *gofpdf.Fpdf) {
+			pdf.SetObjectStreams(true)
+		}},
+		{"encrypted", "user", func(pdf *gofpdf.Fpdf) {
+			pdf.SetProtectionAlgorithm(gofpdf.EncryptAESBits128, 0, "user", "owner")
+		}, func(pdf *gofpdf.Fpdf) {
+			pdf.SetProtection(0, "", "")
+		}},
+	} {
+		src, err := pdfreader.NewReaderPassword(bytes.NewReader(importSource(t, c.src)), c.password)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		c.dst(pdf)
+		var tpls []gofpdf.Template
+		for n := 1; n <= 2; n++ {
+			tpl := pdf.ImportPage(src, n, "")
+			if tpl == nil {
+				t.Fatalf("%s: %s", c.name, pdf.Error())
+			}
+			if _, size := tpl.Size(); fmt.Sprintf("%.3f %.3f", size.Wd, size.Ht) != "105.833 141.111" {
+				t.Errorf("%s: unexpected size %v", c.name, size)
+			}
+			pdf.AddPage()
+			pdf.UseTemplateScaled(tpl, gofpdf.PointType{X: 10, Y: 10}, gofpdf.SizeType{Wd: 75, Ht: 100})
+			tpls = append(tpls, tpl)
+		}
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		r, err := pdfreader.NewReaderBytes(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		var fonts []pdfreader.Object
+		for n, tpl := range tpls {
+			stm := importedForm(t, r, n+1, tpl)
+			if box := pdfreader.Format(stm.Dict["BBox"]); box != "[50 100 350 500]" {
+				t.Errorf("%s: unexpected bounding box %s", c.name, box)
+			}
+			if m := pdfreader.Format(stm.Dict["Matrix"]); m != "[1 0 0 1 -50 -100]" {
+				t.Errorf("%s: unexpected matrix %s", c.name, m)
+			}
+			data, err := stm.Decode()
+			if err != nil || !strings.Contains(string(data), fmt.Sprintf("(Imported %d) Tj", n+1)) {
+				t.Errorf("%s: unexpected content %q %v", c.name, data, err)
+			}
+			res, _ := r.ResolveDict(stm.Dict["Resources"])
+			font, _ := r.ResolveDict(res["Font"])
+			for _, ref := range font {
+				fonts = append(fonts, ref)
+				d, _ := r.ResolveDict(ref)
+				if d["BaseFont"] != pdfreader.Name("Times-Roman") {
+					t.Errorf("%s: unexpected font %s", c.name, pdfreader.Format(d))
+				}
+			}
+		}
+		// The resources shared by the pages are copied once
+		if len(fonts) != 2 || fonts[0] != fonts[1] {
+			t.Errorf("%s: unexpected fonts %v", c.name, fonts)
+		}
+	}
+}
+
+func TestImportPageReproducible(t *testing.T) {
+	src, err := pdfreader.NewReaderBytes(importSource(t, func(pdf *gofpdf.Fpdf) {
+		pdf.SetHeaderFunc(func() {
+			for _, family := range []string{"Helvetica", "Courier", "Symbol", "ZapfDingbats"} {
+				pdf.SetFont(family, "", 12)
+				pdf.Text(60, 140, family)
+			}
+		})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first []byte
+	for j := 0; j < 10; j++ {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetCatalogSort(true)
+		tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		pdf.SetCreationDate(tm)
+		pdf.SetModificationDate(tm)
+		for n := 1; n <= 2; n++ {
+			tpl := pdf.ImportPage(src, n, "")
+			pdf.AddPage()
+			pdf.UseTemplate(tpl)
+		}
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if j == 0 {
+			first = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatalf("output %d differs from the first one", j+1)
+		}
+	}
+}
+
+func TestImportPageRotated(t *testing.T) {
+	src := "%PDF-1.4\n1 0 obj\n<</Type /Catalog /Pages 2 0 R>>\nendobj\n" +
+		"2 0 obj\n<</Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] /Rotate -270>>\nendobj\n" +
+		"3 0 obj\n<</Type /Page /Parent 2 0 R /CropBox [10 5 150 120] /Contents [4 0 R 4 0 R]>>\nendobj\n" +
+		"4 0 obj\n<</Length 6>>\nstream\n0 0 m\n\nendstream\nendobj\n" +
+		"trailer\n<</Root 1 0 R>>\n"
+	r, err := pdfreader.NewReaderBytes([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	tpl := pdf.ImportPage(r, 1, "/CropBox")
+	if tpl == nil {
+		t.Fatal(pdf.Error())
+	}
+	if _, size := tpl.Size(); size.Wd != 95 || size.Ht != 140 {
+		t.Errorf("unexpected size %v", size)
+	}
+	if s := string(tpl.Bytes()); s != "0 0 m\n\n0 0 m\n\n" {
+		t.Errorf("unexpected content %q", s)
+	}
+	pdf.AddPage()
+	pdf.UseTemplate(tpl)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := pdfreader.NewReaderBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stm := importedForm(t, out, 1, tpl)
+	if m := pdfreader.Format(stm.Dict["Matrix"]); m != "[0 -1 1 0 -5 150]" {
+		t.Errorf("unexpected matrix %s", m)
+	}
+	if box := pdfreader.Format(stm.Dict["BBox"]); box != "[10 5 150 100]" {
+		t.Errorf("unexpected bounding box %s", box)
+	}
+}
+
+func TestImportPageErrors(t *testing.T) {
+	r, err := pdfreader.NewReaderBytes(importSource(t, func(pdf *gofpdf.Fpdf) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		page int
+		box  string
+		err  string
+	}{
+		{3, "", "page 3"},
+		{1, "paper", "paper is not a valid page box type"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		if tpl := pdf.ImportPage(r, c.page, c.box); tpl != nil {
+			t.Errorf("page %d imported from box %q", c.page, c.box)
+		}
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+	tpl := gofpdf.New("P", "mm", "A4", "").ImportPage(r, 1, "")
+	if _, err := tpl.Serialize(); err == nil {
+		t.Errorf("imported page serialized")
+	}
+}
@@ -0,0 +1,379 @@
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+)
+
+// kernClassType holds a class-based pair adjustment subtable of GPOS: the
+// adjustment of a pair of glyphs depends on the classes of its glyphs
+type kernClassType struct {
+	coverage map[int]bool // first glyphs to which the subtable applies
+	class1   map[int]int  // class of first glyphs, 0 if not listed
+	class2   map[int]int  // class of second glyphs, 0 if not listed
+	count2   int          // number of second glyph classes
+	values   []int        // adjustment of each pair of classes
+}
+
+// kerningType holds the pair kerning of a font in thousandths of the em. The
+// adjustments are added to the advance width of the first glyph of a pair.
+type kerningType struct {
+	pairs   map[uint32]int // adjustment of individual pairs of glyphs
+	classes []kernClassType
+}
+
+// adjust returns the kerning of the glyphs g1 and g2. Individual pairs take
+// precedence over class-based subtables, of which the first one that covers
+// g1 applies.
+func (kt *kerningType) adjust(g1, g2 int) int {
+	if v, ok := kt.pairs[uint32(g1)<<16|uint32(g2)]; ok {
+		return v
+	}
+	for _, c := range kt.classes {
+		if c.coverage[g1] {
+			if j := c.class1[g1]*c.count2 + c.class2[g2]; c.class2[g2] < c.count2 && j < len(c.values) {
+				return c.values[j]
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// kernUint16 returns the unsigned 16-bit value at pos in data, or 0 if pos
+// is out of range, so that truncated tables do not cause a panic
+func kernUint16(data []byte, pos int) int {
+	if pos < 0 || pos+2 > len(data) {
+		return 0
+	}
+	return int(data[pos])<<8 | int(data[pos+1])
+}
+
+// kernInt16 returns the signed 16-bit value at pos in data
+func kernInt16(data []byte, pos int) int {
+	return int(int16(kernUint16(data, pos)))
+}
+
+// kernUint32 returns the unsigned 32-bit value at pos in data
+func kernUint32(data []byte, pos int) int {
+	return kernUint16(data, pos)<<16 | kernUint16(data, pos+2)
+}
+
+// kernScale converts a value in font units to thousandths of the em
+func kernScale(v int, scale float64) int {
+	return int(math.Round(float64(v) * scale))
+}
+
+// parseKernTable reads the horizontal format 0 subtables of a legacy kern
+// table, in either its Microsoft or its Apple version
+func (kt *kerningType) parseKernTable(data []byte, scale float64) {
+	pos, count, apple := 4, kernUint16(data, 2), false
+	if kernUint16(data, 0) == 1 {
+		pos, count, apple = 8, kernUint32(data, 4), true
+	}
+	for j := 0; j < count && pos < len(data); j++ {
+		var length, format, start int
+		var horizontal bool
+		if apple {
+			coverage := kernUint16(data, pos+4)
+			length, format, start = kernUint32(data, pos), coverage&0xff, pos+8
+			horizontal = coverage&0xe000 == 0
+		} else {
+			coverage := kernUint16(data, pos+4)
+			length, format, start = kernUint16(data, pos+2), coverage>>8, pos+6
+			horizontal = coverage&0x7 == 1
+		}
+		if length <= 0 {
+			break
+		}
+		if format == 0 && horizontal {
+			n := kernUint16(data, start)
+			for k, p := 0, start+8; k < n; k, p = k+1, p+6 {
+				key := uint32(kernUint16(data, p))<<16 | uint32(kernUint16(data, p+2))
+				if _, ok := kt.pairs[key]; !ok {
+					kt.pairs[key] = kernScale(kernInt16(data, p+4), scale)
+				}
+			}
+		}
+		pos += length
+	}
+}
+
+// parseGPOS reads the pair adjustment subtables of the lookups of the kern
+// feature of a GPOS table. It returns false if the table has no such feature.
+func (kt *kerningType) parseGPOS(data []byte, scale float64) bool {
+	featureList, lookupList := kernUint16(data, 6), kernUint16(data, 8)
+	if featureList == 0 || lookupList == 0 {
+		return false
+	}
+	lookups := make(map[int]bool)
+	for j, n := 0, kernUint16(data, featureList); j < n; j++ {
+		rec := featureList + 2 + j*6
+		if rec+4 > len(data) || string(data[rec:rec+4]) != "kern" {
+			continue
+		}
+		feature := featureList + kernUint16(data, rec+4)
+		for k, m := 0, kernUint16(data, feature+2); k < m; k++ {
+			lookups[kernUint16(data, feature+4+k*2)] = true
+		}
+	}
+	if len(lookups) == 0 {
+		return false
+	}
+	for j, n := 0, kernUint16(data, lookupList); j < n; j++ {
+		if !lookups[j] {
+			continue
+		}
+		lookup := lookupList + kernUint16(data, lookupList+2+j*2)
+		tp := kernUint16(data, lookup)
+		for k, m := 0, kernUint16(data, lookup+4); k < m; k++ {
+			sub := lookup + kernUint16(data, lookup+6+k*2)
+			subTp := tp
+			if tp == 9 {
+				// Extension subtable
+				subTp = kernUint16(data, sub+2)
+				sub += kernUint32(data, sub+4)
+			}
+			if subTp == 2 {
+				kt.parsePairPos(data, sub, scale)
+			}
+		}
+	}
+	return true
+}
+
+// kernValueSize returns the size of a GPOS value record of the given format
+// and the offset in it of its horizontal advance, or -1 if it has none
+func kernValueSize(format int) (size, xAdvance int) {
+	xAdvance = -1
+	for bit := 0; bit < 8; bit++ {
+		if format&(1<<uint(bit)) != 0 {
+			if bit == 2 {
+				xAdvance = size
+			}
+			size += 2
+		}
+	}
+	return
+}
+
+// parsePairPos reads a GPOS pair adjustment subtable at pos in data. Only
+// the horizontal advance of the first glyph of a pair is used.
+func (kt *kerningType) parsePairPos(data []byte, pos int, scale float64) {
+	format := kernUint16(data, pos)
+	size1, xAdvance := kernValueSize(kernUint16(data, pos+4))
+	size2, _ := kernValueSize(kernUint16(data, pos+6))
+	if xAdvance < 0 {
+		return
+	}
+	coverage := kernCoverage(data, pos+kernUint16(data, pos+2))
+	switch format {
+	case 1:
+		for g1, index := range coverage {
+			set := pos + kernUint16(data, pos+10+index*2)
+			for j, n, p := 0, kernUint16(data, set), set+2; j < n; j, p = j+1, p+2+size1+size2 {
+				key := uint32(g1)<<16 | uint32(kernUint16(data, p))
+				if _, ok := kt.pairs[key]; !ok {
+					kt.pairs[key] = kernScale(kernInt16(data, p+2+xAdvance), scale)
+				}
+			}
+		}
+	case 2:
+		c := kernClassType{
+			coverage: make(map[int]bool),
+			class1:   kernClassDef(data, pos+kernUint16(data, pos+8)),
+			class2:   kernClassDef(data, pos+kernUint16(data, pos+10)),
+			count2:   kernUint16(data, pos+14),
+		}
+		count1 := kernUint16(data, pos+12)
+		for g := range coverage {
+			c.coverage[g] = c.class1[g] < count1
+		}
+		p := pos + 16
+		for j := 0; j < count1*c.count2; j, p = j+1, p+size1+size2 {
+			c.values = append(c.values, kernScale(kernInt16(data, p+xAdvance), scale))
+		}
+		kt.classes = append(kt.classes, c)
+	}
+}
+
+// kernCoverage reads an OpenType coverage table and returns the index of
+// each glyph it covers
+func kernCoverage(data []byte, pos int) map[int]int {
+	cov := make(map[int]int)
+	switch kernUint16(data, pos) {
+	case 1:
+		for j, n := 0, kernUint16(data, pos+2); j < n; j++ {
+			cov[kernUint16(data, pos+4+j*2)] = j
+		}
+	case 2:
+		for j, n := 0, kernUint16(data, pos+2); j < n; j++ {
+			rec := pos + 4 + j*6
+			start, end, index := kernUint16(data, rec), kernUint16(data, rec+2), kernUint16(data, rec+4)
+			for g := start; g <= end; g++ {
+				cov[g] = index + g - start
+			}
+		}
+	}
+	return cov
+}
+
+// kernClassDef reads an OpenType class definition table and returns the
+// class of each glyph it lists
+func kernClassDef(data []byte, pos int) map[int]int {
+	classes := make(map[int]int)
+	switch kernUint16(data, pos) {
+	case 1:
+		start := kernUint16(data, pos+2)
+		for j, n := 0, kernUint16(data, pos+4); j < n; j++ {
+			classes[start+j] = kernUint16(data, pos+6+j*2)
+		}
+	case 2:
+		for j, n := 0, kernUint16(data, pos+2); j < n; j++ {
+			rec := pos + 4 + j*6
+			for g, end := kernUint16(data, rec), kernUint16(data, rec+2); g <= end; g++ {
+				classes[g] = kernUint16(data, rec+4)
+			}
+		}
+	}
+	return classes
+}
+
+// parseKerning reads the kerning of the font from the kern feature of its
+// GPOS table or, failing that, from its legacy kern table
+func (utf *utf8FontFile) parseKerning() {
+	scale := 1000.0 / float64(utf.fontElementSize)
+	kt := &kerningType{pairs: make(map[uint32]int)}
+	if gpos := utf.getTableData("GPOS"); !kt.parseGPOS(gpos, scale) {
+		if kern := utf.getTableData("kern"); kern != nil {
+			kt.parseKernTable(kern, scale)
+		}
+	}
+	if len(kt.pairs) > 0 || len(kt.classes) > 0 {
+		utf.kerning = kt
+	}
+}
+
+// kern returns the kerning of the runes r1 and r2 in thousandths of the em
+func (utf *utf8FontFile) kern(r1, r2 rune) int {
+	if utf.kerning == nil {
+		return 0
+	}
+	g1, ok1 := utf.charSymbolDictionary[int(r1)]
+	g2, ok2 := utf.charSymbolDictionary[int(r2)]
+	if !ok1 || !ok2 {
+		return 0
+	}
+	return utf.kerning.adjust(g1, g2)
+}
+
+// SetFontKerning enables or disables pair kerning for the font specified by
+// familyStr and styleStr, which must have been added with AddUTF8Font() or
+// AddUTF8FontFromBytes(). The kerning of the font is taken from the kern
+// feature of its GPOS table or, failing that, from its legacy kern table.
+//
+// Kerning is disabled by default, so that the layout of existing documents
+// does not change. When enabled, it is applied to text output with Text(),
+// CellFormat(), MultiCell() and Write(), and taken into account by
+// GetStringWidth(), SplitLines() and SplitText(). Fonts without kerning data
+// are not affected. The aliases of RegisterAlias(), AliasNbPages() and
+// AliasPageLabel() are output without kerning, so that they can be
+// substituted.
+func (f *Fpdf) SetFontKerning(familyStr, styleStr string, kerning bool) {
+	if f.err != nil {
+		return
+	}
+	fontKey := getFontKey(fontFamilyEscape(familyStr), styleStr)
+	def, ok := f.fonts[fontKey]
+	if !ok || def.Tp != "UTF8" {
+		f.err = fmt.Errorf("undefined UTF-8 font: %s %s", familyStr, styleStr)
+		return
+	}
+	def.kern = kerning
+	f.fonts[fontKey] = def
+	if getFontKey(f.fontFamily, f.fontStyle) == fontKey {
+		f.currentFont.kern = kerning
+	}
+}
+
+// kern returns the kerning of the runes r1 and r2, in logical order, with
+// the current font or the fallback font that shows them in thousandths of
+// the em
+func (f *Fpdf) kern(r1, r2 rune) int {
+	font := f.currentFont
+	fb1, ok1 := f.fallbackFont(r1)
+	fb2, ok2 := f.fallbackFont(r2)
+	if ok1 || ok2 {
+		// Runes shown with different fonts are not kerned
+		if !ok1 || !ok2 || fb1.i != fb2.i {
+			return 0
+		}
+		font = fb1
+	}
+	if !font.kern || font.utf8File == nil {
+		return 0
+	}
+	return font.utf8File.kern(r1, r2)
+}
+
+// kernVisual returns the kerning of the runes r1 and r2 in visual order,
+// which is the reverse of the logical order of right-to-left characters
+func (f *Fpdf) kernVisual(r1, r2 rune) int {
+	if isBidiRTL(r1) && isBidiRTL(r2) {
+		return f.kern(r2, r1)
+	}
+	return f.kern(r1, r2)
+}
+
+// kernText returns the elements of a TJ array that shows the UTF-8 string
+// txtStr, in visual order, with the kerning of the current font. An empty
+// string is returned if no pair of runes of txtStr is kerned. Aliases are
+// not kerned, so that they are substituted as the document is closed.
+func (f *Fpdf) kernText(txtStr string) string {
+	if !f.currentFont.kern {
+		return ""
+	}
+	runes := []rune(txtStr)
+	alias := f.aliasRunes(runes)
+	var s fmtBuffer
+	j := 0
+	for i := 1; i < len(runes); i++ {
+		if alias[i-1] || alias[i] {
+			continue
+		}
+		if k := f.kernVisual(runes[i-1], runes[i]); k != 0 {
+			s.printf("(%s) %d ", f.escape(utf8toutf16(string(runes[j:i]), false)), -k)
+			j = i
+		}
+	}
+	if j == 0 {
+		return ""
+	}
+	s.printf("(%s)", f.escape(utf8toutf16(string(runes[j:]), false)))
+	return s.String()
+}
+
+// aliasRunes reports for each rune of runes whether it is part of an
+// occurrence of an alias registered with RegisterAlias(), AliasNbPages() or
+// AliasPageLabel()
+func (f *Fpdf) aliasRunes(runes []rune) []bool {
+	in := make([]bool, len(runes))
+	aliases := []string{f.aliasNbPagesStr, f.aliasLabelStr}
+	for alias := range f.aliasMap {
+		aliases = append(aliases, alias)
+	}
+	for _, alias := range aliases {
+		ar := []rune(alias)
+		if len(ar) == 0 {
+			continue
+		}
+		for i := 0; i+len(ar) <= len(runes); i++ {
+			if string(runes[i:i+len(ar)]) == alias {
+				for j := i; j < i+len(ar); j++ {
+					in[j] = true
+				}
+			}
+		}
+	}
+	return in
+}
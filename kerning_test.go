@@ -0,0 +1,90 @@
+package gofpdf_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+func TestFontKerning(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", "font/DejaVuSansCondensed.ttf")
+	pdf.SetFont("dejavu", "", 10)
+	pdf.AddPage()
+	plain := pdf.GetStringWidth("AVA")
+	pdf.Text(10, 20, "AVA")
+	pdf.SetFontKerning("dejavu", "", true)
+	// The pairs AV and VA are both kerned by -64 thousandths of the em
+	if w := pdf.GetStringWidth("AVA"); math.Abs(plain-w-1.28) > 1e-9 {
+		t.Errorf("kerned width %.3f, plain width %.3f", w, plain)
+	}
+	pdf.Text(10, 40, "AVA")
+	pdf.SetXY(10, 60)
+	pdf.CellFormat(100, 20, "AVA", "", 1, "L", false, 0, "")
+	pdf.Write(20, "AVA")
+	pdf.SetFontKerning("dejavu", "", false)
+	pdf.Text(10, 120, "AVA")
+	if err := pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+	s := updateContent(t, pagesRead(t, pdf), 1)
+	kerned := "[(\x00A) 64 (\x00V) 64 (\x00A)] TJ"
+	if n := strings.Count(s, kerned); n != 3 {
+		t.Errorf("found %d kerned strings in %q", n, s)
+	}
+	if n := strings.Count(s, "(\x00A\x00V\x00A) Tj"); n != 2 {
+		t.Errorf("found %d plain strings in %q", n, s)
+	}
+}
+
+func TestFontKerningAlias(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", "font/DejaVuSansCondensed.ttf")
+	pdf.SetFont("dejavu", "", 10)
+	pdf.SetFontKerning("dejavu", "", true)
+	pdf.AliasNbPages("AVAT")
+	pdf.RegisterAlias("{VA}", "x")
+	pdf.AddPage()
+	pdf.Text(10, 20, "AVAT")
+	pdf.Text(10, 40, "AVAVAT{VA}")
+	s := updateContent(t, pagesRead(t, pdf), 1)
+	for _, want := range []string{"(\x001) Tj", "[(\x00A) 64 (\x00V\x001\x00x)] TJ"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q not found in %q", want, s)
+		}
+	}
+}
+
+func TestFontKerningSplit(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", "font/DejaVuSansCondensed.ttf")
+	pdf.SetFont("dejavu", "", 10)
+	pdf.SetFontKerning("dejavu", "", true)
+	txt := "AVA VAV"
+	w := pdf.GetStringWidth(txt) + 2*pdf.GetCellMargin()
+	for _, kerning := range []bool{true, false} {
+		pdf.SetFontKerning("dejavu", "", kerning)
+		count := 1
+		if !kerning {
+			count = 2
+		}
+		if lines := pdf.SplitText(txt, w); len(lines) != count {
+			t.Errorf("kerning %v: SplitText returned %q", kerning, lines)
+		}
+		if lines := pdf.SplitLines([]byte(txt), w); len(lines) != count {
+			t.Errorf("kerning %v: SplitLines returned %q", kerning, lines)
+		}
+		pdf.AddPage()
+		pdf.MultiCell(w, 12, txt, "", "L", false)
+		_, top, _, _ := pdf.GetMargins()
+		if lines := (pdf.GetY() - top) / 12; math.Abs(lines-float64(count)) > 1e-9 {
+			t.Errorf("kerning %v: MultiCell output %.2f lines", kerning, lines)
+		}
+	}
+	pdf.SetFontKerning("helvetica", "", true)
+	if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), "undefined UTF-8 font") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
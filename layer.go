@@ -55,7 +55,11 @@ func (f *Fpdf) AddLayer(name string, visible bool) (layerID int) {
 func (f *Fpdf) BeginLayer(id int) {
 	f.EndLayer()
 	if id >= 0 && id < len(f.layer.list) {
+		// Marked content of a tagged document is nested in the layer
+		f.tagEndContent()
+		f.tag.hold++
 		f.outf("/OC /OC%d BDC", id)
+		f.tag.hold--
 		f.layer.currentLayer = id
 	}
 }
@@ -64,7 +68,10 @@ func (f *Fpdf) BeginLayer(id int) {
 // BeginLayer for more details.
 func (f *Fpdf) EndLayer() {
 	if f.layer.currentLayer >= 0 {
+		f.tagEndContent()
+		f.tag.hold++
 		f.out("EMC")
+		f.tag.hold--
 		f.layer.currentLayer = -1
 	}
 }
@@ -114,8 +121,5 @@ func (f *Fpdf) layerPutCatalog() {
 			}
 		}
 		f.outf("/OCProperties <</OCGs [%s] /D <</OFF [%s] /Order [%s]>>>>", onStr, offStr, onStr)
-		if f.layer.openLayerPane {
-			f.out("/PageMode /UseOC")
-		}
 	}
 }
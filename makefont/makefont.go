@@ -18,11 +18,10 @@ func showHelp() {
 	fmt.Fprintln(os.Stderr, "\n"+
 		"font_file is the name of the TrueType file (extension .ttf), OpenType file\n"+
 		"(extension .otf) or binary Type1 file (extension .pfb) from which to\n"+
-		"generate a definition file. If an OpenType file is specified, it must be one\n"+
-		"that is based on TrueType outlines, not PostScript outlines; this cannot be\n"+
-		"determined from the file extension alone. If a Type1 file is specified, a\n"+
-		"metric file with the same pathname except with the extension .afm must be\n"+
-		"present.")
+		"generate a definition file. An OpenType file may be based on TrueType\n"+
+		"outlines or on PostScript outlines; the latter is embedded as is (FontFile3)\n"+
+		"and requires PDF 1.6. If a Type1 file is specified, a metric file with the\n"+
+		"same pathname except with the extension .afm must be present.")
 	errPrintf("\nExample: %s --embed --enc=../font/cp1252.map --dst=../font calligra.ttf /opt/font/symbol.pfb\n", os.Args[0])
 }
 
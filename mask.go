@@ -0,0 +1,138 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// maskType holds a soft mask, a transparency group whose luminosity or alpha
+// sets the opacity of the content painted while the mask is in effect
+type maskType struct {
+	sStr    string // Luminosity or Alpha
+	content []byte // content stream of the group
+	bboxStr string // bounding box of the group
+	objNum  int    // graphics state that sets the mask
+}
+
+// addMask adds a soft mask of the subtype sStr drawn by content, covering the
+// current page, and returns its index
+func (f *Fpdf) addMask(sStr string, content []byte) int {
+	f.maskList = append(f.maskList, maskType{sStr: sStr, content: content,
+		bboxStr: sprintf("0 0 %.5f %.5f", f.w*f.k, f.h*f.k)})
+	return len(f.maskList) - 1
+}
+
+// BeginMask begins a soft mask drawn by fn, which sets the opacity of all
+// content drawn until EndMask() is called. fn draws the mask on the current
+// page with the usual methods of the document, such as AddGradient() and
+// PaintGradient(), Image(), Rect() and text output; what it draws is not
+// itself visible. Changes to the colors, font and other settings made by fn do
+// not outlast it. fn must not add pages.
+//
+// maskStr is "Luminosity" (or an empty string) to derive the opacity from
+// the luminosity of the mask: the content is opaque where the mask is white
+// and transparent where it is black or was not drawn. maskStr is "Alpha" to
+// derive the opacity from the opacity of the mask, as set for example with
+// SetAlpha(): the content is visible only where the mask was drawn.
+//
+// Masks can be nested, in which case their effects are combined. The
+// document cannot be successfully output while a mask is active.
+func (f *Fpdf) BeginMask(maskStr string, fn func()) {
+	if f.err != nil {
+		return
+	}
+	switch maskStr {
+	case "", "Luminosity":
+		maskStr = "Luminosity"
+	case "Alpha":
+	default:
+		f.err = fmt.Errorf("unrecognized mask type \"%s\"", maskStr)
+		return
+	}
+	if f.page <= 0 || f.state != 2 {
+		f.err = fmt.Errorf("cannot begin a mask without first adding a page")
+		return
+	}
+	// The mask is drawn in a buffer of its own, and the settings of the
+	// document are restored afterward, since the page content is unaffected
+	page, buf := f.page, f.pages[f.page]
+	x, y, lineWidth, capStyle, joinStyle := f.x, f.y, f.lineWidth, f.capStyle, f.joinStyle
+	clr, colorFlag, alpha, blendMode := f.color, f.colorFlag, f.alpha, f.blendMode
+	family, style, sizePt, size, font := f.fontFamily, f.fontStyle, f.fontSizePt, f.fontSize, f.currentFont
+	underline, strikeout := f.underline, f.strikeout
+	f.pages[page] = new(bytes.Buffer)
+	f.tag.hold++
+	fn()
+	f.tag.hold--
+	content := f.pages[page].Bytes()
+	f.pages[page] = buf
+	if f.page != page {
+		f.err = fmt.Errorf("a mask cannot add pages")
+		return
+	}
+	f.x, f.y, f.lineWidth, f.capStyle, f.joinStyle = x, y, lineWidth, capStyle, joinStyle
+	f.color, f.colorFlag, f.alpha, f.blendMode = clr, colorFlag, alpha, blendMode
+	f.fontFamily, f.fontStyle, f.fontSizePt, f.fontSize, f.currentFont = family, style, sizePt, size, font
+	f.underline, f.strikeout = underline, strikeout
+	if f.err != nil {
+		return
+	}
+	f.maskNest++
+	f.outf("q /SM%d gs", f.addMask(maskStr, content))
+}
+
+// EndMask ends the soft mask begun with the most recent call to BeginMask().
+func (f *Fpdf) EndMask() {
+	if f.err == nil {
+		if f.maskNest > 0 {
+			f.maskNest--
+			f.out("Q")
+		} else {
+			f.err = fmt.Errorf("error attempting to end mask operation out of sequence")
+		}
+	}
+}
+
+// transparencyUsed returns true if the document uses constant opacity, blend
+// modes or soft masks
+func (f *Fpdf) transparencyUsed() bool {
+	return len(f.blendMap) > 0 || len(f.maskList) > 1
+}
+
+// putMasks writes the soft masks. Their transparency groups use the
+// resource dictionary shared by the pages.
+func (f *Fpdf) putMasks() {
+	filter := ""
+	if f.compress {
+		filter = "/Filter /FlateDecode "
+	}
+	for j := 1; j < len(f.maskList); j++ {
+		mask := &f.maskList[j]
+		content := mask.content
+		if f.compress {
+			content = sliceCompress(content)
+		}
+		group := "/S /Transparency"
+		if mask.sStr == "Luminosity" {
+			group += " /CS /DeviceGray"
+		}
+		f.newobj()
+		f.outf("<<%s/Type /XObject /Subtype /Form /BBox [%s] /Group <<%s>>", filter, mask.bboxStr, group)
+		f.outf("/Resources %d 0 R", f.resourcesObj())
+		f.outf("/Length %d>>", f.protect.streamLen(len(content)))
+		f.putstream(content)
+		f.out("endobj")
+		f.newobj()
+		f.outf("<</Type /ExtGState /SMask <</Type /Mask /S /%s /G %d 0 R>>>>", mask.sStr, f.n-1)
+		f.out("endobj")
+		mask.objNum = f.n
+	}
+}
+
+// maskPutExtGStates writes the graphics states of the soft masks of the
+// resource dictionary
+func (f *Fpdf) maskPutExtGStates() {
+	for j := 1; j < len(f.maskList); j++ {
+		f.outf("/SM%d %d 0 R", j, f.maskList[j].objNum)
+	}
+}
@@ -0,0 +1,143 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+func TestMask(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetFillColor(0, 0, 255)
+	pdf.BeginMask("", func() {
+		pdf.SetFillColor(255, 255, 255)
+		pdf.SetFont("Courier", "", 40)
+		pdf.Rect(10, 10, 50, 50, "F")
+	})
+	pdf.Rect(20, 20, 50, 50, "F")
+	pdf.BeginMask("Alpha", func() {
+		pdf.SetAlpha(0.5, "")
+		pdf.Circle(40, 40, 10, "F")
+	})
+	pdf.Text(20, 80, "Masked")
+	pdf.EndMask()
+	pdf.EndMask()
+	if r, g, b := pdf.GetFillColor(); r != 0 || g != 0 || b != 255 {
+		t.Errorf("fill color %d %d %d not restored", r, g, b)
+	}
+	if size, _ := pdf.GetFontSize(); size != 12 {
+		t.Errorf("font size %.0f not restored", size)
+	}
+	r := pagesRead(t, pdf)
+	s := updateContent(t, r, 1)
+	if !strings.Contains(s, "q /SM1 gs\n") || !strings.Contains(s, "q /SM2 gs\n") ||
+		strings.Contains(s, "1.000 g") || strings.Contains(s, "/GS") {
+		t.Errorf("unexpected content %q", s)
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	_, page, _ := r.Page(1)
+	gs, _ := r.ResolveDict(res["ExtGState"])
+	for _, c := range []struct {
+		name    pdfreader.Name
+		subtype string
+		cs      pdfreader.Object
+		op      string
+	}{
+		{"SM1", "Luminosity", pdfreader.Name("DeviceGray"), "1.000 g\n"},
+		{"SM2", "Alpha", nil, "/GS1 gs\n"},
+	} {
+		d, _ := r.ResolveDict(gs[c.name])
+		mask, _ := r.ResolveDict(d["SMask"])
+		obj, _ := r.Resolve(mask["G"])
+		form, ok := obj.(*pdfreader.Stream)
+		if !ok || mask["S"] != pdfreader.Name(c.subtype) {
+			t.Fatalf("unexpected soft mask %s", pdfreader.Format(mask))
+		}
+		group, _ := r.ResolveDict(form.Dict["Group"])
+		if group["S"] != pdfreader.Name("Transparency") || group["CS"] != c.cs ||
+			form.Dict["Resources"] != page["Resources"] {
+			t.Errorf("unexpected group %s", pdfreader.Format(form.Dict))
+		}
+		if data, err := form.Decode(); err != nil || !strings.Contains(string(data), c.op) {
+			t.Errorf("%s: unexpected content %q %v", c.name, data, err)
+		}
+	}
+}
+
+func TestMaskErrors(t *testing.T) {
+	for _, c := range []struct {
+		fn  func(pdf *gofpdf.Fpdf)
+		err string
+	}{
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.EndMask()
+		}, "mask operation out of sequence"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.BeginMask("Opacity", func() {})
+		}, "unrecognized mask type"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.BeginMask("", func() {})
+			pdf.Close()
+		}, "mask procedure must be explicitly ended"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.BeginMask("", func() {
+				pdf.AddPage()
+			})
+		}, "a mask cannot add pages"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		c.fn(pdf)
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
+
+func TestImageMask(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.RegisterImageOptionsReader("mask", gofpdf.ImageOptions{ImageType: "PNG"},
+		bytes.NewReader(iccPNG(t, 10, true, nil)))
+	opt := gofpdf.ImageOptions{ImageType: "JPG", MaskImage: "mask"}
+	pdf.RegisterImageOptionsReader("photo", opt, bytes.NewReader(iccJPEG(t, 20, iccProfile("RGB "))))
+	pdf.ImageOptions("photo", 10, 10, 40, 0, false, opt, 0, "")
+	r := pagesRead(t, pdf)
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	xobjects, _ := r.ResolveDict(res["XObject"])
+	widths := make(map[pdfreader.Ref]int)
+	var smask pdfreader.Object
+	for _, ref := range xobjects {
+		obj, _ := r.Resolve(ref)
+		if stm, ok := obj.(*pdfreader.Stream); ok {
+			widths[ref.(pdfreader.Ref)], _ = stm.Dict["Width"].(int)
+			if stm.Dict["Width"] == 20 {
+				smask = stm.Dict["SMask"]
+			}
+		}
+	}
+	if ref, ok := smask.(pdfreader.Ref); !ok || widths[ref] != 10 {
+		t.Errorf("unexpected soft mask %s", pdfreader.Format(smask))
+	}
+	for _, c := range []struct {
+		mask string
+		err  string
+	}{
+		{"none", "mask image none is not registered"},
+		{"rgb", "mask image rgb is not a plain grayscale image"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.RegisterImageOptionsReader("rgb", gofpdf.ImageOptions{ImageType: "PNG"},
+			bytes.NewReader(iccPNG(t, 10, false, nil)))
+		pdf.RegisterImageOptionsReader("photo", gofpdf.ImageOptions{ImageType: "PNG", MaskImage: c.mask},
+			bytes.NewReader(iccPNG(t, 20, false, nil)))
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package gofpdf
+
+import (
+	"bytes"
+	"sort"
+)
+
+// objStmMaxObjects is the maximum number of objects packed in an object
+// stream
+const objStmMaxObjects = 100
+
+// objStmRecType holds the state of the object stream output mode
+type objStmRecType struct {
+	enabled bool
+	strs    map[int][]objStmString // encrypted strings of each object
+}
+
+// objStmString pairs the encrypted and the plain form of a string literal
+// written to an object
+type objStmString struct {
+	enc, plain string
+}
+
+// SetObjectStreams enables or disables compact output. When enabled, the
+// objects that are not streams, such as page, font and annotation
+// dictionaries, are packed into compressed object streams and the
+// cross-reference table is written as a compressed cross-reference stream.
+// This requires PDF version 1.5, to which the document is raised. Documents
+// that conform to PDF/A-1 cannot use this mode. It is disabled by default.
+func (f *Fpdf) SetObjectStreams(on bool) {
+	f.objStm.enabled = on
+}
+
+// objStmRecord records the plain form of a string literal that is
+// encrypted for object n, so that it can be restored if the object is
+// packed into an object stream, which is encrypted as a whole
+func (f *Fpdf) objStmRecord(n int, enc, plain string) {
+	if f.objStm.strs == nil {
+		f.objStm.strs = make(map[int][]objStmString)
+	}
+	f.objStm.strs[n] = append(f.objStm.strs[n], objStmString{enc: enc, plain: plain})
+}
+
+// objStmPackable returns the body of object n, without its header and
+// endobj keyword, and true if it can be packed into an object stream
+func (f *Fpdf) objStmPackable(n int, obj []byte) ([]byte, bool) {
+	if n == f.protect.objNum && f.protect.encrypted {
+		return nil, false
+	}
+	if f.sign != nil && n == f.sign.objNum {
+		return nil, false
+	}
+	header := []byte(sprintf("%d 0 obj\n", n))
+	if !bytes.HasPrefix(obj, header) || !bytes.HasSuffix(obj, []byte("\nendobj\n")) ||
+		bytes.Contains(obj, []byte("\nstream\n")) {
+		return nil, false
+	}
+	body := obj[len(header) : len(obj)-len("\nendobj\n")]
+	for _, s := range f.objStm.strs[n] {
+		body = bytes.Replace(body, []byte(s.enc), []byte(s.plain), 1)
+	}
+	return body, true
+}
+
+// putObjStreams completes the document with object streams and a
+// cross-reference stream. The objects written so far are rearranged so
+// that those that can be packed are moved into object streams.
+func (f *Fpdf) putObjStreams() {
+	root, info := f.n, f.n-1
+	old := f.buffer.Bytes()
+	nums := make([]int, 0, f.n)
+	for j := 1; j <= f.n; j++ {
+		if f.offsets[j] > 0 {
+			nums = append(nums, j)
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return f.offsets[nums[i]] < f.offsets[nums[j]] })
+	end := len(old)
+	type entry struct{ tp, field2, field3 int }
+	xref := make([]entry, f.n+1)
+	xref[0] = entry{0, 0, 65535}
+	var packed []int
+	bodies := make(map[int][]byte)
+	var buf fmtBuffer
+	buf.Write(old[:f.offsets[nums[0]]])
+	signDelta := 0
+	for i, n := range nums {
+		next := end
+		if i+1 < len(nums) {
+			next = f.offsets[nums[i+1]]
+		}
+		obj := old[f.offsets[n]:next]
+		if body, ok := f.objStmPackable(n, obj); ok {
+			packed = append(packed, n)
+			bodies[n] = body
+			continue
+		}
+		if f.sign != nil && n == f.sign.objNum {
+			signDelta = buf.Len() - f.offsets[n]
+		}
+		f.offsets[n] = buf.Len()
+		xref[n] = entry{1, buf.Len(), 0}
+		buf.Write(obj)
+	}
+	f.buffer = buf
+	if f.sign != nil {
+		f.sign.byteRange += signDelta
+		f.sign.contents += signDelta
+	}
+	// Object streams
+	for len(packed) > 0 {
+		count := len(packed)
+		if count > objStmMaxObjects {
+			count = objStmMaxObjects
+		}
+		f.newobj()
+		xref = append(xref, entry{1, f.offsets[f.n], 0})
+		var head, data bytes.Buffer
+		for j, n := range packed[:count] {
+			xref[n] = entry{2, f.n, j}
+			head.WriteString(sprintf("%d %d ", n, data.Len()))
+			data.Write(bodies[n])
+			data.WriteByte('\n')
+		}
+		packed = packed[count:]
+		first := head.Len()
+		content := append(head.Bytes(), data.Bytes()...)
+		filter := ""
+		if f.compress {
+			content = sliceCompress(content)
+			filter = "/Filter /FlateDecode "
+		}
+		f.outf("<</Type /ObjStm /N %d /First %d %s/Length %d>>",
+			count, first, filter, f.protect.streamLen(len(content)))
+		f.putstream(content)
+		f.out("endobj")
+	}
+	// Cross-reference stream, which is not encrypted
+	f.newobj()
+	xref = append(xref, entry{1, f.offsets[f.n], 0})
+	w := 4
+	if int64(f.offsets[f.n]) >= 1<<32 {
+		w = 8
+	}
+	var data bytes.Buffer
+	for _, e := range xref {
+		data.WriteByte(byte(e.tp))
+		for j := w - 1; j >= 0; j-- {
+			data.WriteByte(byte(e.field2 >> (8 * uint(j))))
+		}
+		data.WriteByte(byte(e.field3 >> 8))
+		data.WriteByte(byte(e.field3))
+	}
+	content := data.Bytes()
+	filter := ""
+	if f.compress {
+		content = sliceCompress(content)
+		filter = "/Filter /FlateDecode "
+	}
+	f.outf("<</Type /XRef /Size %d /W [1 %d 2] %s/Length %d", f.n+1, w, filter, len(content))
+	f.outf("/Root %d 0 R", root)
+	f.outf("/Info %d 0 R", info)
+	f.puttrailerID()
+	f.out(">>")
+	f.out("stream")
+	f.out(string(content))
+	f.out("endstream")
+	f.out("endobj")
+	f.out("startxref")
+	f.outf("%d", f.offsets[f.n])
+	f.out("%%EOF")
+}
@@ -0,0 +1,121 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/internal/example"
+)
+
+// objStmStream returns the data of the stream object that starts at offset
+// off, decompressed if necessary
+func objStmStream(t *testing.T, pdf []byte, off int) (dict string, data []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`(?s)^\d+ 0 obj\n(<<.*?>>)\nstream\n`).FindSubmatch(pdf[off:])
+	if m == nil {
+		t.Fatalf("no stream object at offset %d", off)
+	}
+	dict = string(m[1])
+	n, _ := strconv.Atoi(regexp.MustCompile(`/Length (\d+)`).FindStringSubmatch(dict)[1])
+	start := off + len(m[0])
+	if !strings.Contains(dict, "/FlateDecode") {
+		return dict, pdf[start : start+n]
+	}
+	r, err := zlib.NewReader(bytes.NewReader(pdf[start : start+n]))
+	if err != nil {
+		t.Fatalf("stream at offset %d: %v", off, err)
+	}
+	data, _ = ioutil.ReadAll(r)
+	return
+}
+
+func TestSetObjectStreams(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetObjectStreams(true)
+	pdf.SetTitle("Statement", false)
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.SetFont("dejavu", "", 12)
+	for j := 0; j < 120; j++ {
+		pdf.AddPage()
+		pdf.Cell(40, 10, "Statement page")
+		pdf.Bookmark("Page "+strconv.Itoa(j+1), 0, 0)
+	}
+	out := formOutput(t, pdf)
+	if !bytes.HasPrefix(out, []byte("%PDF-1.5")) {
+		t.Fatalf("version not raised to 1.5")
+	}
+	if bytes.Contains(out, []byte("\nxref\n")) || bytes.Contains(out, []byte("trailer")) {
+		t.Fatalf("classic cross-reference table written")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("startxref missing")
+	}
+	off, _ := strconv.Atoi(string(m[1]))
+	dict, data := objStmStream(t, out, off)
+	if !regexp.MustCompile(`/Type /XRef /Size \d+ /W \[1 4 2\]`).MatchString(dict) ||
+		!regexp.MustCompile(`/Root \d+ 0 R`).MatchString(dict) {
+		t.Fatalf("unexpected cross-reference stream dictionary %s", dict)
+	}
+	size, _ := strconv.Atoi(regexp.MustCompile(`/Size (\d+)`).FindStringSubmatch(dict)[1])
+	if len(data) != 7*size {
+		t.Fatalf("cross-reference stream has %d bytes for %d objects", len(data), size)
+	}
+	streams := map[int][]byte{}
+	packed := 0
+	for n := 1; n < size; n++ {
+		e := data[7*n : 7*n+7]
+		f2 := int(e[1])<<24 | int(e[2])<<16 | int(e[3])<<8 | int(e[4])
+		f3 := int(e[5])<<8 | int(e[6])
+		switch e[0] {
+		case 1:
+			if !bytes.HasPrefix(out[f2:], []byte(strconv.Itoa(n)+" 0 obj\n")) {
+				t.Fatalf("offset of object %d is wrong", n)
+			}
+		case 2:
+			packed++
+			stm, ok := streams[f2]
+			if !ok {
+				e := data[7*f2 : 7*f2+7]
+				d, content := objStmStream(t, out, int(e[1])<<24|int(e[2])<<16|int(e[3])<<8|int(e[4]))
+				if !regexp.MustCompile(`/Type /ObjStm /N \d+ /First \d+`).MatchString(d) {
+					t.Fatalf("unexpected object stream dictionary %s", d)
+				}
+				stm = content
+				streams[f2] = stm
+			}
+			fields := bytes.Fields(stm)
+			if num, _ := strconv.Atoi(string(fields[2*f3])); num != n {
+				t.Fatalf("object stream %d holds object %d at index %d, not %d", f2, num, f3, n)
+			}
+		default:
+			t.Fatalf("object %d is free", n)
+		}
+	}
+	if packed < 240 || len(streams) < 3 {
+		t.Errorf("%d objects packed in %d streams", packed, len(streams))
+	}
+}
+
+func TestObjectStreamsProtection(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetObjectStreams(true)
+	pdf.SetProtection(gofpdf.CnProtectPrint, "", "owner")
+	pdf.SetTitle("Confidential", false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.Cell(40, 10, "Secret")
+	out := formOutput(t, pdf)
+	if !regexp.MustCompile(`\d+ 0 obj\n<<\n/Filter /Standard`).Match(out) {
+		t.Errorf("encryption dictionary must not be packed")
+	}
+	if !regexp.MustCompile(`/Encrypt \d+ 0 R`).Match(out) {
+		t.Errorf("cross-reference stream does not refer to the encryption dictionary")
+	}
+}
@@ -0,0 +1,186 @@
+package gofpdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pageLabelType describes the labels of a range of pages
+type pageLabelType struct {
+	startPage   int    // first page of the range, 1-based
+	style       string // numbering style: "D", "R", "r", "A", "a" or empty
+	prefix      string // UTF-8 label prefix
+	firstNumber int    // numeric portion of the label of the first page
+}
+
+// SetPageLabel defines the labels that viewers display for the pages of the
+// document, starting with page startPage and continuing until the next
+// page for which a label range is defined. Each label is made of prefix
+// followed by a number in the specified style: "D" for decimal numbers, "R"
+// and "r" for uppercase and lowercase roman numerals, "A" and "a" for
+// uppercase and lowercase letters (A to Z, then AA to ZZ and so on), or ""
+// for labels made of the prefix only. firstNumber is the number of page
+// startPage; it is 1 if zero. prefix is a UTF-8 string.
+//
+// For example, a report with four pages of front matter numbered i to iv,
+// followed by the body numbered from 1 and an appendix starting on page 20
+// numbered A-1, A-2 and so on, is labeled with
+//
+//	pdf.SetPageLabel(1, "r", "", 1)
+//	pdf.SetPageLabel(5, "D", "", 1)
+//	pdf.SetPageLabel(20, "D", "A-", 1)
+//
+// Pages that precede the first range are labeled with their page number.
+// Labels can be printed on the pages with AliasPageLabel.
+func (f *Fpdf) SetPageLabel(startPage int, style string, prefix string, firstNumber int) {
+	if f.err != nil {
+		return
+	}
+	switch style {
+	case "D", "R", "r", "A", "a", "":
+	default:
+		f.err = fmt.Errorf("invalid page label style %s", style)
+		return
+	}
+	if startPage < 1 {
+		f.err = fmt.Errorf("invalid page label start page %d", startPage)
+		return
+	}
+	if firstNumber <= 0 {
+		firstNumber = 1
+	}
+	lbl := pageLabelType{startPage: startPage, style: style, prefix: prefix, firstNumber: firstNumber}
+	for j, l := range f.pageLabels {
+		if l.startPage == startPage {
+			f.pageLabels[j] = lbl
+			return
+		}
+	}
+	f.pageLabels = append(f.pageLabels, lbl)
+	sort.Slice(f.pageLabels, func(i, j int) bool {
+		return f.pageLabels[i].startPage < f.pageLabels[j].startPage
+	})
+}
+
+// GetPageLabel returns the label of the specified page as defined with
+// SetPageLabel, or the page number if no label applies to it.
+func (f *Fpdf) GetPageLabel(pageNum int) string {
+	for j := len(f.pageLabels) - 1; j >= 0; j-- {
+		l := f.pageLabels[j]
+		if l.startPage <= pageNum {
+			return l.prefix + pageLabelNumber(l.style, l.firstNumber+pageNum-l.startPage)
+		}
+	}
+	return sprintf("%d", pageNum)
+}
+
+// AliasPageLabel defines an alias for the label of the page on which it is
+// written. It is substituted with the label defined with SetPageLabel as
+// the document is closed, in the same way as the alias for the number of
+// pages defined with AliasNbPages. An empty string is replaced with the
+// string "{pl}".
+func (f *Fpdf) AliasPageLabel(aliasStr string) {
+	if aliasStr == "" {
+		aliasStr = "{pl}"
+	}
+	f.aliasLabelStr = aliasStr
+}
+
+// pageLabelNumber returns the numeric portion of a page label
+func pageLabelNumber(style string, n int) string {
+	switch style {
+	case "D":
+		return sprintf("%d", n)
+	case "R":
+		return romanNumeral(n)
+	case "r":
+		return strings.ToLower(romanNumeral(n))
+	case "A":
+		return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+	case "a":
+		return strings.Repeat(string(rune('a'+(n-1)%26)), (n-1)/26+1)
+	}
+	return ""
+}
+
+// romanNumeral returns the uppercase roman numeral for n
+func romanNumeral(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for j, v := range values {
+		for n >= v {
+			b.WriteString(symbols[j])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// replacePageLabelAliases substitutes the page label alias on each page
+func (f *Fpdf) replacePageLabelAliases() {
+	for n := 1; n <= f.page; n++ {
+		f.replacePageLabelAlias(n)
+	}
+}
+
+// replacePageLabelAlias substitutes the page label alias on page n
+func (f *Fpdf) replacePageLabelAlias(n int) {
+	if f.aliasLabelStr == "" {
+		return
+	}
+	label := f.GetPageLabel(n)
+	s := f.pages[n].String()
+	if !strings.Contains(s, f.aliasLabelStr) &&
+		!strings.Contains(s, utf8toutf16(f.aliasLabelStr, false)) {
+		return
+	}
+	s = strings.Replace(s, f.aliasLabelStr, label, -1)
+	s = strings.Replace(s, utf8toutf16(f.aliasLabelStr, false), utf8toutf16(label, false), -1)
+	f.pages[n].Truncate(0)
+	f.pages[n].WriteString(s)
+	// The glyphs of the label must be included in font subsets
+	for _, font := range f.fonts {
+		if font.Tp == "UTF8" {
+			for _, r := range label {
+				font.usedRunes[int(r)] = int(r)
+			}
+		}
+	}
+}
+
+// putPageLabels writes the page label number tree of the document catalog
+func (f *Fpdf) putPageLabels() {
+	if len(f.pageLabels) == 0 {
+		return
+	}
+	var b fmtBuffer
+	b.printf("/PageLabels <</Nums [")
+	if f.pageLabels[0].startPage > 1 {
+		// The number tree must include the first page
+		b.printf("0 <</S /D>> ")
+	}
+	for _, l := range f.pageLabels {
+		var entries []string
+		if l.style != "" {
+			entries = append(entries, "/S /"+l.style)
+		}
+		if l.prefix != "" {
+			prefix := l.prefix
+			for _, r := range prefix {
+				if r > 0x7e {
+					prefix = utf8toutf16(prefix)
+					break
+				}
+			}
+			entries = append(entries, "/P "+f.textstring(prefix))
+		}
+		if l.firstNumber != 1 {
+			entries = append(entries, sprintf("/St %d", l.firstNumber))
+		}
+		b.printf("%d <<%s>> ", l.startPage-1, strings.Join(entries, " "))
+	}
+	b.printf("]>>")
+	f.out(b.String())
+}
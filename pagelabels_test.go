@@ -0,0 +1,70 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/internal/example"
+)
+
+func TestSetPageLabel(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetPageLabel(3, "D", "", 1)
+	pdf.SetPageLabel(1, "r", "", 0)
+	pdf.SetPageLabel(5, "A", "App-", 27)
+	pdf.AliasPageLabel("")
+	pdf.SetFont("Helvetica", "", 12)
+	for j := 0; j < 5; j++ {
+		pdf.AddPage()
+		pdf.Cell(40, 10, "Page {pl}")
+	}
+	labels := []string{"i", "ii", "1", "2", "App-AA"}
+	for j, want := range labels {
+		if got := pdf.GetPageLabel(j + 1); got != want {
+			t.Errorf("GetPageLabel(%d) = %q, want %q", j+1, got, want)
+		}
+	}
+	s := string(formOutput(t, pdf))
+	if !strings.Contains(s, "/PageLabels <</Nums [0 <</S /r>> 2 <</S /D>> 4 <</S /A /P (App-) /St 27>> ]>>") {
+		t.Errorf("page label number tree missing")
+	}
+	for _, label := range labels {
+		if !strings.Contains(s, "(Page "+label+")") {
+			t.Errorf("alias not replaced with label %s", label)
+		}
+	}
+}
+
+func TestPageLabelUTF8(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetPageLabel(2, "R", "", 4)
+	pdf.AliasPageLabel("{label}")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.SetFont("dejavu", "", 12)
+	pdf.AddPage()
+	pdf.AddPage()
+	pdf.Cell(40, 10, "{label}")
+	s := string(formOutput(t, pdf))
+	if !strings.Contains(s, "/PageLabels <</Nums [0 <</S /D>> 1 <</S /R /St 4>> ]>>") {
+		t.Errorf("page label number tree missing")
+	}
+	if !strings.Contains(s, "\x00I\x00V") {
+		t.Errorf("UTF-8 alias not replaced with label")
+	}
+}
+
+func TestSetPageLabelErrors(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPageLabel(1, "x", "", 1)
+	if pdf.Err() == false {
+		t.Errorf("expected error for invalid style")
+	}
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPageLabel(0, "D", "", 1)
+	if pdf.Err() == false {
+		t.Errorf("expected error for invalid start page")
+	}
+}
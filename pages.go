@@ -0,0 +1,231 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MovePage moves page from so that it becomes page to, shifting the pages in
+// between. Both numbers are 1-based. The content, size, page boxes, links,
+// annotations and form fields of the page move with it, and internal links,
+// named destinations and bookmarks that refer to the page follow it. The order
+// of the bookmarks themselves is not changed; see MoveBookmark().
+//
+// The current page is still the same page after the move, so that a page such
+// as a table of contents can be written at the end of the document, moved
+// to the front and completed afterwards. Its footer is written when the next
+// page is added or the document is closed, as usual. Page numbers written
+// with the alias of AliasPageLabel() reflect the final order of the pages.
+func (f *Fpdf) MovePage(from, to int) {
+	if !f.pageCheck(from) || !f.pageCheck(to) {
+		return
+	}
+	order := make([]int, 0, len(f.pages)-1)
+	for n := 1; n < len(f.pages); n++ {
+		if n != from {
+			order = append(order, n)
+		}
+	}
+	order = append(order[:to-1], append([]int{from}, order[to-1:]...)...)
+	f.arrangePages(order)
+}
+
+// DeletePage removes page n (1-based) from the document, along with its links,
+// annotations and form fields. Internal links, named destinations and
+// bookmarks that refer to the deleted page are redirected to the page that
+// takes its place, or to the new last page if n was the last page. The last
+// remaining page of a document cannot be deleted.
+//
+// If the current page is deleted, the page that takes its place becomes the
+// current page.
+func (f *Fpdf) DeletePage(n int) {
+	if !f.pageCheck(n) {
+		return
+	}
+	if len(f.pages) == 2 {
+		f.err = fmt.Errorf("the only page of the document cannot be deleted")
+		return
+	}
+	if n == f.openPage {
+		// Close the marked content of the page; its footer is not written
+		page := f.page
+		f.page = n
+		f.EndLayer()
+		f.tagEndContent()
+		f.page = page
+		f.openPage = 0
+	}
+	order := make([]int, 0, len(f.pages)-2)
+	for j := 1; j < len(f.pages); j++ {
+		if j != n {
+			order = append(order, j)
+		}
+	}
+	f.arrangePages(order)
+}
+
+// DuplicatePage inserts a copy of page n (1-based) after it, with the same
+// content, size, page boxes, links and annotations. The copy holds the content
+// of page n at the time of the call; if n is the page currently being
+// written, the copy does not include the footer of the page. Form fields are
+// not copied, since a field cannot appear twice with independent values, and
+// the pages of tagged documents cannot be duplicated.
+func (f *Fpdf) DuplicatePage(n int) {
+	if !f.pageCheck(n) {
+		return
+	}
+	if f.tag.enabled {
+		f.err = fmt.Errorf("the pages of a tagged document cannot be duplicated")
+		return
+	}
+	dup := len(f.pages)
+	f.pages = append(f.pages, bytes.NewBuffer(append([]byte{}, f.pages[n].Bytes()...)))
+	f.pageLinks = append(f.pageLinks, append([]linkType{}, f.pageLinks[n]...))
+	f.pageAttachments = append(f.pageAttachments, append([]annotationAttach{}, f.pageAttachments[n]...))
+	f.pageWidgets = append(f.pageWidgets, []*formWidget{})
+	annots := make([]*annotType, len(f.pageAnnots[n]))
+	for j, an := range f.pageAnnots[n] {
+		cp := *an
+		annots[j] = &cp
+	}
+	f.pageAnnots = append(f.pageAnnots, annots)
+	f.tag.pageMCIDs = append(f.tag.pageMCIDs, nil)
+	if sz, ok := f.pageSizes[n]; ok {
+		f.pageSizes[dup] = sz
+	}
+	f.pageBoxes[dup] = make(map[string]PageBox)
+	for box, pb := range f.pageBoxes[n] {
+		f.pageBoxes[dup][box] = pb
+	}
+	f.MovePage(dup, n+1)
+}
+
+// InsertPageAt adds a new page as AddPage() does, and moves it so that it
+// becomes page n (1-based), shifting the following pages. n may be one more
+// than the number of pages to add the page at the end. This makes it possible
+// to add a cover page or a table of contents once the rest of the document is
+// written. The new page is the current page.
+func (f *Fpdf) InsertPageAt(n int) {
+	if f.err != nil {
+		return
+	}
+	if n < 1 || n > len(f.pages) {
+		f.err = fmt.Errorf("page %d cannot be inserted in a document of %d pages", n, len(f.pages)-1)
+		return
+	}
+	if !f.pageCheck(0) {
+		return
+	}
+	f.AddPage()
+	if f.err == nil {
+		f.MovePage(len(f.pages)-1, n)
+	}
+}
+
+// pageCheck verifies that the pages of the document can be rearranged and
+// that page n exists, unless n is zero
+func (f *Fpdf) pageCheck(n int) bool {
+	if f.err != nil {
+		return false
+	}
+	switch {
+	case f.update != nil:
+		f.err = fmt.Errorf("pages cannot be rearranged when updating a document")
+	case f.stream != nil:
+		f.err = fmt.Errorf("pages cannot be rearranged in streaming output mode")
+	case f.state == 3:
+		f.err = fmt.Errorf("pages cannot be rearranged once the document is closed")
+	case n != 0 && (n < 1 || n >= len(f.pages)):
+		f.err = fmt.Errorf("page %d does not exist", n)
+	default:
+		return true
+	}
+	return false
+}
+
+// arrangePages reorders the pages so that page j is former page order[j-1].
+// Pages missing from order are deleted, and references to them are redirected
+// to the next remaining page, or to the last page if none remains after them.
+func (f *Fpdf) arrangePages(order []int) {
+	count := len(f.pages) - 1
+	newNum := make([]int, count+1)
+	for j, old := range order {
+		newNum[old] = j + 1
+	}
+	next := len(order)
+	deleted := make(map[int]bool)
+	for old := count; old >= 1; old-- {
+		if newNum[old] == 0 {
+			newNum[old] = next
+			deleted[old] = true
+		} else {
+			next = newNum[old]
+		}
+	}
+	pages := []*bytes.Buffer{f.pages[0]}
+	pageLinks := [][]linkType{f.pageLinks[0]}
+	pageAttachments := [][]annotationAttach{f.pageAttachments[0]}
+	pageWidgets := [][]*formWidget{f.pageWidgets[0]}
+	pageAnnots := [][]*annotType{f.pageAnnots[0]}
+	pageMCIDs := [][]*structElem{f.tag.pageMCIDs[0]}
+	pageSizes := make(map[int]SizeType)
+	pageBoxes := make(map[int]map[string]PageBox)
+	for j, old := range order {
+		pages = append(pages, f.pages[old])
+		pageLinks = append(pageLinks, f.pageLinks[old])
+		pageAttachments = append(pageAttachments, f.pageAttachments[old])
+		pageWidgets = append(pageWidgets, f.pageWidgets[old])
+		pageAnnots = append(pageAnnots, f.pageAnnots[old])
+		pageMCIDs = append(pageMCIDs, f.tag.pageMCIDs[old])
+		if sz, ok := f.pageSizes[old]; ok {
+			pageSizes[j+1] = sz
+		}
+		pageBoxes[j+1] = f.pageBoxes[old]
+	}
+	f.pages, f.pageLinks, f.pageAttachments = pages, pageLinks, pageAttachments
+	f.pageWidgets, f.pageAnnots, f.tag.pageMCIDs = pageWidgets, pageAnnots, pageMCIDs
+	f.pageSizes, f.pageBoxes = pageSizes, pageBoxes
+	if len(deleted) > 0 {
+		f.formDropDeletedWidgets()
+	}
+	// Page numbers beyond the document may be targets of links to pages that
+	// are not yet added; they are shifted by the number of deleted pages
+	renum := func(p int) int {
+		if p >= 1 && p <= count {
+			return newNum[p]
+		}
+		if p > count {
+			return p - len(deleted)
+		}
+		return p
+	}
+	for j := range f.links {
+		if f.links[j].file == "" {
+			f.links[j].page = renum(f.links[j].page)
+		}
+	}
+	for j := range f.outlines {
+		f.outlines[j].p = renum(f.outlines[j].p)
+	}
+	for name, d := range f.namedDests {
+		d.page = renum(d.page)
+		f.namedDests[name] = d
+	}
+	f.openAction.page = renum(f.openAction.page)
+	for _, e := range f.tag.elems {
+		e.page = renum(e.page)
+		kids := e.kids[:0]
+		for _, k := range e.kids {
+			if k.elem == nil && deleted[k.page] {
+				continue
+			}
+			k.page = renum(k.page)
+			kids = append(kids, k)
+		}
+		e.kids = kids
+	}
+	f.page = renum(f.page)
+	if f.openPage > 0 {
+		f.openPage = renum(f.openPage)
+	}
+}
@@ -0,0 +1,217 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// pagesRead returns a reader of the output of pdf
+func pagesRead(t *testing.T, pdf *gofpdf.Fpdf) *pdfreader.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := pdfreader.NewReaderBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// destPage returns the page number of the explicit destination dest
+func destPage(t *testing.T, r *pdfreader.Reader, dest pdfreader.Object) int {
+	t.Helper()
+	a, _ := r.ResolveArray(dest)
+	for n := 1; n <= r.NumPages(); n++ {
+		if ref, _, _ := r.Page(n); len(a) > 0 && a[0] == ref {
+			return n
+		}
+	}
+	t.Fatalf("destination %s is not a page", pdfreader.Format(dest))
+	return 0
+}
+
+func TestMovePage(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetFooterFunc(func() {
+		pdf.Text(10, 290, "Footer")
+	})
+	var link int
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		pdf.Bookmark(fmt.Sprintf("Chapter %d", j), 0, 0)
+		pdf.Cell(40, 10, fmt.Sprintf("Chapter %d", j))
+		if j == 1 {
+			link = pdf.AddLink()
+			pdf.SetLink(link, 0, -1)
+		}
+	}
+	pdf.Link(10, 10, 30, 10, link)
+	pdf.AddNamedDest("last", -1, 0, "")
+	// Table of contents written at the end and moved after the cover
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Contents")
+	pdf.MovePage(4, 1)
+	pdf.InsertPageAt(1)
+	pdf.Cell(40, 10, "Cover")
+	if pdf.PageNo() != 1 || pdf.PageCount() != 5 {
+		t.Fatalf("current page %d of %d", pdf.PageNo(), pdf.PageCount())
+	}
+	r := pagesRead(t, pdf)
+	for n, s := range []string{"Cover", "Contents", "Chapter 1", "Chapter 2", "Chapter 3"} {
+		content := updateContent(t, r, n+1)
+		if !strings.Contains(content, "("+s+")") {
+			t.Errorf("page %d: %s not found in %q", n+1, s, content)
+		}
+		if c := strings.Count(content, "(Footer)"); c != 1 {
+			t.Errorf("page %d: %d footers", n+1, c)
+		}
+	}
+	catalog, _ := r.Catalog()
+	outlines, _ := r.ResolveDict(catalog["Outlines"])
+	item, _ := r.ResolveDict(outlines["First"])
+	for n := 3; n <= 5; n++ {
+		if p := destPage(t, r, item["Dest"]); p != n {
+			t.Errorf("bookmark %s on page %d, want %d", pdfreader.Format(item["Title"]), p, n)
+		}
+		item, _ = r.ResolveDict(item["Next"])
+	}
+	annots, _ := r.ResolveArray(mustAttr(t, r, 5, "Annots"))
+	annot, _ := r.ResolveDict(annots[0])
+	if p := destPage(t, r, annot["Dest"]); p != 3 {
+		t.Errorf("link to page %d, want 3", p)
+	}
+	names, _ := r.ResolveDict(catalog["Names"])
+	dests, _ := r.ResolveDict(names["Dests"])
+	list, _ := r.ResolveArray(dests["Names"])
+	if len(list) != 2 || destPage(t, r, list[1]) != 5 {
+		t.Errorf("unexpected named destinations %s", pdfreader.Format(list))
+	}
+}
+
+func TestDeleteDuplicatePage(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	link := pdf.AddLink()
+	for j := 1; j <= 4; j++ {
+		if j == 2 {
+			pdf.AddPageFormat("L", gofpdf.SizeType{Wd: 100, Ht: 150})
+			pdf.SetPageBox("trim", 10, 10, 80, 130)
+			pdf.SetLink(link, 0, -1)
+		} else {
+			pdf.AddPage()
+		}
+		pdf.Cell(40, 10, fmt.Sprintf("Page %d", j))
+		pdf.Link(10, 10, 30, 10, link)
+	}
+	pdf.DuplicatePage(2)
+	pdf.DeletePage(1)
+	// The page being written is deleted
+	pdf.DeletePage(4)
+	if pdf.PageNo() != 3 || pdf.PageCount() != 3 {
+		t.Fatalf("current page %d of %d", pdf.PageNo(), pdf.PageCount())
+	}
+	pdf.Cell(40, 10, "Continued")
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Added")
+	r := pagesRead(t, pdf)
+	for n, s := range []string{"Page 2", "Page 2", "Continued", "Added"} {
+		if content := updateContent(t, r, n+1); !strings.Contains(content, "("+s+")") {
+			t.Errorf("page %d: %s not found in %q", n+1, s, content)
+		}
+	}
+	for n := 1; n <= 2; n++ {
+		if box := pdfreader.Format(mustAttr(t, r, n, "MediaBox")); box != "[0 0 425.2 283.46]" {
+			t.Errorf("page %d: unexpected media box %s", n, box)
+		}
+		if box := pdfreader.Format(mustAttr(t, r, n, "TrimBox")); box != "[28.35 28.35 255.12 396.85]" {
+			t.Errorf("page %d: unexpected trim box %s", n, box)
+		}
+	}
+	for n := 1; n <= 3; n++ {
+		annots, _ := r.ResolveArray(mustAttr(t, r, n, "Annots"))
+		annot, _ := r.ResolveDict(annots[0])
+		if p := destPage(t, r, annot["Dest"]); p != 1 {
+			t.Errorf("page %d: link to page %d, want 1", n, p)
+		}
+	}
+}
+
+func TestDeletePageFormFields(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.AddTextField(10, 10, 50, 10, gofpdf.FormFieldOptions{Name: "name"})
+	pdf.AddRadioGroup([]gofpdf.RadioButton{{X: 10, Y: 30, Size: 5, Value: "a"}},
+		gofpdf.FormFieldOptions{Name: "choice"})
+	pdf.AddPage()
+	pdf.AddRadioGroup([]gofpdf.RadioButton{{X: 10, Y: 30, Size: 5, Value: "b"}},
+		gofpdf.FormFieldOptions{Name: "only"})
+	pdf.DeletePage(1)
+	// The name of a deleted field can be used again
+	pdf.AddTextField(10, 10, 50, 10, gofpdf.FormFieldOptions{Name: "name"})
+	r := pagesRead(t, pdf)
+	catalog, _ := r.Catalog()
+	form, _ := r.ResolveDict(catalog["AcroForm"])
+	fields, _ := r.ResolveArray(form["Fields"])
+	var names []string
+	for _, ref := range fields {
+		fld, err := r.ResolveDict(ref)
+		if err != nil {
+			t.Fatalf("field %s: %v", pdfreader.Format(ref), err)
+		}
+		names = append(names, pdfreader.Format(fld["T"]))
+		kids, _ := r.ResolveArray(fld["Kids"])
+		for _, kid := range kids {
+			if _, err := r.ResolveDict(kid); err != nil {
+				t.Errorf("kid %s: %v", pdfreader.Format(kid), err)
+			}
+		}
+	}
+	if s := strings.Join(names, " "); s != "(only) (name)" {
+		t.Errorf("unexpected fields %s", s)
+	}
+}
+
+func TestPageOrderErrors(t *testing.T) {
+	for _, c := range []struct {
+		fn  func(pdf *gofpdf.Fpdf)
+		err string
+	}{
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.MovePage(1, 2)
+		}, "page 2 does not exist"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.DeletePage(1)
+		}, "the only page of the document cannot be deleted"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.InsertPageAt(3)
+		}, "page 3 cannot be inserted in a document of 1 pages"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.SetTagged(true)
+			pdf.AddPage()
+			pdf.DuplicatePage(1)
+		}, "tagged document"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.OpenUpdate(bytes.NewReader(updateSource(t, false)))
+			pdf.MovePage(1, 2)
+		}, "when updating a document"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "", 12)
+		c.fn(pdf)
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// patternType holds a tiling pattern, whose cell is drawn by a template, or
+// a shading pattern
+type patternType struct {
+	tpl       Template
+	uncolored bool
+	shading   int // gradient of a shading pattern
+	objNum    int
+}
+
+// CreatePattern defines a tiling pattern and returns its identifier, which
+// is passed to SetFillPattern(), SetDrawPattern() and SetTextPattern() to fill
+// shapes, stroke lines and paint text with the pattern. The pattern cell is
+// wd by ht in the current unit of measure and is drawn by fn, as with
+// CreateTemplate(), starting from black colors. Cells are repeated without
+// gaps in both directions from the lower left corner of the page.
+//
+// If uncolored is false, the pattern is painted with the colors set by fn.
+// Otherwise, fn must not set colors; the pattern is only a shape that is
+// painted with the color that is current when the pattern is set. This lets
+// one pattern be used in several colors. The zero value is returned in case
+// of error.
+//
+// Patterns can only be used on the pages of the document, not in templates.
+func (f *Fpdf) CreatePattern(wd, ht float64, uncolored bool, fn func(*Tpl)) int {
+	if f.err != nil {
+		return 0
+	}
+	if wd <= 0 || ht <= 0 {
+		f.err = fmt.Errorf("invalid pattern cell size %.2f x %.2f", wd, ht)
+		return 0
+	}
+	// The cell does not inherit the colors of the document
+	clr := f.color
+	f.color.draw = rgbColorValue(0, 0, 0, "G", "RG")
+	f.color.fill = rgbColorValue(0, 0, 0, "g", "rg")
+	f.color.text = rgbColorValue(0, 0, 0, "g", "rg")
+	tpl := f.CreateTemplateCustom(PointType{}, SizeType{Wd: wd, Ht: ht}, fn)
+	f.color = clr
+	f.registerTemplate(tpl)
+	f.patternList = append(f.patternList, patternType{tpl: tpl, uncolored: uncolored})
+	return len(f.patternList) - 1
+}
+
+// CreateHatchPattern defines an uncolored tiling pattern of lines or dots
+// and returns its identifier; see CreatePattern(). styleStr is one of
+// "horizontal", "vertical", "diagonal" (lines rising to the right),
+// "backdiagonal", "cross" (horizontal and vertical lines), "diagonalcross"
+// or "dots". The lines are spacing apart and lineWidth wide; dots are
+// lineWidth in diameter. Both values are in the current unit of measure.
+func (f *Fpdf) CreateHatchPattern(styleStr string, spacing, lineWidth float64) int {
+	if f.err != nil {
+		return 0
+	}
+	s := spacing
+	var fn func(tpl *Tpl)
+	lines := func(tpl *Tpl, diagonal bool, back bool) {
+		for j := -1.0; j <= 1; j++ {
+			// Neighboring lines cover the corners of the cell
+			if diagonal {
+				tpl.Line(j*s, s, (j+1)*s, 0)
+			}
+			if back {
+				tpl.Line(j*s, 0, (j+1)*s, s)
+			}
+		}
+	}
+	switch styleStr {
+	case "horizontal":
+		fn = func(tpl *Tpl) { tpl.Line(0, s/2, s, s/2) }
+	case "vertical":
+		fn = func(tpl *Tpl) { tpl.Line(s/2, 0, s/2, s) }
+	case "cross":
+		fn = func(tpl *Tpl) {
+			tpl.Line(0, s/2, s, s/2)
+			tpl.Line(s/2, 0, s/2, s)
+		}
+	case "diagonal":
+		fn = func(tpl *Tpl) { lines(tpl, true, false) }
+	case "backdiagonal":
+		fn = func(tpl *Tpl) { lines(tpl, false, true) }
+	case "diagonalcross":
+		fn = func(tpl *Tpl) { lines(tpl, true, true) }
+	case "dots":
+		fn = func(tpl *Tpl) { tpl.Circle(s/2, s/2, lineWidth/2, "F") }
+	default:
+		f.err = fmt.Errorf("unrecognized hatch pattern style %s", styleStr)
+		return 0
+	}
+	return f.CreatePattern(s, s, true, func(tpl *Tpl) {
+		tpl.SetLineWidth(lineWidth)
+		tpl.SetLineCapStyle("butt")
+		fn(tpl)
+	})
+}
+
+// setPattern makes clr the tiling pattern pat, set with the color space
+// operator csOp and the color operator scnOp. An uncolored pattern is tinted
+// with the last color of clr that is not a pattern.
+func (clr *colorType) setPattern(pat int, uncolored bool, csOp, scnOp string) error {
+	if clr.mode != colorModePattern {
+		clr.tint = clr.mode
+	}
+	clr.mode = colorModePattern
+	clr.pattern = pat
+	if !uncolored {
+		clr.str = sprintf("/Pattern %s /P%d %s", csOp, pat, scnOp)
+		return nil
+	}
+	var cs, comps string
+	switch clr.tint {
+	case colorModeRGB:
+		cs, comps = "PatRGB", sprintf("%.3f %.3f %.3f", clr.r, clr.g, clr.b)
+	case colorModeCMYK:
+		tint := *clr
+		tint.mode = colorModeCMYK
+		cs, comps = "PatCMYK", tint.components()
+	case colorModeGray:
+		tint := *clr
+		tint.mode = colorModeGray
+		cs, comps = "PatGray", tint.components()
+	default:
+		return fmt.Errorf("uncolored patterns cannot be tinted with a spot color")
+	}
+	clr.str = sprintf("/%s %s %s /P%d %s", cs, csOp, comps, pat, scnOp)
+	return nil
+}
+
+// patternCheck reports an error if pat is not a pattern of the document
+func (f *Fpdf) patternCheck(pat int) bool {
+	if f.err != nil {
+		return false
+	}
+	if pat < 1 || pat >= len(f.patternList) {
+		f.err = fmt.Errorf("pattern %d is not defined", pat)
+		return false
+	}
+	return true
+}
+
+// patternColorCheck reports an error if pat cannot be used as a color
+func (f *Fpdf) patternColorCheck(pat int) bool {
+	if !f.patternCheck(pat) {
+		return false
+	}
+	if sh := f.patternList[pat].shading; sh > 0 && f.gradientList[sh].alpha > 0 {
+		f.err = fmt.Errorf("gradient %d has transparent stops and can only be painted with PaintGradient()", pat)
+		return false
+	}
+	return true
+}
+
+// SetDrawPattern sets the current draw color to the pattern pat returned by
+// CreatePattern(), CreateHatchPattern() or AddGradient(), so that lines and
+// outlines are stroked with the pattern. An uncolored pattern is painted with
+// the draw color in effect before the first pattern is set, which may be an
+// RGB, CMYK or gray color but not a spot color. Another draw color can be set
+// afterward as usual.
+func (f *Fpdf) SetDrawPattern(pat int) {
+	if !f.patternColorCheck(pat) {
+		return
+	}
+	f.err = f.color.draw.setPattern(pat, f.patternList[pat].uncolored, "CS", "SCN")
+	if f.err == nil && f.page > 0 {
+		f.out(f.color.draw.str)
+	}
+}
+
+// SetFillPattern sets the current fill color to the tiling pattern pat, so
+// that shapes drawn with a fill style, such as rectangles, polygons and
+// paths, are filled with the pattern. See SetDrawPattern() for the color of
+// uncolored patterns.
+func (f *Fpdf) SetFillPattern(pat int) {
+	if !f.patternColorCheck(pat) {
+		return
+	}
+	f.err = f.color.fill.setPattern(pat, f.patternList[pat].uncolored, "cs", "scn")
+	if f.err == nil {
+		f.colorFlag = f.color.fill.str != f.color.text.str
+		if f.page > 0 {
+			f.out(f.color.fill.str)
+		}
+	}
+}
+
+// SetTextPattern sets the current text color to the tiling pattern pat, so
+// that text is painted with the pattern. See SetDrawPattern() for the color
+// of uncolored patterns.
+func (f *Fpdf) SetTextPattern(pat int) {
+	if !f.patternColorCheck(pat) {
+		return
+	}
+	f.err = f.color.text.setPattern(pat, f.patternList[pat].uncolored, "cs", "scn")
+	if f.err == nil {
+		f.colorFlag = f.color.fill.str != f.color.text.str
+	}
+}
+
+// putPatterns writes the patterns. Tiling patterns refer to the form XObjects
+// of their cells and shading patterns to their gradients.
+func (f *Fpdf) putPatterns() {
+	for j := 1; j < len(f.patternList); j++ {
+		pat := &f.patternList[j]
+		if pat.shading > 0 {
+			f.newobj()
+			pat.objNum = f.n
+			f.outf("<</Type /Pattern /PatternType 2 /Shading %d 0 R>>", f.gradientList[pat.shading].objNum)
+			f.out("endobj")
+			continue
+		}
+		_, size := pat.tpl.Size()
+		paintType := 1
+		if pat.uncolored {
+			paintType = 2
+		}
+		content := []byte(sprintf("/TPL%s Do", pat.tpl.ID()))
+		f.newobj()
+		pat.objNum = f.n
+		f.outf("<</Type /Pattern /PatternType 1 /PaintType %d /TilingType 1", paintType)
+		f.outf("/BBox [0 0 %.5f %.5f] /XStep %.5f /YStep %.5f", size.Wd*f.k, size.Ht*f.k,
+			size.Wd*f.k, size.Ht*f.k)
+		f.outf("/Resources <</XObject <</TPL%s %d 0 R>>>>", pat.tpl.ID(), f.templateObjects[pat.tpl.ID()])
+		f.outf("/Length %d>>", f.protect.streamLen(len(content)))
+		f.putstream(content)
+		f.out("endobj")
+	}
+}
+
+// patternPutResourceDict writes the patterns of the resource dictionary
+func (f *Fpdf) patternPutResourceDict() {
+	if len(f.patternList) > 1 {
+		f.out("/Pattern <<")
+		for j := 1; j < len(f.patternList); j++ {
+			f.outf("/P%d %d 0 R", j, f.patternList[j].objNum)
+		}
+		f.out(">>")
+	}
+}
+
+// patternPutColorSpaces writes the color spaces of uncolored patterns
+func (f *Fpdf) patternPutColorSpaces() {
+	for _, pat := range f.patternList {
+		if pat.uncolored {
+			f.out("/PatGray [/Pattern /DeviceGray] /PatRGB [/Pattern /DeviceRGB] /PatCMYK [/Pattern /DeviceCMYK]")
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+func TestPattern(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "B", 36)
+	checker := pdf.CreatePattern(4, 4, false, func(tpl *gofpdf.Tpl) {
+		tpl.SetFillColor(255, 0, 0)
+		tpl.Rect(0, 0, 2, 2, "F")
+		tpl.Rect(2, 2, 2, 2, "F")
+	})
+	hatch := pdf.CreateHatchPattern("diagonal", 2, 0.3)
+	pdf.AddPage()
+	pdf.SetFillPattern(checker)
+	pdf.Rect(10, 10, 50, 30, "F")
+	pdf.SetFillColorCMYK(10, 20, 30, 40)
+	pdf.SetFillPattern(hatch)
+	pdf.SetDrawPattern(hatch)
+	pdf.Polygon([]gofpdf.PointType{{X: 10, Y: 50}, {X: 60, Y: 50}, {X: 35, Y: 80}}, "FD")
+	pdf.SetTextColor(0, 0, 255)
+	pdf.SetTextPattern(hatch)
+	pdf.Text(10, 100, "Hatched")
+	if pdf.CreateHatchPattern("diagonal", 2, 0.3) == hatch {
+		t.Errorf("pattern identifier reused")
+	}
+	r := pagesRead(t, pdf)
+	s := updateContent(t, r, 1)
+	for _, op := range []string{
+		"/Pattern cs /P1 scn\n",
+		"/PatCMYK cs 0.100 0.200 0.300 0.400 /P2 scn\n",
+		"/PatRGB CS 0.000 0.000 0.000 /P2 SCN\n",
+		"/PatRGB cs 0.000 0.000 1.000 /P2 scn",
+	} {
+		if !strings.Contains(s, op) {
+			t.Errorf("%q not found in %q", op, s)
+		}
+	}
+	res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+	cs, _ := r.ResolveDict(res["ColorSpace"])
+	if f := pdfreader.Format(cs["PatCMYK"]); f != "[/Pattern /DeviceCMYK]" {
+		t.Errorf("unexpected color space %s", f)
+	}
+	patterns, _ := r.ResolveDict(res["Pattern"])
+	for _, c := range []struct {
+		name      pdfreader.Name
+		paintType int
+		step      string
+		op        string
+	}{
+		{"P1", 1, "11.33858", "1.000 0.000 0.000 rg"},
+		{"P2", 2, "5.66929", " l S"},
+	} {
+		obj, _ := r.Resolve(patterns[c.name])
+		stm, ok := obj.(*pdfreader.Stream)
+		if !ok {
+			t.Fatalf("pattern %s not found", c.name)
+		}
+		if stm.Dict["PaintType"] != c.paintType || pdfreader.Format(stm.Dict["XStep"]) != c.step ||
+			pdfreader.Format(stm.Dict["YStep"]) != c.step {
+			t.Errorf("unexpected pattern %s", pdfreader.Format(stm.Dict))
+		}
+		pres, _ := r.ResolveDict(stm.Dict["Resources"])
+		xobjects, _ := r.ResolveDict(pres["XObject"])
+		for _, ref := range xobjects {
+			form, _ := r.Resolve(ref)
+			cell, ok := form.(*pdfreader.Stream)
+			if !ok {
+				t.Fatalf("pattern %s: cell not found", c.name)
+			}
+			data, err := cell.Decode()
+			if err != nil || !strings.Contains(string(data), c.op) {
+				t.Errorf("pattern %s: unexpected cell %q %v", c.name, data, err)
+			}
+		}
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	for _, c := range []struct {
+		fn  func(pdf *gofpdf.Fpdf)
+		err string
+	}{
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.SetFillPattern(1)
+		}, "pattern 1 is not defined"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.CreateHatchPattern("wavy", 2, 0.2)
+		}, "unrecognized hatch pattern style wavy"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.CreatePattern(0, 2, false, func(*gofpdf.Tpl) {})
+		}, "invalid pattern cell size"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddSpotColor("Ink", 0, 50, 100, 0)
+			pdf.SetDrawSpotColor("Ink", 100)
+			pdf.SetDrawPattern(pdf.CreateHatchPattern("dots", 2, 1))
+		}, "cannot be tinted with a spot color"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		c.fn(pdf)
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package gofpdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+// PDFAConformance specifies the PDF/A (ISO 19005) conformance level of a
+// document. See SetPDFA.
+type PDFAConformance int
+
+const (
+	// PDFANone disables PDF/A conformance; this is the default
+	PDFANone PDFAConformance = iota
+	// PDFA1B specifies PDF/A-1b (ISO 19005-1, level B)
+	PDFA1B
+	// PDFA2B specifies PDF/A-2b (ISO 19005-2, level B)
+	PDFA2B
+	// PDFA3B specifies PDF/A-3b (ISO 19005-3, level B)
+	PDFA3B
+)
+
+// pdfaRecType holds the state of PDF/A conformance
+type pdfaRecType struct {
+	level     PDFAConformance
+	objXmp    int // object number of the metadata stream
+	objIntent int // object number of the output intent
+}
+
+// SetPDFA sets the PDF/A conformance level of the document. When a level
+// other than PDFANone is set, the document is written with an sRGB output
+// intent, an XMP metadata packet generated from the document information
+// (unless one has been set with SetXmpMetadata) and a file identifier. Fonts
+// added with AddUTF8Font are embedded as subsets, whose CIDs are listed in a
+// CIDSet stream as PDF/A-1 requires.
+//
+// Features that are not allowed by the selected level are reported as an
+// error when the document is closed. These include the standard core fonts,
+// which are not embedded (use AddUTF8Font or AddFont instead), encryption,
+// JavaScript, spot colors, CMYK colors, CMYK images without an ICC profile
+// and file attachment annotations. PDF/A-1 additionally prohibits
+// transparency and optional content layers. Document attachments set with SetAttachments are only
+// allowed with PDFA3B; their relationship to the document is specified with
+// Attachment.Relationship.
+func (f *Fpdf) SetPDFA(level PDFAConformance) {
+	if level < PDFANone || level > PDFA3B {
+		f.SetErrorf("invalid PDF/A conformance level %d", level)
+		return
+	}
+	f.pdfa.level = level
+}
+
+// pdfaPart returns the part of ISO 19005 the document conforms to, or zero
+func (f *Fpdf) pdfaPart() int {
+	return int(f.pdfa.level)
+}
+
+// pdfaCheck reports the first feature used by the document that is not
+// allowed by its PDF/A conformance level
+func (f *Fpdf) pdfaCheck() {
+	part := f.pdfaPart()
+	if part == 0 {
+		return
+	}
+	errorf := func(format string, args ...interface{}) {
+		f.SetErrorf("PDF/A-%db: "+format, append([]interface{}{part}, args...)...)
+	}
+	if f.protect.encrypted {
+		errorf("encryption is not allowed")
+	}
+	if f.javascript != nil {
+		errorf("JavaScript is not allowed")
+	}
+	for _, fld := range f.form.fields {
+		if fld.js != "" {
+			errorf("JavaScript action of field %s is not allowed", fld.name)
+		}
+	}
+	var keyList []string
+	for key := range f.fonts {
+		keyList = append(keyList, key)
+	}
+	sort.Strings(keyList)
+	for _, key := range keyList {
+		if font := f.fonts[key]; font.Tp == "Core" {
+			errorf("font %s is not embedded", font.Name)
+		}
+	}
+	if len(f.spotColorMap) > 0 {
+		errorf("spot colors are not allowed with an RGB output intent")
+	}
+	if f.color.cmykUsed {
+		errorf("CMYK colors are not allowed with an RGB output intent")
+	}
+	keyList = keyList[:0]
+	for key := range f.images {
+		keyList = append(keyList, key)
+	}
+	sort.Strings(keyList)
+	for _, key := range keyList {
+		img := f.images[key]
+		if img.cs == "DeviceCMYK" && len(img.icc) == 0 {
+			errorf("CMYK image %s is not allowed with an RGB output intent", key)
+		}
+		if part == 1 && (len(img.smask) > 0 || img.mask != "") {
+			errorf("image %s with transparency is not allowed", key)
+		}
+	}
+	if part == 1 {
+		if len(f.blendList) > 1 || len(f.maskList) > 1 {
+			errorf("transparency is not allowed")
+		}
+		if len(f.layer.list) > 0 {
+			errorf("optional content is not allowed")
+		}
+		if f.objStm.enabled {
+			errorf("object streams are not allowed")
+		}
+	}
+	if part < 3 && len(f.attachments) > 0 {
+		errorf("embedded files are not allowed")
+	}
+	for _, list := range f.pageAttachments {
+		if len(list) > 0 {
+			errorf("file attachment annotations are not supported")
+			break
+		}
+	}
+	for _, l := range f.links {
+		if l.launch {
+			errorf("launch action of link to %s is not allowed", l.file)
+		}
+	}
+	for page, list := range f.pageAnnots {
+		for _, an := range list {
+			if part == 1 && an.opt.Transparency > 0 {
+				errorf("transparent %s annotation on page %d is not allowed", an.subtype, page)
+			}
+			if an.opt.Flags != 0 && (an.opt.Flags&AnnotationFlagPrint == 0 ||
+				an.opt.Flags&(AnnotationFlagInvisible|AnnotationFlagHidden|
+					AnnotationFlagNoView|AnnotationFlagToggleNoView) != 0) {
+				errorf("%s annotation on page %d must be printable and visible", an.subtype, page)
+			}
+		}
+	}
+}
+
+// xmpGenerated returns true if the XMP metadata of the document is
+// generated rather than set with SetXmpMetadata
+func (f *Fpdf) xmpGenerated() bool {
+	return len(f.xmp) == 0 && (f.pdfaPart() > 0 || f.tag.ua)
+}
+
+// pdfaBeginDoc checks the conformance of the document and fixes its dates
+// so that the information dictionary and the XMP packet agree
+func (f *Fpdf) pdfaBeginDoc() {
+	f.pdfaCheck()
+	if !f.xmpGenerated() {
+		return
+	}
+	if f.creationDate.IsZero() {
+		f.creationDate = time.Now()
+	}
+	if f.modDate.IsZero() {
+		f.modDate = f.creationDate
+	}
+}
+
+// pdfDate returns tm formatted as a PDF date string. The time zone is
+// included when XMP metadata is generated so that the dates agree.
+func (f *Fpdf) pdfDate(tm time.Time) string {
+	if f.xmpGenerated() {
+		return pdfDateZone(tm)
+	}
+	return "D:" + tm.Format("20060102150405")
+}
+
+// pdfDateZone returns tm formatted as a PDF date string with the offset of
+// its time zone in hours and minutes, as in D:YYYYMMDDHHmmSS+HH'mm'
+func pdfDateZone(tm time.Time) string {
+	_, offset := tm.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return sprintf("D:%s%c%02d'%02d'", tm.Format("20060102150405"), sign, offset/3600, offset/60%60)
+}
+
+// pdfaPutObjects writes the output intent and its ICC profile
+func (f *Fpdf) pdfaPutObjects() {
+	if f.pdfaPart() == 0 {
+		return
+	}
+	profile := srgbProfile()
+	var filter string
+	if f.compress {
+		profile = sliceCompress(profile)
+		filter = "/Filter /FlateDecode "
+	}
+	f.newobj()
+	f.outf("<</N 3 %s/Length %d>>", filter, f.protect.streamLen(len(profile)))
+	f.putstream(profile)
+	f.out("endobj")
+	f.newobj()
+	f.pdfa.objIntent = f.n
+	f.outf("<</Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier %s "+
+		"/RegistryName %s /Info %s /DestOutputProfile %d 0 R>>",
+		f.textstring(srgbDescription), f.textstring("http://www.color.org"),
+		f.textstring(srgbDescription), f.n-1)
+	f.out("endobj")
+}
+
+// pdfaPutCatalog writes the PDF/A entries of the document catalog
+func (f *Fpdf) pdfaPutCatalog() {
+	if f.pdfa.objXmp > 0 {
+		f.outf("/Metadata %d 0 R", f.pdfa.objXmp)
+	}
+	if f.pdfaPart() == 0 {
+		return
+	}
+	f.outf("/OutputIntents [%d 0 R]", f.pdfa.objIntent)
+	if f.pdfaPart() == 3 && len(f.attachments) > 0 {
+		var af fmtBuffer
+		af.printf("/AF [")
+		for _, a := range f.attachments {
+			af.printf("%d 0 R ", a.objectNumber)
+		}
+		af.printf("]")
+		f.out(af.String())
+	}
+}
+
+// pdfDocText returns the UTF-8 form of a document information string, which
+// is either UTF-16BE with a byte order mark or single-byte encoded
+func pdfDocText(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		b := []byte(s[2:])
+		u := make([]uint16, len(b)/2)
+		for j := range u {
+			u[j] = binary.BigEndian.Uint16(b[2*j:])
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, len(s))
+	for j := 0; j < len(s); j++ {
+		r[j] = rune(s[j])
+	}
+	return string(r)
+}
+
+// documentXmp returns an XMP packet describing the document and its
+// conformance
+func (f *Fpdf) documentXmp() []byte {
+	var b bytes.Buffer
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(pdfDocText(s)))
+		return e.String()
+	}
+	xmpDate := func(tm time.Time) string {
+		return tm.Format("2006-01-02T15:04:05-07:00")
+	}
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	if f.pdfaPart() > 0 {
+		b.WriteString("<rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n")
+		fmt.Fprintf(&b, "<pdfaid:part>%d</pdfaid:part>\n", f.pdfaPart())
+		b.WriteString("<pdfaid:conformance>B</pdfaid:conformance>\n")
+		b.WriteString("</rdf:Description>\n")
+	}
+	if f.tag.ua {
+		b.WriteString("<rdf:Description rdf:about=\"\" xmlns:pdfuaid=\"http://www.aiim.org/pdfua/ns/id/\">\n")
+		b.WriteString("<pdfuaid:part>1</pdfuaid:part>\n")
+		b.WriteString("</rdf:Description>\n")
+	}
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if len(f.title) > 0 {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(f.title))
+	}
+	if len(f.author) > 0 {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(f.author))
+	}
+	if len(f.subject) > 0 {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", esc(f.subject))
+	}
+	b.WriteString("</rdf:Description>\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	if len(f.creator) > 0 {
+		fmt.Fprintf(&b, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", esc(f.creator))
+	}
+	fmt.Fprintf(&b, "<xmp:CreateDate>%s</xmp:CreateDate>\n", xmpDate(f.creationDate))
+	fmt.Fprintf(&b, "<xmp:ModifyDate>%s</xmp:ModifyDate>\n", xmpDate(f.modDate))
+	b.WriteString("</rdf:Description>\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	if len(f.producer) > 0 {
+		fmt.Fprintf(&b, "<pdf:Producer>%s</pdf:Producer>\n", esc(f.producer))
+	}
+	if len(f.keywords) > 0 {
+		fmt.Fprintf(&b, "<pdf:Keywords>%s</pdf:Keywords>\n", esc(f.keywords))
+	}
+	b.WriteString("</rdf:Description>\n")
+	b.WriteString("</rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+const srgbDescription = "sRGB IEC61966-2.1"
+
+// srgbProfile returns an ICC version 2 display profile for the sRGB color
+// space
+func srgbProfile() []byte {
+	s15 := func(v float64) uint32 {
+		return uint32(int32(math.Round(v * 65536)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15(x))
+		binary.BigEndian.PutUint32(b[12:], s15(y))
+		binary.BigEndian.PutUint32(b[16:], s15(z))
+		return b
+	}
+	text := func(s string) []byte {
+		b := make([]byte, 8, 8+len(s)+1)
+		copy(b, "text")
+		return append(append(b, s...), 0)
+	}
+	desc := func(s string) []byte {
+		b := make([]byte, 12, 12+len(s)+1+78)
+		copy(b, "desc")
+		binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+		b = append(append(b, s...), 0)
+		// Empty Unicode and ScriptCode descriptions
+		return append(b, make([]byte, 78)...)
+	}
+	const points = 1024
+	curve := make([]byte, 12+2*points)
+	copy(curve, "curv")
+	binary.BigEndian.PutUint32(curve[8:], points)
+	for j := 0; j < points; j++ {
+		v := float64(j) / (points - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(curve[12+2*j:], uint16(math.Round(v*65535)))
+	}
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc(srgbDescription)},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", nil}, // shares the red curve
+		{"bTRC", nil},
+	}
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntrRGB XYZ ")
+	// Creation date 2000-01-01
+	binary.BigEndian.PutUint16(header[24:], 2000)
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	copy(header[68:], xyz(0.9642, 1.0, 0.8249)[8:])
+
+	table := make([]byte, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	var data []byte
+	offset := len(header) + len(table)
+	var last, lastLen int
+	for j, tag := range tags {
+		entry := table[4+12*j:]
+		copy(entry, tag.sig)
+		if tag.data != nil {
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+			last, lastLen = offset+len(data), len(tag.data)
+			data = append(data, tag.data...)
+		}
+		binary.BigEndian.PutUint32(entry[4:], uint32(last))
+		binary.BigEndian.PutUint32(entry[8:], uint32(lastLen))
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
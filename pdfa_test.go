@@ -0,0 +1,155 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/internal/example"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+func TestSetPDFA(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPDFA(gofpdf.PDFA3B)
+	pdf.SetCompression(false)
+	pdf.SetTitle("Invoice <42>", true)
+	pdf.SetCreationDate(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddPage()
+	pdf.SetFont("dejavu", "", 12)
+	pdf.CellFormat(40, 10, "Invoice", "", 0, "", false, 0, "https://example.com")
+	pdf.SetAttachments([]gofpdf.Attachment{{Content: []byte("<a/>"),
+		Filename: "a.xml", MimeType: "text/xml", Relationship: "Data"}})
+	b := formOutput(t, pdf)
+	for _, s := range []string{
+		"%PDF-1.7\n%\xe2\xe3\xcf\xd3\n",
+		"<pdfaid:part>3</pdfaid:part>",
+		"<pdfaid:conformance>B</pdfaid:conformance>",
+		"<rdf:li xml:lang=\"x-default\">Invoice &lt;42&gt;</rdf:li>",
+		"<xmp:CreateDate>2024-05-06T07:08:09+00:00</xmp:CreateDate>",
+		"/CreationDate (D:20240506070809+00'00')",
+		"/Type /OutputIntent /S /GTS_PDFA1",
+		"/OutputIntents [",
+		"/Metadata ",
+		"/AF [",
+		"/AFRelationship /Data",
+		"/Subtype /text#2Fxml",
+		"/F 4 /A <</S /URI",
+		"/ID [<",
+	} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+}
+
+func TestSetPDFAErrors(t *testing.T) {
+	for _, tc := range []struct {
+		level gofpdf.PDFAConformance
+		setup func(pdf *gofpdf.Fpdf)
+		err   string
+	}{
+		{gofpdf.PDFA2B, func(pdf *gofpdf.Fpdf) { pdf.SetFont("Helvetica", "", 12) }, "not embedded"},
+		{gofpdf.PDFA2B, func(pdf *gofpdf.Fpdf) { pdf.SetJavascript("print();") }, "JavaScript"},
+		{gofpdf.PDFA2B, func(pdf *gofpdf.Fpdf) { pdf.SetProtection(0, "a", "b") }, "encryption"},
+		{gofpdf.PDFA1B, func(pdf *gofpdf.Fpdf) { pdf.SetAlpha(0.5, "Normal") }, "transparency"},
+		{gofpdf.PDFA2B, func(pdf *gofpdf.Fpdf) {
+			pdf.SetAttachments([]gofpdf.Attachment{{Content: []byte("a"), Filename: "a"}})
+		}, "embedded files"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetPDFA(tc.level)
+		pdf.AddPage()
+		tc.setup(pdf)
+		err := pdf.Output(&bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expecting error containing %q, got %v", tc.err, err)
+		}
+	}
+
+	// Transparency is allowed in PDF/A-2
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetPDFA(gofpdf.PDFA2B)
+	pdf.AddPage()
+	pdf.SetAlpha(0.5, "Normal")
+	if err := pdf.Output(&bytes.Buffer{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPDFADateZone(t *testing.T) {
+	for _, tc := range []struct {
+		offset    int
+		info, xmp string
+	}{
+		{5*3600 + 30*60, "+05'30'", "+05:30"},
+		{-(9*3600 + 45*60), "-09'45'", "-09:45"},
+		{0, "+00'00'", "+00:00"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetPDFA(gofpdf.PDFA2B)
+		pdf.SetCompression(false)
+		pdf.SetCreationDate(time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", tc.offset)))
+		pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+		pdf.AddPage()
+		b := formOutput(t, pdf)
+		info := "/CreationDate (D:20240506070809" + tc.info + ")"
+		xmp := "<xmp:CreateDate>2024-05-06T07:08:09" + tc.xmp + "</xmp:CreateDate>"
+		if !bytes.Contains(b, []byte(info)) || !bytes.Contains(b, []byte(xmp)) {
+			t.Errorf("offset %d: dates %q and %q do not agree", tc.offset, info, xmp)
+		}
+	}
+}
+
+func TestPDFACIDSet(t *testing.T) {
+	for _, tc := range []struct {
+		level gofpdf.PDFAConformance
+		file  string
+	}{
+		{gofpdf.PDFA1B, "DejaVuSansCondensed.ttf"},
+		{gofpdf.PDFA1B, "CFFTest.otf"},
+		{gofpdf.PDFA2B, "DejaVuSansCondensed.ttf"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetPDFA(tc.level)
+		pdf.AddUTF8Font("font", "", example.FontFile(tc.file))
+		pdf.AddPage()
+		pdf.SetFont("font", "", 12)
+		pdf.Text(10, 20, "01Q")
+		r := pagesRead(t, pdf)
+		res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+		fonts, _ := r.ResolveDict(res["Font"])
+		for _, ref := range fonts {
+			fd, _ := r.ResolveDict(ref)
+			descendants, _ := r.ResolveArray(fd["DescendantFonts"])
+			cid, _ := r.ResolveDict(descendants[0])
+			desc, _ := r.ResolveDict(cid["FontDescriptor"])
+			obj, _ := r.Resolve(desc["CIDSet"])
+			stm, ok := obj.(*pdfreader.Stream)
+			if tc.level != gofpdf.PDFA1B {
+				if ok {
+					t.Errorf("%s: unexpected CIDSet", tc.file)
+				}
+				continue
+			}
+			if !ok {
+				t.Fatalf("%s: no CIDSet in %s", tc.file, pdfreader.Format(desc))
+			}
+			set, err := stm.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// CID 0 and those of '0', '1' and 'Q'
+			want := make([]byte, 'Q'/8+1)
+			for _, c := range []int{0, '0', '1', 'Q'} {
+				want[c/8] |= 0x80 >> uint(c%8)
+			}
+			if !bytes.Equal(set, want) {
+				t.Errorf("%s: CIDSet %x, expected %x", tc.file, set, want)
+			}
+		}
+	}
+}
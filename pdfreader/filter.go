@@ -0,0 +1,284 @@
+package pdfreader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+)
+
+// Decode returns the data of the stream after applying its filters. The
+// FlateDecode, LZWDecode, ASCII85Decode, ASCIIHexDecode and RunLengthDecode
+// filters are supported; image filters such as DCTDecode are not.
+func (s *Stream) Decode() ([]byte, error) {
+	var filters Array
+	var params Array
+	switch v := s.Dict["Filter"].(type) {
+	case Name:
+		filters = Array{v}
+		params = Array{s.Dict["DecodeParms"]}
+	case Array:
+		filters = v
+		params, _ = s.Dict["DecodeParms"].(Array)
+	}
+	data := s.Data
+	for j, f := range filters {
+		var parms Dict
+		if j < len(params) {
+			parms, _ = params[j].(Dict)
+		}
+		name, _ := f.(Name)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = flateDecode(data)
+			if err == nil {
+				data, err = predictorDecode(data, parms)
+			}
+		case "LZWDecode", "LZW":
+			early := 1
+			if v, ok := parms["EarlyChange"].(int); ok {
+				early = v
+			}
+			data, err = lzwDecode(data, early)
+			if err == nil {
+				data, err = predictorDecode(data, parms)
+			}
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		case "ASCIIHexDecode", "AHx":
+			data, err = asciiHexDecode(data)
+		case "RunLengthDecode", "RL":
+			data = runLengthDecode(data)
+		default:
+			err = fmt.Errorf("pdfreader: unsupported filter %s", Format(f))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// flateDecode decompresses zlib data. Data following a truncated or damaged
+// stream is ignored if some data could be decompressed.
+func flateDecode(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("pdfreader: %s", err)
+	}
+	out, err := ioutil.ReadAll(zr)
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("pdfreader: %s", err)
+	}
+	return out, nil
+}
+
+// lzwDecode decompresses LZW data with codes of 9 to 12 bits. The code width
+// increases one code early if early is 1.
+func lzwDecode(data []byte, early int) ([]byte, error) {
+	const clear, eod = 256, 257
+	var out []byte
+	table := make([][]byte, 258, 4096)
+	width := 9
+	var prev []byte
+	var acc uint32
+	bits := 0
+	for _, c := range data {
+		acc = acc<<8 | uint32(c)
+		bits += 8
+		for bits >= width {
+			code := int(acc>>uint(bits-width)) & (1<<uint(width) - 1)
+			bits -= width
+			switch {
+			case code == clear:
+				table = table[:258]
+				width = 9
+				prev = nil
+				continue
+			case code == eod:
+				return out, nil
+			}
+			var entry []byte
+			switch {
+			case code < 256:
+				entry = []byte{byte(code)}
+			case code < len(table):
+				entry = table[code]
+			case code == len(table) && prev != nil:
+				entry = append(append([]byte{}, prev...), prev[0])
+			default:
+				return nil, fmt.Errorf("pdfreader: invalid LZW code %d", code)
+			}
+			out = append(out, entry...)
+			if prev != nil && len(table) < 4096 {
+				table = append(table, append(append([]byte{}, prev...), entry[0]))
+			}
+			prev = entry
+			if len(table)+early >= 1<<uint(width) && width < 12 {
+				width++
+			}
+		}
+	}
+	return out, nil
+}
+
+// ascii85Decode decodes ASCII base-85 data, which ends with ~>
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, 4*len(data)+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("pdfreader: %s", err)
+	}
+	return out[:n], nil
+}
+
+// asciiHexDecode decodes hexadecimal data, which ends with >
+func asciiHexDecode(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		switch {
+		case isSpace(c):
+		case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+			digits = append(digits, c)
+		default:
+			return nil, fmt.Errorf("pdfreader: invalid character in hexadecimal data")
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+// runLengthDecode decodes run-length encoded data
+func runLengthDecode(data []byte) []byte {
+	var out []byte
+	for pos := 0; pos < len(data); {
+		n := int(data[pos])
+		pos++
+		switch {
+		case n < 128:
+			end := pos + n + 1
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[pos:end]...)
+			pos = end
+		case n > 128 && pos < len(data):
+			out = append(out, bytes.Repeat(data[pos:pos+1], 257-n)...)
+			pos++
+		default:
+			// End of data
+			return out
+		}
+	}
+	return out
+}
+
+// predictorDecode reverses the PNG or TIFF predictor specified by parms
+func predictorDecode(data []byte, parms Dict) ([]byte, error) {
+	predictor, _ := parms["Predictor"].(int)
+	if predictor <= 1 {
+		return data, nil
+	}
+	param := func(key Name, def int) int {
+		if v, ok := parms[key].(int); ok && v > 0 {
+			return v
+		}
+		return def
+	}
+	colors := param("Colors", 1)
+	bpc := param("BitsPerComponent", 8)
+	columns := param("Columns", 1)
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (colors*bpc*columns + 7) / 8
+	if predictor == 2 {
+		if bpc != 8 {
+			return nil, fmt.Errorf("pdfreader: unsupported TIFF predictor with %d bits per component", bpc)
+		}
+		out := make([]byte, len(data))
+		copy(out, data)
+		for row := 0; row+rowLen <= len(out); row += rowLen {
+			for j := bpp; j < rowLen; j++ {
+				out[row+j] += out[row+j-bpp]
+			}
+		}
+		return out, nil
+	}
+	// PNG predictors: each row is preceded by its filter type
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos < len(data); pos += rowLen + 1 {
+		end := pos + 1 + rowLen
+		if end > len(data) {
+			end = len(data)
+		}
+		row := make([]byte, rowLen)
+		copy(row, data[pos+1:end])
+		switch data[pos] {
+		case 0:
+		case 1:
+			for j := bpp; j < rowLen; j++ {
+				row[j] += row[j-bpp]
+			}
+		case 2:
+			for j := range row {
+				row[j] += prev[j]
+			}
+		case 3:
+			for j := range row {
+				left := 0
+				if j >= bpp {
+					left = int(row[j-bpp])
+				}
+				row[j] += byte((left + int(prev[j])) / 2)
+			}
+		case 4:
+			for j := range row {
+				var left, upLeft int
+				if j >= bpp {
+					left, upLeft = int(row[j-bpp]), int(prev[j-bpp])
+				}
+				row[j] += paeth(left, int(prev[j]), upLeft)
+			}
+		default:
+			return nil, fmt.Errorf("pdfreader: invalid PNG predictor %d", data[pos])
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth returns the Paeth predictor of a pixel
+func paeth(a, b, c int) byte {
+	p := a + b - c
+	pa, pb, pc := abs(p-a), abs(p-b), abs(p-c)
+	switch {
+	case pa <= pb && pa <= pc:
+		return byte(a)
+	case pb <= pc:
+		return byte(b)
+	}
+	return byte(c)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
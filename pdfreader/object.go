@@ -0,0 +1,147 @@
+// Package pdfreader parses existing PDF documents for gofpdf.
+//
+// It reads the cross-reference information of a document, including
+// cross-reference streams, object streams and incremental updates, and
+// resolves its indirect objects, so that gofpdf can append an incremental
+// update to the document or import its pages. Documents encrypted with the
+// standard security handler are decrypted.
+//
+// Objects are represented by the following Go types:
+//
+//	null                  nil
+//	boolean               bool
+//	integer               int
+//	real                  float64
+//	string                String
+//	name                  Name
+//	array                 Array
+//	dictionary            Dict
+//	indirect reference    Ref
+//	stream                *Stream
+package pdfreader
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Object is a PDF object, one of the types listed in the package
+// documentation.
+type Object interface{}
+
+// Name is a PDF name, without its leading slash.
+type Name string
+
+// String is a PDF string. It holds the bytes of the string, which are
+// decrypted if the document is encrypted.
+type String string
+
+// Array is a PDF array.
+type Array []Object
+
+// Dict is a PDF dictionary.
+type Dict map[Name]Object
+
+// Ref is a reference to an indirect object.
+type Ref struct {
+	Num int // object number
+	Gen int // generation number
+}
+
+// Stream is a PDF stream. Data holds the encoded data of the stream,
+// decrypted if the document is encrypted; Decode applies its filters.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// Number returns the value of an integer or real object and true, or 0 and
+// false if obj is not a number.
+func Number(obj Object) (float64, bool) {
+	switch v := obj.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Format returns the PDF syntax of obj. The keys of dictionaries are written
+// in sorted order. Streams cannot be formatted with this function; their
+// dictionary is formatted instead.
+func Format(obj Object) string {
+	var b bytes.Buffer
+	format(&b, obj)
+	return b.String()
+}
+
+func format(b *bytes.Buffer, obj Object) {
+	switch v := obj.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case String:
+		b.WriteByte('(')
+		for j := 0; j < len(v); j++ {
+			switch c := v[j]; c {
+			case '(', ')', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\r':
+				b.WriteString("\\r")
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte(')')
+	case Name:
+		b.WriteByte('/')
+		for j := 0; j < len(v); j++ {
+			c := v[j]
+			if c <= ' ' || c >= 0x7f || c == '#' || isDelimiter(c) {
+				fmt.Fprintf(b, "#%02x", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	case Array:
+		b.WriteByte('[')
+		for j, o := range v {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			format(b, o)
+		}
+		b.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for j, k := range keys {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			format(b, Name(k))
+			b.WriteByte(' ')
+			format(b, v[Name(k)])
+		}
+		b.WriteString(">>")
+	case Ref:
+		fmt.Fprintf(b, "%d %d R", v.Num, v.Gen)
+	case *Stream:
+		format(b, v.Dict)
+	default:
+		b.WriteString("null")
+	}
+}
@@ -0,0 +1,406 @@
+package pdfreader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// isSpace reports whether c is a PDF white-space character
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+// isDelimiter reports whether c is a PDF delimiter character
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// keyword is a bare word of PDF syntax, such as obj or stream
+type keyword string
+
+// parser reads objects from PDF data
+type parser struct {
+	data []byte
+	pos  int
+	r    *Reader // resolves indirect stream lengths; may be nil
+}
+
+// errorf returns an error that includes the position of the parser
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("pdfreader: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips white space and comments
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// word returns the regular characters that start at the current position
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// token reads the next token, which is a complete object except for arrays
+// and dictionaries, whose delimiters are returned as keywords
+func (p *parser) token() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of data")
+	}
+	switch c := p.data[p.pos]; c {
+	case '/':
+		p.pos++
+		return p.name(), nil
+	case '(':
+		p.pos++
+		return p.literalString()
+	case '<':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '<' {
+			p.pos += 2
+			return keyword("<<"), nil
+		}
+		p.pos++
+		return p.hexString()
+	case '>':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return keyword(">>"), nil
+		}
+		return nil, p.errorf("unexpected >")
+	case '[', ']', '{', '}':
+		p.pos++
+		return keyword(c), nil
+	case ')':
+		return nil, p.errorf("unexpected )")
+	}
+	w := p.word()
+	switch w {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if c := w[0]; c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if n, err := strconv.Atoi(w); err == nil {
+			return n, nil
+		}
+		if v, err := strconv.ParseFloat(w, 64); err == nil {
+			return v, nil
+		}
+		// Malformed numbers such as "--1" or "1.2.3" are read as 0
+		return 0, nil
+	}
+	return keyword(w), nil
+}
+
+// name reads a name after its slash
+func (p *parser) name() Name {
+	w := []byte(p.word())
+	if bytes.IndexByte(w, '#') < 0 {
+		return Name(w)
+	}
+	var b []byte
+	for j := 0; j < len(w); j++ {
+		if w[j] == '#' && j+2 < len(w) {
+			if v, err := strconv.ParseUint(string(w[j+1:j+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				j += 2
+				continue
+			}
+		}
+		b = append(b, w[j])
+	}
+	return Name(b)
+}
+
+// literalString reads a literal string after its opening parenthesis
+func (p *parser) literalString() (Object, error) {
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(b), nil
+			}
+		case '\r':
+			// End-of-line markers are read as a line feed
+			if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+				p.pos++
+			}
+			c = '\n'
+		case '\\':
+			if p.pos >= len(p.data) {
+				break
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for k := 0; k < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; k++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// hexString reads a hexadecimal string after its opening angle bracket
+func (p *parser) hexString() (Object, error) {
+	var b []byte
+	var digits []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			for j := 0; j < len(digits); j += 2 {
+				v, _ := strconv.ParseUint(string(digits[j:j+2]), 16, 8)
+				b = append(b, byte(v))
+			}
+			return String(b), nil
+		}
+		switch {
+		case isSpace(c):
+		case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+			digits = append(digits, c)
+		default:
+			return nil, p.errorf("invalid character in hexadecimal string")
+		}
+	}
+	return nil, p.errorf("unterminated hexadecimal string")
+}
+
+// object reads a direct object or an indirect reference
+func (p *parser) object() (Object, error) {
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case int:
+		if v < 0 {
+			return v, nil
+		}
+		// An integer may begin an indirect reference
+		save := p.pos
+		p.skipSpace()
+		if gen, ok := p.unsigned(); ok {
+			p.skipSpace()
+			if p.pos < len(p.data) && p.data[p.pos] == 'R' &&
+				(p.pos+1 == len(p.data) || isSpace(p.data[p.pos+1]) || isDelimiter(p.data[p.pos+1])) {
+				p.pos++
+				return Ref{Num: v, Gen: gen}, nil
+			}
+		}
+		p.pos = save
+		return v, nil
+	case keyword:
+		switch v {
+		case "[":
+			var a Array
+			for {
+				p.skipSpace()
+				if p.pos < len(p.data) && p.data[p.pos] == ']' {
+					p.pos++
+					return a, nil
+				}
+				o, err := p.object()
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, o)
+			}
+		case "<<":
+			d := Dict{}
+			for {
+				p.skipSpace()
+				if p.pos+1 < len(p.data) && p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+					p.pos += 2
+					return d, nil
+				}
+				key, err := p.token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := key.(Name)
+				if !ok {
+					return nil, p.errorf("dictionary key is not a name")
+				}
+				o, err := p.object()
+				if err != nil {
+					return nil, err
+				}
+				if o != nil {
+					// A null value is equivalent to an absent entry
+					d[k] = o
+				}
+			}
+		}
+		return nil, p.errorf("unexpected %s", string(v))
+	}
+	return tok, nil
+}
+
+// unsigned reads an unsigned integer
+func (p *parser) unsigned() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(p.data[start:p.pos]))
+	return n, err == nil
+}
+
+// keyword reads the specified keyword
+func (p *parser) keyword(kw string) error {
+	p.skipSpace()
+	if w := p.word(); w != kw {
+		return p.errorf("expected %s, found %q", kw, w)
+	}
+	return nil
+}
+
+// indirect reads the indirect object that starts at the current position,
+// which must have the specified reference
+func (p *parser) indirect(ref Ref) (Object, error) {
+	p.skipSpace()
+	num, ok1 := p.unsigned()
+	p.skipSpace()
+	gen, ok2 := p.unsigned()
+	if !ok1 || !ok2 || num != ref.Num || gen != ref.Gen {
+		return nil, p.errorf("object %d %d not found", ref.Num, ref.Gen)
+	}
+	if err := p.keyword("obj"); err != nil {
+		return nil, err
+	}
+	obj, err := p.object()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := obj.(Dict)
+	if !ok {
+		return obj, nil
+	}
+	save := p.pos
+	p.skipSpace()
+	if p.word() != "stream" {
+		p.pos = save
+		return obj, nil
+	}
+	// The stream keyword is followed by CR LF or LF
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	return &Stream{Dict: d, Data: p.streamData(d)}, nil
+}
+
+// streamData returns the data of a stream that starts at the current
+// position. If the length of the stream is missing or wrong, the data ends
+// at the endstream keyword.
+func (p *parser) streamData(d Dict) []byte {
+	start := p.pos
+	length := -1
+	switch v := d["Length"].(type) {
+	case int:
+		length = v
+	case Ref:
+		if p.r != nil {
+			if o, err := p.r.Object(v); err == nil {
+				if n, ok := o.(int); ok {
+					length = n
+				}
+			}
+		}
+	}
+	if length >= 0 && start+length <= len(p.data) {
+		end := start + length
+		q := end
+		for q < len(p.data) && isSpace(p.data[q]) {
+			q++
+		}
+		if bytes.HasPrefix(p.data[q:], []byte("endstream")) {
+			p.pos = q + len("endstream")
+			return p.data[start:end]
+		}
+	}
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		p.pos = len(p.data)
+		return p.data[start:]
+	}
+	p.pos = start + end + len("endstream")
+	end += start
+	// The data is followed by an end-of-line marker
+	if end > start && p.data[end-1] == '\n' {
+		end--
+	}
+	if end > start && p.data[end-1] == '\r' {
+		end--
+	}
+	return p.data[start:end]
+}
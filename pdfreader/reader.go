@@ -0,0 +1,669 @@
+package pdfreader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// xrefEntry locates an object of the document
+type xrefEntry struct {
+	free   bool // object is deleted
+	stream bool // object is in an object stream
+	offset int  // offset of the object, or number of its object stream
+	gen    int  // generation number, or index in the object stream
+}
+
+// objStream holds a decoded object stream
+type objStream struct {
+	data    []byte
+	offsets map[int]int // offsets of the objects in data
+}
+
+// Reader reads the objects of a PDF document. The document is held in
+// memory.
+type Reader struct {
+	data       []byte
+	xref       map[int]xrefEntry
+	trailer    Dict
+	startxref  int
+	xrefStream bool
+	cache      map[Ref]Object
+	objStreams map[int]*objStream
+	resolving  map[Ref]bool
+	pages      []Ref
+	security   *securityHandler // nil if the document is not encrypted
+}
+
+// Open reads the PDF document in the file fileStr.
+func Open(fileStr string) (*Reader, error) {
+	fl, err := os.Open(fileStr)
+	if err != nil {
+		return nil, err
+	}
+	defer fl.Close()
+	return NewReader(fl)
+}
+
+// NewReader reads the PDF document from rs, which is read from its start.
+// An encrypted document is decrypted if its user password is empty.
+func NewReader(rs io.ReadSeeker) (*Reader, error) {
+	return NewReaderPassword(rs, "")
+}
+
+// NewReaderPassword reads the PDF document from rs, which is read from its
+// start. An encrypted document is decrypted with password, which may be its
+// user or its owner password. Documents encrypted with the standard security
+// handler, using RC4 or AES, are supported.
+func NewReaderPassword(rs io.ReadSeeker, password string) (*Reader, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(rs)
+	if err != nil {
+		return nil, err
+	}
+	return newReader(data, password)
+}
+
+// NewReaderBytes reads the PDF document held in data. The slice must not be
+// modified while the Reader is in use.
+func NewReaderBytes(data []byte) (*Reader, error) {
+	return newReader(data, "")
+}
+
+func newReader(data []byte, password string) (*Reader, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) && bytes.Index(data, []byte("%PDF-")) < 0 {
+		return nil, fmt.Errorf("pdfreader: not a PDF document")
+	}
+	r := &Reader{
+		data:       data,
+		xref:       make(map[int]xrefEntry),
+		cache:      make(map[Ref]Object),
+		objStreams: make(map[int]*objStream),
+		resolving:  make(map[Ref]bool),
+	}
+	if err := r.readXref(); err != nil {
+		// Rebuild the cross-reference information of a damaged document
+		r.xref = make(map[int]xrefEntry)
+		r.trailer = nil
+		r.startxref = 0
+		if err := r.reconstruct(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := r.trailer["Encrypt"]; ok {
+		h, err := newSecurityHandler(r, password)
+		if err != nil {
+			return nil, err
+		}
+		// Objects read so far are decrypted when read again
+		r.security = h
+		r.cache = make(map[Ref]Object)
+		r.objStreams = make(map[int]*objStream)
+	}
+	if _, err := r.loadPages(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Encrypted reports whether the document is encrypted.
+func (r *Reader) Encrypted() bool {
+	return r.security != nil
+}
+
+// Bytes returns the data of the document.
+func (r *Reader) Bytes() []byte {
+	return r.data
+}
+
+// Trailer returns the trailer dictionary of the document. For documents
+// with a cross-reference stream, it is the dictionary of the stream.
+func (r *Reader) Trailer() Dict {
+	return r.trailer
+}
+
+// StartXref returns the offset of the last cross-reference section of the
+// document, which an incremental update refers to, or 0 if the
+// cross-reference information of the document has been rebuilt.
+func (r *Reader) StartXref() int {
+	return r.startxref
+}
+
+// XrefStream reports whether the last cross-reference section of the
+// document is a cross-reference stream.
+func (r *Reader) XrefStream() bool {
+	return r.xrefStream
+}
+
+// Size returns the number of entries of the cross-reference table of the
+// document, one more than the highest object number.
+func (r *Reader) Size() int {
+	size, _ := r.trailer["Size"].(int)
+	for num := range r.xref {
+		if num >= size {
+			size = num + 1
+		}
+	}
+	return size
+}
+
+// Version returns the PDF version of the document, as declared by its
+// header or by its catalog if later.
+func (r *Reader) Version() string {
+	version := ""
+	header := r.data
+	if len(header) > 1024 {
+		header = header[:1024]
+	}
+	if m := regexp.MustCompile(`%PDF-(\d\.\d)`).FindSubmatch(header); m != nil {
+		version = string(m[1])
+	}
+	if catalog, err := r.Catalog(); err == nil {
+		if v, ok := catalog["Version"].(Name); ok && string(v) > version {
+			version = string(v)
+		}
+	}
+	return version
+}
+
+// Object returns the indirect object identified by ref. A missing object is
+// returned as null.
+func (r *Reader) Object(ref Ref) (Object, error) {
+	if obj, ok := r.cache[ref]; ok {
+		return obj, nil
+	}
+	e, ok := r.xref[ref.Num]
+	if !ok || e.free {
+		return nil, nil
+	}
+	if r.resolving[ref] {
+		return nil, fmt.Errorf("pdfreader: object %d %d refers to itself", ref.Num, ref.Gen)
+	}
+	r.resolving[ref] = true
+	defer delete(r.resolving, ref)
+	var obj Object
+	var err error
+	if e.stream {
+		if ref.Gen != 0 {
+			return nil, nil
+		}
+		obj, err = r.compressedObject(e.offset, ref.Num)
+	} else {
+		if e.gen != ref.Gen {
+			return nil, nil
+		}
+		if e.offset >= len(r.data) {
+			return nil, fmt.Errorf("pdfreader: offset of object %d is out of range", ref.Num)
+		}
+		p := &parser{data: r.data, pos: e.offset, r: r}
+		obj, err = p.indirect(ref)
+		if err == nil && r.security != nil {
+			obj = r.security.decrypt(ref, obj)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.cache[ref] = obj
+	return obj, nil
+}
+
+// Resolve returns the object referred to by obj if it is an indirect
+// reference, and obj otherwise.
+func (r *Reader) Resolve(obj Object) (Object, error) {
+	if ref, ok := obj.(Ref); ok {
+		return r.Object(ref)
+	}
+	return obj, nil
+}
+
+// ResolveDict returns the dictionary obj, or the dictionary it refers to.
+// The dictionary of a stream is returned for a stream. A null object yields
+// a nil dictionary.
+func (r *Reader) ResolveDict(obj Object) (Dict, error) {
+	obj, err := r.Resolve(obj)
+	if err != nil {
+		return nil, err
+	}
+	switch v := obj.(type) {
+	case nil:
+		return nil, nil
+	case Dict:
+		return v, nil
+	case *Stream:
+		return v.Dict, nil
+	}
+	return nil, fmt.Errorf("pdfreader: %s is not a dictionary", Format(obj))
+}
+
+// ResolveArray returns the array obj, or the array it refers to. A null
+// object yields a nil array.
+func (r *Reader) ResolveArray(obj Object) (Array, error) {
+	obj, err := r.Resolve(obj)
+	if err != nil {
+		return nil, err
+	}
+	switch v := obj.(type) {
+	case nil:
+		return nil, nil
+	case Array:
+		return v, nil
+	}
+	return nil, fmt.Errorf("pdfreader: %s is not an array", Format(obj))
+}
+
+// Catalog returns the catalog dictionary of the document.
+func (r *Reader) Catalog() (Dict, error) {
+	catalog, err := r.ResolveDict(r.trailer["Root"])
+	if err == nil && catalog == nil {
+		err = fmt.Errorf("pdfreader: document catalog not found")
+	}
+	return catalog, err
+}
+
+// NumPages returns the number of pages of the document.
+func (r *Reader) NumPages() int {
+	return len(r.pages)
+}
+
+// Page returns the reference and the dictionary of page n, which is 1-based.
+func (r *Reader) Page(n int) (Ref, Dict, error) {
+	if n < 1 || n > len(r.pages) {
+		return Ref{}, nil, fmt.Errorf("pdfreader: page %d does not exist", n)
+	}
+	d, err := r.ResolveDict(r.pages[n-1])
+	return r.pages[n-1], d, err
+}
+
+// PageAttr returns the value of the attribute key of page n, which may be
+// inherited from the page tree, such as Resources, MediaBox, CropBox and
+// Rotate. It returns nil if the attribute is not defined.
+func (r *Reader) PageAttr(n int, key Name) (Object, error) {
+	_, d, err := r.Page(n)
+	for depth := 0; err == nil && d != nil && depth < 64; depth++ {
+		if v, ok := d[key]; ok {
+			return r.Resolve(v)
+		}
+		d, err = r.ResolveDict(d["Parent"])
+	}
+	return nil, err
+}
+
+// PageBox returns the lower left and upper right coordinates of the box
+// boxName of page n, such as MediaBox or CropBox. The crop box defaults to
+// the media box and the other boxes to the crop box.
+func (r *Reader) PageBox(n int, boxName Name) (llx, lly, urx, ury float64, err error) {
+	obj, err := r.PageAttr(n, boxName)
+	if err != nil {
+		return
+	}
+	box, _ := obj.(Array)
+	if len(box) != 4 {
+		switch boxName {
+		case "MediaBox":
+			// US Letter is assumed if the media box is missing
+			return 0, 0, 612, 792, nil
+		case "CropBox":
+			return r.PageBox(n, "MediaBox")
+		}
+		return r.PageBox(n, "CropBox")
+	}
+	var v [4]float64
+	for j, o := range box {
+		if o, err = r.Resolve(o); err != nil {
+			return
+		}
+		v[j], _ = Number(o)
+	}
+	llx, urx = v[0], v[2]
+	if urx < llx {
+		llx, urx = urx, llx
+	}
+	lly, ury = v[1], v[3]
+	if ury < lly {
+		lly, ury = ury, lly
+	}
+	return
+}
+
+// loadPages collects the references of the pages in the page tree
+func (r *Reader) loadPages() ([]Ref, error) {
+	catalog, err := r.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	visited := make(map[Ref]bool)
+	var walk func(obj Object, depth int) error
+	walk = func(obj Object, depth int) error {
+		ref, ok := obj.(Ref)
+		if !ok || visited[ref] || depth > 64 {
+			return fmt.Errorf("pdfreader: invalid page tree")
+		}
+		visited[ref] = true
+		d, err := r.ResolveDict(ref)
+		if err != nil {
+			return err
+		}
+		kids, hasKids := d["Kids"]
+		if typ, _ := d["Type"].(Name); typ == "Page" || (typ != "Pages" && !hasKids) {
+			r.pages = append(r.pages, ref)
+			return nil
+		}
+		list, err := r.ResolveArray(kids)
+		if err != nil {
+			return err
+		}
+		for _, kid := range list {
+			if err = walk(kid, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err = walk(catalog["Pages"], 0); err != nil {
+		return nil, err
+	}
+	return r.pages, nil
+}
+
+// readXref reads the cross-reference sections of the document, from the
+// last one to the first
+func (r *Reader) readXref() error {
+	pos := bytes.LastIndex(r.data, []byte("startxref"))
+	if pos < 0 {
+		return fmt.Errorf("pdfreader: startxref not found")
+	}
+	p := &parser{data: r.data, pos: pos + len("startxref")}
+	p.skipSpace()
+	offset, ok := p.unsigned()
+	if !ok {
+		return fmt.Errorf("pdfreader: invalid startxref")
+	}
+	r.startxref = offset
+	visited := make(map[int]bool)
+	for first := true; ; first = false {
+		if visited[offset] || offset >= len(r.data) {
+			return fmt.Errorf("pdfreader: invalid cross-reference offset %d", offset)
+		}
+		visited[offset] = true
+		trailer, isStream, err := r.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		if first {
+			r.trailer = trailer
+			r.xrefStream = isStream
+		}
+		if stm, ok := trailer["XRefStm"].(int); ok && !isStream && !visited[stm] {
+			// Hybrid-reference file
+			visited[stm] = true
+			if _, _, err = r.readXrefSection(stm); err != nil {
+				return err
+			}
+		}
+		prev, ok := trailer["Prev"].(int)
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+	if _, ok := r.trailer["Root"]; !ok {
+		return fmt.Errorf("pdfreader: trailer has no /Root entry")
+	}
+	return nil
+}
+
+// readXrefSection reads the cross-reference table or stream at offset and
+// returns its trailer dictionary. Entries already read take precedence.
+func (r *Reader) readXrefSection(offset int) (trailer Dict, isStream bool, err error) {
+	p := &parser{data: r.data, pos: offset}
+	p.skipSpace()
+	if !bytes.HasPrefix(r.data[p.pos:], []byte("xref")) {
+		trailer, err = r.readXrefStream(p)
+		return trailer, true, err
+	}
+	p.pos += len("xref")
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(r.data[p.pos:], []byte("trailer")) {
+			p.pos += len("trailer")
+			break
+		}
+		start, ok1 := p.unsigned()
+		p.skipSpace()
+		count, ok2 := p.unsigned()
+		if !ok1 || !ok2 {
+			return nil, false, p.errorf("invalid cross-reference table")
+		}
+		for j := 0; j < count; j++ {
+			p.skipSpace()
+			off, ok1 := p.unsigned()
+			p.skipSpace()
+			gen, ok2 := p.unsigned()
+			p.skipSpace()
+			typ := p.word()
+			if !ok1 || !ok2 || (typ != "n" && typ != "f") {
+				return nil, false, p.errorf("invalid cross-reference entry")
+			}
+			num := start + j
+			if _, ok := r.xref[num]; ok || num == 0 {
+				continue
+			}
+			if typ == "n" {
+				r.xref[num] = xrefEntry{offset: off, gen: gen}
+			} else {
+				// Free entries hide older definitions of the object
+				r.xref[num] = xrefEntry{free: true}
+			}
+		}
+	}
+	obj, err := p.object()
+	if err != nil {
+		return nil, false, err
+	}
+	trailer, ok := obj.(Dict)
+	if !ok {
+		return nil, false, p.errorf("invalid trailer")
+	}
+	return trailer, false, nil
+}
+
+// readXrefStream reads the cross-reference stream that starts at the
+// position of p
+func (r *Reader) readXrefStream(p *parser) (Dict, error) {
+	save := p.pos
+	num, _ := p.unsigned()
+	p.skipSpace()
+	gen, _ := p.unsigned()
+	p.pos = save
+	obj, err := p.indirect(Ref{Num: num, Gen: gen})
+	if err != nil {
+		return nil, err
+	}
+	stm, ok := obj.(*Stream)
+	if !ok || stm.Dict["Type"] != Name("XRef") {
+		return nil, p.errorf("invalid cross-reference stream")
+	}
+	data, err := stm.Decode()
+	if err != nil {
+		return nil, err
+	}
+	var w [3]int
+	wa, _ := stm.Dict["W"].(Array)
+	if len(wa) != 3 {
+		return nil, p.errorf("invalid cross-reference stream /W entry")
+	}
+	for j, o := range wa {
+		w[j], _ = o.(int)
+		if w[j] < 0 || w[j] > 8 {
+			return nil, p.errorf("invalid cross-reference stream /W entry")
+		}
+	}
+	size, _ := stm.Dict["Size"].(int)
+	index := Array{0, size}
+	if a, ok := stm.Dict["Index"].(Array); ok {
+		index = a
+	}
+	field := func(b []byte, def int) int {
+		if len(b) == 0 {
+			return def
+		}
+		v := 0
+		for _, c := range b {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	entryLen := w[0] + w[1] + w[2]
+	pos := 0
+	for j := 0; j+1 < len(index); j += 2 {
+		start, _ := index[j].(int)
+		count, _ := index[j+1].(int)
+		for k := 0; k < count; k++ {
+			if pos+entryLen > len(data) {
+				return stm.Dict, nil
+			}
+			e := data[pos : pos+entryLen]
+			pos += entryLen
+			num := start + k
+			if _, ok := r.xref[num]; ok || num == 0 {
+				continue
+			}
+			f2 := field(e[w[0]:w[0]+w[1]], 0)
+			f3 := field(e[w[0]+w[1]:], 0)
+			switch field(e[:w[0]], 1) {
+			case 0:
+				r.xref[num] = xrefEntry{free: true}
+			case 1:
+				r.xref[num] = xrefEntry{offset: f2, gen: f3}
+			case 2:
+				r.xref[num] = xrefEntry{stream: true, offset: f2, gen: f3}
+			}
+		}
+	}
+	return stm.Dict, nil
+}
+
+// reconstruct rebuilds the cross-reference information by scanning the
+// document for objects
+func (r *Reader) reconstruct() error {
+	re := regexp.MustCompile(`(?:^|[\r\n\s])(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+obj\b`)
+	var streams []int
+	for _, m := range re.FindAllSubmatchIndex(r.data, -1) {
+		num, _ := strconv.Atoi(string(r.data[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(r.data[m[4]:m[5]]))
+		// Later definitions replace earlier ones
+		r.xref[num] = xrefEntry{offset: m[2], gen: gen}
+	}
+	for num, e := range r.xref {
+		obj, err := r.Object(Ref{Num: num, Gen: e.gen})
+		if err != nil {
+			continue
+		}
+		if stm, ok := obj.(*Stream); ok {
+			switch stm.Dict["Type"] {
+			case Name("ObjStm"):
+				streams = append(streams, num)
+			case Name("XRef"):
+				if _, ok := stm.Dict["Root"]; ok && r.trailer == nil {
+					r.trailer = stm.Dict
+				}
+			}
+		}
+	}
+	for _, stmNum := range streams {
+		ostm, err := r.objStream(stmNum)
+		if err != nil {
+			continue
+		}
+		for num := range ostm.offsets {
+			if _, ok := r.xref[num]; !ok {
+				r.xref[num] = xrefEntry{stream: true, offset: stmNum}
+			}
+		}
+	}
+	if pos := bytes.LastIndex(r.data, []byte("trailer")); pos >= 0 {
+		p := &parser{data: r.data, pos: pos + len("trailer")}
+		if obj, err := p.object(); err == nil {
+			if d, ok := obj.(Dict); ok {
+				if _, ok := d["Root"]; ok {
+					r.trailer = d
+				}
+			}
+		}
+	}
+	if r.trailer == nil {
+		// Look for the catalog
+		for num, e := range r.xref {
+			if d, err := r.ResolveDict(Ref{Num: num, Gen: e.gen}); err == nil && d["Type"] == Name("Catalog") {
+				r.trailer = Dict{"Root": Ref{Num: num, Gen: e.gen}}
+				break
+			}
+		}
+	}
+	if r.trailer == nil {
+		return fmt.Errorf("pdfreader: document catalog not found")
+	}
+	r.cache = make(map[Ref]Object)
+	return nil
+}
+
+// objStream returns the decoded object stream stmNum
+func (r *Reader) objStream(stmNum int) (*objStream, error) {
+	if ostm, ok := r.objStreams[stmNum]; ok {
+		return ostm, nil
+	}
+	obj, err := r.Object(Ref{Num: stmNum})
+	if err != nil {
+		return nil, err
+	}
+	stm, ok := obj.(*Stream)
+	if !ok {
+		return nil, fmt.Errorf("pdfreader: object stream %d not found", stmNum)
+	}
+	data, err := stm.Decode()
+	if err != nil {
+		return nil, err
+	}
+	n, _ := stm.Dict["N"].(int)
+	first, _ := stm.Dict["First"].(int)
+	if first > len(data) {
+		return nil, fmt.Errorf("pdfreader: invalid object stream %d", stmNum)
+	}
+	ostm := &objStream{data: data, offsets: make(map[int]int)}
+	p := &parser{data: data[:first]}
+	for j := 0; j < n; j++ {
+		p.skipSpace()
+		num, ok1 := p.unsigned()
+		p.skipSpace()
+		off, ok2 := p.unsigned()
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("pdfreader: invalid object stream %d", stmNum)
+		}
+		if _, ok := ostm.offsets[num]; !ok {
+			ostm.offsets[num] = first + off
+		}
+	}
+	r.objStreams[stmNum] = ostm
+	return ostm, nil
+}
+
+// compressedObject returns object num of object stream stmNum
+func (r *Reader) compressedObject(stmNum, num int) (Object, error) {
+	ostm, err := r.objStream(stmNum)
+	if err != nil {
+		return nil, err
+	}
+	off, ok := ostm.offsets[num]
+	if !ok || off > len(ostm.data) {
+		return nil, nil
+	}
+	p := &parser{data: ostm.data, pos: off, r: r}
+	return p.object()
+}
@@ -0,0 +1,234 @@
+package pdfreader_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// buildPDF returns a document made of the specified objects, numbered from
+// 1, with a cross-reference table; the catalog is object 1
+func buildPDF(objs ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for j, o := range objs {
+		offsets[j] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", j+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d /Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
+
+// generate returns a document produced by gofpdf
+func generate(t *testing.T, pages int, fn func(pdf *gofpdf.Fpdf)) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	if fn != nil {
+		fn(pdf)
+	}
+	for j := 1; j <= pages; j++ {
+		pdf.AddPage()
+		pdf.Cell(40, 10, fmt.Sprintf("Page %d", j))
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pageText returns the decoded content of page n
+func pageText(t *testing.T, r *pdfreader.Reader, n int) string {
+	t.Helper()
+	_, page, err := r.Page(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := r.Resolve(page["Contents"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	stm, ok := obj.(*pdfreader.Stream)
+	if !ok {
+		t.Fatalf("page %d has no content stream", n)
+	}
+	data, err := stm.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReaderGenerated(t *testing.T) {
+	for _, objStm := range []bool{false, true} {
+		data := generate(t, 120, func(pdf *gofpdf.Fpdf) {
+			pdf.SetObjectStreams(objStm)
+		})
+		r, err := pdfreader.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.NumPages() != 120 {
+			t.Fatalf("object streams %v: %d pages", objStm, r.NumPages())
+		}
+		if r.XrefStream() != objStm {
+			t.Errorf("object streams %v: cross-reference stream %v", objStm, r.XrefStream())
+		}
+		if s := pageText(t, r, 77); !strings.Contains(s, "(Page 77)Tj") {
+			t.Errorf("object streams %v: unexpected content %q", objStm, s)
+		}
+		llx, lly, urx, ury, err := r.PageBox(3, "TrimBox")
+		if err != nil || llx != 0 || lly != 0 || urx != 595.28 || ury != 841.89 {
+			t.Errorf("object streams %v: unexpected page box %v %v %v %v %v", objStm, llx, lly, urx, ury, err)
+		}
+	}
+}
+
+func TestReaderSyntax(t *testing.T) {
+	data := buildPDF(
+		"<</Type /Catalog /Pages 2 0 R /Test 4 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] /Rotate 90>>",
+		"<</Type /Page /Parent 2 0 R>>",
+		"<</Str (a\\(b\\)\\n\\101\\\r\nc (nested)) /Hex <48 65 6c6C 6> /Name /A#20B"+
+			" /Nums [-1 +2 .5 3.25 1 0 R] /Null null /Bool true % comment\n /Nested <</K [<<>>]>>>>",
+	)
+	r, err := pdfreader.NewReaderBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := r.Object(pdfreader.Ref{Num: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := pdfreader.Format(obj)
+	want := "<</Bool true /Hex (Hell`) /Name /A#20B /Nested <</K [<<>>]>> " +
+		"/Nums [-1 2 0.5 3.25 1 0 R] /Str (a\\(b\\)\nAc \\(nested\\))>>"
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+	if rotate, _ := r.PageAttr(1, "Rotate"); rotate != 90 {
+		t.Errorf("inherited attribute not found: %v", rotate)
+	}
+	if _, _, urx, ury, _ := r.PageBox(1, "CropBox"); urx != 200 || ury != 100 {
+		t.Errorf("unexpected crop box %v %v", urx, ury)
+	}
+}
+
+func TestReaderUpdated(t *testing.T) {
+	data := buildPDF(
+		"<</Type /Catalog /Pages 2 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /Rev 1>>",
+		"(deleted)",
+	)
+	prev := bytes.LastIndex(data, []byte("\nxref")) + 1
+	var b bytes.Buffer
+	b.Write(data)
+	off := b.Len()
+	b.WriteString("3 0 obj\n<</Type /Page /Parent 2 0 R /Rev 2>>\nendobj\n")
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n3 2\n%010d 00000 n \n0000000000 00001 f \n", off)
+	fmt.Fprintf(&b, "trailer\n<</Size 5 /Root 1 0 R /Prev %d>>\nstartxref\n%d\n%%%%EOF\n", prev, xref)
+	r, err := pdfreader.NewReaderBytes(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, page, _ := r.Page(1); page["Rev"] != 2 {
+		t.Errorf("page of the update not read: %v", page)
+	}
+	if obj, _ := r.Object(pdfreader.Ref{Num: 4}); obj != nil {
+		t.Errorf("deleted object read: %v", obj)
+	}
+	if r.StartXref() != xref || r.Size() != 5 {
+		t.Errorf("unexpected startxref %d or size %d", r.StartXref(), r.Size())
+	}
+}
+
+func TestReaderDamaged(t *testing.T) {
+	data := generate(t, 3, nil)
+	// Shift all objects so that the cross-reference table is wrong
+	data = append([]byte("%PDF-1.3\n%garbage\n"), data...)
+	r, err := pdfreader.NewReaderBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NumPages() != 3 || r.StartXref() != 0 {
+		t.Fatalf("document not rebuilt: %d pages", r.NumPages())
+	}
+	if s := pageText(t, r, 2); !strings.Contains(s, "(Page 2)Tj") {
+		t.Errorf("unexpected content %q", s)
+	}
+}
+
+func TestStreamDecode(t *testing.T) {
+	for _, c := range []struct {
+		filter string
+		data   string
+		want   string
+	}{
+		{"/LZWDecode", "\x80\x0b\x60\x50\x22\x0c\x0c\x85\x01", "-----A---B"},
+		{"/ASCII85Decode", "<+U,m z:ddb~>", "Test\x00\x00\x00\x00PDF"},
+		{"/AHx", "48 65 6C\n6c 6f 2>", "Hello "},
+		{"/RunLengthDecode", "\x02abc\xfdx\x00d\x80ignored", "abcxxxxd"},
+		{"[/AHx /RL]", "02616263 FD78 80>", "abcxxxx"},
+	} {
+		r, err := pdfreader.NewReaderBytes(buildPDF(
+			"<</Type /Catalog /Pages 2 0 R>>",
+			"<</Type /Pages /Kids [] /Count 0>>",
+			fmt.Sprintf("<</Filter %s /Length %d>>\nstream\n%s\nendstream", c.filter, len(c.data), c.data),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj, err := r.Object(pdfreader.Ref{Num: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := obj.(*pdfreader.Stream).Decode()
+		if err != nil {
+			t.Errorf("%s: %s", c.filter, err)
+		} else if string(data) != c.want {
+			t.Errorf("%s: got %q, want %q", c.filter, data, c.want)
+		}
+	}
+}
+
+func TestReaderEncrypted(t *testing.T) {
+	for _, alg := range []gofpdf.EncryptionType{gofpdf.EncryptRC4Bits40, gofpdf.EncryptRC4Bits128,
+		gofpdf.EncryptAESBits128, gofpdf.EncryptAESBits256} {
+		data := generate(t, 3, func(pdf *gofpdf.Fpdf) {
+			pdf.SetTitle("Secret (title)", false)
+			pdf.SetProtectionAlgorithm(alg, gofpdf.CnProtectPrint, "user", "owner")
+		})
+		if _, err := pdfreader.NewReaderBytes(data); err == nil || !strings.Contains(err.Error(), "incorrect password") {
+			t.Errorf("algorithm %d: document read without password: %v", alg, err)
+		}
+		for _, password := range []string{"user", "owner"} {
+			r, err := pdfreader.NewReaderPassword(bytes.NewReader(data), password)
+			if err != nil {
+				t.Fatalf("algorithm %d, password %s: %s", alg, password, err)
+			}
+			if !r.Encrypted() {
+				t.Errorf("algorithm %d: document not encrypted", alg)
+			}
+			if s := pageText(t, r, 2); !strings.Contains(s, "(Page 2)Tj") {
+				t.Errorf("algorithm %d, password %s: unexpected content %q", alg, password, s)
+			}
+			info, _ := r.ResolveDict(r.Trailer()["Info"])
+			if title := info["Title"]; title != pdfreader.String("Secret (title)") {
+				t.Errorf("algorithm %d, password %s: unexpected title %q", alg, password, title)
+			}
+		}
+	}
+}
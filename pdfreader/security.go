@@ -0,0 +1,332 @@
+package pdfreader
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+)
+
+// padding is the string used to pad passwords to 32 bytes
+var padding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+	0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+	0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// securityHandler decrypts the strings and streams of a document encrypted
+// with the standard security handler
+type securityHandler struct {
+	key      []byte
+	stmF     Name // crypt method of streams: None, V2, AESV2 or AESV3
+	strF     Name // crypt method of strings
+	metadata bool // metadata streams are encrypted
+	encRef   Ref  // encryption dictionary, which is not encrypted
+}
+
+// newSecurityHandler authenticates password, which may be the user or the
+// owner password, and computes the encryption key of the document
+func newSecurityHandler(r *Reader, password string) (*securityHandler, error) {
+	encRef, _ := r.trailer["Encrypt"].(Ref)
+	enc, err := r.ResolveDict(r.trailer["Encrypt"])
+	if err != nil {
+		return nil, err
+	}
+	if filter, _ := enc["Filter"].(Name); filter != "Standard" {
+		return nil, fmt.Errorf("pdfreader: unsupported security handler %s", Format(enc["Filter"]))
+	}
+	h := &securityHandler{encRef: encRef, metadata: true, stmF: "V2", strF: "V2"}
+	if v, ok := enc["EncryptMetadata"].(bool); ok {
+		h.metadata = v
+	}
+	v, _ := enc["V"].(int)
+	rev, _ := enc["R"].(int)
+	length := 5
+	if bits, ok := enc["Length"].(int); ok && v > 1 {
+		length = bits / 8
+	}
+	if v >= 4 {
+		// Crypt filters
+		cf, _ := r.ResolveDict(enc["CF"])
+		method := func(key Name) Name {
+			name, _ := enc[key].(Name)
+			if name == "" || name == "Identity" {
+				return "None"
+			}
+			d, _ := r.ResolveDict(cf[name])
+			cfm, _ := d["CFM"].(Name)
+			if cfm == "" {
+				cfm = "None"
+			}
+			if n, ok := d["Length"].(int); ok && cfm == "V2" {
+				if n > 16 {
+					// Some writers express the length in bits
+					n /= 8
+				}
+				length = n
+			}
+			return cfm
+		}
+		h.stmF = method("StmF")
+		h.strF = method("StrF")
+		if h.stmF == "AESV2" || h.strF == "AESV2" {
+			length = 16
+		}
+	}
+	for _, m := range []Name{h.stmF, h.strF} {
+		switch m {
+		case "None", "V2", "AESV2", "AESV3":
+		default:
+			return nil, fmt.Errorf("pdfreader: unsupported crypt method %s", m)
+		}
+	}
+	if length < 5 || length > 16 {
+		length = 16
+	}
+	o, _ := enc["O"].(String)
+	u, _ := enc["U"].(String)
+	var id0 String
+	if id, ok := r.trailer["ID"].(Array); ok && len(id) > 0 {
+		id0, _ = id[0].(String)
+	}
+	if rev >= 5 {
+		oe, _ := enc["OE"].(String)
+		ue, _ := enc["UE"].(String)
+		h.key, err = aes256Key(rev, []byte(password), []byte(o), []byte(u), []byte(oe), []byte(ue))
+		return h, err
+	}
+	if len(o) < 32 || len(u) < 16 {
+		return nil, fmt.Errorf("pdfreader: invalid encryption dictionary")
+	}
+	p, _ := enc["P"].(int)
+	keyFn := func(userPass []byte) []byte {
+		hash := md5.New()
+		hash.Write(padPassword(userPass))
+		hash.Write([]byte(o[:32]))
+		hash.Write([]byte{byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)})
+		hash.Write([]byte(id0))
+		if rev >= 4 && !h.metadata {
+			hash.Write([]byte{0xff, 0xff, 0xff, 0xff})
+		}
+		sum := hash.Sum(nil)
+		if rev >= 3 {
+			for j := 0; j < 50; j++ {
+				s := md5.Sum(sum[:length])
+				sum = s[:]
+			}
+		}
+		return sum[:length]
+	}
+	checkUser := func(key []byte) bool {
+		if rev == 2 {
+			return bytes.Equal(rc4Crypt(key, padding), []byte(u[:32]))
+		}
+		sum := md5.Sum(append(append([]byte{}, padding...), id0...))
+		return bytes.Equal(rc4Rounds(key, sum[:], false), []byte(u[:16]))
+	}
+	// Algorithm 6: user password
+	if key := keyFn([]byte(password)); checkUser(key) {
+		h.key = key
+		return h, nil
+	}
+	// Algorithm 7: owner password, which gives access to the user password
+	sum := md5.Sum(padPassword([]byte(password)))
+	if rev >= 3 {
+		for j := 0; j < 50; j++ {
+			sum = md5.Sum(sum[:])
+		}
+	}
+	var userPass []byte
+	if rev == 2 {
+		userPass = rc4Crypt(sum[:length], []byte(o[:32]))
+	} else {
+		userPass = rc4Rounds(sum[:length], []byte(o[:32]), true)
+	}
+	if key := keyFn(userPass); checkUser(key) {
+		h.key = key
+		return h, nil
+	}
+	return nil, fmt.Errorf("pdfreader: incorrect password")
+}
+
+// padPassword pads or truncates a password to 32 bytes
+func padPassword(pass []byte) []byte {
+	if len(pass) > 32 {
+		pass = pass[:32]
+	}
+	return append(append([]byte{}, pass...), padding[:32-len(pass)]...)
+}
+
+// rc4Crypt encrypts or decrypts b with RC4
+func rc4Crypt(key, b []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, len(b))
+	c.XORKeyStream(out, b)
+	return out
+}
+
+// rc4Rounds applies RC4 twenty times to b, using key xor'ed with the round
+// number, in reverse order to decrypt
+func rc4Rounds(key, b []byte, reverse bool) []byte {
+	out := b
+	k := make([]byte, len(key))
+	for j := 0; j < 20; j++ {
+		r := j
+		if reverse {
+			r = 19 - j
+		}
+		for i := range key {
+			k[i] = key[i] ^ byte(r)
+		}
+		out = rc4Crypt(k, out)
+	}
+	return out
+}
+
+// aes256Key authenticates password with the values of the encryption
+// dictionary of revisions 5 and 6 and returns the encryption key
+func aes256Key(rev int, password, o, u, oe, ue []byte) ([]byte, error) {
+	if len(o) < 48 || len(u) < 48 || len(oe) < 32 || len(ue) < 32 {
+		return nil, fmt.Errorf("pdfreader: invalid encryption dictionary")
+	}
+	if len(password) > 127 {
+		password = password[:127]
+	}
+	hash := func(salt, udata []byte) []byte {
+		if rev == 5 {
+			sum := sha256.Sum256(append(append(append([]byte{}, password...), salt...), udata...))
+			return sum[:]
+		}
+		return hashR6(password, salt, udata)
+	}
+	var key []byte
+	switch {
+	case bytes.Equal(hash(u[32:40], nil), u[:32]):
+		key = hash(u[40:48], nil)
+		ue = ue[:32]
+	case bytes.Equal(hash(o[32:40], u[:48]), o[:32]):
+		key = hash(o[40:48], u[:48])
+		ue = oe[:32]
+	default:
+		return nil, fmt.Errorf("pdfreader: incorrect password")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	fileKey := make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(fileKey, ue)
+	return fileKey, nil
+}
+
+// hashR6 computes the password hash of revision 6 (algorithm 2.B)
+func hashR6(pass, salt, udata []byte) []byte {
+	sum := sha256.Sum256(append(append(append([]byte{}, pass...), salt...), udata...))
+	k := sum[:]
+	for round := 1; ; round++ {
+		var seq []byte
+		seq = append(seq, pass...)
+		seq = append(seq, k...)
+		seq = append(seq, udata...)
+		k1 := bytes.Repeat(seq, 64)
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+		total := 0
+		for _, c := range e[:16] {
+			total += int(c)
+		}
+		switch total % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		if round >= 64 && int(e[len(e)-1]) <= round-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// decryptData decrypts the data of a string or stream of object ref with the
+// specified crypt method
+func (h *securityHandler) decryptData(method Name, ref Ref, data []byte) []byte {
+	if method == "None" {
+		return data
+	}
+	key := h.key
+	if method != "AESV3" {
+		// Algorithm 1: key of the object
+		b := append([]byte{}, h.key...)
+		b = append(b, byte(ref.Num), byte(ref.Num>>8), byte(ref.Num>>16), byte(ref.Gen), byte(ref.Gen>>8))
+		if method == "AESV2" {
+			b = append(b, "sAlT"...)
+		}
+		sum := md5.Sum(b)
+		n := len(h.key) + 5
+		if n > 16 {
+			n = 16
+		}
+		key = sum[:n]
+	}
+	if method == "V2" {
+		return rc4Crypt(key, data)
+	}
+	// AES in CBC mode, with the initialization vector at the start of the
+	// data and PKCS#5 padding
+	if len(data) < 2*aes.BlockSize {
+		return nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, len(data)-aes.BlockSize)
+	out = out[:len(out)/aes.BlockSize*aes.BlockSize]
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(out, data[aes.BlockSize:aes.BlockSize+len(out)])
+	if pad := int(out[len(out)-1]); pad >= 1 && pad <= aes.BlockSize {
+		out = out[:len(out)-pad]
+	}
+	return out
+}
+
+// decrypt decrypts the strings and the stream data of obj, the indirect
+// object ref, in place
+func (h *securityHandler) decrypt(ref Ref, obj Object) Object {
+	if ref == h.encRef {
+		return obj
+	}
+	switch v := obj.(type) {
+	case String:
+		return String(h.decryptData(h.strF, ref, []byte(v)))
+	case Array:
+		for j, o := range v {
+			v[j] = h.decrypt(ref, o)
+		}
+	case Dict:
+		for k, o := range v {
+			v[k] = h.decrypt(ref, o)
+		}
+	case *Stream:
+		h.decrypt(ref, v.Dict)
+		typ, _ := v.Dict["Type"].(Name)
+		if typ != "XRef" && (typ != "Metadata" || h.metadata) {
+			v.Data = h.decryptData(h.stmF, ref, v.Data)
+		}
+	}
+	return obj
+}
@@ -81,6 +81,7 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	// Scan chunks looking for palette, transparency and image data
 	pal := make([]byte, 0, 32)
 	var trns []int
+	var icc []byte
 	data := make([]byte, 0, 32)
 	loop := true
 	for loop {
@@ -108,6 +109,9 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 				}
 			}
 			_ = buf.Next(4)
+		case "iCCP":
+			icc = pngICCProfile(buf.Next(n))
+			_ = buf.Next(4)
 		case "IDAT":
 			// dbg("IDAT")
 			// Read image data block
@@ -157,6 +161,10 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	info.dp = dp
 	info.pal = pal
 	info.trns = trns
+	// An embedded profile that does not match the image is ignored
+	if iccComponents(icc) == info.imageComponents() {
+		info.icc = icc
+	}
 	// dbg("ct [%d]", ct)
 	if ct >= 4 {
 		// Separate alpha and color channels
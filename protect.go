@@ -20,8 +20,13 @@
 package gofpdf
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/md5"
+	crand "crypto/rand"
 	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"math/rand"
 )
@@ -32,26 +37,88 @@ const (
 	CnProtectModify     = 8
 	CnProtectCopy       = 16
 	CnProtectAnnotForms = 32
+	// The following flags are only honored by SetProtectionAlgorithm with
+	// an algorithm other than EncryptRC4Bits40.
+	CnProtectFillForms    = 256
+	CnProtectExtract      = 512
+	CnProtectAssemble     = 1024
+	CnProtectPrintHighRes = 2048
+)
+
+// EncryptionType identifies the algorithm used to encrypt a protected
+// document. See SetProtectionAlgorithm.
+type EncryptionType int
+
+const (
+	// EncryptRC4Bits40 is the 40-bit RC4 algorithm (revision 2) used by
+	// SetProtection. It is supported by all readers but is not secure.
+	EncryptRC4Bits40 EncryptionType = iota
+	// EncryptRC4Bits128 is the 128-bit RC4 algorithm (revision 3).
+	EncryptRC4Bits128
+	// EncryptAESBits128 is the 128-bit AES algorithm (revision 4), which
+	// requires PDF 1.6.
+	EncryptAESBits128
+	// EncryptAESBits256 is the 256-bit AES algorithm (revision 6), which
+	// requires PDF 2.0.
+	EncryptAESBits256
 )
 
 type protectType struct {
 	encrypted     bool
+	algorithm     EncryptionType
 	uValue        []byte
 	oValue        []byte
+	ueValue       []byte // revision 6 only
+	oeValue       []byte // revision 6 only
+	perms         []byte // revision 6 only
 	pValue        int
 	padding       []byte
 	encryptionKey []byte
+	id            []byte // first element of the file identifier
 	objNum        int
-	rc4cipher     *rc4.Cipher
-	rc4n          uint32 // Object number associated with rc4 cipher
 }
 
-func (p *protectType) rc4(n uint32, buf *[]byte) {
-	if p.rc4cipher == nil || p.rc4n != n {
-		p.rc4cipher, _ = rc4.NewCipher(p.objectKey(n))
-		p.rc4n = n
+// isAES reports whether strings and streams are encrypted with AES
+func (p *protectType) isAES() bool {
+	return p.algorithm == EncryptAESBits128 || p.algorithm == EncryptAESBits256
+}
+
+// encrypt returns b encrypted for inclusion in object n
+func (p *protectType) encrypt(n int, b []byte) []byte {
+	switch p.algorithm {
+	case EncryptAESBits128:
+		return aesEncrypt(p.objectKey(uint32(n)), b)
+	case EncryptAESBits256:
+		return aesEncrypt(p.encryptionKey, b)
 	}
-	p.rc4cipher.XORKeyStream(*buf, *buf)
+	c, _ := rc4.NewCipher(p.objectKey(uint32(n)))
+	out := make([]byte, len(b))
+	c.XORKeyStream(out, b)
+	return out
+}
+
+// streamLen returns the length of a stream of n bytes once it is encrypted
+func (p *protectType) streamLen(n int) int {
+	if p.encrypted && p.isAES() {
+		// Initialization vector followed by the padded data
+		return aes.BlockSize + (n/aes.BlockSize+1)*aes.BlockSize
+	}
+	return n
+}
+
+// aesEncrypt encrypts b in CBC mode with a random initialization vector,
+// which is prepended to the result
+func aesEncrypt(key, b []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	pad := aes.BlockSize - len(b)%aes.BlockSize
+	out := make([]byte, aes.BlockSize+len(b)+pad)
+	crand.Read(out[:aes.BlockSize])
+	copy(out[aes.BlockSize:], b)
+	for j := len(out) - pad; j < len(out); j++ {
+		out[j] = byte(pad)
+	}
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], out[aes.BlockSize:])
+	return out
 }
 
 func (p *protectType) objectKey(n uint32) []byte {
@@ -60,8 +127,15 @@ func (p *protectType) objectKey(n uint32) []byte {
 	binary.LittleEndian.PutUint32(nbuf, n)
 	b = append(b, p.encryptionKey...)
 	b = append(b, nbuf[0], nbuf[1], nbuf[2], 0, 0)
+	if p.algorithm == EncryptAESBits128 {
+		b = append(b, "sAlT"...)
+	}
 	s := md5.Sum(b)
-	return s[0:10]
+	size := len(p.encryptionKey) + 5
+	if size > 16 {
+		size = 16
+	}
+	return s[0:size]
 }
 
 func oValueGen(userPass, ownerPass []byte) (v []byte) {
@@ -102,6 +176,7 @@ func (p *protectType) setProtection(privFlag byte, userPassStr, ownerPassStr str
 	userPass = append(userPass, p.padding...)[0:32]
 	ownerPass = append(ownerPass, p.padding...)[0:32]
 	p.encrypted = true
+	p.algorithm = EncryptRC4Bits40
 	p.oValue = oValueGen(userPass, ownerPass)
 	var buf []byte
 	buf = append(buf, userPass...)
@@ -112,3 +187,181 @@ func (p *protectType) setProtection(privFlag byte, userPassStr, ownerPassStr str
 	p.uValue = p.uValueGen()
 	p.pValue = -(int(privFlag^255) + 1)
 }
+
+// rc4Rounds encrypts b with RC4 twenty times, using key xor'ed with the
+// round number, as done for the O and U values of revisions 3 and 4
+func rc4Rounds(key, b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	k := make([]byte, len(key))
+	for r := 0; r < 20; r++ {
+		for j := range key {
+			k[j] = key[j] ^ byte(r)
+		}
+		c, _ := rc4.NewCipher(k)
+		c.XORKeyStream(out, out)
+	}
+	return out
+}
+
+// setProtectionAlgorithm initializes the encryption of revisions 3, 4 and
+// 6 of the standard security handler
+func (p *protectType) setProtectionAlgorithm(alg EncryptionType, privFlag int, userPassStr, ownerPassStr string) {
+	if alg == EncryptRC4Bits40 {
+		p.setProtection(byte(privFlag), userPassStr, ownerPassStr)
+		return
+	}
+	p.padding = []byte{
+		0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+		0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+		0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+		0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+	}
+	ownerPass := []byte(ownerPassStr)
+	if ownerPassStr == "" {
+		ownerPass = make([]byte, 16)
+		crand.Read(ownerPass)
+	}
+	p.encrypted = true
+	p.algorithm = alg
+	p.id = make([]byte, 16)
+	crand.Read(p.id)
+	// Reserved bits 7, 8 and 13 to 32 must be set
+	flags := uint32(0xfffff0c0) | uint32(privFlag&0xf3c)
+	p.pValue = int(int32(flags))
+	if alg == EncryptAESBits256 {
+		p.setAES256(flags, []byte(userPassStr), ownerPass)
+		return
+	}
+	userPass := append([]byte(userPassStr), p.padding...)[0:32]
+	ownerPass = append(ownerPass, p.padding...)[0:32]
+	// Algorithm 3: owner password value
+	sum := md5.Sum(ownerPass)
+	for j := 0; j < 50; j++ {
+		sum = md5.Sum(sum[:])
+	}
+	p.oValue = rc4Rounds(sum[:], userPass)
+	// Algorithm 2: encryption key
+	var buf []byte
+	buf = append(buf, userPass...)
+	buf = append(buf, p.oValue...)
+	buf = append(buf, byte(flags), byte(flags>>8), byte(flags>>16), byte(flags>>24))
+	buf = append(buf, p.id...)
+	sum = md5.Sum(buf)
+	for j := 0; j < 50; j++ {
+		sum = md5.Sum(sum[:])
+	}
+	p.encryptionKey = sum[:]
+	// Algorithm 5: user password value, padded to 32 bytes
+	idSum := md5.Sum(append(append([]byte{}, p.padding...), p.id...))
+	p.uValue = append(rc4Rounds(p.encryptionKey, idSum[:]), p.padding[:16]...)
+}
+
+// hashR6 computes the password hash of revision 6 (algorithm 2.B)
+func hashR6(pass, salt, udata []byte) []byte {
+	h := sha256.New()
+	h.Write(pass)
+	h.Write(salt)
+	h.Write(udata)
+	k := h.Sum(nil)
+	for round := 1; ; round++ {
+		var seq []byte
+		seq = append(seq, pass...)
+		seq = append(seq, k...)
+		seq = append(seq, udata...)
+		k1 := make([]byte, 0, 64*len(seq))
+		for j := 0; j < 64; j++ {
+			k1 = append(k1, seq...)
+		}
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+		sum := 0
+		for _, c := range e[:16] {
+			sum += int(c)
+		}
+		switch sum % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		if round >= 64 && int(e[len(e)-1]) <= round-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// aesWrap encrypts the file key with a key derived from a password, using
+// CBC mode with a zero initialization vector and no padding
+func aesWrap(key, b []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(b))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, b)
+	return out
+}
+
+func (p *protectType) setAES256(flags uint32, userPass, ownerPass []byte) {
+	if len(userPass) > 127 {
+		userPass = userPass[:127]
+	}
+	if len(ownerPass) > 127 {
+		ownerPass = ownerPass[:127]
+	}
+	p.encryptionKey = make([]byte, 32)
+	crand.Read(p.encryptionKey)
+	salts := make([]byte, 32)
+	crand.Read(salts)
+	// Algorithm 8: user password values
+	p.uValue = append(hashR6(userPass, salts[0:8], nil), salts[0:16]...)
+	p.ueValue = aesWrap(hashR6(userPass, salts[8:16], nil), p.encryptionKey)
+	// Algorithm 9: owner password values
+	p.oValue = append(hashR6(ownerPass, salts[16:24], p.uValue), salts[16:32]...)
+	p.oeValue = aesWrap(hashR6(ownerPass, salts[24:32], p.uValue), p.encryptionKey)
+	// Algorithm 10: permissions
+	perms := make([]byte, 16)
+	binary.LittleEndian.PutUint32(perms, flags)
+	copy(perms[4:], []byte{0xff, 0xff, 0xff, 0xff, 'T', 'a', 'd', 'b'})
+	crand.Read(perms[12:])
+	block, _ := aes.NewCipher(p.encryptionKey)
+	p.perms = make([]byte, 16)
+	block.Encrypt(p.perms, perms)
+}
+
+// putencryption writes the encryption dictionary
+func (f *Fpdf) putencryption() {
+	p := &f.protect
+	f.newobj()
+	p.objNum = f.n
+	f.out("<<")
+	f.out("/Filter /Standard")
+	switch p.algorithm {
+	case EncryptRC4Bits40:
+		f.out("/V 1")
+		f.out("/R 2")
+	case EncryptRC4Bits128:
+		f.out("/V 2 /R 3 /Length 128")
+	case EncryptAESBits128:
+		f.out("/V 4 /R 4 /Length 128")
+		f.out("/CF <</StdCF <</CFM /AESV2 /AuthEvent /DocOpen /Length 16>>>> /StmF /StdCF /StrF /StdCF")
+	case EncryptAESBits256:
+		f.out("/V 5 /R 6 /Length 256")
+		f.out("/CF <</StdCF <</CFM /AESV3 /AuthEvent /DocOpen /Length 32>>>> /StmF /StdCF /StrF /StdCF")
+	}
+	f.outf("/O (%s)", f.escape(string(p.oValue)))
+	f.outf("/U (%s)", f.escape(string(p.uValue)))
+	if p.algorithm == EncryptAESBits256 {
+		f.outf("/OE (%s)", f.escape(string(p.oeValue)))
+		f.outf("/UE (%s)", f.escape(string(p.ueValue)))
+		f.outf("/Perms (%s)", f.escape(string(p.perms)))
+	}
+	f.outf("/P %d", p.pValue)
+	f.out(">>")
+	f.out("endobj")
+}
@@ -0,0 +1,414 @@
+package gofpdf
+
+import "unicode"
+
+// Forms of Arabic letters, which index their presentation forms and the GSUB
+// features that select them
+const (
+	arabicIsol = iota
+	arabicFina
+	arabicInit
+	arabicMedi
+)
+
+// arabicFeatures are the GSUB features of the forms of Arabic letters
+var arabicFeatures = [4]string{"isol", "fina", "init", "medi"}
+
+// arabicForms maps Arabic letters to their isolated, final, initial and
+// medial presentation forms, 0 if a letter has no such form
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},                // hamza
+	0x0622: {0xFE81, 0xFE82, 0, 0},           // alef with madda above
+	0x0623: {0xFE83, 0xFE84, 0, 0},           // alef with hamza above
+	0x0624: {0xFE85, 0xFE86, 0, 0},           // waw with hamza above
+	0x0625: {0xFE87, 0xFE88, 0, 0},           // alef with hamza below
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C}, // yeh with hamza above
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},           // alef
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92}, // beh
+	0x0629: {0xFE93, 0xFE94, 0, 0},           // teh marbuta
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98}, // teh
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C}, // theh
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0}, // jeem
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4}, // hah
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8}, // khah
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},           // dal
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},           // thal
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},           // reh
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},           // zain
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4}, // seen
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8}, // sheen
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC}, // sad
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0}, // dad
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4}, // tah
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8}, // zah
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC}, // ain
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0}, // ghain
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4}, // feh
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8}, // qaf
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC}, // kaf
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0}, // lam
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4}, // meem
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8}, // noon
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC}, // heh
+	0x0648: {0xFEED, 0xFEEE, 0, 0},           // waw
+	0x0649: {0xFEEF, 0xFEF0, 0xFBE8, 0xFBE9}, // alef maksura
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4}, // yeh
+	0x0671: {0xFB50, 0xFB51, 0, 0},           // alef wasla
+	0x0677: {0xFBDD, 0, 0, 0},                // u with hamza above
+	0x0679: {0xFB66, 0xFB67, 0xFB68, 0xFB69}, // tteh
+	0x067A: {0xFB5E, 0xFB5F, 0xFB60, 0xFB61}, // tteheh
+	0x067B: {0xFB52, 0xFB53, 0xFB54, 0xFB55}, // beeh
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59}, // peh
+	0x067F: {0xFB62, 0xFB63, 0xFB64, 0xFB65}, // teheh
+	0x0680: {0xFB5A, 0xFB5B, 0xFB5C, 0xFB5D}, // beheh
+	0x0683: {0xFB76, 0xFB77, 0xFB78, 0xFB79}, // nyeh
+	0x0684: {0xFB72, 0xFB73, 0xFB74, 0xFB75}, // dyeh
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D}, // tcheh
+	0x0687: {0xFB7E, 0xFB7F, 0xFB80, 0xFB81}, // tcheheh
+	0x0688: {0xFB88, 0xFB89, 0, 0},           // ddal
+	0x068C: {0xFB84, 0xFB85, 0, 0},           // dahal
+	0x068D: {0xFB82, 0xFB83, 0, 0},           // ddahal
+	0x068E: {0xFB86, 0xFB87, 0, 0},           // dul
+	0x0691: {0xFB8C, 0xFB8D, 0, 0},           // rreh
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},           // jeh
+	0x06A4: {0xFB6A, 0xFB6B, 0xFB6C, 0xFB6D}, // veh
+	0x06A6: {0xFB6E, 0xFB6F, 0xFB70, 0xFB71}, // peheh
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91}, // keheh
+	0x06AD: {0xFBD3, 0xFBD4, 0xFBD5, 0xFBD6}, // ng
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95}, // gaf
+	0x06B1: {0xFB9A, 0xFB9B, 0xFB9C, 0xFB9D}, // ngoeh
+	0x06B3: {0xFB96, 0xFB97, 0xFB98, 0xFB99}, // gueh
+	0x06BA: {0xFB9E, 0xFB9F, 0, 0},           // noon ghunna
+	0x06BB: {0xFBA0, 0xFBA1, 0xFBA2, 0xFBA3}, // rnoon
+	0x06BE: {0xFBAA, 0xFBAB, 0xFBAC, 0xFBAD}, // heh doachashmee
+	0x06C0: {0xFBA4, 0xFBA5, 0, 0},           // heh with yeh above
+	0x06C1: {0xFBA6, 0xFBA7, 0xFBA8, 0xFBA9}, // heh goal
+	0x06C5: {0xFBE0, 0xFBE1, 0, 0},           // kirghiz oe
+	0x06C6: {0xFBD9, 0xFBDA, 0, 0},           // oe
+	0x06C7: {0xFBD7, 0xFBD8, 0, 0},           // u
+	0x06C8: {0xFBDB, 0xFBDC, 0, 0},           // yu
+	0x06C9: {0xFBE2, 0xFBE3, 0, 0},           // kirghiz yu
+	0x06CB: {0xFBDE, 0xFBDF, 0, 0},           // ve
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF}, // farsi yeh
+	0x06D0: {0xFBE4, 0xFBE5, 0xFBE6, 0xFBE7}, // e
+	0x06D2: {0xFBAE, 0xFBAF, 0, 0},           // yeh barree
+	0x06D3: {0xFBB0, 0xFBB1, 0, 0},           // yeh barree with hamza above
+}
+
+// arabicLam is the letter that forms a mandatory ligature with a following
+// alef
+const arabicLam = 0x0644
+
+// arabicLamAlef maps the letters alef to the isolated and final presentation
+// forms of their ligature with lam
+var arabicLamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+// Joining types of characters
+const (
+	joinNone        = iota // does not join
+	joinRight              // joins the preceding letter only
+	joinDual               // joins the preceding and following letters
+	joinCausing            // tatweel and zero width joiner
+	joinTransparent        // marks, which are skipped
+)
+
+// arabicJoining returns the joining type of the rune r. The joining type of
+// a letter is deduced from its presentation forms, so that it only joins
+// where its forms can be shown.
+func arabicJoining(r rune) int {
+	switch {
+	case r == 0x0640 || r == 0x200D:
+		return joinCausing
+	case r == 0x200C:
+		return joinNone
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return joinTransparent
+	}
+	forms := arabicForms[r]
+	switch {
+	case forms[arabicInit] != 0 || forms[arabicMedi] != 0:
+		return joinDual
+	case forms[arabicFina] != 0:
+		return joinRight
+	}
+	return joinNone
+}
+
+// arabicNeeded returns true if runes contains Arabic characters
+func arabicNeeded(runes []rune) bool {
+	for _, r := range runes {
+		if r >= 0x0600 && r <= 0x06FF {
+			return true
+		}
+	}
+	return false
+}
+
+// gsubLookups calls fnc with the type and the position in data of each
+// subtable of the lookup at index j of the lookup list of a GSUB table
+func gsubLookups(data []byte, j int, fnc func(tp, pos int)) {
+	lookupList := kernUint16(data, 8)
+	lookup := lookupList + kernUint16(data, lookupList+2+j*2)
+	tp := kernUint16(data, lookup)
+	for k, m := 0, kernUint16(data, lookup+4); k < m; k++ {
+		sub := lookup + kernUint16(data, lookup+6+k*2)
+		subTp := tp
+		if tp == 7 {
+			// Extension subtable
+			subTp = kernUint16(data, sub+2)
+			sub += kernUint32(data, sub+4)
+		}
+		fnc(subTp, sub)
+	}
+}
+
+// gsubFeatureLookups returns the indices of the lookups of the features of a
+// GSUB table that have one of the tags, in the order of the lookup list
+func gsubFeatureLookups(data []byte, tags ...string) (list []int) {
+	featureList, lookupList := kernUint16(data, 6), kernUint16(data, 8)
+	if featureList == 0 || lookupList == 0 {
+		return
+	}
+	lookups := make(map[int]bool)
+	for j, n := 0, kernUint16(data, featureList); j < n; j++ {
+		rec := featureList + 2 + j*6
+		if rec+4 > len(data) {
+			break
+		}
+		for _, tag := range tags {
+			if string(data[rec:rec+4]) == tag {
+				feature := featureList + kernUint16(data, rec+4)
+				for k, m := 0, kernUint16(data, feature+2); k < m; k++ {
+					lookups[kernUint16(data, feature+4+k*2)] = true
+				}
+			}
+		}
+	}
+	for j, n := 0, kernUint16(data, lookupList); j < n; j++ {
+		if lookups[j] {
+			list = append(list, j)
+		}
+	}
+	return
+}
+
+// gsubSingle reads the single substitutions of the lookups of a GSUB table.
+// It returns the substitutes of the glyphs of each lookup, in which the first
+// subtable that covers a glyph applies.
+func gsubSingle(data []byte, lookups []int) (subst []map[int]int) {
+	for _, j := range lookups {
+		m := make(map[int]int)
+		gsubLookups(data, j, func(tp, pos int) {
+			if tp != 1 {
+				return
+			}
+			format := kernUint16(data, pos)
+			for g, index := range kernCoverage(data, pos+kernUint16(data, pos+2)) {
+				if _, ok := m[g]; ok {
+					continue
+				}
+				switch format {
+				case 1:
+					m[g] = (g + kernInt16(data, pos+4)) & 0xFFFF
+				case 2:
+					if index < kernUint16(data, pos+4) {
+						m[g] = kernUint16(data, pos+6+index*2)
+					}
+				}
+			}
+		})
+		subst = append(subst, m)
+	}
+	return
+}
+
+// gsubLigatures reads the ligatures of two glyphs of the lookups of a GSUB
+// table. The key of a ligature is made of its first and second glyphs.
+func gsubLigatures(data []byte, lookups []int) map[uint32]int {
+	ligatures := make(map[uint32]int)
+	for _, j := range lookups {
+		gsubLookups(data, j, func(tp, pos int) {
+			if tp != 4 || kernUint16(data, pos) != 1 {
+				return
+			}
+			for g, index := range kernCoverage(data, pos+kernUint16(data, pos+2)) {
+				if index >= kernUint16(data, pos+4) {
+					continue
+				}
+				set := pos + kernUint16(data, pos+6+index*2)
+				for k, m := 0, kernUint16(data, set); k < m; k++ {
+					lig := set + kernUint16(data, set+2+k*2)
+					if kernUint16(data, lig+2) != 2 {
+						continue
+					}
+					key := uint32(g)<<16 | uint32(kernUint16(data, lig+4))
+					if _, ok := ligatures[key]; !ok {
+						ligatures[key] = kernUint16(data, lig)
+					}
+				}
+			}
+		})
+	}
+	return ligatures
+}
+
+// parseShaping reads the glyphs of the forms of Arabic letters from the isol,
+// fina, init and medi features of the GSUB table of the font, and those of
+// the lam-alef ligatures from its rlig and liga features. They are assigned
+// to the presentation forms of the letters and ligatures, which the cmap
+// table may lack or map to other glyphs, so that shaped text is made of
+// runes like any other text.
+func (utf *utf8FontFile) parseShaping(symbolCharDictionary map[int][]int) {
+	gsub := utf.getTableData("GSUB")
+	if gsub == nil {
+		return
+	}
+	var subst [4][]map[int]int
+	for form, tag := range arabicFeatures {
+		subst[form] = gsubSingle(gsub, gsubFeatureLookups(gsub, tag))
+	}
+	// formGlyph returns the glyph of the form of the letter r and whether it
+	// differs from the nominal glyph of r
+	formGlyph := func(r rune, form int) (int, bool) {
+		g := utf.charSymbolDictionary[int(r)]
+		if g == 0 {
+			return 0, false
+		}
+		s := g
+		for _, m := range subst[form] {
+			if sub, ok := m[s]; ok {
+				s = sub
+			}
+		}
+		return s, s != g
+	}
+	utf.shapedGlyphs = make(map[int]int)
+	for r, forms := range arabicForms {
+		for form, pf := range forms {
+			if g, ok := formGlyph(r, form); ok && pf != 0 {
+				utf.shapedGlyphs[int(pf)] = g
+			}
+		}
+	}
+	ligatures := gsubLigatures(gsub, gsubFeatureLookups(gsub, "rlig", "liga"))
+	for alef, ligs := range arabicLamAlef {
+		alefNominal := utf.charSymbolDictionary[int(alef)]
+		alefFina, _ := formGlyph(alef, arabicFina)
+		for j, form := range []int{arabicInit, arabicMedi} {
+			lam, _ := formGlyph(arabicLam, form)
+			lamNominal := utf.charSymbolDictionary[arabicLam]
+			for _, pair := range [][2]int{{lam, alefFina}, {lam, alefNominal}, {lamNominal, alefNominal}} {
+				if g, ok := ligatures[uint32(pair[0])<<16|uint32(pair[1])]; ok && pair[0] != 0 && pair[1] != 0 {
+					utf.shapedGlyphs[int(ligs[j])] = g
+					break
+				}
+			}
+		}
+	}
+	utf.addShapedGlyphs(symbolCharDictionary)
+}
+
+// addShapedGlyphs maps the presentation forms read from the GSUB table to
+// their glyphs, in place of the glyphs of the cmap table
+func (utf *utf8FontFile) addShapedGlyphs(symbolCharDictionary map[int][]int) {
+	for r, g := range utf.shapedGlyphs {
+		if old, ok := utf.charSymbolDictionary[r]; ok {
+			chars := symbolCharDictionary[old]
+			for j, c := range chars {
+				if c == r {
+					symbolCharDictionary[old] = append(chars[:j:j], chars[j+1:]...)
+					break
+				}
+			}
+		}
+		utf.charSymbolDictionary[r] = g
+		symbolCharDictionary[g] = append(symbolCharDictionary[g], r)
+	}
+}
+
+// hasShape returns true if the rune r, a presentation form, can be shown
+// with the current font or one of its fallbacks
+func (f *Fpdf) hasShape(r rune) bool {
+	if f.currentFont.utf8File.hasRune(r) {
+		return true
+	}
+	_, ok := f.fallbackFont(r)
+	return ok
+}
+
+// shapeRunes returns the runes of UTF-8 text, in logical order, with the
+// Arabic letters replaced by the presentation forms that fit their context
+// and lam followed by alef replaced by their ligature. Forms that neither the
+// current font nor its fallbacks have are left as they are.
+func (f *Fpdf) shapeRunes(runes []rune) []rune {
+	if !f.isCurrentUTF8 || !arabicNeeded(runes) {
+		return runes
+	}
+	// joining returns the joining type of the first rune from pos in the
+	// direction step that is not transparent
+	joining := func(pos, step int) (int, int) {
+		for ; pos >= 0 && pos < len(runes); pos += step {
+			if jt := arabicJoining(runes[pos]); jt != joinTransparent {
+				return jt, pos
+			}
+		}
+		return joinNone, pos
+	}
+	shaped := make([]rune, 0, len(runes))
+	for j := 0; j < len(runes); j++ {
+		r := runes[j]
+		forms, ok := arabicForms[r]
+		if !ok {
+			shaped = append(shaped, r)
+			continue
+		}
+		jt := arabicJoining(r)
+		prev, _ := joining(j-1, -1)
+		next, k := joining(j+1, 1)
+		joinPrev := (jt == joinRight || jt == joinDual) && (prev == joinDual || prev == joinCausing)
+		joinNext := jt == joinDual && (next == joinRight || next == joinDual || next == joinCausing)
+		if r == arabicLam && k < len(runes) {
+			if ligs, ok := arabicLamAlef[runes[k]]; ok {
+				lig := ligs[0]
+				if joinPrev {
+					lig = ligs[1]
+				}
+				if f.hasShape(lig) {
+					// The marks of lam follow the ligature
+					shaped = append(append(shaped, lig), runes[j+1:k]...)
+					j = k
+					continue
+				}
+			}
+		}
+		form := arabicIsol
+		switch {
+		case joinPrev && joinNext:
+			form = arabicMedi
+		case joinPrev:
+			form = arabicFina
+		case joinNext:
+			form = arabicInit
+		}
+		if pf := forms[form]; pf != 0 && f.hasShape(pf) {
+			r = pf
+		}
+		shaped = append(shaped, r)
+	}
+	return shaped
+}
+
+// shapeText returns the UTF-8 string txtStr with its Arabic letters shaped.
+// See shapeRunes().
+func (f *Fpdf) shapeText(txtStr string) string {
+	runes := []rune(txtStr)
+	if !f.isCurrentUTF8 || !arabicNeeded(runes) {
+		return txtStr
+	}
+	return string(f.shapeRunes(runes))
+}
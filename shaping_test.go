@@ -0,0 +1,183 @@
+package gofpdf_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// ttfTable returns the table directory entry and the data of the table of
+// the TrueType font ttf with the tag
+func ttfTable(ttf []byte, tag string) (rec, data []byte) {
+	for j, n := 0, int(binary.BigEndian.Uint16(ttf[4:])); j < n; j++ {
+		rec = ttf[12+j*16 : 28+j*16]
+		if string(rec[:4]) == tag {
+			pos, size := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+			return rec, ttf[pos : pos+size]
+		}
+	}
+	return nil, nil
+}
+
+// ttfWithoutPresentationForms removes the Arabic presentation forms from the
+// format 4 subtables of the cmap table of the TrueType font ttf, by emptying
+// the segments that contain them
+func ttfWithoutPresentationForms(ttf []byte) {
+	_, cmap := ttfTable(ttf, "cmap")
+	for j, n := 0, int(binary.BigEndian.Uint16(cmap[2:])); j < n; j++ {
+		sub := cmap[binary.BigEndian.Uint32(cmap[8+j*8:]):]
+		if binary.BigEndian.Uint16(sub) != 4 {
+			continue
+		}
+		segX2 := int(binary.BigEndian.Uint16(sub[6:]))
+		for k := 0; k < segX2; k += 2 {
+			end := binary.BigEndian.Uint16(sub[14+k:])
+			start := sub[16+segX2+k:]
+			if end >= 0xFB50 && end != 0xFFFF && binary.BigEndian.Uint16(start) <= 0xFEFC {
+				binary.BigEndian.PutUint16(start, end+1)
+			}
+		}
+	}
+}
+
+// arabicPdf returns a document whose current font is DejaVu or, if patch
+// is not nil, DejaVu modified by patch
+func arabicPdf(t *testing.T, patch func(ttf []byte)) *gofpdf.Fpdf {
+	t.Helper()
+	ttf, err := ioutil.ReadFile("font/DejaVuSansCondensed.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch != nil {
+		patch(ttf)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("dejavu", "", ttf)
+	pdf.SetFont("dejavu", "", 12)
+	pdf.AddPage()
+	pdf.RTL()
+	return pdf
+}
+
+func TestArabicShaping(t *testing.T) {
+	for _, test := range []struct {
+		txt, visual string
+	}{
+		{"سلام", "ﻡﻼﺳ"},
+		{"مرحبا بالعالم", "ﻢﻟﺎﻌﻟﺎﺑ ﺎﺒﺣﺮﻣ"},
+		{"لا", "ﻻ"},
+		{"لَا", "َﻻ"},
+		{"بـ", "ـﺑ"},
+		{"ب‌ب", "ﺏ‌ﺏ"},
+		{"پیام 12", "12 ﻡﺎﯿﭘ"},
+		{"ﺑ", "ﺑ"},
+	} {
+		pdf := arabicPdf(t, nil)
+		pdf.CellFormat(0, 10, test.txt, "", 1, "", false, 0, "")
+		if err := pdf.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if lines := bidiLines(t, pdf); len(lines) != 1 || lines[0] != test.visual {
+			t.Errorf("%q: displayed as %q, expected %q", test.txt, lines, test.visual)
+		}
+	}
+
+	// Wrapping takes the widths of the forms into account, which are smaller
+	// than those of isolated letters
+	pdf := arabicPdf(t, nil)
+	txt := "سلام سلام"
+	w := pdf.GetStringWidth(txt)
+	if isolated := pdf.GetStringWidth("ﺱﻝﺍﻡ ﺱﻝﺍﻡ"); isolated <= w {
+		t.Errorf("unexpected widths %.2f, %.2f", w, isolated)
+	}
+	if lines := pdf.SplitText(txt, w+2*pdf.GetCellMargin()); len(lines) != 1 {
+		t.Errorf("SplitText returned %q", lines)
+	}
+	if lines := pdf.SplitLines([]byte(txt), w+2*pdf.GetCellMargin()); len(lines) != 1 {
+		t.Errorf("SplitLines returned %q", lines)
+	}
+	pdf.MultiCell(w+2*pdf.GetCellMargin(), 10, txt, "", "R", false)
+	if _, top, _, _ := pdf.GetMargins(); math.Abs(pdf.GetY()-top-10) > 1e-9 {
+		t.Errorf("MultiCell output %.2f lines", (pdf.GetY()-top)/10)
+	}
+}
+
+func TestArabicShapingSources(t *testing.T) {
+	orig, err := ioutil.ReadFile("font/DejaVuSansCondensed.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fo, err := sfnt.Parse(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt, shaped := "بلا", "ﻼﺑ"
+	for _, test := range []struct {
+		name   string
+		patch  func(ttf []byte)
+		visual string
+	}{
+		{"presentation forms", func(ttf []byte) {
+			rec, _ := ttfTable(ttf, "GSUB")
+			copy(rec, "GSUX")
+		}, shaped},
+		{"GSUB", ttfWithoutPresentationForms, shaped},
+		{"none", func(ttf []byte) {
+			rec, _ := ttfTable(ttf, "GSUB")
+			copy(rec, "GSUX")
+			ttfWithoutPresentationForms(ttf)
+		}, "الب"},
+	} {
+		pdf := arabicPdf(t, test.patch)
+		pdf.CellFormat(0, 10, txt, "", 1, "", false, 0, "")
+		if err := pdf.Error(); err != nil {
+			t.Fatal(err)
+		}
+		r := pagesRead(t, pdf)
+		if lines := bidiReaderLines(t, r); len(lines) != 1 || lines[0] != test.visual {
+			t.Errorf("%s: displayed as %q, expected %q", test.name, lines, test.visual)
+			continue
+		}
+		// The subset has the glyphs of the forms, which its cmap table maps
+		// from their CIDs
+		res, _ := r.ResolveDict(mustAttr(t, r, 1, "Resources"))
+		fonts, _ := r.ResolveDict(res["Font"])
+		for _, ref := range fonts {
+			fd, _ := r.ResolveDict(ref)
+			descendants, _ := r.ResolveArray(fd["DescendantFonts"])
+			cid, _ := r.ResolveDict(descendants[0])
+			desc, _ := r.ResolveDict(cid["FontDescriptor"])
+			obj, _ := r.Resolve(desc["FontFile2"])
+			stm, ok := obj.(*pdfreader.Stream)
+			if !ok {
+				t.Fatalf("%s: no embedded font", test.name)
+			}
+			data, err := stm.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs, err := sfnt.Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b sfnt.Buffer
+			for _, c := range test.visual {
+				g, _ := fo.GlyphIndex(&b, c)
+				want, _ := fo.LoadGlyph(&b, g, fixed.I(1000), nil)
+				want = append([]sfnt.Segment{}, want...)
+				g, _ = fs.GlyphIndex(&b, c)
+				got, err := fs.LoadGlyph(&b, g, fixed.I(1000), nil)
+				if err != nil || g == 0 || !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: glyph of %U differs from the original (%v)", test.name, c, err)
+				}
+			}
+		}
+	}
+}
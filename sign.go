@@ -0,0 +1,314 @@
+package gofpdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignatureOptions specifies the digital signature applied to the document
+// by SetSignature.
+type SignatureOptions struct {
+	// Signer holds the private key of the signing certificate. RSA and ECDSA
+	// keys are supported.
+	Signer crypto.Signer
+	// Certificates is the certificate chain, starting with the signing
+	// certificate.
+	Certificates []*x509.Certificate
+	// Name, Reason, Location and ContactInfo are optional informative
+	// entries displayed by viewers.
+	Name        string
+	Reason      string
+	Location    string
+	ContactInfo string
+	// SigningTime is the time of signing. If zero, the current time is used.
+	SigningTime time.Time
+	// FieldName is the name of the signature form field. If empty,
+	// "Signature1" is used.
+	FieldName string
+	// Page is the page on which the signature is displayed. If zero, the
+	// signature is invisible.
+	Page int
+	// X, Y, W and H specify the rectangle of a visible signature on Page.
+	X, Y, W, H float64
+	// ImageName, if not empty, is the name of a registered image drawn in
+	// the signature rectangle.
+	ImageName string
+	// Text, if not empty, is written in the signature rectangle with the
+	// font and text color in effect when SetSignature is called. Lines are
+	// separated with "\n".
+	Text string
+}
+
+type signatureType struct {
+	opt         SignatureOptions
+	ap          []byte // appearance stream of a visible signature
+	objNum      int    // object number of the signature dictionary
+	byteRange   int    // buffer offset of the /ByteRange placeholder
+	contents    int    // buffer offset of the /Contents placeholder
+	contentsLen int    // length in bytes of the /Contents placeholder
+}
+
+const signByteRangePlaceholder = "[0 ********** ********** **********]"
+
+// SetSignature arranges for the document to be digitally signed with a
+// detached PKCS#7 signature when it is closed. The signature covers the
+// entire file and is computed with the key in opt.Signer over a SHA-256
+// digest; opt.Certificates are embedded in the signature. If opt.Page is
+// not zero, the signature is displayed in the rectangle defined by opt.X,
+// opt.Y, opt.W and opt.H on that page, using opt.ImageName and opt.Text. A
+// visible signature must use a page that exists when the document is closed.
+func (f *Fpdf) SetSignature(opt SignatureOptions) {
+	if f.err != nil {
+		return
+	}
+	if opt.Signer == nil || len(opt.Certificates) == 0 {
+		f.err = fmt.Errorf("signature requires a signer and a certificate")
+		return
+	}
+	switch opt.Signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		f.err = fmt.Errorf("unsupported signature key type %T", opt.Signer.Public())
+		return
+	}
+	if opt.FieldName == "" {
+		opt.FieldName = "Signature1"
+	}
+	f.sign = &signatureType{opt: opt}
+	if opt.Page > 0 {
+		f.sign.ap = f.signAppearance(opt.W*f.k, opt.H*f.k)
+	}
+}
+
+// signAppearance returns the appearance stream of a visible signature
+func (f *Fpdf) signAppearance(w, h float64) []byte {
+	var b fmtBuffer
+	opt := f.sign.opt
+	if opt.ImageName != "" {
+		info, ok := f.images[opt.ImageName]
+		if !ok {
+			f.err = fmt.Errorf("signature image %s is not registered", opt.ImageName)
+			return nil
+		}
+		// Scale the image to fit the rectangle, keeping its proportions
+		iw, ih := w, info.h*w/info.w
+		if ih > h {
+			iw, ih = info.w*h/info.h, h
+		}
+		b.printf("q %.2f 0 0 %.2f %.2f %.2f cm /I%s Do Q\n", iw, ih, (w-iw)/2, (h-ih)/2, info.i)
+	}
+	if opt.Text != "" {
+		if f.currentFont.Name == "" {
+			f.err = fmt.Errorf("font must be set before adding signature text")
+			return nil
+		}
+		lines := strings.Split(opt.Text, "\n")
+		lead := f.fontSizePt * 1.15
+		y := h - 2 - 0.9*f.fontSizePt
+		for _, str := range lines {
+			b.printf("BT %s /F%s %.2f Tf 2 %.2f Td (%s) Tj ET\n",
+				f.color.text.str, f.currentFont.i, f.fontSizePt, y, f.formEncode(str))
+			y -= lead
+		}
+	}
+	return b.Bytes()
+}
+
+// signPutDict writes the signature dictionary with placeholders for the
+// byte range and the signature, and adds the signature field to the
+// document
+func (f *Fpdf) signPutDict() {
+	if f.sign == nil {
+		return
+	}
+	opt := f.sign.opt
+	if opt.Page > f.page {
+		f.err = fmt.Errorf("signature page %d does not exist", opt.Page)
+		return
+	}
+	size := 1024
+	for _, cert := range opt.Certificates {
+		size += len(cert.Raw)
+	}
+	switch pub := opt.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		size += pub.Size()
+	default:
+		size += 256
+	}
+	f.sign.contentsLen = 2 * size
+	tm := timeOrNow(opt.SigningTime)
+	f.sign.opt.SigningTime = tm
+	f.newobj()
+	f.sign.objNum = f.n
+	f.out("<</Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached")
+	f.outf("/M %s", f.textstring(pdfDateZone(tm)))
+	if opt.Name != "" {
+		f.outf("/Name %s", f.textstring(utf8toutf16(opt.Name)))
+	}
+	if opt.Reason != "" {
+		f.outf("/Reason %s", f.textstring(utf8toutf16(opt.Reason)))
+	}
+	if opt.Location != "" {
+		f.outf("/Location %s", f.textstring(utf8toutf16(opt.Location)))
+	}
+	if opt.ContactInfo != "" {
+		f.outf("/ContactInfo %s", f.textstring(utf8toutf16(opt.ContactInfo)))
+	}
+	f.buffer.WriteString("/ByteRange ")
+	f.sign.byteRange = f.buffer.Len()
+	f.out(signByteRangePlaceholder)
+	f.buffer.WriteString("/Contents ")
+	f.sign.contents = f.buffer.Len()
+	f.out("<" + strings.Repeat("0", f.sign.contentsLen) + ">>>")
+	f.out("endobj")
+
+	fld := &formField{ft: "Sig", name: opt.FieldName, ref: f.sign.objNum}
+	if f.form.names[fld.name] {
+		f.err = fmt.Errorf("form field %s is already defined", fld.name)
+		return
+	}
+	f.form.fields = append(f.form.fields, fld)
+	// An invisible signature has an empty rectangle on the first page
+	wd := &formWidget{field: fld}
+	page := 1
+	if opt.Page > 0 {
+		page = opt.Page
+		var hPt float64
+		if sz, ok := f.pageSizes[page]; ok {
+			hPt = sz.Ht
+		} else if f.defOrientation == "P" {
+			hPt = f.defPageSize.Ht * f.k
+		} else {
+			hPt = f.defPageSize.Wd * f.k
+		}
+		wd.x, wd.y = opt.X*f.k, hPt-(opt.Y+opt.H)*f.k
+		wd.w, wd.h = opt.W*f.k, opt.H*f.k
+	}
+	wd.ap = []formAppearance{{content: f.sign.ap}}
+	f.pageWidgets[page] = append(f.pageWidgets[page], wd)
+}
+
+// derTLV returns the DER encoding of a value with the specified tag and the
+// concatenation of content as contents
+func derTLV(tag byte, content ...[]byte) []byte {
+	var body []byte
+	for _, c := range content {
+		body = append(body, c...)
+	}
+	n := len(body)
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	case n < 0x10000:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+func derMarshal(val interface{}) []byte {
+	b, _ := asn1.Marshal(val)
+	return b
+}
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// pkcs7Sign returns a detached CMS SignedData structure (RFC 5652) for the
+// specified SHA-256 digest
+func pkcs7Sign(digest []byte, opt SignatureOptions) ([]byte, error) {
+	asnNull := []byte{0x05, 0x00}
+	algSHA256 := derTLV(0x30, derMarshal(oidSHA256), asnNull)
+	var algSig []byte
+	if _, ok := opt.Signer.Public().(*rsa.PublicKey); ok {
+		algSig = derTLV(0x30, derMarshal(oidRSA), asnNull)
+	} else {
+		algSig = derTLV(0x30, derMarshal(oidECDSASHA256))
+	}
+	// Signed attributes, sorted as required for a DER encoded SET OF
+	attrs := [][]byte{
+		derTLV(0x30, derMarshal(oidContentType), derTLV(0x31, derMarshal(oidData))),
+		derTLV(0x30, derMarshal(oidSigningTime), derTLV(0x31, derMarshal(opt.SigningTime.UTC()))),
+		derTLV(0x30, derMarshal(oidMessageDigest), derTLV(0x31, derTLV(0x04, digest))),
+	}
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	attrDigest := sha256.Sum256(derTLV(0x31, attrs...))
+	sig, err := opt.Signer.Sign(rand.Reader, attrDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	cert := opt.Certificates[0]
+	signerInfo := derTLV(0x30,
+		derMarshal(1),
+		derTLV(0x30, cert.RawIssuer, derMarshal(cert.SerialNumber)),
+		algSHA256,
+		derTLV(0xa0, attrs...),
+		algSig,
+		derTLV(0x04, sig))
+	var certs [][]byte
+	for _, c := range opt.Certificates {
+		certs = append(certs, c.Raw)
+	}
+	signedData := derTLV(0x30,
+		derMarshal(1),
+		derTLV(0x31, algSHA256),
+		derTLV(0x30, derMarshal(oidData)),
+		derTLV(0xa0, certs...),
+		derTLV(0x31, signerInfo))
+	return derTLV(0x30, derMarshal(oidSignedData), derTLV(0xa0, signedData)), nil
+}
+
+// signDocument fills in the byte range and signature placeholders of the
+// completed document
+func (f *Fpdf) signDocument() {
+	if f.sign == nil || f.err != nil {
+		return
+	}
+	buf := f.buffer.Bytes()
+	start := f.sign.contents
+	end := start + f.sign.contentsLen + 2 // including angle brackets
+	byteRange := sprintf("[0 %d %d %d", start, end, len(buf)-end)
+	byteRange += strings.Repeat(" ", len(signByteRangePlaceholder)-len(byteRange)-1) + "]"
+	if len(byteRange) != len(signByteRangePlaceholder) {
+		f.err = fmt.Errorf("signature byte range does not fit placeholder")
+		return
+	}
+	copy(buf[f.sign.byteRange:], byteRange)
+	h := sha256.New()
+	h.Write(buf[:start])
+	h.Write(buf[end:])
+	sig, err := pkcs7Sign(h.Sum(nil), f.sign.opt)
+	if err != nil {
+		f.err = err
+		return
+	}
+	if 2*len(sig) > f.sign.contentsLen {
+		f.err = fmt.Errorf("signature does not fit placeholder")
+		return
+	}
+	hex.Encode(buf[start+1:], sig)
+}
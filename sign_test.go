@@ -0,0 +1,152 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+// selfSignedCert returns a throwaway RSA key and certificate for signing
+func selfSignedCert(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "gofpdf test signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+type testContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type testSignerInfo struct {
+	Version   int
+	SID       asn1.RawValue
+	DigestAlg asn1.RawValue
+	Attrs     asn1.RawValue `asn1:"tag:0"`
+	SigAlg    asn1.RawValue
+	Signature []byte
+}
+
+type testSignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue    `asn1:"optional,tag:0"`
+	SignerInfos      []testSignerInfo `asn1:"set"`
+}
+
+func TestSetSignature(t *testing.T) {
+	key, cert := selfSignedCert(t)
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.Cell(40, 10, "Signed document")
+	pdf.SetSignature(gofpdf.SignatureOptions{
+		Signer:       key,
+		Certificates: []*x509.Certificate{cert},
+		Reason:       "Approval",
+		SigningTime:  time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 5*3600+30*60)),
+		Page:         1,
+		X:            20, Y: 40, W: 60, H: 20,
+		Text: "Signed by\ngofpdf test signer",
+	})
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	b := buf.Bytes()
+
+	var r [4]int
+	pos := bytes.Index(b, []byte("/ByteRange ["))
+	if pos < 0 {
+		t.Fatalf("byte range not found")
+	}
+	if _, err := fmt.Sscanf(string(b[pos+len("/ByteRange ["):]), "%d %d %d %d", &r[0], &r[1], &r[2], &r[3]); err != nil {
+		t.Fatalf("unable to parse byte range: %v", err)
+	}
+	if r[0] != 0 || r[2]+r[3] != len(b) || b[r[1]] != '<' || b[r[2]-1] != '>' {
+		t.Fatalf("byte range %v does not match document of length %d", r, len(b))
+	}
+	sig, err := hex.DecodeString(string(b[r[1]+1 : r[2]-1]))
+	if err != nil {
+		t.Fatalf("unable to decode signature: %v", err)
+	}
+	digest := sha256.New()
+	digest.Write(b[:r[1]])
+	digest.Write(b[r[2]:])
+
+	var ci testContentInfo
+	if _, err = asn1.Unmarshal(sig, &ci); err != nil {
+		t.Fatalf("unable to parse content info: %v", err)
+	}
+	var sd testSignedData
+	if _, err = asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatalf("unable to parse signed data: %v", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		t.Fatalf("expecting one signer, got %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	if !bytes.Contains(si.Attrs.Bytes, digest.Sum(nil)) {
+		t.Errorf("signed attributes do not contain document digest")
+	}
+	// The signature covers the attributes encoded as a SET
+	attrs := append([]byte{0x31}, si.Attrs.FullBytes[1:]...)
+	attrDigest := sha256.Sum256(attrs)
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, attrDigest[:], si.Signature); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	for _, s := range []string{"/FT /Sig", "/SigFlags 3", "/SubFilter /adbe.pkcs7.detached",
+		"/M (D:20240506070809+05'30')"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+}
+
+func TestSetSignatureErrors(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetSignature(gofpdf.SignatureOptions{})
+	if pdf.Error() == nil {
+		t.Errorf("expecting error for signature without signer")
+	}
+
+	key, cert := selfSignedCert(t)
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetSignature(gofpdf.SignatureOptions{Signer: key,
+		Certificates: []*x509.Certificate{cert}, Page: 3, W: 10, H: 10})
+	if err := pdf.Output(&bytes.Buffer{}); err == nil {
+		t.Errorf("expecting error for signature on missing page")
+	}
+}
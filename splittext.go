@@ -10,10 +10,15 @@ import (
 // font. Each line has its length limited to a maximum width given by w. This
 // function can be used to determine the total height of wrapped text for
 // vertical placement purposes.
+//
+// Lines are returned in logical order. Bidirectional text is reordered when
+// each line is output with CellFormat(); see SetTextDirection(). Arabic
+// letters are replaced by the presentation forms that fit their context, whose
+// widths are used to wrap the text.
 func (f *Fpdf) SplitText(txt string, w float64) (lines []string) {
 	cw := f.currentFont.Cw
 	wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
-	s := []rune(txt) // Return slice of UTF-8 runes
+	s := f.shapeRunes([]rune(txt)) // Return slice of UTF-8 runes
 	nb := len(s)
 	for nb > 0 && s[nb-1] == '\n' {
 		nb--
@@ -25,7 +30,14 @@ func (f *Fpdf) SplitText(txt string, w float64) (lines []string) {
 	l := 0
 	for i < nb {
 		c := s[i]
-		l += cw[c]
+		if fw, ok := f.fallbackWidth(c); ok {
+			l += fw
+		} else {
+			l += cw[c]
+		}
+		if i > j {
+			l += f.kern(s[i-1], c)
+		}
 		if unicode.IsSpace(c) || isChinese(c) {
 			sep = i
 		}
@@ -180,5 +180,6 @@ func (f *Fpdf) spotColorPutResourceDict() {
 	for _, clr := range f.spotColorMap {
 		f.outf("/CS%d %d 0 R", clr.id, clr.objID)
 	}
+	f.patternPutColorSpaces()
 	f.out(">>")
 }
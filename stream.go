@@ -0,0 +1,106 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// streamType holds the state of the streaming output mode
+type streamType struct {
+	w        io.Writer // destination of the document
+	written  int       // number of bytes written to w
+	version  string    // PDF version of the file header, empty until written
+	contents []int     // 1-based array of content stream object numbers (per page)
+}
+
+// SetOutputStream enables streaming output, for documents too large to be
+// held in memory. The document is written to w as it is generated: the
+// content of each page is written when the next page is added, together with
+// the images used so far, and the page dictionaries, fonts and
+// cross-reference table are written when Close() is called. Close() must be
+// used to complete the document instead of Output() and its variants; Err()
+// then reports any error, including write errors. This method must be called
+// before the first page is added.
+//
+// Since the content of a page is not kept once it is written, SetPage() cannot
+// return to an earlier page and AliasNbPages() is not supported, as the total
+// number of pages is not known in advance. Aliases registered with
+// RegisterAlias() and AliasPageLabel() are substituted with the values known
+// when each page is written. Object streams and digital signatures cannot be
+// used in this mode. If a feature used after the header is written requires a
+// later PDF version, the version is declared in the document catalog.
+func (f *Fpdf) SetOutputStream(w io.Writer) {
+	if f.err != nil {
+		return
+	}
+	if f.page > 0 {
+		f.err = fmt.Errorf("output stream must be set before the first page is added")
+		return
+	}
+	f.stream = &streamType{w: w, contents: []int{0}}
+	f.streamCheck()
+}
+
+// streamCheck verifies that the features in use are compatible with the
+// streaming output mode
+func (f *Fpdf) streamCheck() {
+	switch {
+	case f.stream == nil:
+	case f.aliasNbPagesStr != "":
+		f.SetErrorf("alias for the number of pages cannot be used with streaming output")
+	case f.objStm.enabled:
+		f.SetErrorf("object streams cannot be used with streaming output")
+	case f.sign != nil:
+		f.SetErrorf("digital signature cannot be used with streaming output")
+	}
+}
+
+// offset returns the position in the document of the next byte written to
+// the document buffer
+func (f *Fpdf) offset() int {
+	if f.stream != nil {
+		return f.stream.written + f.buffer.Len()
+	}
+	return f.buffer.Len()
+}
+
+// streamPutPage writes the content stream of the current page and the images
+// registered so far to the output stream, and releases the page content
+func (f *Fpdf) streamPutPage() {
+	if f.stream == nil || f.page == 0 || f.err != nil {
+		return
+	}
+	f.streamCheck()
+	if f.err != nil {
+		return
+	}
+	f.putheader()
+	f.replacePageAliases(f.page)
+	f.replacePageLabelAlias(f.page)
+	f.putpagecontent(f.page)
+	f.stream.contents = append(f.stream.contents, f.n)
+	f.pages[f.page] = new(bytes.Buffer)
+	f.putimages()
+	f.streamFlush()
+}
+
+// streamFlush writes the document buffer to the output stream
+func (f *Fpdf) streamFlush() {
+	if f.stream == nil || f.err != nil {
+		return
+	}
+	n, err := f.buffer.WriteTo(f.stream.w)
+	f.stream.written += int(n)
+	if err != nil {
+		f.err = err
+	}
+}
+
+// streamPutVersion declares in the document catalog the PDF version required
+// by features used after the header was written to the output stream
+func (f *Fpdf) streamPutVersion() {
+	if f.stream != nil && f.pdfVersion > f.stream.version {
+		f.outf("/Version /%s", f.pdfVersion)
+	}
+}
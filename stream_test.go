@@ -0,0 +1,150 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/internal/example"
+)
+
+// streamXref verifies that the cross-reference table of pdf points to the
+// objects it lists and returns the number of entries
+func streamXref(t *testing.T, pdf []byte) int {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatalf("startxref missing")
+	}
+	off, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[off:], []byte("xref\n0 ")) {
+		t.Fatalf("no cross-reference table at offset %d", off)
+	}
+	lines := strings.Split(string(pdf[off:]), "\n")
+	size, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for n := 1; n < size; n++ {
+		pos, _ := strconv.Atoi(lines[2+n][:10])
+		if !bytes.HasPrefix(pdf[pos:], []byte(strconv.Itoa(n)+" 0 obj\n")) {
+			t.Fatalf("offset of object %d is wrong", n)
+		}
+	}
+	return size
+}
+
+func TestSetOutputStream(t *testing.T) {
+	var buf bytes.Buffer
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetOutputStream(&buf)
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.SetFont("dejavu", "", 12)
+	pdf.AliasPageLabel("")
+	pdf.SetPageLabel(1, "r", "", 1)
+	last := pdf.AddLink()
+	var written []int
+	for j := 1; j <= 5; j++ {
+		pdf.AddPage()
+		written = append(written, buf.Len())
+		pdf.Cell(40, 10, "Page {pl} – Straße")
+		pdf.Image(example.ImageFile("logo.png"), 10, 30, 30, 0, false, "", 0, "")
+		pdf.Link(10, 50, 30, 10, last)
+		pdf.Bookmark("Page "+strconv.Itoa(j), 0, 0)
+	}
+	pdf.SetLink(last, 0, -1)
+	pdf.Close()
+	if err := pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if written[0] != 0 || written[1] == 0 || written[4] <= written[3] {
+		t.Fatalf("pages are not written as they are completed: %v", written)
+	}
+	out := buf.Bytes()
+	streamXref(t, out)
+	if n := bytes.Count(out, []byte("/Subtype /Image")); n != 1 {
+		t.Errorf("image written %d times", n)
+	}
+	if !bytes.Contains(out, []byte("(ii)")) && !bytes.Contains(out, []byte("\x00i\x00i")) {
+		t.Errorf("page label alias not substituted")
+	}
+	m := regexp.MustCompile(`/Kids \[(\d+) 0 R (\d+) 0 R (\d+) 0 R (\d+) 0 R (\d+) 0 R \]`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("page tree missing")
+	}
+	first, _ := strconv.Atoi(string(m[1]))
+	lastPage, _ := strconv.Atoi(string(m[5]))
+	if lastPage != first+4 {
+		t.Fatalf("page objects are not consecutive: %s", m[0])
+	}
+	if !bytes.Contains(out, []byte("/Dest ["+string(m[5])+" 0 R /XYZ 0 ")) {
+		t.Errorf("link to the last page not found")
+	}
+}
+
+func TestOutputStreamVersion(t *testing.T) {
+	var buf bytes.Buffer
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetOutputStream(&buf)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Opaque")
+	pdf.AddPage()
+	pdf.SetAlpha(0.5, "Normal")
+	pdf.Cell(40, 10, "Transparent")
+	pdf.Close()
+	if err := pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.3")) {
+		t.Fatalf("unexpected header %q", out[:8])
+	}
+	if !bytes.Contains(out, []byte("/Version /1.4")) {
+		t.Errorf("catalog does not declare the version")
+	}
+	streamXref(t, out)
+}
+
+func TestOutputStreamErrors(t *testing.T) {
+	var buf bytes.Buffer
+	tests := []struct {
+		name string
+		fn   func(pdf *gofpdf.Fpdf)
+		err  string
+	}{
+		{"late", func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.SetOutputStream(&buf)
+		}, "before the first page"},
+		{"setpage", func(pdf *gofpdf.Fpdf) {
+			pdf.SetOutputStream(&buf)
+			pdf.AddPage()
+			pdf.AddPage()
+			pdf.SetPage(1)
+		}, "page 1 has been written"},
+		{"alias", func(pdf *gofpdf.Fpdf) {
+			pdf.SetOutputStream(&buf)
+			pdf.AliasNbPages("")
+		}, "number of pages"},
+		{"objstm", func(pdf *gofpdf.Fpdf) {
+			pdf.SetOutputStream(&buf)
+			pdf.SetObjectStreams(true)
+			pdf.AddPage()
+			pdf.AddPage()
+		}, "object streams"},
+		{"output", func(pdf *gofpdf.Fpdf) {
+			pdf.SetOutputStream(&buf)
+			pdf.AddPage()
+			pdf.Output(&buf)
+		}, "must be completed with Close"},
+	}
+	for _, tt := range tests {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		tt.fn(pdf)
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package table
 
 import (
 	"fmt"
+
+	"github.com/looksocial/gofpdf"
 )
 
 // shouldFillRow returns true if current row should be filled (for zebra striping)
@@ -121,6 +123,11 @@ func (t *Table) checkPageBreak(requiredHeight float64) bool {
 		// Add new page
 		t.pdf.AddPage()
 
+		// Repeated headers are artifacts in tagged documents; the header
+		// is part of the structure only once
+		t.artifactBegin()
+		t.repeating = t.pdf.GetTagged()
+
 		// Repeat header if enabled - check for custom header callback first
 		if t.CustomRepeatHeader != nil {
 			// Call custom header function which returns new Y position
@@ -130,9 +137,52 @@ func (t *Table) checkPageBreak(requiredHeight float64) bool {
 			t.AddHeader()
 		}
 
+		t.repeating = false
+		t.artifactEnd()
+
 		return true
 	}
 
 	return false
 }
 
+// tagged returns true if structure elements are emitted for the table, that
+// is, if the document is tagged and a repeated header is not being rendered
+func (t *Table) tagged() bool {
+	return t.pdf.GetTagged() && !t.repeating
+}
+
+// tagBegin starts a structure element of the table in a tagged document,
+// starting the Table element itself if needed
+func (t *Table) tagBegin(role string, opt gofpdf.TagOptions) {
+	if !t.tagged() {
+		return
+	}
+	if !t.tagOpen {
+		t.pdf.BeginTag("Table")
+		t.tagOpen = true
+	}
+	t.pdf.BeginTagOptions(role, opt)
+}
+
+// tagEnd ends the structure element started by tagBegin
+func (t *Table) tagEnd() {
+	if t.tagged() {
+		t.pdf.EndTag()
+	}
+}
+
+// artifactBegin marks the following output, such as the borders of spanned
+// cells, as an artifact in a tagged document
+func (t *Table) artifactBegin() {
+	if t.tagged() {
+		t.pdf.BeginArtifact()
+	}
+}
+
+// artifactEnd ends the artifact started by artifactBegin
+func (t *Table) artifactEnd() {
+	if t.tagged() {
+		t.pdf.EndArtifact()
+	}
+}
@@ -3,6 +3,8 @@ package table
 import (
 	"fmt"
 	"math"
+
+	"github.com/looksocial/gofpdf"
 )
 
 // AddHeader renders the table header row using the column definitions and HeaderStyle.
@@ -39,6 +41,7 @@ func (t *Table) AddHeader() {
 	// Render header cells
 	xPos := startX
 	rowHeight := t.getRowHeight()
+	t.tagBegin("TR", gofpdf.TagOptions{})
 
 	for i := 0; i < len(t.Columns); i++ {
 		col := t.Columns[i]
@@ -52,6 +55,7 @@ func (t *Table) AddHeader() {
 			}
 		}
 
+		t.tagBegin("TH", gofpdf.TagOptions{Scope: "Column", ColSpan: col.ColSpan})
 		if col.ColSpan > 1 {
 			// Calculate width for merged cells by summing from current index
 			totalWidth := 0.0
@@ -71,7 +75,9 @@ func (t *Table) AddHeader() {
 			// Advance xPos by single column width
 			xPos += col.Width
 		}
+		t.tagEnd()
 	}
+	t.tagEnd()
 
 	// Move to next line
 	t.pdf.Ln(rowHeight)
@@ -318,6 +324,7 @@ func (t *Table) AddRow(data map[string]interface{}) {
 	// Render cells
 	xPos := startX
 	colIndex := 0
+	t.tagBegin("TR", gofpdf.TagOptions{})
 	for i, col := range t.Columns {
 		// Skip columns that are part of a column span from previous cells
 		if remaining, ok := colSpanTracker[i]; ok && remaining > 0 {
@@ -333,7 +340,9 @@ func (t *Table) AddRow(data map[string]interface{}) {
 				border = "R"
 			}
 			t.pdf.SetXY(xPos, currentY)
+			t.artifactBegin()
 			t.pdf.CellFormat(col.Width, baseRowHeight, "", border, 0, "", false, 0, "")
+			t.artifactEnd()
 			xPos += col.Width
 			colIndex++
 			continue
@@ -384,7 +393,9 @@ func (t *Table) AddRow(data map[string]interface{}) {
 				border = "R"
 			}
 			t.pdf.SetXY(xPos, currentY)
+			t.artifactBegin()
 			t.pdf.CellFormat(col.Width, baseRowHeight, "", border, 0, "", false, 0, "")
+			t.artifactEnd()
 			xPos += col.Width
 			colIndex++
 			continue
@@ -435,6 +446,7 @@ func (t *Table) AddRow(data map[string]interface{}) {
 		t.pdf.SetXY(xPos, currentY)
 		fill := t.shouldFillRow()
 		border := t.DataStyle.Border
+		t.tagBegin("TD", gofpdf.TagOptions{RowSpan: cellRowSpan, ColSpan: cellColSpan})
 
 		// Calculate total height for row span
 		// IMPORTANT: When a cell has a row span, we should use the ORIGINAL rowHeight
@@ -609,6 +621,8 @@ func (t *Table) AddRow(data map[string]interface{}) {
 			}
 		}
 
+		t.tagEnd()
+
 		// Advance xPos by the cell width (or total width if column span)
 		if cellColSpan > 1 {
 			for j := 0; j < cellColSpan && (i+j) < len(t.Columns); j++ {
@@ -619,6 +633,7 @@ func (t *Table) AddRow(data map[string]interface{}) {
 		}
 		colIndex++
 	}
+	t.tagEnd()
 
 	// Move to next line based on the actual row height
 	// Use baseRowHeight for the current row (which accounts for nested tables)
@@ -698,12 +713,15 @@ func (t *Table) AddSummaryRow(label string, labelSpan int, totals map[string]int
 	}
 
 	// Render label cell spanning multiple columns
+	t.tagBegin("TR", gofpdf.TagOptions{})
 	t.pdf.SetXY(xPos, currentY)
 	border := style.Border
 	if border == "" {
 		border = t.DataStyle.Border
 	}
+	t.tagBegin("TD", gofpdf.TagOptions{ColSpan: labelSpan})
 	t.pdf.CellFormat(labelWidth, rowHeight, label, border, 0, "L", false, 0, "")
+	t.tagEnd()
 	xPos += labelWidth
 	colIndex = labelSpan
 
@@ -722,12 +740,15 @@ func (t *Table) AddSummaryRow(label string, labelSpan int, totals map[string]int
 		}
 
 		t.pdf.SetXY(xPos, currentY)
+		t.tagBegin("TD", gofpdf.TagOptions{})
 		t.pdf.CellFormat(t.Columns[i].Width, rowHeight, value, border, 0,
 			t.getAlignStr(align), false, 0, "")
+		t.tagEnd()
 
 		xPos += t.Columns[i].Width
 		colIndex++
 	}
+	t.tagEnd()
 
 	t.pdf.Ln(rowHeight + t.Spacing)
 }
@@ -815,6 +836,24 @@ func (t *Table) Render(headers bool, data []map[string]interface{}) {
 		// Clear StartY after rendering all rows
 		t.StartY = 0
 	}
+
+	t.Finish()
+}
+
+// Finish ends the Table structure element of a tagged document.
+//
+// When tagging is enabled on the document (see gofpdf.Fpdf.SetTagged), the
+// header and data rows are tagged with TR, TH and TD structure elements
+// inside a Table element, which is started by the first row and remains
+// open until Finish is called. Render calls Finish automatically; call it
+// after the last row when the table is built with AddHeader and AddRow.
+//
+// Finish has no effect if the document is not tagged.
+func (t *Table) Finish() {
+	if t.tagOpen {
+		t.tagOpen = false
+		t.pdf.EndTag()
+	}
 }
 
 // calculateNestedTableHeight calculates the total height required to render a nested table,
@@ -1035,6 +1074,9 @@ func (t *Table) renderNestedTable(nestedTable *Table, startX, startY, maxWidth,
 		}
 	}
 
+	// End the nested Table structure element inside the parent cell
+	nestedTable.Finish()
+
 	// Restore nested table's original StartX/StartY and spacing after all rows are rendered
 	nestedTable.StartX = originalStartX
 	nestedTable.StartY = originalStartY
@@ -1574,4 +1574,63 @@ func TestTableWithPageBreakMode(t *testing.T) {
 	if !tbl.PageBreakMode {
 		t.Error("Expected PageBreakMode to be true after WithPageBreakMode(true)")
 	}
-}
\ No newline at end of file
+}
+
+// TestTableTagging tests the structure elements of a table in a tagged document
+func TestTableTagging(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetTagged(true)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 12)
+
+	columns := []Column{
+		{Key: "id", Label: "ID", Width: 20},
+		{Key: "name", Label: "Name", Width: 60},
+	}
+	tbl := NewTable(pdf, columns)
+	var data []map[string]interface{}
+	for i := 0; i < 80; i++ {
+		data = append(data, map[string]interface{}{"id": i, "name": "Item"})
+	}
+	tbl.Render(true, data)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	for _, s := range []string{"/S /Table", "/S /TR", "/S /TD", "/S /TH", "/Scope /Column", "/Artifact BMC"} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("Expected output to contain %q", s)
+		}
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("/S /TH ")); n != 2 {
+		t.Errorf("Expected 2 TH elements (repeated headers are artifacts), got %d", n)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("/S /Table ")); n != 1 {
+		t.Errorf("Expected 1 Table element, got %d", n)
+	}
+}
+
+// TestTableFontFallback tests that wrapping uses the widths of fallback fonts
+func TestTableFontFallback(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "../font")
+	pdf.AddUTF8Font("calligra", "", "calligra.ttf")
+	pdf.AddUTF8Font("dejavu", "", "DejaVuSansCondensed.ttf")
+	pdf.SetFont("calligra", "", 12)
+	pdf.SetFontFallback("calligra", "dejavu")
+	pdf.AddPage()
+
+	// The Cyrillic runes, which calligra lacks, fit with the widths of dejavu
+	value := "Мир Мир Мир"
+	tbl := NewTable(pdf, []Column{{Key: "name", Label: "Name", Width: pdf.GetStringWidth(value) + 3}})
+	y := pdf.GetY()
+	tbl.AddRow(map[string]interface{}{"name": value})
+
+	if pdf.Error() != nil {
+		t.Fatalf("Unexpected error: %v", pdf.Error())
+	}
+	if h := pdf.GetY() - y; h != tbl.getRowHeight() {
+		t.Errorf("Expected row height %.2f without wrapping, got %.2f", tbl.getRowHeight(), h)
+	}
+}
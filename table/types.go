@@ -112,6 +112,9 @@ type RowStyle struct {
 //     for nested tables that need to be rendered within parent table cells.
 //   - currentRow: Internal counter tracking the logical row index for zebra striping.
 //     Incremented each time AddRow is called, resets only when a new table is created.
+//   - tagOpen: Internal flag set while the Table structure element of a tagged
+//     document is open. See Finish.
+//   - repeating: Internal flag set while a header is repeated on a new page.
 type Table struct {
 	pdf                *gofpdf.Fpdf
 	Columns            []Column
@@ -130,5 +133,7 @@ type Table struct {
 	rowSpanTracker     map[string]int           // Tracks row spans: "colIndex-rowIndex" -> remaining rows
 	storedRows         []map[string]interface{} // Stored rows for deferred rendering (used for nested tables)
 	currentRow         int                      // Logical row index for zebra striping (0-indexed)
+	tagOpen            bool                     // Table structure element is open (tagged documents)
+	repeating          bool                     // Header is being repeated after a page break
 }
 
@@ -0,0 +1,402 @@
+package gofpdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TagOptions specifies optional attributes of a structure element started
+// with BeginTagOptions.
+type TagOptions struct {
+	// Alt is the alternate description of the element. It is required for
+	// Figure and Formula elements in PDF/UA documents.
+	Alt string
+	// ActualText is the replacement text of the element content, for
+	// example the text of a word drawn with graphics.
+	ActualText string
+	// Lang is the language of the element content, such as "fr-CA", if it
+	// differs from the document language.
+	Lang string
+	// Scope is the scope of a TH element: "Row", "Column" or "Both".
+	Scope string
+	// RowSpan and ColSpan are the number of rows and columns spanned by a TH
+	// or TD element. Zero is the same as one.
+	RowSpan, ColSpan int
+}
+
+// structElem is a node of the structure tree
+type structElem struct {
+	role   string
+	opt    TagOptions
+	parent *structElem
+	kids   []structKid
+	page   int       // page of the first marked content of the element
+	bbox   []float64 // bounding box of a figure, in points
+	objNum int       // object number, assigned when the document is closed
+}
+
+// structKid is either a child element or a marked-content sequence
+type structKid struct {
+	elem *structElem
+	page int
+	mcid int
+}
+
+type tagRecType struct {
+	enabled   bool
+	ua        bool
+	lang      string
+	roots     []*structElem   // top-level elements
+	elems     []*structElem   // all elements in creation order
+	stack     []*structElem   // currently open elements
+	pageMCIDs [][]*structElem // 1-based per page: element of each marked-content ID
+	open      bool            // a marked-content sequence is open on the current page
+	hold      int             // marked content is suspended while positive
+	artifacts int             // nesting level of artifacts
+	objRoot   int             // object number of the structure tree root
+}
+
+// structRoles holds the standard structure types of PDF 1.7
+var structRoles = map[string]bool{
+	"Document": true, "Part": true, "Art": true, "Sect": true, "Div": true,
+	"BlockQuote": true, "Caption": true, "TOC": true, "TOCI": true,
+	"Index": true, "NonStruct": true, "Private": true, "H": true, "H1": true,
+	"H2": true, "H3": true, "H4": true, "H5": true, "H6": true, "P": true,
+	"L": true, "LI": true, "Lbl": true, "LBody": true, "Table": true,
+	"TR": true, "TH": true, "TD": true, "THead": true, "TBody": true,
+	"TFoot": true, "Span": true, "Quote": true, "Note": true,
+	"Reference": true, "BibEntry": true, "Code": true, "Link": true,
+	"Annot": true, "Ruby": true, "RB": true, "RT": true, "RP": true,
+	"Warichu": true, "WT": true, "WP": true, "Figure": true,
+	"Formula": true, "Form": true,
+}
+
+// SetTagged enables or disables the generation of a tagged document. In a
+// tagged document, content is grouped in a tree of structure elements that
+// conveys the logical structure of the document to assistive technologies
+// and reflowing viewers. Structure elements are delimited with BeginTag and
+// EndTag; content added outside of any structure element is not tagged.
+// Output of the functions set with SetHeaderFunc and SetFooterFunc is marked
+// as pagination artifacts, and images placed with ImageOptions are tagged
+// as figures with the alternate text of the options, or as artifacts if they
+// have none.
+func (f *Fpdf) SetTagged(enabled bool) {
+	f.tag.enabled = enabled
+}
+
+// GetTagged returns true if the document is tagged. See SetTagged.
+func (f *Fpdf) GetTagged() bool {
+	return f.tag.enabled
+}
+
+// SetPDFUA enables or disables PDF/UA-1 (ISO 14289-1) identification of the
+// document. Enabling it also enables tagging (see SetTagged) and instructs
+// viewers to display the document title, which must be set with SetTitle.
+// The document is identified as PDF/UA in its XMP metadata, which is
+// generated unless set with SetXmpMetadata. Standard core fonts, which are
+// not embedded, and figures without alternate text are reported as errors
+// when the document is closed. It is the responsibility of the application
+// to tag all meaningful content.
+func (f *Fpdf) SetPDFUA(enabled bool) {
+	f.tag.ua = enabled
+	if enabled {
+		f.tag.enabled = true
+	}
+}
+
+// SetLang sets the natural language of the document, such as "en-US". It
+// is required in accessible documents.
+func (f *Fpdf) SetLang(lang string) {
+	f.tag.lang = lang
+}
+
+// BeginTag starts a structure element of the specified role in a tagged
+// document. role is one of the standard structure types of PDF, such as
+// "Document", "Sect", "H1" through "H6", "P", "L", "LI", "Lbl", "LBody",
+// "Table", "TR", "TH", "TD", "Figure" or "Span". Structure elements may be
+// nested; content added until the matching call to EndTag belongs to the
+// innermost element. BeginTag has no effect if tagging is not enabled.
+func (f *Fpdf) BeginTag(role string) {
+	f.BeginTagOptions(role, TagOptions{})
+}
+
+// BeginTagOptions starts a structure element of the specified role with
+// the attributes in opt. See BeginTag for details.
+func (f *Fpdf) BeginTagOptions(role string, opt TagOptions) {
+	if f.err != nil || !f.tag.enabled {
+		return
+	}
+	if !structRoles[role] {
+		f.err = fmt.Errorf("unknown structure type %s", role)
+		return
+	}
+	if f.tag.artifacts > 0 {
+		f.err = fmt.Errorf("structure element %s cannot be started in an artifact", role)
+		return
+	}
+	switch opt.Scope {
+	case "", "Row", "Column", "Both":
+	default:
+		f.err = fmt.Errorf("invalid table header scope %s", opt.Scope)
+		return
+	}
+	f.tagEndContent()
+	e := &structElem{role: role, opt: opt}
+	if n := len(f.tag.stack); n > 0 {
+		e.parent = f.tag.stack[n-1]
+		e.parent.kids = append(e.parent.kids, structKid{elem: e})
+	} else {
+		f.tag.roots = append(f.tag.roots, e)
+	}
+	f.tag.elems = append(f.tag.elems, e)
+	f.tag.stack = append(f.tag.stack, e)
+}
+
+// EndTag ends the structure element started by the most recent call to
+// BeginTag or BeginTagOptions that has not been ended yet.
+func (f *Fpdf) EndTag() {
+	if f.err != nil || !f.tag.enabled {
+		return
+	}
+	n := len(f.tag.stack)
+	if n == 0 {
+		f.err = fmt.Errorf("EndTag called without matching BeginTag")
+		return
+	}
+	f.tagEndContent()
+	f.tag.stack = f.tag.stack[:n-1]
+}
+
+// BeginArtifact starts content that is not part of the logical structure
+// of a tagged document, such as decorations, rules and background images.
+// It must be followed by a call to EndArtifact. BeginArtifact has no effect
+// if tagging is not enabled.
+func (f *Fpdf) BeginArtifact() {
+	f.tagBeginArtifact("")
+}
+
+// EndArtifact ends content started with BeginArtifact.
+func (f *Fpdf) EndArtifact() {
+	f.tagEndArtifact()
+}
+
+func (f *Fpdf) tagBeginArtifact(subtype string) {
+	if f.err != nil || !f.tag.enabled || f.state != 2 {
+		return
+	}
+	f.tagEndContent()
+	f.tag.artifacts++
+	f.tag.hold++
+	if subtype == "" {
+		f.tagOut("/Artifact BMC")
+	} else {
+		f.tagOut("/Artifact <</Type /Pagination /Subtype /" + subtype + ">> BDC")
+	}
+}
+
+func (f *Fpdf) tagEndArtifact() {
+	if f.err != nil || !f.tag.enabled || f.state != 2 {
+		return
+	}
+	if f.tag.artifacts == 0 {
+		f.err = fmt.Errorf("EndArtifact called without matching BeginArtifact")
+		return
+	}
+	f.tag.artifacts--
+	f.tag.hold--
+	f.tagOut("EMC")
+}
+
+// tagOut writes an operator to the current page without starting marked
+// content
+func (f *Fpdf) tagOut(s string) {
+	f.pages[f.page].WriteString(s)
+	f.pages[f.page].WriteString("\n")
+}
+
+// tagContent returns true if content written to the current page must be
+// preceded by the start of a marked-content sequence
+func (f *Fpdf) tagContent() bool {
+	return f.tag.enabled && !f.tag.open && f.tag.hold == 0 && len(f.tag.stack) > 0
+}
+
+// tagBeginContent starts a marked-content sequence for the innermost open
+// structure element
+func (f *Fpdf) tagBeginContent() {
+	e := f.tag.stack[len(f.tag.stack)-1]
+	mcid := len(f.tag.pageMCIDs[f.page])
+	f.tag.pageMCIDs[f.page] = append(f.tag.pageMCIDs[f.page], e)
+	e.kids = append(e.kids, structKid{page: f.page, mcid: mcid})
+	if e.page == 0 {
+		e.page = f.page
+	}
+	f.tag.open = true
+	f.tagOut(sprintf("/%s <</MCID %d>> BDC", e.role, mcid))
+}
+
+// tagEndContent ends the current marked-content sequence, if any
+func (f *Fpdf) tagEndContent() {
+	if f.tag.open {
+		f.tag.open = false
+		f.tagOut("EMC")
+	}
+}
+
+// tagBeginImage starts a figure with the alternate text alt, or an artifact
+// if alt is empty, for an image drawn in the specified rectangle
+func (f *Fpdf) tagBeginImage(alt string, x, y, w, h float64) {
+	if !f.tag.enabled {
+		return
+	}
+	if alt == "" {
+		f.BeginArtifact()
+		return
+	}
+	f.BeginTagOptions("Figure", TagOptions{Alt: alt})
+	if n := len(f.tag.stack); n > 0 {
+		f.tag.stack[n-1].bbox = []float64{x * f.k, (f.h - (y + h)) * f.k, (x + w) * f.k, (f.h - y) * f.k}
+	}
+}
+
+// tagEndImage ends the element started by tagBeginImage
+func (f *Fpdf) tagEndImage(alt string) {
+	if !f.tag.enabled {
+		return
+	}
+	if alt == "" {
+		f.EndArtifact()
+	} else {
+		f.EndTag()
+	}
+}
+
+// tagEndDoc checks the structure of the document before it is written
+func (f *Fpdf) tagEndDoc() {
+	if !f.tag.enabled {
+		return
+	}
+	if n := len(f.tag.stack); n > 0 {
+		f.err = fmt.Errorf("structure element %s must be explicitly ended", f.tag.stack[n-1].role)
+		return
+	}
+	if f.tag.artifacts > 0 {
+		f.err = fmt.Errorf("artifact must be explicitly ended")
+		return
+	}
+	if !f.tag.ua {
+		return
+	}
+	if len(f.title) == 0 {
+		f.err = fmt.Errorf("PDF/UA: document title is required")
+		return
+	}
+	var keyList []string
+	for key := range f.fonts {
+		keyList = append(keyList, key)
+	}
+	sort.Strings(keyList)
+	for _, key := range keyList {
+		if font := f.fonts[key]; font.Tp == "Core" {
+			f.err = fmt.Errorf("PDF/UA: font %s is not embedded", font.Name)
+			return
+		}
+	}
+	for _, e := range f.tag.elems {
+		if (e.role == "Figure" || e.role == "Formula") && e.opt.Alt == "" {
+			f.err = fmt.Errorf("PDF/UA: %s element requires alternate text", e.role)
+			return
+		}
+	}
+}
+
+// tagPutStructTree writes the structure tree root and the structure
+// elements
+func (f *Fpdf) tagPutStructTree() {
+	if !f.tag.enabled {
+		return
+	}
+	f.tag.objRoot = f.n + 1
+	for j, e := range f.tag.elems {
+		e.objNum = f.tag.objRoot + 1 + j
+	}
+	refs := func(list []*structElem) string {
+		var b fmtBuffer
+		for _, e := range list {
+			b.printf("%d 0 R ", e.objNum)
+		}
+		return b.String()
+	}
+	f.newobj()
+	f.outf("<</Type /StructTreeRoot /K [%s]", refs(f.tag.roots))
+	var nums fmtBuffer
+	for n := 1; n <= f.page; n++ {
+		nums.printf("%d [%s] ", n-1, refs(f.tag.pageMCIDs[n]))
+	}
+	f.outf("/ParentTree <</Nums [%s]>> /ParentTreeNextKey %d>>", nums.String(), f.page)
+	f.out("endobj")
+	for _, e := range f.tag.elems {
+		f.newobj()
+		parent := f.tag.objRoot
+		if e.parent != nil {
+			parent = e.parent.objNum
+		}
+		var b fmtBuffer
+		b.printf("<</Type /StructElem /S /%s /P %d 0 R", e.role, parent)
+		if e.page > 0 {
+			b.printf(" /Pg %d 0 R", f.pageObj(e.page))
+		}
+		b.printf(" /K [")
+		for _, k := range e.kids {
+			switch {
+			case k.elem != nil:
+				b.printf("%d 0 R ", k.elem.objNum)
+			case k.page == e.page:
+				b.printf("%d ", k.mcid)
+			default:
+				b.printf("<</Type /MCR /Pg %d 0 R /MCID %d>> ", f.pageObj(k.page), k.mcid)
+			}
+		}
+		b.printf("]")
+		if e.opt.Alt != "" {
+			b.printf(" /Alt %s", f.textstring(utf8toutf16(e.opt.Alt)))
+		}
+		if e.opt.ActualText != "" {
+			b.printf(" /ActualText %s", f.textstring(utf8toutf16(e.opt.ActualText)))
+		}
+		if e.opt.Lang != "" {
+			b.printf(" /Lang %s", f.textstring(e.opt.Lang))
+		}
+		var attr []string
+		if e.opt.Scope != "" {
+			attr = append(attr, "/Scope /"+e.opt.Scope)
+		}
+		if e.opt.RowSpan > 1 {
+			attr = append(attr, sprintf("/RowSpan %d", e.opt.RowSpan))
+		}
+		if e.opt.ColSpan > 1 {
+			attr = append(attr, sprintf("/ColSpan %d", e.opt.ColSpan))
+		}
+		if len(attr) > 0 {
+			b.printf(" /A <</O /Table %s>>", strings.Join(attr, " "))
+		} else if e.bbox != nil {
+			b.printf(" /A <</O /Layout /BBox [%.2f %.2f %.2f %.2f]>>", e.bbox[0], e.bbox[1], e.bbox[2], e.bbox[3])
+		}
+		b.printf(">>")
+		f.out(b.String())
+		f.out("endobj")
+	}
+}
+
+// tagPutCatalog writes the entries of the document catalog related to
+// accessibility
+func (f *Fpdf) tagPutCatalog() {
+	if f.tag.lang != "" {
+		f.outf("/Lang %s", f.textstring(f.tag.lang))
+	}
+	if !f.tag.enabled {
+		return
+	}
+	f.out("/MarkInfo <</Marked true>>")
+	f.outf("/StructTreeRoot %d 0 R", f.tag.objRoot)
+}
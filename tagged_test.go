@@ -0,0 +1,81 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/internal/example"
+)
+
+func TestSetTagged(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetTagged(true)
+	pdf.SetLang("en-US")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetHeaderFunc(func() {
+		pdf.Cell(0, 10, "Header")
+		pdf.Ln(10)
+	})
+	pdf.AddPage()
+	pdf.BeginTag("Document")
+	pdf.BeginTag("H1")
+	pdf.Cell(0, 10, "Heading")
+	pdf.EndTag()
+	pdf.Ln(10)
+	pdf.BeginTag("P")
+	pdf.MultiCell(0, 5, strings.Repeat("Paragraph text spanning pages. ", 500), "", "", false)
+	pdf.EndTag()
+	pdf.ImageOptions(example.ImageFile("logo.png"), 10, 30, 30, 0, false,
+		gofpdf.ImageOptions{AltText: "Company logo"}, 0, "")
+	pdf.EndTag()
+	b := formOutput(t, pdf)
+	for _, s := range []string{
+		"/Artifact <</Type /Pagination /Subtype /Header>> BDC",
+		"/H1 <</MCID 0>> BDC",
+		"/P <</MCID 0>> BDC",
+		"/Type /StructTreeRoot",
+		"/ParentTree <</Nums [0 [",
+		"/S /H1",
+		"/Type /MCR",
+		"/S /Figure",
+		"/Alt (\xfe\xff\x00C\x00o\x00m",
+		"/StructParents 1",
+		"/MarkInfo <</Marked true>>",
+		"/Lang (en-US)",
+	} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+	if bytes.Count(b, []byte("BDC"))+bytes.Count(b, []byte("BMC")) != bytes.Count(b, []byte("EMC")) {
+		t.Errorf("marked content operators are not balanced")
+	}
+}
+
+func TestTagErrors(t *testing.T) {
+	for _, tc := range []struct {
+		setup func(pdf *gofpdf.Fpdf)
+		err   string
+	}{
+		{func(pdf *gofpdf.Fpdf) { pdf.BeginTag("Paragraph") }, "unknown structure type"},
+		{func(pdf *gofpdf.Fpdf) { pdf.EndTag() }, "without matching BeginTag"},
+		{func(pdf *gofpdf.Fpdf) { pdf.BeginTag("P") }, "must be explicitly ended"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.BeginArtifact()
+			pdf.BeginTag("P")
+		}, "cannot be started in an artifact"},
+		{func(pdf *gofpdf.Fpdf) { pdf.SetPDFUA(true) }, "title is required"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetTagged(true)
+		pdf.AddPage()
+		tc.setup(pdf)
+		err := pdf.Output(&bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expecting error containing %q, got %v", tc.err, err)
+		}
+	}
+}
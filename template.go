@@ -78,8 +78,22 @@ func (f *Fpdf) UseTemplateScaled(t Template, corner PointType, size SizeType) {
 		return
 	}
 
-	// make a note of the fact that we actually use this template, as well as any other templates,
-	// images or fonts it uses
+	f.registerTemplate(t)
+
+	// template data
+	_, templateSize := t.Size()
+	scaleX := size.Wd / templateSize.Wd
+	scaleY := size.Ht / templateSize.Ht
+	tx := corner.X * f.k
+	ty := (f.curPageSize.Ht - corner.Y - size.Ht) * f.k
+
+	f.outf("q %.4f 0 0 %.4f %.4f %.4f cm", scaleX, scaleY, tx, ty) // Translate
+	f.outf("/TPL%s Do Q", t.ID())
+}
+
+// registerTemplate makes a note of the fact that we actually use template t,
+// as well as any other templates, images or fonts it uses
+func (f *Fpdf) registerTemplate(t Template) {
 	f.templates[t.ID()] = t
 	for _, tt := range t.Templates() {
 		f.templates[tt.ID()] = tt
@@ -99,16 +113,6 @@ func (f *Fpdf) UseTemplateScaled(t Template, corner PointType, size SizeType) {
 		name = sprintf("t%s-%s", t.ID(), name)
 		f.images[name] = ti
 	}
-
-	// template data
-	_, templateSize := t.Size()
-	scaleX := size.Wd / templateSize.Wd
-	scaleY := size.Ht / templateSize.Ht
-	tx := corner.X * f.k
-	ty := (f.curPageSize.Ht - corner.Y - size.Ht) * f.k
-
-	f.outf("q %.4f 0 0 %.4f %.4f %.4f cm", scaleX, scaleY, tx, ty) // Translate
-	f.outf("/TPL%s Do Q", t.ID())
 }
 
 // Template is an object that can be written to, then used and re-used any number of times within a document.
@@ -163,6 +167,10 @@ func (f *Fpdf) putTemplates() {
 	templates := sortTemplates(f.templates, f.catalogSort)
 	var t Template
 	for _, t = range templates {
+		if it, ok := t.(*importedTpl); ok {
+			f.putImportedTpl(it)
+			continue
+		}
 		corner, size := t.Size()
 
 		f.newobj()
@@ -218,7 +226,7 @@ func (f *Fpdf) putTemplates() {
 		if f.compress {
 			buffer = sliceCompress(buffer)
 		}
-		f.outf("/Length %d >>", len(buffer))
+		f.outf("/Length %d >>", f.protect.streamLen(len(buffer)))
 		f.putstream(buffer)
 		f.out("endobj")
 	}
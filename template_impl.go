@@ -290,6 +290,8 @@ func (t *Tpl) loadParamsFromFpdf(f *Fpdf) {
 	t.Fpdf.color.text = f.color.text
 
 	t.Fpdf.fonts = f.fonts
+	t.Fpdf.fontFallbacks = f.fontFallbacks
+	t.Fpdf.missingGlyphFnc = f.missingGlyphFnc
 	t.Fpdf.currentFont = f.currentFont
 	t.Fpdf.fontFamily = f.fontFamily
 	t.Fpdf.fontSize = f.fontSize
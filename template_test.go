@@ -1,6 +1,7 @@
 package gofpdf_test
 
 import (
+	"bytes"
 	"testing"
 
 	gofpdf "github.com/looksocial/gofpdf"
@@ -325,6 +326,46 @@ func TestSetProtection(t *testing.T) {
 	}
 }
 
+func TestSetProtectionAlgorithm(t *testing.T) {
+	for _, c := range []struct {
+		alg     gofpdf.EncryptionType
+		version string
+		dict    string
+	}{
+		{gofpdf.EncryptRC4Bits40, "%PDF-1.3", "/V 1"},
+		{gofpdf.EncryptRC4Bits128, "%PDF-1.4", "/V 2 /R 3 /Length 128"},
+		{gofpdf.EncryptAESBits128, "%PDF-1.6", "/CFM /AESV2"},
+		{gofpdf.EncryptAESBits256, "%PDF-2.0", "/CFM /AESV3"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetCompression(false)
+		pdf.SetProtectionAlgorithm(c.alg, gofpdf.CnProtectPrint|gofpdf.CnProtectPrintHighRes, "userpass", "ownerpass")
+		pdf.AddPage()
+		pdf.SetFont("Arial", "", 12)
+		pdf.Cell(40, 10, "Protected text")
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatalf("algorithm %d: %v", c.alg, err)
+		}
+		b := buf.Bytes()
+		if !bytes.HasPrefix(b, []byte(c.version)) {
+			t.Errorf("algorithm %d: expecting %s header", c.alg, c.version)
+		}
+		if !bytes.Contains(b, []byte(c.dict)) {
+			t.Errorf("algorithm %d: encryption dictionary does not contain %s", c.alg, c.dict)
+		}
+		if bytes.Contains(b, []byte("Protected text")) {
+			t.Errorf("algorithm %d: content is not encrypted", c.alg)
+		}
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtectionAlgorithm(gofpdf.EncryptionType(9), 0, "", "")
+	if pdf.Error() == nil {
+		t.Errorf("expecting error for unknown algorithm")
+	}
+}
+
 func TestAddSpotColor(t *testing.T) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
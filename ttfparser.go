@@ -58,6 +58,9 @@ type TtfType struct {
 	CapHeight int16
 	// Widths contains the width values for each glyph in the font.
 	Widths []uint16
+	// PostScriptOutlines indicates whether the glyphs are PostScript outlines
+	// in a CFF table (OpenType font with the signature "OTTO").
+	PostScriptOutlines bool
 	// Chars maps Unicode code points to glyph indices.
 	Chars map[uint16]uint16
 }
@@ -82,10 +85,8 @@ func TtfParse(fileStr string) (TtfRec TtfType, err error) {
 		return
 	}
 	if version == "OTTO" {
-		err = fmt.Errorf("fonts based on PostScript outlines are not supported")
-		return
-	}
-	if version != "\x00\x01\x00\x00" {
+		t.rec.PostScriptOutlines = true
+	} else if version != "\x00\x01\x00\x00" {
 		err = fmt.Errorf("unrecognized file format")
 		return
 	}
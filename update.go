@@ -0,0 +1,708 @@
+package gofpdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// updateType holds the state of an incremental update of an existing
+// document
+type updateType struct {
+	r        *pdfreader.Reader
+	pagesRef pdfreader.Ref                    // root of the page tree
+	pages    []updatePageType                 // existing pages, 0-based
+	resObj   int                              // object number of the resource dictionary
+	values   map[string]string                // form field values by qualified name
+	fields   map[string]pdfreader.Ref         // existing form fields by qualified name
+	gens     map[int]int                      // generation numbers of rewritten objects
+	written  map[pdfreader.Ref]pdfreader.Dict // objects rewritten so far
+}
+
+// updatePageType describes a page of the updated document
+type updatePageType struct {
+	ref      pdfreader.Ref
+	llx, lly float64 // origin of the media box in points
+	w, h     float64 // size of the media box in user units
+	initLen  int     // length of the content that only sets the initial state
+}
+
+// OpenUpdate opens the existing PDF document read from rs so that it can be
+// modified with an incremental update: Output() then writes the original
+// document unchanged, followed by the objects that are added or modified and
+// a cross-reference section that refers to the original one. This preserves
+// the existing content byte for byte, including any digital signature.
+//
+// This method must be called before any page is added. The pages of the
+// document become pages 1 to n of the Fpdf instance, and the last one is the
+// current page. SetPage() selects an existing page to add content, links,
+// annotations and form fields to it; the content is drawn over the original
+// content, with the origin at the top left corner of the media box of the
+// page. Page rotation is not taken into account. Pages added with AddPage()
+// are appended to the document. The header and footer functions are only
+// called for the added pages.
+//
+// SetFormValue() changes the values of the existing form fields. The document
+// catalog is only modified if necessary, so that the viewer settings,
+// outline and other document level features of the document are kept; the
+// corresponding Fpdf features, such as bookmarks, layers, attachments,
+// encryption, PDF/A conformance and tagging, cannot be used when updating a
+// document. Encrypted documents and documents whose cross-reference
+// information is damaged cannot be updated.
+func (f *Fpdf) OpenUpdate(rs io.ReadSeeker) {
+	if f.err != nil {
+		return
+	}
+	if f.page > 0 || f.update != nil {
+		f.err = fmt.Errorf("document to update must be opened before the first page is added")
+		return
+	}
+	r, err := pdfreader.NewReader(rs)
+	if err != nil {
+		f.err = err
+		return
+	}
+	if r.StartXref() == 0 {
+		f.err = fmt.Errorf("document with damaged cross-reference information cannot be updated")
+		return
+	}
+	if r.Encrypted() {
+		f.err = fmt.Errorf("encrypted documents cannot be updated")
+		return
+	}
+	catalog, err := r.Catalog()
+	if err != nil {
+		f.err = err
+		return
+	}
+	up := &updateType{
+		r:       r,
+		values:  make(map[string]string),
+		gens:    make(map[int]int),
+		written: make(map[pdfreader.Ref]pdfreader.Dict),
+	}
+	up.pagesRef, _ = catalog["Pages"].(pdfreader.Ref)
+	for n := 1; n <= r.NumPages(); n++ {
+		ref, _, err := r.Page(n)
+		if err == nil && ref.Gen != 0 {
+			err = fmt.Errorf("page %d of the document has generation number %d", n, ref.Gen)
+		}
+		var llx, lly, urx, ury float64
+		if err == nil {
+			llx, lly, urx, ury, err = r.PageBox(n, "MediaBox")
+		}
+		if err != nil {
+			f.err = err
+			return
+		}
+		up.pages = append(up.pages, updatePageType{ref: ref, llx: llx, lly: lly,
+			w: (urx - llx) / f.k, h: (ury - lly) / f.k})
+	}
+	if f.state == 0 {
+		f.open()
+	}
+	f.update = up
+	familyStr := f.fontFamily
+	for n, pg := range up.pages {
+		if n > 0 {
+			f.endpage()
+		}
+		f.beginpage("P", SizeType{Wd: pg.w, Ht: pg.h})
+		f.fontFamily = familyStr
+		f.updatePageState()
+		up.pages[n].initLen = f.pages[f.page].Len()
+	}
+}
+
+// OpenUpdateFile opens the existing PDF document fileStr so that it can be
+// modified with an incremental update. See OpenUpdate() for details.
+func (f *Fpdf) OpenUpdateFile(fileStr string) {
+	if f.err != nil {
+		return
+	}
+	fl, err := os.Open(fileStr)
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer fl.Close()
+	f.OpenUpdate(fl)
+}
+
+// SetFormValue sets the value of the form field fieldName of the document
+// opened with OpenUpdate(). fieldName is the fully qualified name of the
+// field, with the names of its ancestors separated by periods. The value of
+// a check box or radio button is the name of the appearance state to select,
+// or "Off". Viewers are asked to regenerate the appearance of the fields.
+func (f *Fpdf) SetFormValue(fieldName, value string) {
+	if f.err != nil {
+		return
+	}
+	if f.update == nil {
+		f.err = fmt.Errorf("form values can only be set when updating a document")
+		return
+	}
+	if f.update.fields == nil {
+		f.updateLoadFields()
+		if f.err != nil {
+			return
+		}
+	}
+	if _, ok := f.update.fields[fieldName]; !ok {
+		f.err = fmt.Errorf("form field %s does not exist", fieldName)
+		return
+	}
+	f.update.values[fieldName] = value
+}
+
+// updatePage reports whether page n belongs to the updated document
+func (f *Fpdf) updatePage(n int) bool {
+	return f.update != nil && n <= len(f.update.pages)
+}
+
+// updatePageState writes the current line, font and color settings at the
+// start of the content of a page of the updated document
+func (f *Fpdf) updatePageState() {
+	f.outf("%d J", f.capStyle)
+	f.outf("%d j", f.joinStyle)
+	f.outf("%.2f w", f.lineWidth*f.k)
+	if len(f.dashArray) > 0 {
+		f.outputDashPattern()
+	}
+	if familyStr := f.fontFamily; familyStr != "" {
+		style := f.fontStyle
+		if f.underline {
+			style += "U"
+		}
+		if f.strikeout {
+			style += "S"
+		}
+		f.fontFamily = ""
+		f.SetFont(familyStr, style, f.fontSizePt)
+	}
+	if f.color.draw.str != "0 G" {
+		f.out(f.color.draw.str)
+	}
+	if f.color.fill.str != "0 g" {
+		f.out(f.color.fill.str)
+	}
+}
+
+// updateSetPage sets the dimensions of the current page after SetPage() and
+// writes the current settings to its content
+func (f *Fpdf) updateSetPage() {
+	if f.update == nil {
+		return
+	}
+	var size SizeType
+	if f.updatePage(f.page) {
+		pg := f.update.pages[f.page-1]
+		size = SizeType{Wd: pg.w, Ht: pg.h}
+	} else if sz, ok := f.pageSizes[f.page]; ok {
+		size = SizeType{Wd: sz.Wd / f.k, Ht: sz.Ht / f.k}
+	} else {
+		size = f.updateDefaultSize()
+	}
+	f.w, f.h = size.Wd, size.Ht
+	f.wPt, f.hPt = f.w*f.k, f.h*f.k
+	f.pageBreakTrigger = f.h - f.bMargin
+	f.curOrientation = "P"
+	f.curPageSize = size
+	// Visiting a page does not modify it
+	unmodified := f.updatePage(f.page) && f.pages[f.page].Len() == f.update.pages[f.page-1].initLen
+	f.updatePageState()
+	if unmodified {
+		f.update.pages[f.page-1].initLen = f.pages[f.page].Len()
+	}
+}
+
+// updateDefaultSize returns the size of the pages added with AddPage(), in
+// user units
+func (f *Fpdf) updateDefaultSize() SizeType {
+	if f.defOrientation == "P" {
+		return f.defPageSize
+	}
+	return SizeType{Wd: f.defPageSize.Ht, Ht: f.defPageSize.Wd}
+}
+
+// updateCheck verifies that the features in use are compatible with an
+// incremental update
+func (f *Fpdf) updateCheck() {
+	var feature string
+	switch {
+	case f.objStm.enabled:
+		feature = "object streams"
+	case f.stream != nil:
+		feature = "streaming output"
+	case f.sign != nil:
+		feature = "digital signature"
+	case f.protect.encrypted:
+		feature = "protection"
+	case f.pdfaPart() > 0:
+		feature = "PDF/A conformance"
+	case f.tag.enabled:
+		feature = "tagged PDF"
+	case len(f.layer.list) > 0:
+		feature = "layers"
+	case len(f.outlines) > 0:
+		feature = "bookmarks"
+	case len(f.namedDests) > 0:
+		feature = "named destinations"
+	case len(f.pageLabels) > 0:
+		feature = "page labels"
+	case len(f.attachments) > 0:
+		feature = "document attachments"
+	case f.javascript != nil:
+		feature = "JavaScript"
+	case f.openAction.page > 0:
+		feature = "open action"
+	case f.pageMode != "":
+		feature = "page mode"
+	default:
+		return
+	}
+	f.err = fmt.Errorf("%s cannot be used when updating a document", feature)
+}
+
+// updateEndDoc writes the original document followed by the incremental
+// update
+func (f *Fpdf) updateEndDoc() {
+	f.updateCheck()
+	if f.err != nil {
+		return
+	}
+	up := f.update
+	data := up.r.Bytes()
+	f.buffer.Write(data)
+	if len(data) > 0 && data[len(data)-1] != '\n' && data[len(data)-1] != '\r' {
+		f.out("")
+	}
+	start := f.buffer.Len()
+	// New objects are numbered after the existing ones
+	f.n = up.r.Size() - 1
+	f.offsets = make([]int, f.n+1)
+	f.n++
+	up.resObj = f.n
+	f.offsets = append(f.offsets, 0)
+	if f.transparencyUsed() && f.pdfVersion < "1.4" {
+		f.pdfVersion = "1.4"
+	}
+	f.putAnnotationsAttachments()
+	f.putpages()
+	f.putresources()
+	if f.err != nil {
+		return
+	}
+	f.updatePutValues()
+	f.updatePutCatalog()
+	if f.err != nil {
+		return
+	}
+	f.updatePutXref(start)
+	f.state = 3
+}
+
+// updateObject returns the dictionary of ref, as rewritten by the update if
+// it has already been modified
+func (f *Fpdf) updateObject(ref pdfreader.Ref) pdfreader.Dict {
+	if d, ok := f.update.written[ref]; ok {
+		return d
+	}
+	d, err := f.update.r.ResolveDict(ref)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return updateCopy(d)
+}
+
+// updatePutObject writes a new version of the existing object ref
+func (f *Fpdf) updatePutObject(ref pdfreader.Ref, d pdfreader.Dict, extra string) {
+	for j := len(f.offsets); j <= ref.Num; j++ {
+		f.offsets = append(f.offsets, 0)
+	}
+	f.offsets[ref.Num] = f.offset()
+	f.update.gens[ref.Num] = ref.Gen
+	f.update.written[ref] = d
+	s := pdfreader.Format(d)
+	if extra != "" {
+		s = s[:len(s)-2] + " " + extra + ">>"
+	}
+	f.outf("%d %d obj", ref.Num, ref.Gen)
+	f.out(s)
+	f.out("endobj")
+}
+
+// updateCopy returns a shallow copy of d
+func updateCopy(d pdfreader.Dict) pdfreader.Dict {
+	c := make(pdfreader.Dict, len(d))
+	for k, v := range d {
+		c[k] = v
+	}
+	return c
+}
+
+// updateRefs returns the elements of obj, an array or a reference to an
+// array or to another object
+func (f *Fpdf) updateRefs(obj pdfreader.Object) pdfreader.Array {
+	if ref, ok := obj.(pdfreader.Ref); ok {
+		o, err := f.update.r.Object(ref)
+		if err != nil && f.err == nil {
+			f.err = err
+		}
+		if a, ok := o.(pdfreader.Array); ok {
+			return a
+		}
+		if o == nil {
+			return nil
+		}
+		return pdfreader.Array{ref}
+	}
+	a, _ := obj.(pdfreader.Array)
+	return a
+}
+
+// updatePutPages writes the existing pages that have been modified, and the
+// root of the page tree if pages have been added
+func (f *Fpdf) updatePutPages() {
+	up := f.update
+	qObj := 0
+	for n := 1; n <= len(up.pages); n++ {
+		pg := up.pages[n-1]
+		content := f.pages[n].Bytes()
+		hasContent := len(content) > pg.initLen
+		if !hasContent && !f.pageHasAnnots(n) {
+			continue
+		}
+		page := f.updateObject(pg.ref)
+		if hasContent {
+			// The original content is enclosed in q and Q, so that the new
+			// content is drawn in the initial graphics state
+			if qObj == 0 {
+				f.newobj()
+				qObj = f.n
+				f.out("<</Length 1>>")
+				f.putstream([]byte("q"))
+				f.out("endobj")
+			}
+			// The new content is a form XObject with its own resources
+			f.newobj()
+			xObj := f.n
+			f.outf("<</Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f] /Matrix [1 0 0 1 %.2f %.2f] /Resources %d 0 R",
+				pg.w*f.k, pg.h*f.k, pg.llx, pg.lly, f.resourcesObj())
+			if f.compress {
+				content = sliceCompress(content)
+				f.out("/Filter /FlateDecode")
+			}
+			f.outf("/Length %d>>", len(content))
+			f.putstream(content)
+			f.out("endobj")
+			res, err := f.update.r.PageAttr(n, "Resources")
+			if err != nil {
+				f.err = err
+				return
+			}
+			resources, _ := res.(pdfreader.Dict)
+			resources = updateCopy(resources)
+			xobjects, err := f.update.r.ResolveDict(resources["XObject"])
+			if err != nil {
+				f.err = err
+				return
+			}
+			xobjects = updateCopy(xobjects)
+			name := pdfreader.Name("FpdfOverlay")
+			for j := 1; xobjects[name] != nil; j++ {
+				name = pdfreader.Name(sprintf("FpdfOverlay%d", j))
+			}
+			xobjects[name] = pdfreader.Ref{Num: xObj}
+			resources["XObject"] = xobjects
+			page["Resources"] = resources
+			f.newobj()
+			final := "Q\n" + pdfreader.Format(name) + " Do"
+			f.outf("<</Length %d>>", len(final))
+			f.putstream([]byte(final))
+			f.out("endobj")
+			contents := pdfreader.Array{pdfreader.Ref{Num: qObj}}
+			contents = append(contents, f.updateRefs(page["Contents"])...)
+			page["Contents"] = append(contents, pdfreader.Ref{Num: f.n})
+		}
+		extra := ""
+		if f.pageHasAnnots(n) {
+			var annots fmtBuffer
+			annots.printf("/Annots [")
+			for _, a := range f.updateRefs(page["Annots"]) {
+				annots.printf("%s ", pdfreader.Format(a))
+			}
+			f.putPageAnnots(&annots, n)
+			annots.printf("]")
+			extra = annots.String()
+			delete(page, "Annots")
+		}
+		f.updatePutObject(pg.ref, page, extra)
+	}
+	if f.page > len(up.pages) {
+		root := f.updateObject(up.pagesRef)
+		kids := f.updateRefs(root["Kids"])
+		for n := len(up.pages) + 1; n <= f.page; n++ {
+			kids = append(kids, pdfreader.Ref{Num: f.pageObj(n)})
+		}
+		root["Kids"] = kids
+		count, _ := root["Count"].(int)
+		root["Count"] = count + f.page - len(up.pages)
+		f.updatePutObject(up.pagesRef, root, "")
+	}
+}
+
+// updateLoadFields collects the form fields of the updated document
+func (f *Fpdf) updateLoadFields() {
+	up := f.update
+	up.fields = make(map[string]pdfreader.Ref)
+	catalog, err := up.r.Catalog()
+	if err != nil {
+		f.err = err
+		return
+	}
+	form, err := up.r.ResolveDict(catalog["AcroForm"])
+	if err != nil {
+		f.err = err
+		return
+	}
+	var walk func(obj pdfreader.Object, prefix string, depth int)
+	walk = func(obj pdfreader.Object, prefix string, depth int) {
+		ref, ok := obj.(pdfreader.Ref)
+		if !ok || depth > 32 {
+			return
+		}
+		d, err := up.r.ResolveDict(ref)
+		if err != nil {
+			return
+		}
+		t, ok := d["T"].(pdfreader.String)
+		if !ok {
+			// Widget annotation of a field
+			return
+		}
+		name := updateText(t)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		up.fields[name] = ref
+		kids, _ := up.r.ResolveArray(d["Kids"])
+		for _, kid := range kids {
+			walk(kid, name, depth+1)
+		}
+	}
+	fields, _ := up.r.ResolveArray(form["Fields"])
+	for _, fld := range fields {
+		walk(fld, "", 0)
+	}
+}
+
+// updateText decodes a text string of the updated document
+func updateText(s pdfreader.String) string {
+	if !strings.HasPrefix(string(s), "\xfe\xff") {
+		return string(s)
+	}
+	b := []byte(s[2:])
+	units := make([]uint16, len(b)/2)
+	for j := range units {
+		units[j] = uint16(b[2*j])<<8 | uint16(b[2*j+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// updatePutValues writes the form fields whose value has been set
+func (f *Fpdf) updatePutValues() {
+	up := f.update
+	names := make([]string, 0, len(up.values))
+	for name := range up.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := up.values[name]
+		ref := up.fields[name]
+		fld := f.updateObject(ref)
+		// The field type may be inherited
+		ft, _ := fld["FT"].(pdfreader.Name)
+		parent := fld["Parent"]
+		for depth := 0; ft == "" && parent != nil && depth < 32; depth++ {
+			d, err := up.r.ResolveDict(parent)
+			if err != nil {
+				break
+			}
+			ft, _ = d["FT"].(pdfreader.Name)
+			parent = d["Parent"]
+		}
+		if ft != "Btn" {
+			text := value
+			for _, c := range value {
+				if c >= 0x80 {
+					text = utf8toutf16(value)
+					break
+				}
+			}
+			fld["V"] = pdfreader.String(text)
+			f.updatePutObject(ref, fld, "")
+			continue
+		}
+		if value == "" {
+			value = "Off"
+		}
+		fld["V"] = pdfreader.Name(value)
+		kids, _ := up.r.ResolveArray(fld["Kids"])
+		if len(kids) == 0 {
+			f.updateSetState(fld, value)
+		}
+		f.updatePutObject(ref, fld, "")
+		for _, kid := range kids {
+			kidRef, ok := kid.(pdfreader.Ref)
+			if !ok {
+				continue
+			}
+			wd := f.updateObject(kidRef)
+			if _, isField := wd["T"]; !isField {
+				f.updateSetState(wd, value)
+				f.updatePutObject(kidRef, wd, "")
+			}
+		}
+	}
+}
+
+// updateSetState selects the appearance state of a button widget that
+// corresponds to value
+func (f *Fpdf) updateSetState(wd pdfreader.Dict, value string) {
+	state := pdfreader.Name("Off")
+	if ap, err := f.update.r.ResolveDict(wd["AP"]); err == nil {
+		if normal, err := f.update.r.ResolveDict(ap["N"]); err == nil && normal[pdfreader.Name(value)] != nil {
+			state = pdfreader.Name(value)
+		}
+	}
+	wd["AS"] = state
+}
+
+// updatePutCatalog writes the interactive form dictionary and the catalog of
+// the updated document if they have been modified
+func (f *Fpdf) updatePutCatalog() {
+	up := f.update
+	rootRef, _ := up.r.Trailer()["Root"].(pdfreader.Ref)
+	catalog := f.updateObject(rootRef)
+	modified := false
+	if len(f.form.fields) > 0 || len(up.values) > 0 {
+		formRef, isRef := catalog["AcroForm"].(pdfreader.Ref)
+		var form pdfreader.Dict
+		if isRef {
+			form = f.updateObject(formRef)
+		} else {
+			d, _ := catalog["AcroForm"].(pdfreader.Dict)
+			form = updateCopy(d)
+		}
+		if len(f.form.fields) > 0 {
+			fields := append(pdfreader.Array{}, f.updateRefs(form["Fields"])...)
+			for _, fld := range f.form.fields {
+				fields = append(fields, pdfreader.Ref{Num: fld.objNum})
+			}
+			form["Fields"] = fields
+			if form["DR"] == nil {
+				form["DR"] = pdfreader.Ref{Num: f.resourcesObj()}
+			}
+		}
+		if len(up.values) > 0 {
+			form["NeedAppearances"] = true
+		}
+		if isRef {
+			f.updatePutObject(formRef, form, "")
+		} else {
+			catalog["AcroForm"] = form
+			modified = true
+		}
+	}
+	if f.pdfVersion > up.r.Version() {
+		catalog["Version"] = pdfreader.Name(f.pdfVersion)
+		modified = true
+	}
+	if modified {
+		f.updatePutObject(rootRef, catalog, "")
+	}
+}
+
+// updatePutXref writes the cross-reference section and the trailer of the
+// update, which starts at offset start
+func (f *Fpdf) updatePutXref(start int) {
+	up := f.update
+	trailer := pdfreader.Dict{"Root": up.r.Trailer()["Root"], "Prev": up.r.StartXref()}
+	if info, ok := up.r.Trailer()["Info"]; ok {
+		trailer["Info"] = info
+	}
+	if id, ok := up.r.Trailer()["ID"].(pdfreader.Array); ok && len(id) == 2 {
+		sum := md5.Sum(f.buffer.Bytes()[start:])
+		trailer["ID"] = pdfreader.Array{id[0], pdfreader.String(sum[:])}
+	}
+	if up.r.XrefStream() {
+		// The cross-reference stream is part of the section it describes
+		f.newobj()
+	}
+	size := f.n + 1
+	if up.r.Size() > size {
+		size = up.r.Size()
+	}
+	trailer["Size"] = size
+	// Subsections of consecutive object numbers
+	var index []int
+	for j := 1; j <= f.n; j++ {
+		if f.offsets[j] == 0 {
+			continue
+		}
+		if k := len(index); k > 0 && index[k-2]+index[k-1] == j {
+			index[k-1]++
+		} else {
+			index = append(index, j, 1)
+		}
+	}
+	if up.r.XrefStream() {
+		var data bytes.Buffer
+		var idx pdfreader.Array
+		for k := 0; k < len(index); k += 2 {
+			idx = append(idx, index[k], index[k+1])
+			for j := index[k]; j < index[k]+index[k+1]; j++ {
+				off, gen := f.offsets[j], up.gens[j]
+				data.Write([]byte{1, byte(off >> 24), byte(off >> 16), byte(off >> 8), byte(off), byte(gen >> 8), byte(gen)})
+			}
+		}
+		content := data.Bytes()
+		trailer["Type"] = pdfreader.Name("XRef")
+		trailer["Index"] = idx
+		trailer["W"] = pdfreader.Array{1, 4, 2}
+		if f.compress {
+			content = sliceCompress(content)
+			trailer["Filter"] = pdfreader.Name("FlateDecode")
+		}
+		trailer["Length"] = len(content)
+		f.out(pdfreader.Format(trailer))
+		f.putstream(content)
+		f.out("endobj")
+		f.out("startxref")
+		f.outf("%d", f.offsets[f.n])
+		f.out("%%EOF")
+		return
+	}
+	o := f.offset()
+	f.out("xref")
+	for k := 0; k < len(index); k += 2 {
+		f.outf("%d %d", index[k], index[k+1])
+		for j := index[k]; j < index[k]+index[k+1]; j++ {
+			f.outf("%010d %05d n ", f.offsets[j], up.gens[j])
+		}
+	}
+	f.out("trailer")
+	f.out(pdfreader.Format(trailer))
+	f.out("startxref")
+	f.outf("%d", o)
+	f.out("%%EOF")
+}
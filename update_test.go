@@ -0,0 +1,226 @@
+package gofpdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+	"github.com/looksocial/gofpdf/pdfreader"
+)
+
+// updateSource returns a document of three pages with a text field and a
+// check box on its first page
+func updateSource(t *testing.T, objStm bool) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetObjectStreams(objStm)
+	pdf.SetFont("Helvetica", "", 12)
+	for j := 0; j < 3; j++ {
+		pdf.AddPage()
+		pdf.Cell(40, 10, "Original")
+		if j == 0 {
+			pdf.AddTextField(20, 40, 50, 10, gofpdf.FormFieldOptions{Name: "name"})
+			pdf.AddCheckBox(20, 60, 5, false, gofpdf.FormFieldOptions{Name: "agree"})
+		}
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// updateContent returns the decoded content of page n
+func updateContent(t *testing.T, r *pdfreader.Reader, n int) string {
+	t.Helper()
+	_, page, err := r.Page(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s strings.Builder
+	contents := []pdfreader.Object{page["Contents"]}
+	if a, ok := page["Contents"].(pdfreader.Array); ok {
+		contents = a
+	}
+	for _, c := range contents {
+		obj, err := r.Resolve(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := obj.(*pdfreader.Stream).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Write(data)
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+func TestOpenUpdate(t *testing.T) {
+	for _, objStm := range []bool{false, true} {
+		orig := updateSource(t, objStm)
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.SetFooterFunc(func() { pdf.Text(10, 280, "Footer") })
+		pdf.OpenUpdate(bytes.NewReader(orig))
+		if pdf.PageCount() != 3 {
+			t.Fatalf("object streams %v: %d pages opened", objStm, pdf.PageCount())
+		}
+		pdf.SetPage(2)
+		pdf.Text(20, 70, "Overlay")
+		pdf.LinkString(20, 60, 20, 10, "http://example.com")
+		pdf.SetPage(1)
+		pdf.SetFormValue("name", "Jürgen")
+		pdf.SetFormValue("agree", "Yes")
+		pdf.SetPage(3)
+		pdf.AddPage()
+		pdf.Text(20, 20, "Added")
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if !bytes.HasPrefix(data, orig) {
+			t.Fatalf("object streams %v: original document modified", objStm)
+		}
+		r, err := pdfreader.NewReaderBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.NumPages() != 4 || r.XrefStream() != objStm {
+			t.Fatalf("object streams %v: %d pages, cross-reference stream %v", objStm, r.NumPages(), r.XrefStream())
+		}
+		if prev, _ := r.Trailer()["Prev"].(int); prev == 0 || prev >= len(orig) {
+			t.Errorf("object streams %v: invalid /Prev %d", objStm, prev)
+		}
+		// Only the modified pages are written
+		for n, modified := range []bool{false, true, false, true} {
+			ref, _, _ := r.Page(n + 1)
+			written := bytes.Contains(data[len(orig)-1:], []byte(fmt.Sprintf("\n%d 0 obj\n", ref.Num)))
+			if written != modified {
+				t.Errorf("object streams %v: page %d written %v", objStm, n+1, written)
+			}
+		}
+		s := updateContent(t, r, 2)
+		if !strings.Contains(s, "(Original)Tj") || !strings.Contains(s, "/FpdfOverlay Do") || strings.Contains(s, "Footer") {
+			t.Errorf("object streams %v: unexpected content of page 2 %q", objStm, s)
+		}
+		xobjects, _ := r.ResolveDict(mustAttr(t, r, 2, "Resources").(pdfreader.Dict)["XObject"])
+		overlay, _ := r.Resolve(xobjects["FpdfOverlay"])
+		stm, ok := overlay.(*pdfreader.Stream)
+		if !ok {
+			t.Fatalf("object streams %v: overlay missing", objStm)
+		}
+		if s, _ := stm.Decode(); !strings.Contains(string(s), "(Overlay) Tj") {
+			t.Errorf("object streams %v: unexpected overlay %q", objStm, s)
+		}
+		annots, _ := r.ResolveArray(mustAttr(t, r, 2, "Annots"))
+		if len(annots) != 1 {
+			t.Errorf("object streams %v: %d annotations on page 2", objStm, len(annots))
+		}
+		if s := updateContent(t, r, 4); !strings.Contains(s, "(Added) Tj") || !strings.Contains(s, "(Footer) Tj") {
+			t.Errorf("object streams %v: unexpected content of page 4 %q", objStm, s)
+		}
+		annots, _ = r.ResolveArray(mustAttr(t, r, 1, "Annots"))
+		values := make(map[string]string)
+		for _, a := range annots {
+			d, _ := r.ResolveDict(a)
+			values[string(d["T"].(pdfreader.String))] = pdfreader.Format(d["V"]) + pdfreader.Format(d["AS"])
+		}
+		if values["name"] != "(\xfe\xff\x00J\x00\xfc\x00r\x00g\x00e\x00n)null" || values["agree"] != "/Yes/Yes" {
+			t.Errorf("object streams %v: unexpected values %q", objStm, values)
+		}
+		catalog, _ := r.Catalog()
+		form, _ := r.ResolveDict(catalog["AcroForm"])
+		if form["NeedAppearances"] != true {
+			t.Errorf("object streams %v: appearances not regenerated", objStm)
+		}
+	}
+}
+
+// mustAttr returns the attribute key of page n
+func mustAttr(t *testing.T, r *pdfreader.Reader, n int, key pdfreader.Name) pdfreader.Object {
+	t.Helper()
+	obj, err := r.PageAttr(n, key)
+	if err != nil || obj == nil {
+		t.Fatalf("page %d has no %s: %v", n, key, err)
+	}
+	return obj
+}
+
+func TestOpenUpdateFields(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.OpenUpdate(bytes.NewReader(updateSource(t, false)))
+	pdf.SetPage(3)
+	pdf.AddTextField(20, 40, 50, 10, gofpdf.FormFieldOptions{Name: "added"})
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := pdfreader.NewReaderBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, _ := r.Catalog()
+	form, _ := r.ResolveDict(catalog["AcroForm"])
+	fields, _ := r.ResolveArray(form["Fields"])
+	if len(fields) != 3 {
+		t.Fatalf("%d fields", len(fields))
+	}
+	fld, _ := r.ResolveDict(fields[2])
+	if fld["T"] != pdfreader.String("added") {
+		t.Errorf("unexpected field %s", pdfreader.Format(fld))
+	}
+	if ref, _, _ := r.Page(3); fld["P"] != ref {
+		t.Errorf("field is not on page 3: %s", pdfreader.Format(fld))
+	}
+}
+
+func TestOpenUpdateErrors(t *testing.T) {
+	orig := updateSource(t, false)
+	protected := gofpdf.New("P", "mm", "A4", "")
+	protected.SetProtection(0, "", "owner")
+	protected.AddPage()
+	var encrypted bytes.Buffer
+	if err := protected.Output(&encrypted); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		fn  func(pdf *gofpdf.Fpdf)
+		err string
+	}{
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.AddPage()
+			pdf.OpenUpdate(bytes.NewReader(orig))
+		}, "before the first page"},
+		{func(pdf *gofpdf.Fpdf) {
+			// Shift all objects so that the cross-reference table is wrong
+			pdf.OpenUpdate(bytes.NewReader(append([]byte("%PDF-1.3\n%garbage\n"), orig...)))
+		}, "cross-reference"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.OpenUpdate(bytes.NewReader(encrypted.Bytes()))
+		}, "encrypted documents cannot be updated"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.OpenUpdate(bytes.NewReader(orig))
+			pdf.SetFormValue("missing", "")
+		}, "form field missing does not exist"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.SetFormValue("name", "")
+		}, "only be set when updating"},
+		{func(pdf *gofpdf.Fpdf) {
+			pdf.OpenUpdate(bytes.NewReader(orig))
+			pdf.Bookmark("Outline", 0, 0)
+		}, "bookmarks cannot be used when updating a document"},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		c.fn(pdf)
+		pdf.Close()
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
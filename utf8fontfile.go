@@ -55,6 +55,9 @@ type utf8FontFile struct {
 	DefaultWidth         float64
 	symbolData           map[int]map[string][]int
 	CodeSymbolDictionary map[int]int
+	kerning              *kerningType
+	cffFont              *cffFontType
+	shapedGlyphs         map[int]int
 }
 
 type tableDescription struct {
@@ -102,17 +105,21 @@ func (utf *utf8FontFile) parseFile() error {
 	utf.Ascent = 0
 	utf.Descent = 0
 	codeType := uint32(utf.readUint32())
-	if codeType == 0x4F54544F {
-		return fmt.Errorf("not supported\n ")
-	}
+	cff := codeType == 0x4F54544F // OpenType font with PostScript outlines
 	if codeType == 0x74746366 {
 		return fmt.Errorf("not supported\n ")
 	}
-	if codeType != 0x00010000 && codeType != 0x74727565 {
+	if codeType != 0x00010000 && codeType != 0x74727565 && !cff {
 		return fmt.Errorf("Not a TrueType font: codeType=%v\n ", codeType)
 	}
 	utf.generateTableDescriptions()
 	utf.parseTables()
+	if cff {
+		var err error
+		if utf.cffFont, err = parseCFF(utf.getTableData("CFF ")); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -489,9 +496,12 @@ func (utf *utf8FontFile) parseTables() {
 	symbolCharDictionary := make(map[int][]int)
 	charSymbolDictionary := make(map[int]int)
 	utf.generateSCCSDictionaries(runeCMAPPosition, symbolCharDictionary, charSymbolDictionary)
+	utf.charSymbolDictionary = charSymbolDictionary
+	utf.parseShaping(symbolCharDictionary)
 
 	scale := 1000.0 / float64(utf.fontElementSize)
 	utf.parseHMTXTable(n, numSymbols, symbolCharDictionary, scale)
+	utf.parseKerning()
 }
 
 func (utf *utf8FontFile) generateCMAP() map[int][]int {
@@ -635,8 +645,12 @@ func (utf *utf8FontFile) generateCMAPTable(cidSymbolPairCollection map[int]int,
 	return cmapstr
 }
 
-// GenerateCutFont fill utf8FontFile from .utf file, only with runes from usedRunes
+// GenerateCutFont fill utf8FontFile from .utf file, only with runes from usedRunes.
+// The presentation forms of shaped Arabic text keep the glyphs selected by GSUB.
 func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
+	if utf.cffFont != nil {
+		return utf.generateCutCFF(usedRunes)
+	}
 	utf.fileReader.readerPosition = 0
 	utf.symbolPosition = make([]int, 0)
 	utf.charSymbolDictionary = make(map[int]int)
@@ -665,6 +679,7 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 	if symbolCharDictionary == nil {
 		return nil
 	}
+	utf.addShapedGlyphs(symbolCharDictionary)
 
 	utf.parseHMTXTable(metricsCount, numSymbols, symbolCharDictionary, 1.0)
 
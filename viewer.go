@@ -0,0 +1,261 @@
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ViewerPreferences specifies the way the document is to be presented on
+// the screen or in print. It is passed to SetViewerPreferences. The zero
+// value leaves every preference to the viewer.
+type ViewerPreferences struct {
+	HideToolbar       bool   // hide the viewer tool bars
+	HideMenubar       bool   // hide the viewer menu bar
+	HideWindowUI      bool   // hide the scroll bars and navigation controls
+	FitWindow         bool   // resize the window to fit the first page
+	CenterWindow      bool   // center the window on the screen
+	DisplayDocTitle   bool   // show the document title rather than the file name
+	Direction         string // reading order: "L2R" or "R2L"
+	PrintScaling      string // print dialog scaling: "AppDefault" or "None"
+	Duplex            string // "Simplex", "DuplexFlipShortEdge" or "DuplexFlipLongEdge"
+	PickTrayByPDFSize bool   // choose the paper tray from the page size
+	NumCopies         int    // number of copies in the print dialog
+	PrintPageRange    []int  // pairs of first and last pages to print, 1-based
+}
+
+// openActionType holds the destination displayed when the document is
+// opened
+type openActionType struct {
+	page   int       // 1-based page number, 0 if not set
+	fitStr string    // destination type
+	params []float64 // destination parameters in user units
+}
+
+// destParamCount maps the destination types to their number of parameters
+var destParamCount = map[string]int{
+	"XYZ":   3,
+	"Fit":   0,
+	"FitH":  1,
+	"FitV":  1,
+	"FitR":  4,
+	"FitB":  0,
+	"FitBH": 1,
+	"FitBV": 1,
+}
+
+// SetViewerPreferences specifies how the document viewer presents the
+// document window and its print dialog. Preferences that require a later
+// PDF version than the current one raise the version of the document.
+func (f *Fpdf) SetViewerPreferences(prefs ViewerPreferences) {
+	if f.err != nil {
+		return
+	}
+	switch prefs.Direction {
+	case "", "L2R", "R2L":
+	default:
+		f.err = fmt.Errorf("incorrect viewer reading direction: %s", prefs.Direction)
+		return
+	}
+	switch prefs.PrintScaling {
+	case "", "AppDefault", "None":
+	default:
+		f.err = fmt.Errorf("incorrect viewer print scaling: %s", prefs.PrintScaling)
+		return
+	}
+	switch prefs.Duplex {
+	case "", "Simplex", "DuplexFlipShortEdge", "DuplexFlipLongEdge":
+	default:
+		f.err = fmt.Errorf("incorrect viewer duplex mode: %s", prefs.Duplex)
+		return
+	}
+	if prefs.NumCopies < 0 {
+		f.err = fmt.Errorf("incorrect viewer number of copies: %d", prefs.NumCopies)
+		return
+	}
+	if len(prefs.PrintPageRange)%2 != 0 {
+		f.err = fmt.Errorf("viewer print page range must contain pairs of pages")
+		return
+	}
+	for j := 0; j < len(prefs.PrintPageRange); j += 2 {
+		first, last := prefs.PrintPageRange[j], prefs.PrintPageRange[j+1]
+		if first < 1 || last < first {
+			f.err = fmt.Errorf("incorrect viewer print page range: %d-%d", first, last)
+			return
+		}
+	}
+	if prefs.PrintScaling != "" && f.pdfVersion < "1.6" {
+		f.pdfVersion = "1.6"
+	}
+	if (prefs.Duplex != "" || prefs.PickTrayByPDFSize || prefs.NumCopies > 0 ||
+		len(prefs.PrintPageRange) > 0) && f.pdfVersion < "1.7" {
+		f.pdfVersion = "1.7"
+	}
+	f.viewerPrefs = prefs
+}
+
+// SetPageMode specifies how the document is displayed when opened. modeStr
+// can be "UseNone" to show neither the outline nor thumbnails,
+// "UseOutlines" to show the document outline (bookmarks), "UseThumbs" to
+// show page thumbnails, "FullScreen" for full-screen mode, "UseOC" to show
+// the optional content (layer) panel or "UseAttachments" to show the
+// attachments panel. An empty string restores the default behavior, which
+// shows the outline if the document has bookmarks and the layer panel if
+// OpenLayerPane has been called.
+func (f *Fpdf) SetPageMode(modeStr string) {
+	if f.err != nil {
+		return
+	}
+	switch modeStr {
+	case "", "UseNone", "UseOutlines", "UseThumbs", "FullScreen":
+	case "UseOC":
+		if f.pdfVersion < "1.5" {
+			f.pdfVersion = "1.5"
+		}
+	case "UseAttachments":
+		if f.pdfVersion < "1.6" {
+			f.pdfVersion = "1.6"
+		}
+	default:
+		f.err = fmt.Errorf("incorrect page mode: %s", modeStr)
+		return
+	}
+	f.pageMode = modeStr
+}
+
+// SetOpenAction specifies the page and the view displayed when the
+// document is opened. It takes precedence over the zoom mode set with
+// SetDisplayMode. pageNum is 1-based and must refer to a page of the
+// completed document. fitStr is the destination type, with its parameters
+// given in params in the units established in New(), measured from the
+// upper-left corner of the page:
+//
+//	"XYZ"   left, top, zoom: position (left, top) at the upper-left corner of
+//	        the window, magnified by zoom (1 for 100%; 0 keeps the current
+//	        magnification)
+//	"Fit"   fit the entire page in the window
+//	"FitH"  top: fit the width of the page, with top at the top of the window
+//	"FitV"  left: fit the height of the page, with left at the window edge
+//	"FitR"  left, top, right, bottom: fit the rectangle in the window
+//	"FitB"  fit the bounding box of the page contents in the window
+//	"FitBH" top: as "FitH", using the bounding box of the contents
+//	"FitBV" left: as "FitV", using the bounding box of the contents
+func (f *Fpdf) SetOpenAction(pageNum int, fitStr string, params ...float64) {
+	if f.err != nil {
+		return
+	}
+	if pageNum < 1 {
+		f.err = fmt.Errorf("incorrect open action page: %d", pageNum)
+		return
+	}
+	count, ok := destParamCount[fitStr]
+	if !ok {
+		f.err = fmt.Errorf("incorrect open action destination type: %s", fitStr)
+		return
+	}
+	if len(params) != count {
+		f.err = fmt.Errorf("open action destination %s requires %d parameters", fitStr, count)
+		return
+	}
+	f.openAction = openActionType{page: pageNum, fitStr: fitStr, params: params}
+}
+
+// destArray returns an explicit destination array for the specified page;
+// params are given in user units from the upper-left corner of the page
+func (f *Fpdf) destArray(pageNum int, fitStr string, params []float64) string {
+	_, ht, _ := f.PageSize(pageNum)
+	x := func(v float64) string { return sprintf("%.2f", v*f.k) }
+	y := func(v float64) string { return sprintf("%.2f", (ht-v)*f.k) }
+	s := sprintf("[%d 0 R /%s", f.pageObj(pageNum), fitStr)
+	switch fitStr {
+	case "XYZ":
+		zoom := "null"
+		if params[2] != 0 {
+			zoom = sprintf("%.2f", params[2])
+		}
+		s += " " + x(params[0]) + " " + y(params[1]) + " " + zoom
+	case "FitH", "FitBH":
+		s += " " + y(params[0])
+	case "FitV", "FitBV":
+		s += " " + x(params[0])
+	case "FitR":
+		s += " " + x(params[0]) + " " + y(params[3]) + " " + x(params[2]) + " " + y(params[1])
+	}
+	return s + "]"
+}
+
+// viewerEndDoc checks that the open action refers to an existing page
+func (f *Fpdf) viewerEndDoc() {
+	if f.openAction.page > f.page {
+		f.err = fmt.Errorf("open action page %d does not exist", f.openAction.page)
+	}
+}
+
+// viewerPutOpenAction writes the destination displayed when the document
+// is opened
+func (f *Fpdf) viewerPutOpenAction() {
+	if f.openAction.page > 0 {
+		f.outf("/OpenAction %s", f.destArray(f.openAction.page, f.openAction.fitStr, f.openAction.params))
+		return
+	}
+	switch f.zoomMode {
+	case "fullpage":
+		f.outf("/OpenAction [%d 0 R /Fit]", f.pageObj(1))
+	case "fullwidth":
+		f.outf("/OpenAction [%d 0 R /FitH null]", f.pageObj(1))
+	case "real":
+		f.outf("/OpenAction [%d 0 R /XYZ null null 1]", f.pageObj(1))
+	}
+}
+
+// viewerPutCatalog writes the page mode and the viewer preferences of the
+// document catalog
+func (f *Fpdf) viewerPutCatalog() {
+	mode := f.pageMode
+	if mode == "" {
+		if len(f.layer.list) > 0 && f.layer.openLayerPane {
+			mode = "UseOC"
+		} else if len(f.outlines) > 0 {
+			mode = "UseOutlines"
+		}
+	}
+	if mode != "" {
+		f.out("/PageMode /" + mode)
+	}
+	p := f.viewerPrefs
+	var entries []string
+	flag := func(set bool, key string) {
+		if set {
+			entries = append(entries, "/"+key+" true")
+		}
+	}
+	flag(p.HideToolbar, "HideToolbar")
+	flag(p.HideMenubar, "HideMenubar")
+	flag(p.HideWindowUI, "HideWindowUI")
+	flag(p.FitWindow, "FitWindow")
+	flag(p.CenterWindow, "CenterWindow")
+	// PDF/UA requires the title to be displayed
+	flag(p.DisplayDocTitle || f.tag.ua, "DisplayDocTitle")
+	if p.Direction != "" {
+		entries = append(entries, "/Direction /"+p.Direction)
+	}
+	if p.PrintScaling != "" {
+		entries = append(entries, "/PrintScaling /"+p.PrintScaling)
+	}
+	if p.Duplex != "" {
+		entries = append(entries, "/Duplex /"+p.Duplex)
+	}
+	flag(p.PickTrayByPDFSize, "PickTrayByPDFSize")
+	if len(p.PrintPageRange) > 0 {
+		var pages []string
+		for _, n := range p.PrintPageRange {
+			pages = append(pages, sprintf("%d", n))
+		}
+		entries = append(entries, "/PrintPageRange ["+strings.Join(pages, " ")+"]")
+	}
+	if p.NumCopies > 0 {
+		entries = append(entries, sprintf("/NumCopies %d", p.NumCopies))
+	}
+	if len(entries) > 0 {
+		f.outf("/ViewerPreferences <<%s>>", strings.Join(entries, " "))
+	}
+}
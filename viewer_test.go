@@ -0,0 +1,100 @@
+package gofpdf_test
+
+import (
+	"strings"
+	"testing"
+
+	gofpdf "github.com/looksocial/gofpdf"
+)
+
+func TestSetViewerPreferences(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetViewerPreferences(gofpdf.ViewerPreferences{
+		HideToolbar:     true,
+		FitWindow:       true,
+		DisplayDocTitle: true,
+		Direction:       "R2L",
+		PrintScaling:    "None",
+		Duplex:          "DuplexFlipLongEdge",
+		NumCopies:       2,
+		PrintPageRange:  []int{1, 1, 3, 4},
+	})
+	pdf.SetPageMode("FullScreen")
+	pdf.AddPage()
+	pdf.Bookmark("Start", 0, 0)
+	pdf.AddPage()
+	pdf.SetOpenAction(2, "XYZ", 10, 20, 1.5)
+	s := string(formOutput(t, pdf))
+	for _, want := range []string{
+		"%PDF-1.7",
+		"/ViewerPreferences <</HideToolbar true /FitWindow true /DisplayDocTitle true " +
+			"/Direction /R2L /PrintScaling /None /Duplex /DuplexFlipLongEdge " +
+			"/PrintPageRange [1 1 3 4] /NumCopies 2>>",
+		"/PageMode /FullScreen",
+		"/OpenAction [5 0 R /XYZ 28.35 785.20 1.50]",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(s, "/PageMode /UseOutlines") {
+		t.Errorf("explicit page mode not honored")
+	}
+}
+
+func TestSetOpenAction(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetCompression(false)
+	pdf.SetDisplayMode("fullpage", "")
+	pdf.AddPage()
+	pdf.SetOpenAction(1, "FitR", 10, 20, 110, 70)
+	s := string(formOutput(t, pdf))
+	if !strings.Contains(s, "/OpenAction [3 0 R /FitR 10.00 722.00 110.00 772.00]") {
+		t.Errorf("open action missing")
+	}
+	if strings.Contains(s, "/Fit]") {
+		t.Errorf("zoom mode open action not overridden")
+	}
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.Bookmark("Start", 0, 0)
+	pdf.AddPage()
+	pdf.Bookmark("Start", 0, 0)
+	s = string(formOutput(t, pdf))
+	if strings.Count(s, "/PageMode /UseOutlines") != 1 {
+		t.Errorf("default page mode missing")
+	}
+}
+
+func TestViewerErrors(t *testing.T) {
+	for _, fn := range []func(pdf *gofpdf.Fpdf){
+		func(pdf *gofpdf.Fpdf) { pdf.SetPageMode("UseNothing") },
+		func(pdf *gofpdf.Fpdf) { pdf.SetViewerPreferences(gofpdf.ViewerPreferences{Duplex: "Both"}) },
+		func(pdf *gofpdf.Fpdf) { pdf.SetViewerPreferences(gofpdf.ViewerPreferences{PrintPageRange: []int{3}}) },
+		func(pdf *gofpdf.Fpdf) { pdf.SetOpenAction(1, "FitH") },
+		func(pdf *gofpdf.Fpdf) { pdf.SetOpenAction(1, "Zoom") },
+		func(pdf *gofpdf.Fpdf) { pdf.SetOpenAction(2, "Fit") },
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		fn(pdf)
+		pdf.Close()
+		if !pdf.Err() {
+			t.Errorf("expected error")
+		}
+	}
+}
+
+func TestViewerPreferencesPDFUA(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetPDFUA(true)
+	pdf.SetTitle("Report", true)
+	pdf.SetViewerPreferences(gofpdf.ViewerPreferences{HideMenubar: true})
+	pdf.AddPage()
+	s := string(formOutput(t, pdf))
+	if strings.Count(s, "/ViewerPreferences") != 1 ||
+		!strings.Contains(s, "/ViewerPreferences <</HideMenubar true /DisplayDocTitle true>>") {
+		t.Errorf("viewer preferences not merged with PDF/UA requirements")
+	}
+}